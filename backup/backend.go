@@ -0,0 +1,61 @@
+package backup
+
+import (
+	"fmt"
+	"path/filepath"
+)
+
+// Backend is a destination that backup files can be stored in and retrieved
+// from.
+type Backend interface {
+	// Store stores the data under the given file name, overwriting any
+	// existing file with the same name.
+	Store(data []byte, filename string) error
+
+	// Retrieve returns the data stored under the given file name.
+	Retrieve(filename string) ([]byte, error)
+}
+
+// MultiBackend stores backups in multiple backends at the same time.
+type MultiBackend struct {
+	Backends []Backend
+}
+
+// Store stores the data in all backends. All backends are tried even if one of
+// them fails.
+func (m *MultiBackend) Store(data []byte, filename string) error {
+	var firstErr error
+	for idx, backend := range m.Backends {
+		err := backend.Store(data, filename)
+		if err != nil && firstErr == nil {
+			firstErr = fmt.Errorf("error storing in backend %d: %v",
+				idx, err)
+		}
+	}
+	return firstErr
+}
+
+// Retrieve returns the data from the first backend that has the file.
+func (m *MultiBackend) Retrieve(filename string) ([]byte, error) {
+	lastErr := fmt.Errorf("no backends configured")
+	for idx, backend := range m.Backends {
+		data, err := backend.Retrieve(filename)
+		if err == nil {
+			return data, nil
+		}
+		lastErr = fmt.Errorf("error retrieving from backend %d: %v",
+			idx, err)
+	}
+	return nil, lastErr
+}
+
+// checkFilename makes sure the file name doesn't contain a path so backends
+// can't be tricked into writing outside of their target location.
+func checkFilename(filename string) error {
+	if filename == "" || filename != filepath.Base(filename) ||
+		filename == "." || filename == ".." {
+
+		return fmt.Errorf("invalid backup file name: %s", filename)
+	}
+	return nil
+}
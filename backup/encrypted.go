@@ -0,0 +1,75 @@
+package backup
+
+import (
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"fmt"
+
+	"github.com/lightningnetwork/lnd/keychain"
+	"golang.org/x/crypto/chacha20poly1305"
+)
+
+// EncryptedBackup is a backend that encrypts all backups before delegating
+// them to another backend. The encryption key is derived from the node
+// identity key the same way lnd derives the channel backup key from the
+// static backup key: It's the SHA256 of the compressed public key. The stored
+// data is the random nonce followed by the XChaCha20-Poly1305 ciphertext.
+type EncryptedBackup struct {
+	// Backend is the backend the encrypted data is stored in.
+	Backend Backend
+
+	// KeyRing is used to derive the node identity key.
+	KeyRing keychain.KeyRing
+}
+
+func (e *EncryptedBackup) Store(data []byte, filename string) error {
+	aead, err := e.aead()
+	if err != nil {
+		return err
+	}
+
+	nonce := make([]byte, chacha20poly1305.NonceSizeX)
+	if _, err := rand.Read(nonce); err != nil {
+		return fmt.Errorf("error creating nonce: %v", err)
+	}
+	ciphertext := aead.Seal(nonce, nonce, data, nonce)
+	return e.Backend.Store(ciphertext, filename)
+}
+
+func (e *EncryptedBackup) Retrieve(filename string) ([]byte, error) {
+	ciphertext, err := e.Backend.Retrieve(filename)
+	if err != nil {
+		return nil, err
+	}
+	if len(ciphertext) < chacha20poly1305.NonceSizeX {
+		return nil, fmt.Errorf("encrypted backup too short")
+	}
+
+	aead, err := e.aead()
+	if err != nil {
+		return nil, err
+	}
+	nonce := ciphertext[:chacha20poly1305.NonceSizeX]
+	plaintext, err := aead.Open(
+		nil, nonce, ciphertext[chacha20poly1305.NonceSizeX:], nonce,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("error decrypting backup: %v", err)
+	}
+	return plaintext, nil
+}
+
+func (e *EncryptedBackup) aead() (cipher.AEAD, error) {
+	identityKey, err := e.KeyRing.DeriveKey(keychain.KeyLocator{
+		Family: keychain.KeyFamilyNodeKey,
+		Index:  0,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error deriving identity key: %v", err)
+	}
+	encryptionKey := sha256.Sum256(
+		identityKey.PubKey.SerializeCompressed(),
+	)
+	return chacha20poly1305.NewX(encryptionKey[:])
+}
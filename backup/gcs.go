@@ -0,0 +1,75 @@
+package backup
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/url"
+)
+
+const (
+	defaultGCSEndpoint = "https://storage.googleapis.com"
+)
+
+// GCS is a backend that stores backups in a Google Cloud Storage bucket using
+// the JSON API.
+type GCS struct {
+	// Endpoint is the base URL of the API. If empty, the Google endpoint is
+	// used.
+	Endpoint string
+
+	// Bucket is the name of the bucket.
+	Bucket string
+
+	// Prefix is prepended to all object names.
+	Prefix string
+
+	// AccessToken is the OAuth2 access token to authenticate with, for
+	// example the output of "gcloud auth print-access-token".
+	AccessToken string
+
+	// Client is the HTTP client to use. If nil, the default client is
+	// used.
+	Client *http.Client
+}
+
+func (g *GCS) Store(data []byte, filename string) error {
+	if err := checkFilename(filename); err != nil {
+		return err
+	}
+	reqURL := fmt.Sprintf("%s/upload/storage/v1/b/%s/o?uploadType=media&"+
+		"name=%s", g.endpoint(), url.PathEscape(g.Bucket),
+		url.QueryEscape(g.Prefix+filename))
+	req, err := http.NewRequest(
+		http.MethodPost, reqURL, bytes.NewReader(data),
+	)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/octet-stream")
+	req.Header.Set("Authorization", "Bearer "+g.AccessToken)
+	_, err = doRequest(g.Client, req)
+	return err
+}
+
+func (g *GCS) Retrieve(filename string) ([]byte, error) {
+	if err := checkFilename(filename); err != nil {
+		return nil, err
+	}
+	reqURL := fmt.Sprintf("%s/storage/v1/b/%s/o/%s?alt=media",
+		g.endpoint(), url.PathEscape(g.Bucket),
+		url.PathEscape(g.Prefix+filename))
+	req, err := http.NewRequest(http.MethodGet, reqURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+g.AccessToken)
+	return doRequest(g.Client, req)
+}
+
+func (g *GCS) endpoint() string {
+	if g.Endpoint != "" {
+		return g.Endpoint
+	}
+	return defaultGCSEndpoint
+}
@@ -0,0 +1,27 @@
+package backup
+
+import (
+	"io/ioutil"
+	"path/filepath"
+)
+
+// LocalFS is a backend that stores backups in a directory of the local file
+// system.
+type LocalFS struct {
+	// Dir is the directory to store the backups in.
+	Dir string
+}
+
+func (l *LocalFS) Store(data []byte, filename string) error {
+	if err := checkFilename(filename); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filepath.Join(l.Dir, filename), data, 0644)
+}
+
+func (l *LocalFS) Retrieve(filename string) ([]byte, error) {
+	if err := checkFilename(filename); err != nil {
+		return nil, err
+	}
+	return ioutil.ReadFile(filepath.Join(l.Dir, filename))
+}
@@ -0,0 +1,167 @@
+package backup
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	s3Service   = "s3"
+	s3Algorithm = "AWS4-HMAC-SHA256"
+)
+
+// S3 is a backend that stores backups in an Amazon S3 (or S3 compatible)
+// bucket. Requests are signed with AWS signature version 4.
+type S3 struct {
+	// Endpoint is the base URL of the S3 API. If empty, the AWS endpoint
+	// of the region is used.
+	Endpoint string
+
+	// Region is the region of the bucket, for example us-east-1.
+	Region string
+
+	// Bucket is the name of the bucket.
+	Bucket string
+
+	// Prefix is prepended to all object names.
+	Prefix string
+
+	// AccessKeyID is the ID of the access key.
+	AccessKeyID string
+
+	// SecretAccessKey is the secret of the access key.
+	SecretAccessKey string
+
+	// Client is the HTTP client to use. If nil, the default client is
+	// used.
+	Client *http.Client
+}
+
+func (s *S3) Store(data []byte, filename string) error {
+	if err := checkFilename(filename); err != nil {
+		return err
+	}
+	req, err := s.newRequest(http.MethodPut, filename, data)
+	if err != nil {
+		return err
+	}
+	_, err = doRequest(s.Client, req)
+	return err
+}
+
+func (s *S3) Retrieve(filename string) ([]byte, error) {
+	if err := checkFilename(filename); err != nil {
+		return nil, err
+	}
+	req, err := s.newRequest(http.MethodGet, filename, nil)
+	if err != nil {
+		return nil, err
+	}
+	return doRequest(s.Client, req)
+}
+
+// newRequest creates a signed path style request for the object with the
+// given file name.
+func (s *S3) newRequest(method, filename string, body []byte) (*http.Request,
+	error) {
+
+	endpoint := s.Endpoint
+	if endpoint == "" {
+		endpoint = fmt.Sprintf("https://s3.%s.amazonaws.com", s.Region)
+	}
+	baseURL, err := url.Parse(endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("invalid S3 endpoint: %v", err)
+	}
+	objectPath := strings.TrimSuffix(baseURL.Path, "/") + "/" +
+		awsURIEncode(s.Bucket) + "/" + awsURIEncode(s.Prefix+filename)
+	reqURL := fmt.Sprintf("%s://%s%s", baseURL.Scheme, baseURL.Host,
+		objectPath)
+
+	req, err := http.NewRequest(method, reqURL, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	s.sign(req, objectPath, body, time.Now().UTC())
+	return req, nil
+}
+
+// sign adds the AWS signature version 4 headers to the request.
+func (s *S3) sign(req *http.Request, canonicalURI string, body []byte,
+	now time.Time) {
+
+	var (
+		amzDate     = now.Format("20060102T150405Z")
+		dateStamp   = now.Format("20060102")
+		payloadHash = sha256Hex(body)
+		scope       = strings.Join([]string{
+			dateStamp, s.Region, s3Service, "aws4_request",
+		}, "/")
+		signedHeaders = "host;x-amz-content-sha256;x-amz-date"
+	)
+	req.Header.Set("x-amz-content-sha256", payloadHash)
+	req.Header.Set("x-amz-date", amzDate)
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		canonicalURI,
+		"",
+		"host:" + req.URL.Host + "\n" +
+			"x-amz-content-sha256:" + payloadHash + "\n" +
+			"x-amz-date:" + amzDate + "\n",
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+	stringToSign := strings.Join([]string{
+		s3Algorithm, amzDate, scope,
+		sha256Hex([]byte(canonicalRequest)),
+	}, "\n")
+
+	signingKey := hmacSHA256([]byte("AWS4"+s.SecretAccessKey), dateStamp)
+	signingKey = hmacSHA256(signingKey, s.Region)
+	signingKey = hmacSHA256(signingKey, s3Service)
+	signingKey = hmacSHA256(signingKey, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(signingKey, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf(
+		"%s Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		s3Algorithm, s.AccessKeyID, scope, signedHeaders, signature,
+	))
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	_, _ = mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+func sha256Hex(data []byte) string {
+	hash := sha256.Sum256(data)
+	return hex.EncodeToString(hash[:])
+}
+
+// awsURIEncode encodes all characters except the unreserved ones as required
+// by AWS. Slashes are kept so object names can contain directories.
+func awsURIEncode(s string) string {
+	var b strings.Builder
+	for _, c := range []byte(s) {
+		switch {
+		case c >= 'A' && c <= 'Z', c >= 'a' && c <= 'z',
+			c >= '0' && c <= '9', c == '-', c == '_', c == '.',
+			c == '~', c == '/':
+
+			b.WriteByte(c)
+
+		default:
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,84 @@
+package backup
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// SCP is a backend that copies backups to a remote host with the scp command
+// line tool. Authentication is up to the local ssh configuration.
+type SCP struct {
+	// Target is the remote directory in the format [user@]host:dir.
+	Target string
+
+	// Port is the ssh port of the remote host. The default port is used
+	// if it is zero.
+	Port uint16
+
+	// IdentityFile is the private key file to authenticate with. The
+	// default keys of the ssh configuration are used if empty.
+	IdentityFile string
+}
+
+func (s *SCP) Store(data []byte, filename string) error {
+	if err := checkFilename(filename); err != nil {
+		return err
+	}
+	tempDir, err := ioutil.TempDir("", "chantools-scp")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tempDir)
+
+	localFile := filepath.Join(tempDir, filename)
+	if err := ioutil.WriteFile(localFile, data, 0600); err != nil {
+		return err
+	}
+	return s.run(localFile, s.remotePath(filename))
+}
+
+func (s *SCP) Retrieve(filename string) ([]byte, error) {
+	if err := checkFilename(filename); err != nil {
+		return nil, err
+	}
+	tempDir, err := ioutil.TempDir("", "chantools-scp")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(tempDir)
+
+	localFile := filepath.Join(tempDir, filename)
+	if err := s.run(s.remotePath(filename), localFile); err != nil {
+		return nil, err
+	}
+	return ioutil.ReadFile(localFile)
+}
+
+func (s *SCP) remotePath(filename string) string {
+	return strings.TrimSuffix(s.Target, "/") + "/" + filename
+}
+
+func (s *SCP) run(from, to string) error {
+	// Never ask for a password, we're not attached to a terminal when
+	// running scheduled backups.
+	args := []string{"-q", "-o", "BatchMode=yes"}
+	if s.Port != 0 {
+		args = append(args, "-P", strconv.Itoa(int(s.Port)))
+	}
+	if s.IdentityFile != "" {
+		args = append(args, "-i", s.IdentityFile)
+	}
+	args = append(args, from, to)
+
+	output, err := exec.Command("scp", args...).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("error running scp: %v (%s)", err,
+			strings.TrimSpace(string(output)))
+	}
+	return nil
+}
@@ -0,0 +1,129 @@
+package bip39
+
+import (
+	"crypto/sha256"
+	"crypto/sha512"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"golang.org/x/crypto/pbkdf2"
+)
+
+const (
+	// seedIterations is the number of PBKDF2 rounds used to derive the
+	// seed from a mnemonic as defined in BIP39.
+	seedIterations = 2048
+
+	// SeedSize is the size in bytes of the seed derived from a mnemonic.
+	SeedSize = 64
+
+	// bitsPerWord is the number of bits that are encoded in each word.
+	bitsPerWord = 11
+)
+
+var (
+	// ErrInvalidMnemonic is returned if a mnemonic contains an invalid
+	// number of words or a word that is not part of the word list.
+	ErrInvalidMnemonic = errors.New("invalid mnemonic")
+
+	// ErrChecksumIncorrect is returned if the checksum of a mnemonic does
+	// not match its entropy.
+	ErrChecksumIncorrect = errors.New("checksum incorrect")
+)
+
+// NewMnemonic encodes the given entropy as a mnemonic sentence. The entropy
+// must be between 128 and 256 bits long and a multiple of 32 bits.
+func NewMnemonic(entropy []byte) (string, error) {
+	entropyBits := len(entropy) * 8
+	if entropyBits < 128 || entropyBits > 256 || entropyBits%32 != 0 {
+		return "", fmt.Errorf("invalid entropy length %d", len(entropy))
+	}
+	checksumBits := entropyBits / 32
+	numWords := (entropyBits + checksumBits) / bitsPerWord
+
+	// Append the first bits of the SHA256 hash of the entropy as the
+	// checksum.
+	hash := sha256.Sum256(entropy)
+	data := new(big.Int).SetBytes(entropy)
+	data.Lsh(data, uint(checksumBits))
+	data.Or(data, big.NewInt(int64(hash[0]>>(8-uint(checksumBits)))))
+
+	// Now split everything into 11 bit chunks, starting with the last
+	// word.
+	words := make([]string, numWords)
+	mask := big.NewInt(1<<bitsPerWord - 1)
+	word := new(big.Int)
+	for i := numWords - 1; i >= 0; i-- {
+		word.And(data, mask)
+		data.Rsh(data, bitsPerWord)
+		words[i] = englishWordList[word.Int64()]
+	}
+	return strings.Join(words, " "), nil
+}
+
+// EntropyFromMnemonic decodes the given mnemonic sentence and returns the
+// entropy it encodes after verifying its checksum.
+func EntropyFromMnemonic(mnemonic string) ([]byte, error) {
+	words := strings.Fields(mnemonic)
+	numWords := len(words)
+	if numWords < 12 || numWords > 24 || numWords%3 != 0 {
+		return nil, ErrInvalidMnemonic
+	}
+
+	data := new(big.Int)
+	for _, word := range words {
+		index, ok := reverseWordMap[word]
+		if !ok {
+			return nil, ErrInvalidMnemonic
+		}
+		data.Lsh(data, bitsPerWord)
+		data.Or(data, big.NewInt(int64(index)))
+	}
+
+	// Separate the checksum from the entropy bits.
+	checksumBits := numWords * bitsPerWord / 33
+	entropyBytes := (numWords*bitsPerWord - checksumBits) / 8
+	checksum := new(big.Int).And(
+		data, big.NewInt(int64(1<<uint(checksumBits)-1)),
+	)
+	data.Rsh(data, uint(checksumBits))
+
+	entropy := make([]byte, entropyBytes)
+	dataBytes := data.Bytes()
+	copy(entropy[entropyBytes-len(dataBytes):], dataBytes)
+
+	hash := sha256.Sum256(entropy)
+	expected := int64(hash[0] >> (8 - uint(checksumBits)))
+	if checksum.Int64() != expected {
+		return nil, ErrChecksumIncorrect
+	}
+	return entropy, nil
+}
+
+// IsMnemonicValid returns true if the given mnemonic consists of words of the
+// English word list and has a valid checksum.
+func IsMnemonicValid(mnemonic string) bool {
+	_, err := EntropyFromMnemonic(mnemonic)
+	return err == nil
+}
+
+// NewSeed derives the 64 byte seed from a mnemonic sentence and an optional
+// passphrase as defined in BIP39. The mnemonic is not validated.
+func NewSeed(mnemonic, passphrase string) []byte {
+	return pbkdf2.Key(
+		[]byte(strings.Join(strings.Fields(mnemonic), " ")),
+		[]byte("mnemonic"+passphrase), seedIterations, SeedSize,
+		sha512.New,
+	)
+}
+
+// NewSeedWithErrorChecking derives the 64 byte seed from a mnemonic sentence
+// after making sure the mnemonic is valid.
+func NewSeedWithErrorChecking(mnemonic, passphrase string) ([]byte, error) {
+	if _, err := EntropyFromMnemonic(mnemonic); err != nil {
+		return nil, err
+	}
+	return NewSeed(mnemonic, passphrase), nil
+}
@@ -0,0 +1,131 @@
+package bip85
+
+import (
+	"crypto/hmac"
+	"crypto/sha512"
+	"errors"
+	"fmt"
+
+	"github.com/btcsuite/btcd/btcec"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcutil"
+	"github.com/btcsuite/btcutil/hdkeychain"
+	"github.com/guggero/chantools/bip39"
+	"github.com/guggero/chantools/lnd"
+)
+
+const (
+	// Purpose is the purpose of the BIP32 derivation path that all BIP85
+	// child entropy is derived from.
+	Purpose = 83696968
+
+	// AppBIP39 is the application number of BIP39 mnemonics.
+	AppBIP39 = 39
+
+	// AppWIF is the application number of WIF encoded private keys.
+	AppWIF = 2
+
+	// AppHex is the application number of raw entropy.
+	AppHex = 128169
+
+	// LanguageEnglish is the language number of the English BIP39 word
+	// list, the only one that is supported.
+	LanguageEnglish = 0
+
+	// MinHexBytes and MaxHexBytes are the bounds of the number of bytes of
+	// raw entropy that can be derived.
+	MinHexBytes = 16
+	MaxHexBytes = 64
+)
+
+var (
+	// hmacKey is the key of the HMAC that turns a derived private key into
+	// entropy.
+	hmacKey = []byte("bip-entropy-from-k")
+
+	// ErrInvalidWords is returned if a mnemonic with an unsupported number
+	// of words is requested.
+	ErrInvalidWords = errors.New("number of words must be 12, 18 or 24")
+)
+
+// DeriveEntropy derives the 64 bytes of entropy of the given application path
+// below the BIP85 purpose. All indexes of the path are hardened.
+func DeriveEntropy(rootKey *hdkeychain.ExtendedKey, appPath ...uint32) ([]byte,
+	error) {
+
+	path := []uint32{lnd.HardenedKeyStart + Purpose}
+	for _, index := range appPath {
+		path = append(path, lnd.HardenedKeyStart+index)
+	}
+	derivedKey, err := lnd.DeriveChildren(rootKey, path)
+	if err != nil {
+		return nil, fmt.Errorf("could not derive key: %v", err)
+	}
+	privKey, err := derivedKey.ECPrivKey()
+	if err != nil {
+		return nil, fmt.Errorf("could not derive private key: %v", err)
+	}
+
+	mac := hmac.New(sha512.New, hmacKey)
+	_, _ = mac.Write(privKey.Serialize())
+	return mac.Sum(nil), nil
+}
+
+// DeriveBIP39 derives the English BIP39 mnemonic with the given number of
+// words and index.
+func DeriveBIP39(rootKey *hdkeychain.ExtendedKey, words, index uint32) (string,
+	error) {
+
+	var entropyLen int
+	switch words {
+	case 12:
+		entropyLen = 16
+
+	case 18:
+		entropyLen = 24
+
+	case 24:
+		entropyLen = 32
+
+	default:
+		return "", ErrInvalidWords
+	}
+
+	entropy, err := DeriveEntropy(
+		rootKey, AppBIP39, LanguageEnglish, words, index,
+	)
+	if err != nil {
+		return "", err
+	}
+	return bip39.NewMnemonic(entropy[:entropyLen])
+}
+
+// DeriveWIF derives the compressed WIF encoded private key with the given
+// index.
+func DeriveWIF(rootKey *hdkeychain.ExtendedKey, index uint32,
+	params *chaincfg.Params) (*btcutil.WIF, error) {
+
+	entropy, err := DeriveEntropy(rootKey, AppWIF, index)
+	if err != nil {
+		return nil, err
+	}
+	privKey, _ := btcec.PrivKeyFromBytes(btcec.S256(), entropy[:32])
+	return btcutil.NewWIF(privKey, params, true)
+}
+
+// DeriveHex derives the given number of bytes of raw entropy with the given
+// index.
+func DeriveHex(rootKey *hdkeychain.ExtendedKey, numBytes,
+	index uint32) ([]byte, error) {
+
+	if numBytes < MinHexBytes || numBytes > MaxHexBytes {
+		return nil, fmt.Errorf("number of bytes must be between %d "+
+			"and %d", MinHexBytes, MaxHexBytes)
+	}
+
+	entropy, err := DeriveEntropy(rootKey, AppHex, numBytes, index)
+	if err != nil {
+		return nil, err
+	}
+	return entropy[:numBytes], nil
+}
@@ -0,0 +1,131 @@
+package bip85
+
+import (
+	"encoding/hex"
+	"testing"
+
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcutil/hdkeychain"
+)
+
+// testMasterKey is the master key of all test vectors of BIP85.
+const testMasterKey = "xprv9s21ZrQH143K2LBWUUQRFXhucrQqBpKdRRxNVq2zBqsx8HVqF" +
+	"k2uYo8kmbaLLHRdqtQpUm98uKfu3vca1LqdGhUtyoFnCNkfmXRyPXLjbKb"
+
+func testRootKey(t *testing.T) *hdkeychain.ExtendedKey {
+	rootKey, err := hdkeychain.NewKeyFromString(testMasterKey)
+	if err != nil {
+		t.Fatalf("error parsing master key: %v", err)
+	}
+	return rootKey
+}
+
+// TestDeriveEntropy tests the derivation of the raw entropy with the test cases
+// of BIP85.
+func TestDeriveEntropy(t *testing.T) {
+	testCases := []struct {
+		appPath  []uint32
+		expected string
+	}{{
+		appPath: []uint32{0, 0},
+		expected: "efecfbccffea313214232d29e71563d9" +
+			"41229afb4338c21f9517c41aaa0d16f00b83d2a09ef747e7" +
+			"a64e8e2bd5a14869e693da66ce94ac2da570ab7ee48618f7",
+	}, {
+		appPath: []uint32{0, 1},
+		expected: "70c6e3e8ebee8dc4c0dbba66076819bb" +
+			"8c09672527c4277ca8729532ad711872218f826919f6b672" +
+			"18adde99018a6df9095ab2b58d803b5b93ec9802085a690e",
+	}}
+
+	rootKey := testRootKey(t)
+	for _, tc := range testCases {
+		entropy, err := DeriveEntropy(rootKey, tc.appPath...)
+		if err != nil {
+			t.Fatalf("error deriving entropy of %v: %v", tc.appPath,
+				err)
+		}
+		if hex.EncodeToString(entropy) != tc.expected {
+			t.Fatalf("unexpected entropy of %v, got %x wanted %s",
+				tc.appPath, entropy, tc.expected)
+		}
+	}
+}
+
+// TestDeriveBIP39 tests the derivation of English BIP39 mnemonics with the
+// test vectors of BIP85.
+func TestDeriveBIP39(t *testing.T) {
+	testCases := []struct {
+		words    uint32
+		expected string
+	}{{
+		words: 12,
+		expected: "girl mad pet galaxy egg matter matrix " +
+			"prison refuse sense ordinary nose",
+	}, {
+		words: 18,
+		expected: "near account window bike charge season " +
+			"chef number sketch tomorrow excuse sniff circle " +
+			"vital hockey outdoor supply token",
+	}, {
+		words: 24,
+		expected: "puppy ocean match cereal symbol another " +
+			"shed magic wrap hammer bulb intact gadget divorce " +
+			"twin tonight reason outdoor destroy simple truth " +
+			"cigar social volcano",
+	}}
+
+	rootKey := testRootKey(t)
+	for _, tc := range testCases {
+		mnemonic, err := DeriveBIP39(rootKey, tc.words, 0)
+		if err != nil {
+			t.Fatalf("error deriving %d word mnemonic: %v",
+				tc.words, err)
+		}
+		if mnemonic != tc.expected {
+			t.Fatalf("unexpected %d word mnemonic, got '%s' "+
+				"wanted '%s'", tc.words, mnemonic, tc.expected)
+		}
+	}
+
+	if _, err := DeriveBIP39(rootKey, 15, 0); err != ErrInvalidWords {
+		t.Fatalf("expected error %v, got %v", ErrInvalidWords, err)
+	}
+}
+
+// TestDeriveWIF tests the derivation of a WIF encoded private key with the
+// test vector of BIP85.
+func TestDeriveWIF(t *testing.T) {
+	const expected = "Kzyv4uF39d4Jrw2W7UryTHwZr1zQVNk4dAFyqE6BuMrMh1Za7uhp"
+
+	wif, err := DeriveWIF(testRootKey(t), 0, &chaincfg.MainNetParams)
+	if err != nil {
+		t.Fatalf("error deriving WIF: %v", err)
+	}
+	if wif.String() != expected {
+		t.Fatalf("unexpected WIF, got %s wanted %s", wif, expected)
+	}
+}
+
+// TestDeriveHex tests the derivation of raw entropy with the test vector of
+// BIP85.
+func TestDeriveHex(t *testing.T) {
+	const expected = "492db4698cf3b73a5a24998aa3e9d7fa" +
+		"96275d85724a91e71aa2d645442f878555d078fd1f1f67e3" +
+		"68976f04137b1f7a0d19232136ca50c44614af72b5582a5c"
+
+	rootKey := testRootKey(t)
+	entropy, err := DeriveHex(rootKey, 64, 0)
+	if err != nil {
+		t.Fatalf("error deriving hex: %v", err)
+	}
+	if hex.EncodeToString(entropy) != expected {
+		t.Fatalf("unexpected hex, got %x wanted %s", entropy, expected)
+	}
+
+	for _, numBytes := range []uint32{MinHexBytes - 1, MaxHexBytes + 1} {
+		if _, err := DeriveHex(rootKey, numBytes, 0); err == nil {
+			t.Fatalf("expected error deriving %d bytes", numBytes)
+		}
+	}
+}
@@ -0,0 +1,263 @@
+package btc
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync/atomic"
+)
+
+// Bitcoind is a minimal JSON-RPC client for a bitcoind node.
+type Bitcoind struct {
+	// Host is the host:port of the bitcoind RPC interface.
+	Host string
+
+	// User is the RPC user name.
+	User string
+
+	// Password is the RPC password.
+	Password string
+
+	// Wallet is the name of the wallet to send wallet RPCs to. If empty,
+	// the default wallet is used.
+	Wallet string
+
+	requestID uint64
+}
+
+type rpcRequest struct {
+	JSONRPC string        `json:"jsonrpc"`
+	ID      uint64        `json:"id"`
+	Method  string        `json:"method"`
+	Params  []interface{} `json:"params"`
+}
+
+// RPCError is an error returned by bitcoind.
+type RPCError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+type rpcResponse struct {
+	Result json.RawMessage `json:"result"`
+	Error  *RPCError       `json:"error"`
+}
+
+type ImportDescriptorRequest struct {
+	Desc      string      `json:"desc"`
+	Active    bool        `json:"active"`
+	Range     [2]uint32   `json:"range"`
+	Timestamp interface{} `json:"timestamp"`
+	Internal  bool        `json:"internal"`
+	Label     string      `json:"label,omitempty"`
+}
+
+type ImportDescriptorResult struct {
+	Success  bool      `json:"success"`
+	Warnings []string  `json:"warnings"`
+	Error    *RPCError `json:"error"`
+}
+
+type WalletInfo struct {
+	WalletName         string `json:"walletname"`
+	Descriptors        bool   `json:"descriptors"`
+	PrivateKeysEnabled bool   `json:"private_keys_enabled"`
+
+	// Scanning is either false or an object containing the duration and
+	// progress of the current rescan.
+	Scanning json.RawMessage `json:"scanning"`
+}
+
+// ScanProgress returns the progress of the current wallet rescan and true if
+// a rescan is running.
+func (i *WalletInfo) ScanProgress() (float64, bool) {
+	var scanning struct {
+		Duration int64   `json:"duration"`
+		Progress float64 `json:"progress"`
+	}
+	if err := json.Unmarshal(i.Scanning, &scanning); err != nil {
+		return 0, false
+	}
+	return scanning.Progress, true
+}
+
+// MempoolAcceptResult is the result of testing a single transaction against
+// the mempool policy of bitcoind.
+type MempoolAcceptResult struct {
+	TxID         string `json:"txid"`
+	Allowed      bool   `json:"allowed"`
+	RejectReason string `json:"reject-reason"`
+}
+
+type TxOut struct {
+	BestBlock     string  `json:"bestblock"`
+	Confirmations uint32  `json:"confirmations"`
+	Value         float64 `json:"value"`
+}
+
+type BlockHeader struct {
+	Hash   string `json:"hash"`
+	Height uint32 `json:"height"`
+	Time   int64  `json:"time"`
+}
+
+type RawTransaction struct {
+	TxID          string `json:"txid"`
+	Hex           string `json:"hex"`
+	BlockHash     string `json:"blockhash"`
+	Confirmations uint32 `json:"confirmations"`
+}
+
+type Block struct {
+	Hash   string   `json:"hash"`
+	Height uint32   `json:"height"`
+	Tx     []string `json:"tx"`
+}
+
+// Call sends a single JSON-RPC request to bitcoind and decodes the result into
+// the given target.
+func (b *Bitcoind) Call(method string, target interface{},
+	params ...interface{}) error {
+
+	if params == nil {
+		params = []interface{}{}
+	}
+	reqBytes, err := json.Marshal(&rpcRequest{
+		JSONRPC: "1.0",
+		ID:      atomic.AddUint64(&b.requestID, 1),
+		Method:  method,
+		Params:  params,
+	})
+	if err != nil {
+		return err
+	}
+
+	url := fmt.Sprintf("http://%s", b.Host)
+	if b.Wallet != "" {
+		url = fmt.Sprintf("%s/wallet/%s", url, b.Wallet)
+	}
+	req, err := http.NewRequest(
+		http.MethodPost, url, bytes.NewReader(reqBytes),
+	)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.SetBasicAuth(b.User, b.Password)
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("error calling %s: %v", method, err)
+	}
+	defer resp.Body.Close()
+
+	body := new(bytes.Buffer)
+	_, err = body.ReadFrom(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode == http.StatusUnauthorized {
+		return fmt.Errorf("bitcoind RPC authentication failed")
+	}
+
+	response := &rpcResponse{}
+	err = json.Unmarshal(body.Bytes(), response)
+	if err != nil {
+		return fmt.Errorf("error decoding response of %s: %v (%s)",
+			method, err, strings.TrimSpace(body.String()))
+	}
+	if response.Error != nil {
+		return fmt.Errorf("error calling %s: %s (code %d)", method,
+			response.Error.Message, response.Error.Code)
+	}
+	if target == nil {
+		return nil
+	}
+	return json.Unmarshal(response.Result, target)
+}
+
+func (b *Bitcoind) GetBlockCount() (uint32, error) {
+	var count uint32
+	err := b.Call("getblockcount", &count)
+	return count, err
+}
+
+func (b *Bitcoind) GetBlockHeader(height uint32) (*BlockHeader, error) {
+	var hash string
+	if err := b.Call("getblockhash", &hash, height); err != nil {
+		return nil, err
+	}
+	header := &BlockHeader{}
+	if err := b.Call("getblockheader", header, hash); err != nil {
+		return nil, err
+	}
+	return header, nil
+}
+
+// GetRawTransaction returns a transaction together with the hash of the block
+// it was confirmed in. Without the transaction index of bitcoind, the block
+// hash must be known and passed in.
+func (b *Bitcoind) GetRawTransaction(txid, blockHash string) (*RawTransaction,
+	error) {
+
+	params := []interface{}{txid, true}
+	if blockHash != "" {
+		params = append(params, blockHash)
+	}
+	tx := &RawTransaction{}
+	err := b.Call("getrawtransaction", tx, params...)
+	return tx, err
+}
+
+// GetBlock returns a block with the IDs of all its transactions.
+func (b *Bitcoind) GetBlock(hash string) (*Block, error) {
+	block := &Block{}
+	err := b.Call("getblock", block, hash, 1)
+	return block, err
+}
+
+func (b *Bitcoind) GetWalletInfo() (*WalletInfo, error) {
+	info := &WalletInfo{}
+	err := b.Call("getwalletinfo", info)
+	return info, err
+}
+
+func (b *Bitcoind) ImportDescriptors(requests []*ImportDescriptorRequest) (
+	[]*ImportDescriptorResult, error) {
+
+	var results []*ImportDescriptorResult
+	err := b.Call("importdescriptors", &results, requests)
+	return results, err
+}
+
+func (b *Bitcoind) SendRawTransaction(rawTxHex string) (string, error) {
+	var txid string
+	err := b.Call("sendrawtransaction", &txid, rawTxHex)
+	return txid, err
+}
+
+func (b *Bitcoind) TestMempoolAccept(rawTxHex string) (*MempoolAcceptResult,
+	error) {
+
+	var results []*MempoolAcceptResult
+	err := b.Call("testmempoolaccept", &results, []string{rawTxHex})
+	if err != nil {
+		return nil, err
+	}
+	if len(results) != 1 {
+		return nil, fmt.Errorf("unexpected number of results: %d",
+			len(results))
+	}
+	return results[0], nil
+}
+
+// GetTxOut returns the unspent transaction output with the given outpoint,
+// taking the mempool into account. If the output is spent or doesn't exist,
+// nil is returned.
+func (b *Bitcoind) GetTxOut(txid string, vout uint32) (*TxOut, error) {
+	var txOut *TxOut
+	err := b.Call("gettxout", &txOut, txid, vout, true)
+	return txOut, err
+}
@@ -0,0 +1,99 @@
+package btc
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	// descriptorInputCharset are all characters that are allowed in an
+	// output descriptor, ordered so that the checksum catches case errors
+	// and the most common confusions.
+	descriptorInputCharset = "0123456789()[],'/*abcdefgh@:$%{}" +
+		"IJKLMNOPQRSTUVWXYZ&+-.;<=>?!^_|~" +
+		"ijklmnopqrstuvwxyzABCDEFGH`#\"\\ "
+
+	// descriptorChecksumCharset is the character set the checksum is
+	// encoded in, which is the same as the one used by bech32.
+	descriptorChecksumCharset = "qpzry9x8gf2tvdw0s3jn54khce6mua7l"
+)
+
+func descriptorPolyMod(c uint64, val int) uint64 {
+	c0 := c >> 35
+	c = ((c & 0x7ffffffff) << 5) ^ uint64(val)
+	if c0&1 != 0 {
+		c ^= 0xf5dee51989
+	}
+	if c0&2 != 0 {
+		c ^= 0xa9fdca3312
+	}
+	if c0&4 != 0 {
+		c ^= 0x1bab10e32d
+	}
+	if c0&8 != 0 {
+		c ^= 0x3706b1677a
+	}
+	if c0&16 != 0 {
+		c ^= 0x644d626ffd
+	}
+	return c
+}
+
+// DescriptorChecksum calculates the checksum of an output descriptor as defined
+// in BIP-0380.
+func DescriptorChecksum(desc string) (string, error) {
+	var (
+		c        = uint64(1)
+		cls      = 0
+		clsCount = 0
+	)
+	for _, ch := range desc {
+		pos := strings.IndexRune(descriptorInputCharset, ch)
+		if pos == -1 {
+			return "", fmt.Errorf("invalid character '%c' in "+
+				"descriptor", ch)
+		}
+
+		// Emit a symbol for the position inside the group, for every
+		// character.
+		c = descriptorPolyMod(c, pos&31)
+
+		// Accumulate the group numbers.
+		cls = cls*3 + (pos >> 5)
+		clsCount++
+		if clsCount == 3 {
+			// Emit an extra symbol representing the group numbers,
+			// for every 3 characters.
+			c = descriptorPolyMod(c, cls)
+			cls = 0
+			clsCount = 0
+		}
+	}
+	if clsCount > 0 {
+		c = descriptorPolyMod(c, cls)
+	}
+
+	// Shift further to determine the checksum.
+	for j := 0; j < 8; j++ {
+		c = descriptorPolyMod(c, 0)
+	}
+
+	// Prevent appending zeroes from not affecting the checksum.
+	c ^= 1
+
+	checksum := make([]byte, 8)
+	for j := 0; j < 8; j++ {
+		checksum[j] = descriptorChecksumCharset[(c>>(5*(7-j)))&31]
+	}
+	return string(checksum), nil
+}
+
+// DescriptorWithChecksum returns the given output descriptor with its checksum
+// appended.
+func DescriptorWithChecksum(desc string) (string, error) {
+	checksum, err := DescriptorChecksum(desc)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s#%s", desc, checksum), nil
+}
@@ -5,8 +5,16 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io/ioutil"
 	"net/http"
+	"strconv"
 	"strings"
+	"time"
+)
+
+const (
+	// retryDelay is the time to wait before retrying a failed request.
+	retryDelay = time.Second
 )
 
 var (
@@ -15,11 +23,27 @@ var (
 
 type ExplorerAPI struct {
 	BaseURL string
+
+	// Timeout is the timeout of a single request of the block and address
+	// lookups. No timeout is used if it is zero.
+	Timeout time.Duration
+
+	// Retries is the number of times a failed request of the block and
+	// address lookups is retried.
+	Retries uint32
 }
 
 type TX struct {
-	Vin  []*Vin  `json:"vin"`
-	Vout []*Vout `json:"vout"`
+	Vin    []*Vin  `json:"vin"`
+	Vout   []*Vout `json:"vout"`
+	Weight int64   `json:"weight"`
+	Fee    int64   `json:"fee"`
+	Status *Status `json:"status"`
+}
+
+// VSize returns the virtual size of the transaction in vbytes.
+func (t *TX) VSize() int64 {
+	return (t.Weight + 3) / 4
 }
 
 type Vin struct {
@@ -45,6 +69,51 @@ type Outspend struct {
 	Status *Status `json:"status"`
 }
 
+type UTXO struct {
+	Txid   string  `json:"txid"`
+	Vout   uint32  `json:"vout"`
+	Value  uint64  `json:"value"`
+	Status *Status `json:"status"`
+}
+
+type AddressStats struct {
+	FundedTXOCount uint32 `json:"funded_txo_count"`
+	FundedTXOSum   uint64 `json:"funded_txo_sum"`
+	SpentTXOSum    uint64 `json:"spent_txo_sum"`
+}
+
+type Address struct {
+	Address      string        `json:"address"`
+	ChainStats   *AddressStats `json:"chain_stats"`
+	MempoolStats *AddressStats `json:"mempool_stats"`
+}
+
+// Balance returns the confirmed balance of the address.
+func (a *Address) Balance() uint64 {
+	if a.ChainStats == nil {
+		return 0
+	}
+	return a.ChainStats.FundedTXOSum - a.ChainStats.SpentTXOSum
+}
+
+// Used returns true if the address ever received funds, including unconfirmed
+// ones.
+func (a *Address) Used() bool {
+	for _, stats := range []*AddressStats{a.ChainStats, a.MempoolStats} {
+		if stats != nil && stats.FundedTXOCount > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+type ExplorerBlock struct {
+	ID         string `json:"id"`
+	Height     uint32 `json:"height"`
+	Timestamp  int64  `json:"timestamp"`
+	MedianTime int64  `json:"mediantime"`
+}
+
 type Status struct {
 	Confirmed   bool   `json:"confirmed"`
 	BlockHeight int    `json:"block_height"`
@@ -71,6 +140,128 @@ func (a *ExplorerAPI) Transaction(txid string) (*TX, error) {
 	return tx, nil
 }
 
+// Outspend returns the spending status of a single transaction output. An
+// output that is spent by an unconfirmed transaction counts as spent.
+func (a *ExplorerAPI) Outspend(txid string, vout uint32) (*Outspend, error) {
+	outspend := &Outspend{}
+	url := fmt.Sprintf("%s/tx/%s/outspend/%d", a.BaseURL, txid, vout)
+	if err := fetchJSON(url, outspend); err != nil {
+		return nil, err
+	}
+	return outspend, nil
+}
+
+// Address returns the statistics of an address. The lookup uses the timeout and
+// retries of the API, as it is done for many addresses in a row.
+func (a *ExplorerAPI) Address(addr string) (*Address, error) {
+	body, err := a.getWithRetry(fmt.Sprintf("%s/address/%s", a.BaseURL,
+		addr))
+	if err != nil {
+		return nil, err
+	}
+	address := &Address{}
+	if err := json.Unmarshal(body, address); err != nil {
+		return nil, fmt.Errorf("error parsing address %s: %v", addr,
+			err)
+	}
+	return address, nil
+}
+
+func (a *ExplorerAPI) Unspent(addr string) ([]*UTXO, error) {
+	var utxos []*UTXO
+	err := fetchJSON(fmt.Sprintf("%s/address/%s/utxo", a.BaseURL, addr),
+		&utxos)
+	if err != nil {
+		return nil, err
+	}
+	return utxos, nil
+}
+
+// FeeEstimates returns the fee rates in satoshis per vbyte that are needed to
+// confirm a transaction within the number of blocks used as the key.
+func (a *ExplorerAPI) FeeEstimates() (map[uint32]float64, error) {
+	var estimates map[string]float64
+	err := fetchJSON(fmt.Sprintf("%s/fee-estimates", a.BaseURL), &estimates)
+	if err != nil {
+		return nil, err
+	}
+	result := make(map[uint32]float64, len(estimates))
+	for target, feeRate := range estimates {
+		numBlocks, err := strconv.ParseUint(target, 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("invalid confirmation target "+
+				"%s: %v", target, err)
+		}
+		result[uint32(numBlocks)] = feeRate
+	}
+	return result, nil
+}
+
+// TipHeight returns the height of the best block of the chain.
+func (a *ExplorerAPI) TipHeight() (uint32, error) {
+	body, err := a.getWithRetry(fmt.Sprintf("%s/blocks/tip/height",
+		a.BaseURL))
+	if err != nil {
+		return 0, err
+	}
+	heightStr := strings.TrimSpace(string(body))
+	height, err := strconv.ParseUint(heightStr, 10, 32)
+	if err != nil {
+		return 0, fmt.Errorf("invalid tip height %s: %v", heightStr,
+			err)
+	}
+	return uint32(height), nil
+}
+
+// BlockAtHeight returns the block of the current chain at the given height.
+func (a *ExplorerAPI) BlockAtHeight(height uint32) (*ExplorerBlock, error) {
+	hash, err := a.getWithRetry(fmt.Sprintf("%s/block-height/%d",
+		a.BaseURL, height))
+	if err != nil {
+		return nil, err
+	}
+	body, err := a.getWithRetry(fmt.Sprintf("%s/block/%s", a.BaseURL,
+		strings.TrimSpace(string(hash))))
+	if err != nil {
+		return nil, err
+	}
+	block := &ExplorerBlock{}
+	if err := json.Unmarshal(body, block); err != nil {
+		return nil, fmt.Errorf("error parsing block %d: %v", height,
+			err)
+	}
+	return block, nil
+}
+
+// FirstBlockAfter returns the height of the first block with a median time
+// past the given timestamp. Because the median time of the blocks is
+// monotonic, the block can be found with a binary search over the heights of
+// the chain. The tip height is returned if no block is past the timestamp yet.
+func (a *ExplorerAPI) FirstBlockAfter(timestamp time.Time) (uint32, error) {
+	tip, err := a.TipHeight()
+	if err != nil {
+		return 0, err
+	}
+
+	var (
+		unix   = timestamp.Unix()
+		lo, hi = uint32(0), tip
+	)
+	for lo < hi {
+		mid := lo + (hi-lo)/2
+		block, err := a.BlockAtHeight(mid)
+		if err != nil {
+			return 0, err
+		}
+		if block.MedianTime > unix {
+			hi = mid
+		} else {
+			lo = mid + 1
+		}
+	}
+	return lo, nil
+}
+
 func (a *ExplorerAPI) PublishTx(rawTxHex string) (string, error) {
 	url := fmt.Sprintf("%s/tx", a.BaseURL)
 	resp, err := http.Post(url, "text/plain", strings.NewReader(rawTxHex))
@@ -106,3 +297,38 @@ func fetchJSON(url string, target interface{}) error {
 	}
 	return err
 }
+
+// getWithRetry fetches the body of the given URL with the timeout of the API.
+// Failed requests and responses with an error status are retried up to the
+// configured number of times.
+func (a *ExplorerAPI) getWithRetry(url string) ([]byte, error) {
+	client := &http.Client{Timeout: a.Timeout}
+
+	var lastErr error
+	for attempt := uint32(0); attempt <= a.Retries; attempt++ {
+		if attempt > 0 {
+			time.Sleep(retryDelay)
+		}
+
+		resp, err := client.Get(url)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		body, err := ioutil.ReadAll(resp.Body)
+		_ = resp.Body.Close()
+		switch {
+		case err != nil:
+			lastErr = err
+
+		case resp.StatusCode != http.StatusOK:
+			lastErr = fmt.Errorf("%s returned status %d: %s", url,
+				resp.StatusCode,
+				strings.TrimSpace(string(body)))
+
+		default:
+			return body, nil
+		}
+	}
+	return nil, lastErr
+}
@@ -0,0 +1,39 @@
+package btc
+
+import (
+	"fmt"
+	"strings"
+)
+
+// NodeAPI is a client for a public Lightning Network graph API that is
+// compatible with mempool.space's lightning endpoints.
+type NodeAPI struct {
+	BaseURL string
+}
+
+type Node struct {
+	PublicKey string `json:"public_key"`
+	Alias     string `json:"alias"`
+	Sockets   string `json:"sockets"`
+}
+
+// Addresses returns the list of all addresses the node announced.
+func (n *Node) Addresses() []string {
+	var addrs []string
+	for _, socket := range strings.Split(n.Sockets, ",") {
+		socket = strings.TrimSpace(socket)
+		if socket != "" {
+			addrs = append(addrs, socket)
+		}
+	}
+	return addrs
+}
+
+func (a *NodeAPI) Node(pubKey string) (*Node, error) {
+	node := &Node{}
+	err := fetchJSON(fmt.Sprintf("%s/nodes/%s", a.BaseURL, pubKey), node)
+	if err != nil {
+		return nil, err
+	}
+	return node, nil
+}
@@ -0,0 +1,162 @@
+package btc
+
+import (
+	"bytes"
+	"fmt"
+	"math/big"
+
+	"github.com/btcsuite/btcd/btcec"
+)
+
+const (
+	// BIP340AuxTag, BIP340NonceTag and BIP340ChallengeTag are the tags of
+	// the tagged hashes that are used to create a Schnorr signature as
+	// defined in BIP340.
+	BIP340AuxTag       = "BIP0340/aux"
+	BIP340NonceTag     = "BIP0340/nonce"
+	BIP340ChallengeTag = "BIP0340/challenge"
+
+	// SchnorrSigSize is the size of a BIP340 Schnorr signature.
+	SchnorrSigSize = 64
+)
+
+// SchnorrSign creates a BIP340 Schnorr signature of the 32 byte message with
+// the private key. The 32 bytes of auxiliary randomness are mixed into the
+// nonce as recommended by BIP340. The signature is verified before it is
+// returned, so a faulty signature is never published.
+func SchnorrSign(privKey *btcec.PrivateKey, msg, aux []byte) ([]byte, error) {
+	if len(msg) != 32 || len(aux) != 32 {
+		return nil, fmt.Errorf("message and auxiliary randomness " +
+			"must be 32 bytes")
+	}
+
+	// BIP340 public keys are x-only, so the private key is negated if its
+	// public key has an odd Y coordinate.
+	curve := btcec.S256()
+	d := new(big.Int).Set(privKey.D)
+	if d.Sign() == 0 || d.Cmp(curve.N) >= 0 {
+		return nil, fmt.Errorf("invalid private key")
+	}
+	pubX, pubY := curve.ScalarBaseMult(scalarBytes(d))
+	if pubY.Bit(0) == 1 {
+		d.Sub(curve.N, d)
+	}
+	pubKeyBytes := scalarBytes(pubX)
+
+	// The nonce is derived from the private key, the auxiliary randomness
+	// and the message, so it's never reused for a different message.
+	t := scalarBytes(d)
+	auxHash := TaggedHash(BIP340AuxTag, aux)
+	for i := range t {
+		t[i] ^= auxHash[i]
+	}
+	k := new(big.Int).SetBytes(
+		TaggedHash(BIP340NonceTag, t, pubKeyBytes, msg),
+	)
+	k.Mod(k, curve.N)
+	if k.Sign() == 0 {
+		return nil, fmt.Errorf("nonce is zero")
+	}
+	nonceX, nonceY := curve.ScalarBaseMult(scalarBytes(k))
+	if nonceY.Bit(0) == 1 {
+		k.Sub(curve.N, k)
+	}
+	nonceBytes := scalarBytes(nonceX)
+
+	e := challenge(nonceBytes, pubKeyBytes, msg)
+	s := new(big.Int).Mul(e, d)
+	s.Add(s, k)
+	s.Mod(s, curve.N)
+
+	sig := append(nonceBytes, scalarBytes(s)...)
+	if err := SchnorrVerify(pubKeyBytes, msg, sig); err != nil {
+		return nil, fmt.Errorf("created invalid signature: %v", err)
+	}
+	return sig, nil
+}
+
+// SchnorrVerify verifies a BIP340 Schnorr signature of the 32 byte message
+// with the 32 byte x-only public key.
+func SchnorrVerify(pubKey, msg, sig []byte) error {
+	if len(msg) != 32 {
+		return fmt.Errorf("message must be 32 bytes")
+	}
+	if len(sig) != SchnorrSigSize {
+		return fmt.Errorf("signature must be %d bytes, got %d",
+			SchnorrSigSize, len(sig))
+	}
+	key, err := ParseXOnlyPubKey(pubKey)
+	if err != nil {
+		return err
+	}
+
+	curve := btcec.S256()
+	r := new(big.Int).SetBytes(sig[:32])
+	s := new(big.Int).SetBytes(sig[32:])
+	if r.Cmp(curve.P) >= 0 || s.Cmp(curve.N) >= 0 {
+		return fmt.Errorf("signature is out of range")
+	}
+
+	// R = s*G - e*P must be a point with an even Y coordinate and the X
+	// coordinate r.
+	e := challenge(sig[:32], pubKey, msg)
+	e.Sub(curve.N, e)
+	sX, sY := curve.ScalarBaseMult(sig[32:])
+	eX, eY := curve.ScalarMult(key.X, key.Y, scalarBytes(e))
+	nonceX, nonceY := curve.Add(sX, sY, eX, eY)
+	switch {
+	case nonceX.Sign() == 0 && nonceY.Sign() == 0:
+		return fmt.Errorf("nonce is the point at infinity")
+
+	case nonceY.Bit(0) == 1:
+		return fmt.Errorf("nonce has an odd Y coordinate")
+
+	case !bytes.Equal(scalarBytes(nonceX), sig[:32]):
+		return fmt.Errorf("signature doesn't match")
+	}
+	return nil
+}
+
+// TweakTaprootPrivKey tweaks the private key of the internal key of a Taproot
+// output with the root of its script tree, which results in the private key of
+// the output key that is needed to spend the output with the key path.
+func TweakTaprootPrivKey(privKey *btcec.PrivateKey,
+	scriptRoot []byte) (*btcec.PrivateKey, error) {
+
+	curve := btcec.S256()
+	tweak, _, err := TaprootOutputKey(privKey.PubKey(), scriptRoot)
+	if err != nil {
+		return nil, err
+	}
+
+	// The tweak is added to the internal key with the even Y coordinate,
+	// so the private key is negated first if that's not the case.
+	d := new(big.Int).Set(privKey.D)
+	if privKey.PubKey().Y.Bit(0) == 1 {
+		d.Sub(curve.N, d)
+	}
+	d.Add(d, new(big.Int).SetBytes(tweak))
+	d.Mod(d, curve.N)
+	if d.Sign() == 0 {
+		return nil, fmt.Errorf("tweaked private key is zero")
+	}
+	tweakedKey, _ := btcec.PrivKeyFromBytes(curve, scalarBytes(d))
+	return tweakedKey, nil
+}
+
+// challenge returns the challenge of a BIP340 signature as a scalar.
+func challenge(nonce, pubKey, msg []byte) *big.Int {
+	e := new(big.Int).SetBytes(
+		TaggedHash(BIP340ChallengeTag, nonce, pubKey, msg),
+	)
+	return e.Mod(e, btcec.S256().N)
+}
+
+// scalarBytes returns the 32 byte big endian encoding of a scalar or a
+// coordinate.
+func scalarBytes(i *big.Int) []byte {
+	b := make([]byte, 32)
+	iBytes := i.Bytes()
+	copy(b[32-len(iBytes):], iBytes)
+	return b
+}
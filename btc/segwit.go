@@ -0,0 +1,272 @@
+package btc
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcutil"
+	"github.com/btcsuite/btcutil/bech32"
+)
+
+const (
+	bech32Charset = "qpzry9x8gf2tvdw0s3jn54khce6mua7l"
+
+	// bech32mConst is the checksum constant of bech32m as defined in
+	// BIP350. Addresses of segwit version 1 and higher must use bech32m
+	// instead of bech32.
+	bech32mConst = 0x2bc830a3
+
+	// bech32Const is the checksum constant of the original bech32 as
+	// defined in BIP173.
+	bech32Const = 1
+
+	// MaxSegWitVersion is the highest segwit version that can be encoded in
+	// an address.
+	MaxSegWitVersion = 16
+
+	// TaprootVersion is the segwit version of Taproot outputs.
+	TaprootVersion = 1
+
+	// bech32MaxLength is the maximum length of a bech32 or bech32m string.
+	bech32MaxLength = 90
+
+	// bech32ChecksumLength is the number of 5 bit characters of the
+	// checksum.
+	bech32ChecksumLength = 6
+)
+
+// AddressTaproot is a pay-to-taproot (P2TR) address. The btcutil version we use
+// doesn't know segwit version 1 addresses, so it implements btcutil.Address
+// for them.
+type AddressTaproot struct {
+	hrp       string
+	outputKey [32]byte
+}
+
+// A compile time check to make sure AddressTaproot is a btcutil.Address.
+var _ btcutil.Address = (*AddressTaproot)(nil)
+
+// NewAddressTaproot returns the P2TR address of the 32 byte x-only output key.
+func NewAddressTaproot(outputKey []byte,
+	params *chaincfg.Params) (*AddressTaproot, error) {
+
+	if len(outputKey) != 32 {
+		return nil, fmt.Errorf("invalid output key length %d, must be "+
+			"32 bytes", len(outputKey))
+	}
+	addr := &AddressTaproot{hrp: strings.ToLower(params.Bech32HRPSegwit)}
+	copy(addr.outputKey[:], outputKey)
+	return addr, nil
+}
+
+// EncodeAddress returns the bech32m encoding of the address.
+func (a *AddressTaproot) EncodeAddress() string {
+	addr, err := EncodeSegWitAddress(a.hrp, TaprootVersion, a.outputKey[:])
+	if err != nil {
+		return ""
+	}
+	return addr
+}
+
+// ScriptAddress returns the x-only output key of the address.
+func (a *AddressTaproot) ScriptAddress() []byte {
+	return a.outputKey[:]
+}
+
+// IsForNet returns true if the address belongs to the given network.
+func (a *AddressTaproot) IsForNet(params *chaincfg.Params) bool {
+	return a.hrp == strings.ToLower(params.Bech32HRPSegwit)
+}
+
+// String returns the bech32m encoding of the address.
+func (a *AddressTaproot) String() string {
+	return a.EncodeAddress()
+}
+
+// DecodeAddress decodes an address of the given network. P2TR addresses are
+// decoded as AddressTaproot, all other addresses are decoded by btcutil.
+func DecodeAddress(addr string, params *chaincfg.Params) (btcutil.Address,
+	error) {
+
+	hrp := strings.ToLower(params.Bech32HRPSegwit)
+	if !strings.HasPrefix(strings.ToLower(addr), hrp+"1p") {
+		return btcutil.DecodeAddress(addr, params)
+	}
+
+	version, program, err := DecodeSegWitAddress(hrp, addr)
+	if err != nil {
+		return nil, err
+	}
+	if version != TaprootVersion || len(program) != 32 {
+		return nil, fmt.Errorf("unsupported segwit version %d address "+
+			"with a program of %d bytes", version, len(program))
+	}
+	return NewAddressTaproot(program, params)
+}
+
+// EncodeSegWitAddress encodes a witness program of the given segwit version as
+// an address with the human readable part of a network. Version 0 programs are
+// encoded with bech32, all higher versions with bech32m.
+func EncodeSegWitAddress(hrp string, version byte, program []byte) (string,
+	error) {
+
+	if version > MaxSegWitVersion {
+		return "", fmt.Errorf("invalid segwit version %d, must be "+
+			"between 0 and %d", version, MaxSegWitVersion)
+	}
+	if len(program) < 2 || len(program) > 40 {
+		return "", fmt.Errorf("invalid witness program length %d",
+			len(program))
+	}
+	if version == 0 && len(program) != 20 && len(program) != 32 {
+		return "", fmt.Errorf("invalid witness program length %d for "+
+			"segwit version 0", len(program))
+	}
+
+	converted, err := bech32.ConvertBits(program, 8, 5, true)
+	if err != nil {
+		return "", err
+	}
+	data := append([]byte{version}, converted...)
+	if version == 0 {
+		return bech32.Encode(hrp, data)
+	}
+	return bech32mEncode(hrp, data)
+}
+
+// DecodeSegWitAddress decodes a segwit address with the human readable part of
+// a network and returns its segwit version and witness program. Version 0
+// addresses must be encoded with bech32, all higher versions with bech32m.
+func DecodeSegWitAddress(hrp, addr string) (byte, []byte, error) {
+	decodedHrp, data, checksumConst, err := bech32Decode(addr)
+	if err != nil {
+		return 0, nil, err
+	}
+	if decodedHrp != strings.ToLower(hrp) {
+		return 0, nil, fmt.Errorf("invalid human readable part %s, "+
+			"expected %s", decodedHrp, hrp)
+	}
+	if len(data) == 0 {
+		return 0, nil, fmt.Errorf("missing segwit version")
+	}
+
+	version := data[0]
+	if version > MaxSegWitVersion {
+		return 0, nil, fmt.Errorf("invalid segwit version %d", version)
+	}
+	switch {
+	case version == 0 && checksumConst != bech32Const:
+		return 0, nil, fmt.Errorf("segwit version 0 address must be " +
+			"encoded with bech32")
+
+	case version > 0 && checksumConst != bech32mConst:
+		return 0, nil, fmt.Errorf("segwit version %d address must be "+
+			"encoded with bech32m", version)
+	}
+
+	program, err := bech32.ConvertBits(data[1:], 5, 8, false)
+	if err != nil {
+		return 0, nil, err
+	}
+	if len(program) < 2 || len(program) > 40 {
+		return 0, nil, fmt.Errorf("invalid witness program length %d",
+			len(program))
+	}
+	if version == 0 && len(program) != 20 && len(program) != 32 {
+		return 0, nil, fmt.Errorf("invalid witness program length %d "+
+			"for segwit version 0", len(program))
+	}
+	return version, program, nil
+}
+
+// bech32Decode decodes a bech32 or bech32m string and returns its human
+// readable part, the 5 bit data without the checksum and the constant of the
+// checksum, which tells the two encodings apart.
+func bech32Decode(str string) (string, []byte, int, error) {
+	if len(str) > bech32MaxLength {
+		return "", nil, 0, fmt.Errorf("invalid length %d", len(str))
+	}
+	if strings.ToLower(str) != str && strings.ToUpper(str) != str {
+		return "", nil, 0, fmt.Errorf("string uses mixed case")
+	}
+	str = strings.ToLower(str)
+
+	sep := strings.LastIndexByte(str, '1')
+	if sep < 1 || sep+bech32ChecksumLength+1 > len(str) {
+		return "", nil, 0, fmt.Errorf("invalid separator index %d",
+			sep)
+	}
+	hrp := str[:sep]
+	for i := 0; i < len(hrp); i++ {
+		if hrp[i] < 33 || hrp[i] > 126 {
+			return "", nil, 0, fmt.Errorf("invalid character in "+
+				"human readable part: %d", hrp[i])
+		}
+	}
+
+	data := make([]byte, 0, len(str)-sep-1)
+	for i := sep + 1; i < len(str); i++ {
+		idx := strings.IndexByte(bech32Charset, str[i])
+		if idx < 0 {
+			return "", nil, 0, fmt.Errorf("invalid character %c",
+				str[i])
+		}
+		data = append(data, byte(idx))
+	}
+
+	checksumConst := bech32Polymod(append(bech32HrpExpand(hrp), data...))
+	if checksumConst != bech32Const && checksumConst != bech32mConst {
+		return "", nil, 0, fmt.Errorf("invalid checksum")
+	}
+	return hrp, data[:len(data)-bech32ChecksumLength], checksumConst, nil
+}
+
+// bech32mEncode encodes the 5 bit data with the bech32m checksum.
+func bech32mEncode(hrp string, data []byte) (string, error) {
+	hrp = strings.ToLower(hrp)
+	values := append(bech32HrpExpand(hrp), data...)
+	polymod := bech32Polymod(append(values, 0, 0, 0, 0, 0, 0)) ^
+		bech32mConst
+
+	var b strings.Builder
+	b.WriteString(hrp)
+	b.WriteString("1")
+	for _, d := range data {
+		if int(d) >= len(bech32Charset) {
+			return "", fmt.Errorf("invalid data byte %d", d)
+		}
+		b.WriteByte(bech32Charset[d])
+	}
+	for i := 0; i < 6; i++ {
+		b.WriteByte(bech32Charset[(polymod>>uint(5*(5-i)))&31])
+	}
+	return b.String(), nil
+}
+
+func bech32Polymod(values []byte) int {
+	gen := []int{0x3b6a57b2, 0x26508e6d, 0x1ea119fa, 0x3d4233dd, 0x2a1462b3}
+	chk := 1
+	for _, v := range values {
+		b := chk >> 25
+		chk = (chk&0x1ffffff)<<5 ^ int(v)
+		for i := 0; i < 5; i++ {
+			if (b>>uint(i))&1 == 1 {
+				chk ^= gen[i]
+			}
+		}
+	}
+	return chk
+}
+
+func bech32HrpExpand(hrp string) []byte {
+	values := make([]byte, 0, len(hrp)*2+1)
+	for i := 0; i < len(hrp); i++ {
+		values = append(values, hrp[i]>>5)
+	}
+	values = append(values, 0)
+	for i := 0; i < len(hrp); i++ {
+		values = append(values, hrp[i]&31)
+	}
+	return values
+}
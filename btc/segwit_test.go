@@ -0,0 +1,135 @@
+package btc
+
+import (
+	"encoding/hex"
+	"strings"
+	"testing"
+
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcutil"
+)
+
+// TestDecodeSegWitAddress tests the segwit address decoding with the test
+// vectors of BIP350.
+func TestDecodeSegWitAddress(t *testing.T) {
+	validAddrs := []struct {
+		hrp      string
+		addr     string
+		pkScript string
+	}{{
+		hrp:      "bc",
+		addr:     "BC1QW508D6QEJXTDG4Y5R3ZARVARY0C5XW7KV8F3T4",
+		pkScript: "0014751e76e8199196d454941c45d1b3a323f1433bd6",
+	}, {
+		hrp: "bc",
+		addr: "bc1pw508d6qejxtdg4y5r3zarvary0c5xw7kw508d6qejxtdg4y5r3" +
+			"zarvary0c5xw7kt5nd6y",
+		pkScript: "5128751e76e8199196d454941c45d1b3a3" +
+			"23f1433bd6751e76e8199196d454941c45d1b3a323f1433bd6",
+	}, {
+		hrp: "bc",
+		addr: "bc1p0xlxvlhemja6c4dqv22uapctqupfhlxm9h8z3k2e72q4k9hcz7" +
+			"vqzk5jj0",
+		pkScript: "512079be667ef9dcbbac55a06295ce870b" +
+			"07029bfcdb2dce28d959f2815b16f81798",
+	}, {
+		hrp: "tb",
+		addr: "tb1pqqqqp399et2xygdj5xreqhjjvcmzhxw4aywxecjdzew6hylgvs" +
+			"esf3hn0c",
+		pkScript: "5120000000c4a5cad46221b2a187905e52" +
+			"66362b99d5e91c6ce24d165dab93e86433",
+	}}
+	for _, tc := range validAddrs {
+		version, program, err := DecodeSegWitAddress(tc.hrp, tc.addr)
+		if err != nil {
+			t.Fatalf("error decoding %s: %v", tc.addr, err)
+		}
+
+		// The script starts with OP_0 or OP_1 to OP_16, followed by
+		// the push of the program.
+		opcode := version
+		if version > 0 {
+			opcode += 0x50
+		}
+		pkScript := append(
+			[]byte{opcode, byte(len(program))}, program...,
+		)
+		if hex.EncodeToString(pkScript) != tc.pkScript {
+			t.Fatalf("unexpected script of %s, got %x wanted %s",
+				tc.addr, pkScript, tc.pkScript)
+		}
+
+		encoded, err := EncodeSegWitAddress(tc.hrp, version, program)
+		if err != nil {
+			t.Fatalf("error encoding %s: %v", tc.addr, err)
+		}
+		if encoded != strings.ToLower(tc.addr) {
+			t.Fatalf("unexpected encoding of %s, got %s", tc.addr,
+				encoded)
+		}
+	}
+
+	invalidAddrs := []string{
+		// Segwit version 1 with the bech32 checksum.
+		"bc1p0xlxvlhemja6c4dqv22uapctqupfhlxm9h8z" +
+			"3k2e72q4k9hcz7vqh2y7hd",
+
+		// Segwit version 16 with the bech32 checksum.
+		"BC1S0XLXVLHEMJA6C4DQV22UAPCTQUPFHLXM9H8Z" +
+			"3K2E72Q4K9HCZ7VQ54WELL",
+
+		// Segwit version 0 with the bech32m checksum.
+		"bc1qw508d6qejxtdg4y5r3zarvary0c5xw7kemeawh",
+
+		// Wrong human readable part.
+		"tb1p0xlxvlhemja6c4dqv22uapctqupfhlxm9h8z" +
+			"3k2e72q4k9hcz7vqzk5jj0",
+
+		// Mixed case.
+		"bc1p0xlxvlhemja6c4dqv22uapctqupfhlxm9h8z" +
+			"3k2e72q4k9hcz7vqzk5jJ0",
+	}
+	for _, addr := range invalidAddrs {
+		if _, _, err := DecodeSegWitAddress("bc", addr); err == nil {
+			t.Fatalf("expected error decoding %s", addr)
+		}
+	}
+}
+
+// TestDecodeAddress makes sure P2TR addresses are decoded as AddressTaproot of
+// the right network and all other addresses are still decoded by btcutil.
+func TestDecodeAddress(t *testing.T) {
+	const taprootAddr = "bc1p0xlxvlhemja6c4dqv22uapctqupfhlxm9h8z3k" +
+		"2e72q4k9hcz7vqzk5jj0"
+
+	addr, err := DecodeAddress(taprootAddr, &chaincfg.MainNetParams)
+	if err != nil {
+		t.Fatalf("error decoding address: %v", err)
+	}
+	if _, ok := addr.(*AddressTaproot); !ok {
+		t.Fatalf("unexpected address type %T", addr)
+	}
+	if addr.String() != taprootAddr {
+		t.Fatalf("unexpected address %s", addr)
+	}
+	if !addr.IsForNet(&chaincfg.MainNetParams) ||
+		addr.IsForNet(&chaincfg.TestNet3Params) {
+
+		t.Fatalf("address has the wrong network")
+	}
+	_, err = DecodeAddress(taprootAddr, &chaincfg.TestNet3Params)
+	if err == nil {
+		t.Fatalf("expected error decoding mainnet address on testnet")
+	}
+
+	addr, err = DecodeAddress(
+		"bc1qw508d6qejxtdg4y5r3zarvary0c5xw7kv8f3t4",
+		&chaincfg.MainNetParams,
+	)
+	if err != nil {
+		t.Fatalf("error decoding address: %v", err)
+	}
+	if _, ok := addr.(*btcutil.AddressWitnessPubKeyHash); !ok {
+		t.Fatalf("unexpected address type %T", addr)
+	}
+}
@@ -0,0 +1,69 @@
+package btc
+
+import (
+	"math/big"
+	"time"
+
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/wire"
+)
+
+// sigNetGenesisHash is the hash of the genesis block of the default signet.
+var sigNetGenesisHash, _ = chainhash.NewHashFromStr(
+	"00000008819873e925422c1ff0f99f7cc9bbb232af63a077a480a3633bee1ef6",
+)
+
+var (
+	// sigNetPowLimitBits is the highest proof of work value a block of the
+	// default signet can have in its compact form.
+	sigNetPowLimitBits uint32 = 0x1e0377ae
+
+	// sigNetPowLimit is the expanded form of sigNetPowLimitBits, the
+	// mantissa shifted by the exponent minus its own size in bytes.
+	sigNetPowLimit = new(big.Int).Lsh(big.NewInt(0x0377ae), 8*(0x1e-3))
+
+	mainNetGenesis = chaincfg.MainNetParams.GenesisBlock
+
+	// sigNetGenesisBlock is the genesis block of the default signet. It
+	// has the same coinbase transaction as all other networks.
+	sigNetGenesisBlock = wire.MsgBlock{
+		Header: wire.BlockHeader{
+			Version:    1,
+			PrevBlock:  chainhash.Hash{},
+			MerkleRoot: mainNetGenesis.Header.MerkleRoot,
+			Timestamp:  time.Unix(1598918400, 0),
+			Bits:       sigNetPowLimitBits,
+			Nonce:      52613770,
+		},
+		Transactions: mainNetGenesis.Transactions,
+	}
+
+	// SigNetParams are the parameters of the default signet as defined in
+	// BIP325. The btcd version we use doesn't know about signet yet, so
+	// only the parameters needed for key derivation, addresses and the
+	// genesis block are defined. Keys and addresses use the same encoding
+	// as on testnet.
+	SigNetParams = chaincfg.Params{
+		Name:        "signet",
+		Net:         wire.BitcoinNet(0x40cf030a),
+		DefaultPort: "38333",
+
+		GenesisBlock: &sigNetGenesisBlock,
+		GenesisHash:  sigNetGenesisHash,
+		PowLimit:     sigNetPowLimit,
+		PowLimitBits: sigNetPowLimitBits,
+
+		Bech32HRPSegwit: "tb",
+
+		PubKeyHashAddrID:        0x6f,
+		ScriptHashAddrID:        0xc4,
+		PrivateKeyID:            0xef,
+		WitnessPubKeyHashAddrID: 0x03,
+		WitnessScriptHashAddrID: 0x28,
+
+		HDPrivateKeyID: [4]byte{0x04, 0x35, 0x83, 0x94},
+		HDPublicKeyID:  [4]byte{0x04, 0x35, 0x87, 0xcf},
+		HDCoinType:     1,
+	}
+)
@@ -0,0 +1,15 @@
+package btc
+
+import (
+	"testing"
+)
+
+// TestSigNetGenesisBlock makes sure the genesis block of the signet parameters
+// hashes to the genesis hash of the default signet.
+func TestSigNetGenesisBlock(t *testing.T) {
+	hash := SigNetParams.GenesisBlock.BlockHash()
+	if !hash.IsEqual(SigNetParams.GenesisHash) {
+		t.Fatalf("unexpected genesis hash, got %v wanted %v", hash,
+			SigNetParams.GenesisHash)
+	}
+}
@@ -0,0 +1,291 @@
+package btc
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"math/big"
+
+	"github.com/btcsuite/btcd/btcec"
+	"github.com/btcsuite/btcd/wire"
+)
+
+const (
+	// TapTweakTag is the tag of the tagged hash that is used to tweak the
+	// internal key of a Taproot output as defined in BIP341.
+	TapTweakTag = "TapTweak"
+
+	// TapLeafTag and TapBranchTag are the tags of the tagged hashes of the
+	// leaves and branches of a Taproot script tree as defined in BIP341.
+	TapLeafTag   = "TapLeaf"
+	TapBranchTag = "TapBranch"
+
+	// BaseLeafVersion is the leaf version of Tapscript as defined in
+	// BIP342.
+	BaseLeafVersion = 0xc0
+
+	// TapSighashTag is the tag of the tagged hash of the signature message
+	// of a Taproot input as defined in BIP341.
+	TapSighashTag = "TapSighash"
+
+	// controlBlockBaseSize is the size of a control block without any
+	// merkle proof, which is the leaf version and the internal key.
+	controlBlockBaseSize = 33
+)
+
+// TaggedHash returns the tagged hash of the messages as defined in BIP340,
+// which is sha256(sha256(tag) || sha256(tag) || msg).
+func TaggedHash(tag string, msgs ...[]byte) []byte {
+	tagHash := sha256.Sum256([]byte(tag))
+	h := sha256.New()
+	h.Write(tagHash[:])
+	h.Write(tagHash[:])
+	for _, msg := range msgs {
+		h.Write(msg)
+	}
+	return h.Sum(nil)
+}
+
+// ParseXOnlyPubKey parses a 32 byte x-only public key as defined in BIP340.
+// The point with the even Y coordinate is returned.
+func ParseXOnlyPubKey(xOnly []byte) (*btcec.PublicKey, error) {
+	if len(xOnly) != 32 {
+		return nil, fmt.Errorf("x-only public key must be 32 bytes, "+
+			"got %d", len(xOnly))
+	}
+	compressed := append([]byte{0x02}, xOnly...)
+	return btcec.ParsePubKey(compressed, btcec.S256())
+}
+
+// TaprootOutputKey tweaks the internal key of a Taproot output with the root
+// of its script tree as defined in BIP341. An empty script root is used for
+// outputs that can only be spent with the key path. The tweak and the output
+// key are returned, the output key is encoded in the witness program of the
+// output with its X coordinate only.
+func TaprootOutputKey(internalKey *btcec.PublicKey, scriptRoot []byte) ([]byte,
+	*btcec.PublicKey, error) {
+
+	if len(scriptRoot) != 0 && len(scriptRoot) != sha256.Size {
+		return nil, nil, fmt.Errorf("script root must be %d bytes, "+
+			"got %d", sha256.Size, len(scriptRoot))
+	}
+
+	// BIP341 always uses the point with the even Y coordinate of the
+	// internal key, so we discard the parity of the key we got.
+	curve := btcec.S256()
+	xOnly := internalKey.SerializeCompressed()[1:]
+	liftedKey, err := ParseXOnlyPubKey(xOnly)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	tweak := TaggedHash(TapTweakTag, xOnly, scriptRoot)
+	if new(big.Int).SetBytes(tweak).Cmp(curve.N) >= 0 {
+		return nil, nil, fmt.Errorf("tweak is not a valid scalar")
+	}
+	tweakX, tweakY := curve.ScalarBaseMult(tweak)
+	outputX, outputY := curve.Add(liftedKey.X, liftedKey.Y, tweakX, tweakY)
+	if outputX.Sign() == 0 && outputY.Sign() == 0 {
+		return nil, nil, fmt.Errorf("output key is the point at " +
+			"infinity")
+	}
+	return tweak, &btcec.PublicKey{
+		Curve: curve,
+		X:     outputX,
+		Y:     outputY,
+	}, nil
+}
+
+// TapLeafHash returns the hash of a leaf of a Taproot script tree as defined in
+// BIP341.
+func TapLeafHash(leafVersion byte, script []byte) []byte {
+	var scriptBuf bytes.Buffer
+	_ = wire.WriteVarBytes(&scriptBuf, 0, script)
+	return TaggedHash(TapLeafTag, []byte{leafVersion}, scriptBuf.Bytes())
+}
+
+// TapBranchHash returns the hash of a branch of a Taproot script tree as
+// defined in BIP341. The children are sorted, so their order doesn't matter.
+func TapBranchHash(a, b []byte) []byte {
+	if bytes.Compare(a, b) > 0 {
+		a, b = b, a
+	}
+	return TaggedHash(TapBranchTag, a, b)
+}
+
+// TapTreeProofs builds a Taproot script tree from the hashes of its leaves by
+// pairing up neighboring nodes on every level until only the root is left. A
+// node without a neighbor is moved up to the next level unchanged. The root of
+// the tree and the merkle proof of every leaf, ordered from the leaf up, are
+// returned.
+func TapTreeProofs(leafHashes [][]byte) ([]byte, [][][]byte, error) {
+	if len(leafHashes) == 0 {
+		return nil, nil, fmt.Errorf("at least one leaf is required")
+	}
+
+	type node struct {
+		hash   []byte
+		leaves []int
+	}
+	nodes := make([]*node, len(leafHashes))
+	for idx, leafHash := range leafHashes {
+		nodes[idx] = &node{hash: leafHash, leaves: []int{idx}}
+	}
+	proofs := make([][][]byte, len(leafHashes))
+
+	for len(nodes) > 1 {
+		var nextLevel []*node
+		for i := 0; i < len(nodes); i += 2 {
+			if i+1 == len(nodes) {
+				nextLevel = append(nextLevel, nodes[i])
+				continue
+			}
+			left, right := nodes[i], nodes[i+1]
+			for _, leaf := range left.leaves {
+				proofs[leaf] = append(proofs[leaf], right.hash)
+			}
+			for _, leaf := range right.leaves {
+				proofs[leaf] = append(proofs[leaf], left.hash)
+			}
+			nextLevel = append(nextLevel, &node{
+				hash:   TapBranchHash(left.hash, right.hash),
+				leaves: append(left.leaves, right.leaves...),
+			})
+		}
+		nodes = nextLevel
+	}
+	return nodes[0].hash, proofs, nil
+}
+
+// TapControlBlock returns the control block that is needed to spend a Taproot
+// output with the script of a leaf as defined in BIP341.
+func TapControlBlock(leafVersion byte, internalKey,
+	outputKey *btcec.PublicKey, proof [][]byte) []byte {
+
+	parity := outputKey.SerializeCompressed()[0] - 0x02
+	controlBlock := []byte{leafVersion | parity}
+	controlBlock = append(
+		controlBlock, internalKey.SerializeCompressed()[1:]...,
+	)
+	for _, hash := range proof {
+		controlBlock = append(controlBlock, hash...)
+	}
+	return controlBlock
+}
+
+// ParseControlBlock parses the control block of a Taproot script path spend
+// and returns the Taproot output key it commits to together with the given leaf
+// script. The parity of the output key in the control block must match the
+// computed key.
+func ParseControlBlock(controlBlock, leafScript []byte) (*btcec.PublicKey,
+	error) {
+
+	proofLen := len(controlBlock) - controlBlockBaseSize
+	if proofLen < 0 || proofLen%sha256.Size != 0 {
+		return nil, fmt.Errorf("invalid control block size %d",
+			len(controlBlock))
+	}
+	leafVersion := controlBlock[0] &^ 1
+	parity := controlBlock[0] & 1
+	internalKey, err := ParseXOnlyPubKey(
+		controlBlock[1:controlBlockBaseSize],
+	)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing internal key: %v", err)
+	}
+
+	// The merkle proof is ordered from the leaf up, so we can hash our way
+	// up to the root.
+	node := TapLeafHash(leafVersion, leafScript)
+	for i := controlBlockBaseSize; i < len(controlBlock); i += sha256.Size {
+		node = TapBranchHash(node, controlBlock[i:i+sha256.Size])
+	}
+	_, outputKey, err := TaprootOutputKey(internalKey, node)
+	if err != nil {
+		return nil, err
+	}
+	if outputKey.SerializeCompressed()[0]-0x02 != parity {
+		return nil, fmt.Errorf("parity of the output key doesn't " +
+			"match the control block")
+	}
+	return outputKey, nil
+}
+
+// TaprootSigHash returns the hash of the signature message of a Taproot input
+// that is signed with SIGHASH_DEFAULT as defined in BIP341. All outputs spent
+// by the transaction are needed, in the order of its inputs. For a script path
+// spend, the leaf hash of the script is committed to as well, for a key path
+// spend it must be nil.
+func TaprootSigHash(tx *wire.MsgTx, prevOuts []*wire.TxOut, idx int,
+	leafHash []byte) ([]byte, error) {
+
+	if len(prevOuts) != len(tx.TxIn) {
+		return nil, fmt.Errorf("got %d previous outputs for %d inputs",
+			len(prevOuts), len(tx.TxIn))
+	}
+	if idx < 0 || idx >= len(tx.TxIn) {
+		return nil, fmt.Errorf("invalid input index %d", idx)
+	}
+
+	var (
+		prevOutsHash    = sha256.New()
+		amountsHash     = sha256.New()
+		pkScriptsHash   = sha256.New()
+		sequencesHash   = sha256.New()
+		outputsHash     = sha256.New()
+		littleEndian    = binary.LittleEndian
+		uint32Bytes     = make([]byte, 4)
+		uint64Bytes     = make([]byte, 8)
+		sigMsg          bytes.Buffer
+		spendType       = byte(0)
+		codeSepPosition = uint32(0xffffffff)
+	)
+	for i, txIn := range tx.TxIn {
+		prevOutsHash.Write(txIn.PreviousOutPoint.Hash[:])
+		littleEndian.PutUint32(uint32Bytes, txIn.PreviousOutPoint.Index)
+		prevOutsHash.Write(uint32Bytes)
+
+		littleEndian.PutUint64(uint64Bytes, uint64(prevOuts[i].Value))
+		amountsHash.Write(uint64Bytes)
+		_ = wire.WriteVarBytes(pkScriptsHash, 0, prevOuts[i].PkScript)
+
+		littleEndian.PutUint32(uint32Bytes, txIn.Sequence)
+		sequencesHash.Write(uint32Bytes)
+	}
+	for _, txOut := range tx.TxOut {
+		err := wire.WriteTxOut(outputsHash, 0, 0, txOut)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if leafHash != nil {
+		spendType = 2
+	}
+
+	// The signature message starts with the epoch, which is always zero,
+	// and the sighash type, which is SIGHASH_DEFAULT.
+	sigMsg.Write([]byte{0x00, 0x00})
+	littleEndian.PutUint32(uint32Bytes, uint32(tx.Version))
+	sigMsg.Write(uint32Bytes)
+	littleEndian.PutUint32(uint32Bytes, tx.LockTime)
+	sigMsg.Write(uint32Bytes)
+	sigMsg.Write(prevOutsHash.Sum(nil))
+	sigMsg.Write(amountsHash.Sum(nil))
+	sigMsg.Write(pkScriptsHash.Sum(nil))
+	sigMsg.Write(sequencesHash.Sum(nil))
+	sigMsg.Write(outputsHash.Sum(nil))
+	sigMsg.WriteByte(spendType)
+	littleEndian.PutUint32(uint32Bytes, uint32(idx))
+	sigMsg.Write(uint32Bytes)
+	if leafHash != nil {
+		sigMsg.Write(leafHash)
+
+		// The key version is always zero and we don't support
+		// OP_CODESEPARATOR.
+		sigMsg.WriteByte(0x00)
+		littleEndian.PutUint32(uint32Bytes, codeSepPosition)
+		sigMsg.Write(uint32Bytes)
+	}
+	return TaggedHash(TapSighashTag, sigMsg.Bytes()), nil
+}
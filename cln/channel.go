@@ -0,0 +1,91 @@
+package cln
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"io"
+
+	"github.com/btcsuite/btcd/btcec"
+	"golang.org/x/crypto/hkdf"
+)
+
+var (
+	// peerSeedInfo is the HKDF info that is used to derive the base secret
+	// of all channels from the hsm_secret.
+	peerSeedInfo = []byte("peer seed")
+
+	// channelSeedInfo is the HKDF info that is used to derive the seed of
+	// a single channel. Despite the name, the seed is unique per channel.
+	channelSeedInfo = []byte("per-peer seed")
+
+	// basepointsInfo is the HKDF info that is used to derive all basepoint
+	// secrets of a channel from its seed.
+	basepointsInfo = []byte("c-lightning")
+)
+
+// Basepoints contains all private keys c-lightning derives for a single
+// channel plus the seed of the channel's shachain.
+type Basepoints struct {
+	Funding        *btcec.PrivateKey
+	Revocation     *btcec.PrivateKey
+	Payment        *btcec.PrivateKey
+	Htlc           *btcec.PrivateKey
+	DelayedPayment *btcec.PrivateKey
+	ShaSeed        [32]byte
+}
+
+// PeerSeed derives the base secret from which all channel seeds are derived.
+func PeerSeed(secret [HsmSecretSize]byte) ([32]byte, error) {
+	var peerSeed [32]byte
+	reader := hkdf.New(sha256.New, secret[:], nil, peerSeedInfo)
+	_, err := io.ReadFull(reader, peerSeed[:])
+	return peerSeed, err
+}
+
+// ChannelSeed derives the seed of the channel with the given peer and database
+// ID. The database ID is the unique ID c-lightning assigns to each channel and
+// is encoded as a little endian 64-bit integer, exactly like hsmd does.
+func ChannelSeed(peerSeed [32]byte, peerID *btcec.PublicKey, dbid uint64) (
+	[32]byte, error) {
+
+	var (
+		channelSeed [32]byte
+		salt        [btcec.PubKeyBytesLenCompressed + 8]byte
+	)
+	copy(salt[:], peerID.SerializeCompressed())
+	binary.LittleEndian.PutUint64(
+		salt[btcec.PubKeyBytesLenCompressed:], dbid,
+	)
+
+	reader := hkdf.New(sha256.New, peerSeed[:], salt[:], channelSeedInfo)
+	_, err := io.ReadFull(reader, channelSeed[:])
+	return channelSeed, err
+}
+
+// DeriveBasepoints derives all basepoint secrets of a channel from its seed.
+func DeriveBasepoints(channelSeed [32]byte) (*Basepoints, error) {
+	// The secrets are derived in one go, in the order of the struct keys in
+	// hsmd: funding, revocation, htlc, payment, delayed payment and finally
+	// the shachain seed.
+	var keys [6 * 32]byte
+	reader := hkdf.New(sha256.New, channelSeed[:], nil, basepointsInfo)
+	if _, err := io.ReadFull(reader, keys[:]); err != nil {
+		return nil, err
+	}
+
+	privKey := func(idx int) *btcec.PrivateKey {
+		key, _ := btcec.PrivKeyFromBytes(
+			btcec.S256(), keys[idx*32:(idx+1)*32],
+		)
+		return key
+	}
+	basepoints := &Basepoints{
+		Funding:        privKey(0),
+		Revocation:     privKey(1),
+		Htlc:           privKey(2),
+		Payment:        privKey(3),
+		DelayedPayment: privKey(4),
+	}
+	copy(basepoints.ShaSeed[:], keys[5*32:])
+	return basepoints, nil
+}
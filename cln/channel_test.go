@@ -0,0 +1,95 @@
+package cln
+
+import (
+	"encoding/hex"
+	"testing"
+
+	"github.com/btcsuite/btcd/btcec"
+)
+
+const (
+	testPeerID = "0279be667ef9dcbbac55a06295ce870b" +
+		"07029bfcdb2dce28d959f2815b16f81798"
+	testDBID = 7
+)
+
+// TestDeriveBasepoints makes sure the channel keys are derived exactly like
+// c-lightning's hsmd does, with the secrets ordered as in its struct keys
+// (funding, revocation, htlc, payment, delayed payment, shachain seed).
+func TestDeriveBasepoints(t *testing.T) {
+	var secret [HsmSecretSize]byte
+	for i := range secret {
+		secret[i] = byte(i)
+	}
+	peerIDBytes, _ := hex.DecodeString(testPeerID)
+	peerID, err := btcec.ParsePubKey(peerIDBytes, btcec.S256())
+	if err != nil {
+		t.Fatalf("error parsing peer ID: %v", err)
+	}
+
+	peerSeed, err := PeerSeed(secret)
+	if err != nil {
+		t.Fatalf("error deriving peer seed: %v", err)
+	}
+	assertHex(t, "peer seed", peerSeed[:], "8cf7ea2f20e9dd032d3c6cd049"+
+		"2d2057bb8d55966fed80e9681e7eb7bce59c8a")
+
+	channelSeed, err := ChannelSeed(peerSeed, peerID, testDBID)
+	if err != nil {
+		t.Fatalf("error deriving channel seed: %v", err)
+	}
+	assertHex(t, "channel seed", channelSeed[:], "6ce12112d5d51b03a8368e6a"+
+		"ae9ba6e3e216befbe81527c0b3b30ec4e7c92304")
+
+	basepoints, err := DeriveBasepoints(channelSeed)
+	if err != nil {
+		t.Fatalf("error deriving basepoints: %v", err)
+	}
+	testCases := []struct {
+		name     string
+		key      []byte
+		expected string
+	}{{
+		name: "funding",
+		key:  basepoints.Funding.Serialize(),
+		expected: "6862d00a4e81ea6593ada3d6ccddbb4c" +
+			"b3a0ecc54053355fc241b2859896257f",
+	}, {
+		name: "revocation",
+		key:  basepoints.Revocation.Serialize(),
+		expected: "fbff3dfffe1ae5ba23afc585955bac4f" +
+			"1026a56c57701b2f0c84084861ba29ae",
+	}, {
+		name: "htlc",
+		key:  basepoints.Htlc.Serialize(),
+		expected: "c40e8bd410ca5f9d23760cbb83d51044" +
+			"d8a8fcf006c03761d87b48a411a5c8a6",
+	}, {
+		name: "payment",
+		key:  basepoints.Payment.Serialize(),
+		expected: "71f9aaa2ab1862c9253f96b52c76acd6" +
+			"b04967845cf350be6e1b9f1208099263",
+	}, {
+		name: "delayed payment",
+		key:  basepoints.DelayedPayment.Serialize(),
+		expected: "1031cfa9c31aea07b66bb47996e23475" +
+			"410c1c7382eabffacc0c114b6bb44dda",
+	}, {
+		name: "shachain seed",
+		key:  basepoints.ShaSeed[:],
+		expected: "8d4e4125fc945e421f9722f9cc9696d7" +
+			"423b0ded04e0fad32a639f608a7eeb2d",
+	}}
+	for _, tc := range testCases {
+		assertHex(t, tc.name, tc.key, tc.expected)
+	}
+}
+
+func assertHex(t *testing.T, name string, actual []byte, expected string) {
+	t.Helper()
+
+	if hex.EncodeToString(actual) != expected {
+		t.Fatalf("unexpected %s, got %x wanted %s", name, actual,
+			expected)
+	}
+}
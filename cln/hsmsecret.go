@@ -0,0 +1,111 @@
+package cln
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"io/ioutil"
+
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcutil/hdkeychain"
+	"golang.org/x/crypto/hkdf"
+)
+
+const (
+	// HsmSecretSize is the size of an unencrypted c-lightning hsm_secret
+	// file.
+	HsmSecretSize = 32
+
+	// encryptedHsmSecretSize is the size of an hsm_secret file that was
+	// encrypted with a password (libsodium secretstream header, secret
+	// and authentication tag).
+	encryptedHsmSecretSize = 73
+
+	// WalletBasePath is the BIP32 path below which c-lightning derives all
+	// its on-chain wallet keys. Unlike lnd, c-lightning uses a flat
+	// structure, the keys are simply at m/0/0/<index>.
+	WalletBasePath = "m/0/0"
+)
+
+var (
+	// bip32SeedInfo is the HKDF info that is used to derive the BIP32 seed
+	// from the hsm_secret.
+	bip32SeedInfo = []byte("bip32 seed")
+)
+
+// ReadHsmSecret reads an unencrypted c-lightning hsm_secret file.
+func ReadHsmSecret(fileName string) ([HsmSecretSize]byte, error) {
+	var secret [HsmSecretSize]byte
+	content, err := ioutil.ReadFile(fileName)
+	if err != nil {
+		return secret, err
+	}
+
+	switch len(content) {
+	case HsmSecretSize:
+		copy(secret[:], content)
+		return secret, nil
+
+	case encryptedHsmSecretSize:
+		return secret, fmt.Errorf("hsm_secret is encrypted, decrypt it " +
+			"first with 'lightning-hsmtool decrypt'")
+
+	default:
+		return secret, fmt.Errorf("invalid hsm_secret size %d, "+
+			"expected %d bytes", len(content), HsmSecretSize)
+	}
+}
+
+// HsmSecretFromSeed returns the hsm_secret c-lightning creates from a BIP39
+// seed. The lightning-hsmtool uses the first 32 bytes of the 64 byte seed.
+func HsmSecretFromSeed(seed []byte) ([HsmSecretSize]byte, error) {
+	var secret [HsmSecretSize]byte
+	if len(seed) < HsmSecretSize {
+		return secret, fmt.Errorf("seed too short")
+	}
+	copy(secret[:], seed[:HsmSecretSize])
+	return secret, nil
+}
+
+// MasterKey derives the BIP32 master key from the hsm_secret the same way
+// c-lightning's hsmd does: The BIP32 seed is created with HKDF-SHA256 using a
+// little endian 32-bit counter as the salt. The counter is increased until the
+// resulting seed produces a valid master key.
+func MasterKey(secret [HsmSecretSize]byte, params *chaincfg.Params) (
+	*hdkeychain.ExtendedKey, error) {
+
+	var salt [4]byte
+	for counter := uint32(0); ; counter++ {
+		binary.LittleEndian.PutUint32(salt[:], counter)
+		seed := make([]byte, 32)
+		reader := hkdf.New(sha256.New, secret[:], salt[:], bip32SeedInfo)
+		if _, err := io.ReadFull(reader, seed); err != nil {
+			return nil, err
+		}
+
+		masterKey, err := hdkeychain.NewMaster(seed, params)
+		switch err {
+		case nil:
+			return masterKey, nil
+
+		case hdkeychain.ErrUnusableSeed:
+			continue
+
+		default:
+			return nil, err
+		}
+	}
+}
+
+// WalletBaseKey derives the extended key at WalletBasePath from the master
+// key. All on-chain keys of c-lightning are direct children of this key.
+func WalletBaseKey(masterKey *hdkeychain.ExtendedKey) (*hdkeychain.ExtendedKey,
+	error) {
+
+	child, err := masterKey.Child(0)
+	if err != nil {
+		return nil, err
+	}
+	return child.Child(0)
+}
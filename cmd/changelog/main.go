@@ -0,0 +1,239 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/jessevdk/go-flags"
+)
+
+const (
+	defaultTo      = "HEAD"
+	defaultOutput  = "CHANGELOG.md"
+	defaultRepoURL = "https://github.com/guggero/chantools"
+
+	// fieldSeparator and recordSeparator are used to split the output of
+	// git log into commits and their fields. They can't appear in commit
+	// messages.
+	fieldSeparator  = "\x1f"
+	recordSeparator = "\x1e"
+)
+
+var (
+	// conventionalCommit matches the subject of a commit in the
+	// conventional commit format, for example "fix(sweep): wrong fee".
+	conventionalCommit = regexp.MustCompile(
+		`^(\w+)(\([^)]*\))?(!)?: (.+)$`,
+	)
+
+	// issueReference matches references to issues or pull requests, either
+	// in the same repository (#123) or in another one (owner/repo#123).
+	issueReference = regexp.MustCompile(
+		`(^|[\s(\[])([\w.-]+/[\w.-]+)?#(\d+)\b`,
+	)
+)
+
+// group is a section of the changelog that contains all commits of the same
+// type.
+type group struct {
+	commitType string
+	title      string
+	entries    []string
+}
+
+type config struct {
+	From    string `long:"from" description:"The tag or commit to start from (exclusive). Leave empty to use the last tag before the end."`
+	To      string `long:"to" description:"The tag or commit to end at (inclusive)."`
+	Version string `long:"version" description:"The version to use as the title of the entry. Leave empty to use the end tag or commit."`
+	Output  string `long:"output" description:"The changelog file the new entry is added to. Use - to print the entry to stdout only."`
+	RepoURL string `long:"repourl" description:"The URL of the repository used for issue and pull request links."`
+}
+
+func main() {
+	err := run()
+	if err == nil {
+		return
+	}
+
+	_, ok := err.(*flags.Error)
+	if !ok {
+		fmt.Printf("Error generating changelog: %v\n", err)
+	}
+	os.Exit(1)
+}
+
+func run() error {
+	cfg := &config{
+		To:      defaultTo,
+		Output:  defaultOutput,
+		RepoURL: defaultRepoURL,
+	}
+	if _, err := flags.Parse(cfg); err != nil {
+		return err
+	}
+
+	cfg.RepoURL = strings.TrimSuffix(cfg.RepoURL, "/")
+	if cfg.From == "" {
+		lastTag, err := git(
+			"describe", "--tags", "--abbrev=0", cfg.To+"^",
+		)
+		if err != nil {
+			return fmt.Errorf("cannot find last tag, specify "+
+				"--from: %v", err)
+		}
+		cfg.From = lastTag
+	}
+	if cfg.Version == "" {
+		cfg.Version = cfg.To
+	}
+
+	subjects, err := commitSubjects(cfg.From, cfg.To)
+	if err != nil {
+		return err
+	}
+	entry := formatEntry(
+		cfg.Version, time.Now(), groupCommits(subjects, cfg.RepoURL),
+	)
+
+	if cfg.Output == "-" {
+		fmt.Print(entry)
+		return nil
+	}
+	return prependEntry(cfg.Output, entry)
+}
+
+// git runs a git command and returns its trimmed output.
+func git(args ...string) (string, error) {
+	var stderr bytes.Buffer
+	cmd := exec.Command("git", args...)
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("git %s failed: %v: %s",
+			strings.Join(args, " "), err,
+			strings.TrimSpace(stderr.String()))
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// commitSubjects returns the subjects of all commits between the two revisions,
+// oldest first. Merge commits are skipped.
+func commitSubjects(from, to string) ([]string, error) {
+	out, err := git(
+		"log", "--reverse", "--no-merges",
+		"--format=%H"+fieldSeparator+"%s"+recordSeparator,
+		from+".."+to,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	var subjects []string
+	for _, record := range strings.Split(out, recordSeparator) {
+		fields := strings.SplitN(
+			strings.TrimSpace(record), fieldSeparator, 2,
+		)
+		if len(fields) != 2 {
+			continue
+		}
+		subjects = append(subjects, fields[1])
+	}
+	return subjects, nil
+}
+
+// groupCommits sorts the commit subjects into the groups of the changelog.
+// Subjects that aren't in the conventional commit format or are of a type
+// that isn't listed explicitly end up in the last group.
+func groupCommits(subjects []string, repoURL string) []*group {
+	groups := []*group{
+		{commitType: "feat", title: "Features"},
+		{commitType: "fix", title: "Bug Fixes"},
+		{commitType: "perf", title: "Performance Improvements"},
+		{commitType: "refactor", title: "Refactoring"},
+		{title: "Other Changes"},
+	}
+	other := groups[len(groups)-1]
+
+	for _, subject := range subjects {
+		target, description := other, subject
+		matches := conventionalCommit.FindStringSubmatch(subject)
+		if matches != nil {
+			for _, g := range groups {
+				if g.commitType == strings.ToLower(matches[1]) {
+					target = g
+					description = matches[4]
+					break
+				}
+			}
+			scope := strings.Trim(matches[2], "()")
+			if target != other && scope != "" {
+				description = fmt.Sprintf("**%s:** %s", scope,
+					description)
+			}
+			if matches[3] == "!" {
+				description = "**BREAKING:** " + description
+			}
+		}
+		target.entries = append(
+			target.entries, linkReferences(description, repoURL),
+		)
+	}
+	return groups
+}
+
+// linkReferences turns references to issues and pull requests into links.
+// GitHub redirects issue links to the pull request if the number belongs to
+// one.
+func linkReferences(text, repoURL string) string {
+	replace := func(ref string) string {
+		parts := issueReference.FindStringSubmatch(ref)
+		prefix, repo, number := parts[1], parts[2], parts[3]
+		if repo == "" {
+			return fmt.Sprintf("%s[#%s](%s/issues/%s)", prefix,
+				number, repoURL, number)
+		}
+		return fmt.Sprintf("%s[%s#%s](https://github.com/%s/issues/%s)",
+			prefix, repo, number, repo, number)
+	}
+	return issueReference.ReplaceAllStringFunc(text, replace)
+}
+
+func formatEntry(version string, date time.Time, groups []*group) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "## %s (%s)\n", version, date.Format("2006-01-02"))
+	for _, g := range groups {
+		if len(g.entries) == 0 {
+			continue
+		}
+		fmt.Fprintf(&b, "\n### %s\n\n", g.title)
+		for _, entry := range g.entries {
+			fmt.Fprintf(&b, "- %s\n", entry)
+		}
+	}
+	return b.String()
+}
+
+// prependEntry adds the entry to the top of the changelog file, below its
+// title. The file is created if it doesn't exist yet.
+func prependEntry(fileName, entry string) error {
+	const title = "# Changelog\n"
+
+	content, err := ioutil.ReadFile(fileName)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	rest := strings.TrimPrefix(string(content), title)
+	rest = strings.TrimLeft(rest, "\n")
+
+	newContent := title + "\n" + entry
+	if rest != "" {
+		newContent += "\n" + rest
+	}
+	return ioutil.WriteFile(fileName, []byte(newContent), 0644)
+}
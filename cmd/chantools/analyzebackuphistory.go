@@ -0,0 +1,199 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/btcsuite/btcutil/hdkeychain"
+	"github.com/guggero/chantools/lnd"
+	"github.com/lightningnetwork/lnd/chanbackup"
+	"github.com/lightningnetwork/lnd/keychain"
+)
+
+// backupFile is a channel.backup file found in the backup directory together
+// with the channels it contains.
+type backupFile struct {
+	name     string
+	modTime  time.Time
+	channels map[string]bool
+	err      error
+}
+
+type analyzeBackupHistoryCommand struct {
+	RootKey   string `long:"rootkey" description:"BIP32 HD root key of the wallet that was used to create the backups. Leave empty to prompt for lnd 24 word aezeed."`
+	BackupDir string `long:"backupdir" description:"The directory that contains the *.backup files to analyze."`
+}
+
+func (c *analyzeBackupHistoryCommand) Execute(_ []string) error {
+	setupChainParams(cfg)
+
+	var (
+		extendedKey *hdkeychain.ExtendedKey
+		err         error
+	)
+
+	// Check that root key is valid or fall back to console input.
+	switch {
+	case c.RootKey != "":
+		extendedKey, err = hdkeychain.NewKeyFromString(c.RootKey)
+
+	default:
+		extendedKey, _, err = rootKeyFromConsole()
+	}
+	if err != nil {
+		return fmt.Errorf("error reading root key: %v", err)
+	}
+
+	// Check that we have a backup directory.
+	if c.BackupDir == "" {
+		return fmt.Errorf("backup directory is required")
+	}
+	keyRing := &lnd.HDKeyRing{
+		ExtendedKey: extendedKey,
+		ChainParams: chainParams,
+	}
+	files, err := readBackupDir(cleanAndExpandPath(c.BackupDir), keyRing)
+	if err != nil {
+		return err
+	}
+	if len(files) == 0 {
+		return fmt.Errorf("no *.backup files found in %s", c.BackupDir)
+	}
+
+	// The files are sorted oldest first, so the last file that contains a
+	// channel is the most recent backup of it.
+	latest := make(map[string]*backupFile)
+	fmt.Printf("Backup files (oldest first):\n")
+	for _, file := range files {
+		if file.err != nil {
+			fmt.Printf("  %s (%s): invalid, %v\n", file.name,
+				file.modTime.Format(time.RFC3339), file.err)
+			continue
+		}
+		fmt.Printf("  %s (%s): %d channels\n", file.name,
+			file.modTime.Format(time.RFC3339), len(file.channels))
+		for chanPoint := range file.channels {
+			latest[chanPoint] = file
+		}
+	}
+	if len(latest) == 0 {
+		return fmt.Errorf("none of the backup files could be decrypted")
+	}
+
+	var chanPoints []string
+	for chanPoint := range latest {
+		chanPoints = append(chanPoints, chanPoint)
+	}
+	sort.Strings(chanPoints)
+
+	fmt.Printf("\nChannels (%d in total):\n", len(chanPoints))
+	for _, chanPoint := range chanPoints {
+		var missing []string
+		for _, file := range files {
+			if file.err == nil && !file.channels[chanPoint] {
+				missing = append(missing, file.name)
+			}
+		}
+		fmt.Printf("  %s: most recent in %s", chanPoint,
+			latest[chanPoint].name)
+		if len(missing) > 0 {
+			fmt.Printf(", missing in %s",
+				strings.Join(missing, ", "))
+		}
+		fmt.Println()
+	}
+
+	cover := coveringBackupFiles(files, len(chanPoints))
+	fmt.Printf("\nRecommendation:\n")
+	if len(cover) == 1 {
+		fmt.Printf("  %s contains all channels, use it for the "+
+			"recovery.\n", cover[0].name)
+		return nil
+	}
+	fmt.Printf("  No single file contains all channels. Use the "+
+		"following %d files together, channels that are in more than "+
+		"one of them only need to be restored once:\n", len(cover))
+	for _, file := range cover {
+		fmt.Printf("    %s\n", file.name)
+	}
+	return nil
+}
+
+// readBackupDir decrypts all *.backup files in a directory and returns them
+// sorted by their modification time, oldest first. Files that can't be
+// decrypted are returned with their error set.
+func readBackupDir(dir string, ring keychain.KeyRing) ([]*backupFile, error) {
+	infos, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("error reading backup directory: %v",
+			err)
+	}
+
+	var files []*backupFile
+	for _, info := range infos {
+		if info.IsDir() || filepath.Ext(info.Name()) != ".backup" {
+			continue
+		}
+		file := &backupFile{
+			name:     info.Name(),
+			modTime:  info.ModTime(),
+			channels: make(map[string]bool),
+		}
+		files = append(files, file)
+
+		multiFile := chanbackup.NewMultiFile(
+			filepath.Join(dir, info.Name()),
+		)
+		multi, err := multiFile.ExtractMulti(ring)
+		if err != nil {
+			file.err = err
+			continue
+		}
+		for _, single := range multi.StaticBackups {
+			file.channels[single.FundingOutpoint.String()] = true
+		}
+	}
+
+	sort.SliceStable(files, func(i, j int) bool {
+		return files[i].modTime.Before(files[j].modTime)
+	})
+	return files, nil
+}
+
+// coveringBackupFiles returns a small set of files that together contain all
+// channels. A file that adds the most missing channels is picked in every step
+// and the newer file wins a tie, which gives the optimal result for the usual
+// case of a growing set of channels.
+func coveringBackupFiles(files []*backupFile, numChannels int) []*backupFile {
+	covered := make(map[string]bool)
+	var cover []*backupFile
+	for len(covered) < numChannels {
+		var (
+			best      *backupFile
+			bestCount int
+		)
+		for _, file := range files {
+			count := 0
+			for chanPoint := range file.channels {
+				if !covered[chanPoint] {
+					count++
+				}
+			}
+			if count > 0 && count >= bestCount {
+				best, bestCount = file, count
+			}
+		}
+		if best == nil {
+			break
+		}
+		for chanPoint := range best.channels {
+			covered[chanPoint] = true
+		}
+		cover = append(cover, best)
+	}
+	return cover
+}
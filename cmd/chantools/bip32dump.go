@@ -0,0 +1,145 @@
+package main
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	"github.com/btcsuite/btcutil"
+	"github.com/btcsuite/btcutil/hdkeychain"
+	"github.com/guggero/chantools/lnd"
+)
+
+const (
+	defaultBip32DumpDepth    = 2
+	defaultBip32DumpChildren = 2
+)
+
+type bip32DumpCommand struct {
+	RootKey        string  `long:"rootkey" description:"BIP32 HD root key to dump the key hierarchy of. Leave empty to prompt for lnd 24 word aezeed."`
+	Path           string  `long:"path" description:"The BIP32 derivation path to walk down to, every intermediate key is printed. Must start with \"m\". (default m)"`
+	Depth          *uint32 `long:"depth" description:"The number of levels below the path to dump, for example 2 for the branch and leaf levels below an account path. With 0 only the keys of the path itself are printed. (default 2)"`
+	Children       uint32  `long:"children" description:"The number of children to derive of every key below the path. (default 2)"`
+	ShowOnlyPublic bool    `long:"showonlypublic" description:"Replace all extended private keys with <redacted> in the output."`
+}
+
+func (c *bip32DumpCommand) Execute(_ []string) error {
+	setupChainParams(cfg)
+
+	var (
+		extendedKey *hdkeychain.ExtendedKey
+		err         error
+	)
+
+	// Check that root key is valid or fall back to console input.
+	switch {
+	case c.RootKey != "":
+		extendedKey, err = hdkeychain.NewKeyFromString(c.RootKey)
+
+	default:
+		extendedKey, _, err = rootKeyFromConsole()
+	}
+	if err != nil {
+		return fmt.Errorf("error reading root key: %v", err)
+	}
+
+	// Set default values.
+	if c.Path == "" {
+		c.Path = "m"
+	}
+	depth := uint32(defaultBip32DumpDepth)
+	if c.Depth != nil {
+		depth = *c.Depth
+	}
+	if c.Children == 0 {
+		c.Children = defaultBip32DumpChildren
+	}
+
+	// The root key itself has an empty path that can't be parsed.
+	var path []uint32
+	if c.Path != "m" {
+		path, err = lnd.ParsePath(c.Path)
+		if err != nil {
+			return fmt.Errorf("error parsing path: %v", err)
+		}
+	}
+
+	// Walk down the path first, each level is indented one step further.
+	// The last key of the path is the root of the tree that is dumped.
+	key := extendedKey
+	if err := c.printKey(key, nil); err != nil {
+		return err
+	}
+	for idx, index := range path {
+		key, err = key.Child(index)
+		if err != nil {
+			return fmt.Errorf("error deriving child %s: %v",
+				lnd.FormatPath(path[:idx+1]), err)
+		}
+		if err := c.printKey(key, path[:idx+1]); err != nil {
+			return err
+		}
+	}
+	return c.dumpChildren(key, path, depth)
+}
+
+// dumpChildren prints the first children of the given key and all of their
+// children recursively, until the given number of levels is printed.
+func (c *bip32DumpCommand) dumpChildren(key *hdkeychain.ExtendedKey,
+	path []uint32, levels uint32) error {
+
+	if levels == 0 {
+		return nil
+	}
+	for index := uint32(0); index < c.Children; index++ {
+		child, err := key.Child(index)
+		if err != nil {
+			return fmt.Errorf("error deriving child %d of %s: %v",
+				index, lnd.FormatPath(path), err)
+		}
+		childPath := append(append([]uint32{}, path...), index)
+		if err := c.printKey(child, childPath); err != nil {
+			return err
+		}
+		err = c.dumpChildren(child, childPath, levels-1)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// printKey prints a single key of the hierarchy, indented by its depth. The
+// root key has no child index.
+func (c *bip32DumpCommand) printKey(key *hdkeychain.ExtendedKey,
+	path []uint32) error {
+
+	pubKey, err := key.ECPubKey()
+	if err != nil {
+		return fmt.Errorf("error deriving public key: %v", err)
+	}
+	xpub, err := key.Neuter()
+	if err != nil {
+		return fmt.Errorf("error neutering key: %v", err)
+	}
+	xprv := "<redacted>"
+	if key.IsPrivate() && !c.ShowOnlyPublic {
+		xprv = key.String()
+	}
+	childIndex := "none"
+	if len(path) > 0 {
+		childIndex = strings.TrimPrefix(
+			lnd.FormatPath(path[len(path)-1:]), "m/",
+		)
+	}
+	fingerprint := btcutil.Hash160(pubKey.SerializeCompressed())[:4]
+
+	indent := strings.Repeat("  ", len(path))
+	fmt.Printf("%s%s\n", indent, lnd.FormatPath(path))
+	fmt.Printf("%s  child index: %s\n", indent, childIndex)
+	fmt.Printf("%s  fingerprint: %s\n", indent,
+		hex.EncodeToString(fingerprint))
+	fmt.Printf("%s  xprv: %s\n", indent, xprv)
+	fmt.Printf("%s  xpub: %s\n", indent, xpub.String())
+	return nil
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"runtime"
+	"time"
+
+	"github.com/guggero/chantools/btc"
+	"github.com/guggero/chantools/lnd"
+)
+
+type bip32XPubWatchCommand struct {
+	XPub               string `long:"xpub" description:"The extended public key of the account to watch, for example exported from a hardware wallet. Can be encoded as xpub, ypub, zpub or tpub, upub, vpub on test networks."`
+	ExtendedKeyVersion string `long:"extendedkeyversion" description:"The SLIP-0132 version to interpret the xpub with, one of xpub, ypub, zpub or tpub, upub, vpub for test networks. Only needed if the version can't be detected from the prefix of the xpub."`
+	Format             string `long:"format" description:"The format of the generated import script. Currently supported are: bitcoin-cli-watchonly, bitcoin-cli-taproot, bitcoin-descriptors. (default bitcoin-cli-watchonly)"`
+	DerivationPath     string `long:"derivationpath" description:"The derivation path of the account the xpub belongs to. It is used for the labels of the keys and its purpose determines the script type of the descriptors (44: pkh, 49: sh(wpkh), 84: wpkh, 86: tr). (default m/49'/0'/0' for ypub and upub, m/84'/0'/0' otherwise)"`
+	RecoveryWindow     uint32 `long:"recoverywindow" description:"The number of keys to derive per internal/external branch. (default 2500)"`
+	RescanFrom         uint32 `long:"rescanfrom" description:"The block number to rescan from. (default 500000, 0 on signet)"`
+	LabelPrefix        string `long:"labelprefix" description:"A prefix that is added to the labels of all keys, for example to tell the keys of multiple wallets apart."`
+}
+
+func (c *bip32XPubWatchCommand) Execute(_ []string) error {
+	setupChainParams(cfg)
+
+	// Set default values.
+	if c.Format == "" {
+		c.Format = formatWatchOnly
+	}
+	if c.RecoveryWindow == 0 {
+		c.RecoveryWindow = defaultRecoveryWindow
+	}
+	if c.RescanFrom == 0 {
+		c.RescanFrom = defaultRescanFromHeight()
+	}
+
+	switch c.Format {
+	case formatWatchOnly, formatTaproot, formatDescriptors:
+
+	case "bitcoin-cli", formatImportWallet, formatJSON, formatColdcard:
+		return fmt.Errorf("the %s format needs the private keys, use "+
+			"genimportscript with the root key instead", c.Format)
+
+	default:
+		return fmt.Errorf("unknown format %s", c.Format)
+	}
+
+	if c.XPub == "" {
+		return fmt.Errorf("xpub is required")
+	}
+	accountKey, prefix, err := slip132Decode(c.XPub, c.ExtendedKeyVersion)
+	if err != nil {
+		return err
+	}
+
+	// The SLIP-0132 version tells us the purpose of the account, so it
+	// must match the derivation path.
+	purpose := uint32(84)
+	for versionPurpose, prefixes := range accountSlip132Prefixes {
+		if prefix == prefixes[0] || prefix == prefixes[1] {
+			purpose = versionPurpose
+		}
+	}
+	if c.DerivationPath == "" {
+		c.DerivationPath = fmt.Sprintf("m/%d'/0'/0'", purpose)
+	}
+	path, err := lnd.ParsePath(c.DerivationPath)
+	if err != nil {
+		return fmt.Errorf("error parsing path: %v", err)
+	}
+	if len(path) == 0 {
+		return fmt.Errorf("derivation path must contain the purpose")
+	}
+	pathPurpose := path[0] - lnd.HardenedKeyStart
+	if _, ok := accountSlip132Prefixes[purpose]; ok &&
+		pathPurpose != purpose {
+
+		return fmt.Errorf("derivation path %s doesn't match the %s "+
+			"version of the xpub, which is for purpose %d",
+			c.DerivationPath, prefix, purpose)
+	}
+	pathString := lnd.FormatPath(path)
+
+	if c.Format == formatDescriptors {
+		var requests []*btc.ImportDescriptorRequest
+		for branch := uint32(0); branch <= 1; branch++ {
+			// We don't know the fingerprint of the master key, so
+			// the descriptors have no key origin.
+			desc, err := accountDescriptor(
+				path, "", accountKey.String(), branch,
+			)
+			if err != nil {
+				return err
+			}
+			request := &btc.ImportDescriptorRequest{
+				Desc:      desc,
+				Active:    true,
+				Range:     [2]uint32{0, c.RecoveryWindow - 1},
+				Timestamp: blockToTimestamp(c.RescanFrom),
+				Internal:  branch == 1,
+			}
+			requests = append(requests, request)
+		}
+		content, err := json.MarshalIndent(requests, "", " ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(content))
+		return nil
+	}
+
+	keys, err := deriveBranchKeys(
+		accountKey, nil, pathString, c.RecoveryWindow,
+		uint32(runtime.NumCPU()), true, nil,
+	)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("# Watch-only wallet dump created by chantools on %s\n",
+		time.Now().UTC())
+	printFn := importScriptPrintFn(
+		os.Stdout, c.Format, importWalletBirthday(c.RescanFrom), nil,
+	)
+
+	// External branch first (<DerivationPath>/0/i), then the internal
+	// branch (<DerivationPath>/1/i).
+	for _, key := range keys {
+		if err := printFn(os.Stdout, key, c.LabelPrefix); err != nil {
+			return err
+		}
+	}
+	fmt.Printf("bitcoin-cli rescanblockchain %d\n", c.RescanFrom)
+	return nil
+}
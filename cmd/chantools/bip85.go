@@ -0,0 +1,116 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/btcsuite/btcutil/hdkeychain"
+	"github.com/guggero/chantools/bip85"
+)
+
+const (
+	defaultBIP85Words    = 24
+	defaultBIP85HexBytes = 32
+)
+
+// bip85Command only groups the BIP85 applications as sub commands.
+type bip85Command struct{}
+
+// bip85RootKey are the options all BIP85 applications share.
+type bip85RootKey struct {
+	RootKey string `long:"rootkey" description:"BIP32 HD root key to derive the child entropy from. Leave empty to prompt for lnd 24 word aezeed."`
+	Index   uint32 `long:"index" description:"The index of the child to derive."`
+}
+
+// rootKey parses the root key or falls back to console input.
+func (r *bip85RootKey) rootKey() (*hdkeychain.ExtendedKey, error) {
+	var (
+		extendedKey *hdkeychain.ExtendedKey
+		err         error
+	)
+	switch {
+	case r.RootKey != "":
+		extendedKey, err = hdkeychain.NewKeyFromString(r.RootKey)
+
+	default:
+		extendedKey, _, err = rootKeyFromConsole()
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading root key: %v", err)
+	}
+	return extendedKey, nil
+}
+
+type bip85BIP39Command struct {
+	bip85RootKey
+
+	Words uint32 `long:"words" description:"The number of words of the mnemonic, either 12, 18 or 24. (default 24)"`
+}
+
+func (c *bip85BIP39Command) Execute(_ []string) error {
+	setupChainParams(cfg)
+
+	// Set default values.
+	if c.Words == 0 {
+		c.Words = defaultBIP85Words
+	}
+
+	extendedKey, err := c.rootKey()
+	if err != nil {
+		return err
+	}
+	mnemonic, err := bip85.DeriveBIP39(extendedKey, c.Words, c.Index)
+	if err != nil {
+		return fmt.Errorf("error deriving mnemonic: %v", err)
+	}
+	fmt.Printf("Child BIP39 mnemonic (m/%d'/%d'/%d'/%d'/%d'): %s\n",
+		bip85.Purpose, bip85.AppBIP39, bip85.LanguageEnglish, c.Words,
+		c.Index, mnemonic)
+	return nil
+}
+
+type bip85WIFCommand struct {
+	bip85RootKey
+}
+
+func (c *bip85WIFCommand) Execute(_ []string) error {
+	setupChainParams(cfg)
+
+	extendedKey, err := c.rootKey()
+	if err != nil {
+		return err
+	}
+	wif, err := bip85.DeriveWIF(extendedKey, c.Index, chainParams)
+	if err != nil {
+		return fmt.Errorf("error deriving WIF: %v", err)
+	}
+	fmt.Printf("Child private key (m/%d'/%d'/%d'): %s\n", bip85.Purpose,
+		bip85.AppWIF, c.Index, wif.String())
+	return nil
+}
+
+type bip85HexCommand struct {
+	bip85RootKey
+
+	NumBytes uint32 `long:"numbytes" description:"The number of bytes of entropy to derive, between 16 and 64. (default 32)"`
+}
+
+func (c *bip85HexCommand) Execute(_ []string) error {
+	setupChainParams(cfg)
+
+	// Set default values.
+	if c.NumBytes == 0 {
+		c.NumBytes = defaultBIP85HexBytes
+	}
+
+	extendedKey, err := c.rootKey()
+	if err != nil {
+		return err
+	}
+	entropy, err := bip85.DeriveHex(extendedKey, c.NumBytes, c.Index)
+	if err != nil {
+		return fmt.Errorf("error deriving entropy: %v", err)
+	}
+	fmt.Printf("Child entropy (m/%d'/%d'/%d'/%d'): %x\n", bip85.Purpose,
+		bip85.AppHex, c.NumBytes, c.Index, entropy)
+	return nil
+}
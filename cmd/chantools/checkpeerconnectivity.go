@@ -0,0 +1,198 @@
+package main
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/btcsuite/btcd/btcec"
+	"github.com/guggero/chantools/btc"
+	"github.com/lightningnetwork/lnd/channeldb"
+)
+
+const (
+	defaultNodeAPIURL  = "https://mempool.space/api/v1/lightning"
+	defaultDialTimeout = 5
+)
+
+// peerAddress is the result of a connection attempt to one address of a peer.
+type peerAddress struct {
+	Address   string
+	Reachable bool
+	LatencyMs int64
+	Error     string
+}
+
+// peerConnectivity is the connectivity report of a single channel peer.
+type peerConnectivity struct {
+	PubKey    string
+	Alias     string
+	Channels  int
+	Reachable bool
+	Addresses []*peerAddress
+}
+
+type checkPeerConnectivityCommand struct {
+	ChannelDB string `long:"channeldb" description:"The lnd channel.db file to read the channel peers from."`
+	NodeAPI   string `long:"nodeapi" description:"The URL of a mempool.space compatible lightning API to look up the peers' addresses. Set to 'none' to only use the addresses stored in the channel DB. (default https://mempool.space/api/v1/lightning)"`
+	Timeout   uint32 `long:"timeout" description:"The timeout in seconds for each connection attempt. (default 5)"`
+}
+
+func (c *checkPeerConnectivityCommand) Execute(_ []string) error {
+	setupChainParams(cfg)
+
+	// Check that we have a channel DB.
+	if c.ChannelDB == "" {
+		return fmt.Errorf("channel DB is required")
+	}
+	db, err := channeldb.Open(
+		path.Dir(c.ChannelDB), channeldb.OptionSetSyncFreelist(true),
+		channeldb.OptionReadOnly(true),
+	)
+	if err != nil {
+		return fmt.Errorf("error opening channel DB: %v", err)
+	}
+
+	// Set default values.
+	if c.NodeAPI == "" {
+		c.NodeAPI = defaultNodeAPIURL
+	}
+	if c.Timeout == 0 {
+		c.Timeout = defaultDialTimeout
+	}
+
+	channels, err := db.FetchAllChannels()
+	if err != nil {
+		return fmt.Errorf("error fetching channels: %v", err)
+	}
+
+	// Group the channels by peer, we only need to check each peer once.
+	var (
+		peers     []*btcec.PublicKey
+		chanCount = make(map[string]int)
+	)
+	for _, channel := range channels {
+		key := hex.EncodeToString(
+			channel.IdentityPub.SerializeCompressed(),
+		)
+		if chanCount[key] == 0 {
+			peers = append(peers, channel.IdentityPub)
+		}
+		chanCount[key]++
+	}
+
+	var (
+		api     = &btc.NodeAPI{BaseURL: c.NodeAPI}
+		timeout = time.Duration(c.Timeout) * time.Second
+		results = make([]*peerConnectivity, len(peers))
+	)
+	for idx, peer := range peers {
+		pubKey := hex.EncodeToString(peer.SerializeCompressed())
+		result := &peerConnectivity{
+			PubKey:   pubKey,
+			Channels: chanCount[pubKey],
+		}
+		results[idx] = result
+
+		addrs, alias := peerAddresses(
+			db, api, peer, c.NodeAPI != "none",
+		)
+		result.Alias = alias
+		if len(addrs) == 0 {
+			log.Infof("Peer %s: no known addresses", pubKey)
+			continue
+		}
+
+		for _, addr := range addrs {
+			peerAddr := checkAddress(addr, timeout)
+			result.Addresses = append(result.Addresses, peerAddr)
+			if peerAddr.Reachable {
+				result.Reachable = true
+			}
+		}
+		log.Infof("Peer %s (%s, %d channels): reachable=%v",
+			pubKey, alias, result.Channels, result.Reachable)
+	}
+
+	summaryBytes, err := json.MarshalIndent(results, "", " ")
+	if err != nil {
+		return err
+	}
+	fileName := fmt.Sprintf("results/peerconnectivity-%s.json",
+		time.Now().Format("2006-01-02-15-04-05"))
+	log.Infof("Writing result to %s", fileName)
+	return ioutil.WriteFile(fileName, summaryBytes, 0644)
+}
+
+// peerAddresses returns all unique addresses of a peer that are stored in the
+// channel DB and, if enabled, announced in the public graph.
+func peerAddresses(db *channeldb.DB, api *btc.NodeAPI,
+	peer *btcec.PublicKey, useAPI bool) ([]string, string) {
+
+	var (
+		addrs []string
+		alias string
+		seen  = make(map[string]bool)
+	)
+	addAddr := func(addr string) {
+		if !seen[addr] {
+			seen[addr] = true
+			addrs = append(addrs, addr)
+		}
+	}
+
+	dbAddrs, err := db.AddrsForNode(peer)
+	if err != nil {
+		log.Debugf("No addresses in DB for %x: %v",
+			peer.SerializeCompressed(), err)
+	}
+	for _, addr := range dbAddrs {
+		addAddr(addr.String())
+	}
+
+	if useAPI {
+		node, err := api.Node(hex.EncodeToString(
+			peer.SerializeCompressed(),
+		))
+		if err != nil {
+			log.Warnf("Could not look up node %x: %v",
+				peer.SerializeCompressed(), err)
+		} else {
+			alias = node.Alias
+			for _, addr := range node.Addresses() {
+				addAddr(addr)
+			}
+		}
+	}
+	return addrs, alias
+}
+
+// checkAddress tries to open a TCP connection to the given address and
+// measures the time it took.
+func checkAddress(addr string, timeout time.Duration) *peerAddress {
+	result := &peerAddress{Address: addr}
+
+	// We can't reach Tor addresses without a Tor proxy.
+	host, _, err := net.SplitHostPort(addr)
+	if err == nil && strings.HasSuffix(host, ".onion") {
+		result.Error = "tor address, not checked"
+		return result
+	}
+
+	start := time.Now()
+	conn, err := net.DialTimeout("tcp", addr, timeout)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	_ = conn.Close()
+
+	result.Reachable = true
+	result.LatencyMs = time.Since(start).Milliseconds()
+	return result
+}
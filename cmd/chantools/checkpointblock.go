@@ -0,0 +1,137 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/guggero/chantools/btc"
+)
+
+const (
+	checkpointDateFormat = "2006-01-02"
+
+	// checkpointSafetyMargin is subtracted from the date to get the block
+	// to rescan from. The btcwallet gives the seed birthday the same slack.
+	checkpointSafetyMargin = 48 * time.Hour
+
+	// checkpointVerboseBlocks is the number of blocks before and after the
+	// found block that are printed in verbose mode.
+	checkpointVerboseBlocks = 10
+)
+
+type checkpointBlockCommand struct {
+	Date    string `long:"date" description:"The approximate birthday of the wallet in the format YYYY-MM-DD, as midnight UTC."`
+	Verbose bool   `long:"verbose" description:"Also print the 10 blocks before and after the found block to verify the result. Requires the API of --apiurl."`
+}
+
+func (c *checkpointBlockCommand) Execute(_ []string) error {
+	setupChainParams(cfg)
+
+	if c.Date == "" {
+		return fmt.Errorf("date is required")
+	}
+	date, err := time.Parse(checkpointDateFormat, c.Date)
+	if err != nil {
+		return fmt.Errorf("invalid date %s, expected YYYY-MM-DD: %v",
+			c.Date, err)
+	}
+	rescanDate := date.Add(-checkpointSafetyMargin)
+
+	// Without an API we can only estimate the block from the checkpoints,
+	// so there is no hash or actual timestamp to show.
+	if cfg.APIURL == "" {
+		if c.Verbose {
+			return fmt.Errorf("verbose requires a chain backend " +
+				"to look up the blocks, set one with --apiurl")
+		}
+		log.Warnf("No API URL configured, estimating the block from " +
+			"the checkpoints instead")
+		fmt.Printf("Estimated block on %s: %d\n", chainParams.Name,
+			estimateBirthdayBlock(date))
+		fmt.Printf("Suggested --rescanfrom: %d (%.0f hours before "+
+			"%s)\n", estimateBirthdayBlock(rescanDate),
+			checkpointSafetyMargin.Hours(), c.Date)
+		return nil
+	}
+
+	api := &btc.ExplorerAPI{
+		BaseURL: cfg.APIURL,
+		Timeout: cfg.APITimeout,
+		Retries: cfg.APIRetries,
+	}
+	block, err := lastBlockBefore(api, date)
+	if err != nil {
+		return err
+	}
+	rescanBlock, err := lastBlockBefore(api, rescanDate)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Block on %s: %d\n", chainParams.Name, block.Height)
+	fmt.Printf("Hash: %s\n", block.ID)
+	fmt.Printf("Timestamp: %v\n", time.Unix(block.Timestamp, 0).UTC())
+	fmt.Printf("Suggested --rescanfrom: %d (%.0f hours before %s)\n",
+		rescanBlock.Height, checkpointSafetyMargin.Hours(), c.Date)
+
+	if !c.Verbose {
+		return nil
+	}
+
+	// The blocks around the result show how far off the timestamps of the
+	// blocks are from the date.
+	tip, err := api.TipHeight()
+	if err != nil {
+		return err
+	}
+	from := uint32(0)
+	if block.Height > checkpointVerboseBlocks {
+		from = block.Height - checkpointVerboseBlocks
+	}
+	to := block.Height + checkpointVerboseBlocks
+	if to > tip {
+		to = tip
+	}
+	fmt.Println()
+	for height := from; height <= to; height++ {
+		verboseBlock, err := api.BlockAtHeight(height)
+		if err != nil {
+			return fmt.Errorf("error looking up block %d: %v",
+				height, err)
+		}
+		marker := " "
+		if height == block.Height {
+			marker = "*"
+		}
+		fmt.Printf("%s %d %s %v\n", marker, height, verboseBlock.ID,
+			time.Unix(verboseBlock.Timestamp, 0).UTC())
+	}
+	return nil
+}
+
+// lastBlockBefore returns the last block with a timestamp at or before the
+// given time. The timestamps of the blocks are not monotonic, so we start at
+// the first block with a median time past the given time and go back until
+// the timestamp of a block isn't past it either.
+func lastBlockBefore(api *btc.ExplorerAPI,
+	timestamp time.Time) (*btc.ExplorerBlock, error) {
+
+	height, err := api.FirstBlockAfter(timestamp)
+	if err != nil {
+		return nil, fmt.Errorf("error looking up block: %v", err)
+	}
+	for {
+		block, err := api.BlockAtHeight(height)
+		if err != nil {
+			return nil, fmt.Errorf("error looking up block %d: %v",
+				height, err)
+		}
+		if block.Timestamp <= timestamp.Unix() {
+			return block, nil
+		}
+		if height == 0 {
+			return nil, fmt.Errorf("no block was mined before %v",
+				timestamp.UTC())
+		}
+		height--
+	}
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"sort"
+	"time"
+)
+
+// blockCheckpoint is a block of which both the height and the timestamp are
+// known.
+type blockCheckpoint struct {
+	height    uint32
+	timestamp int64
+}
+
+// mainNetCheckpoints are known blocks of the main network, sorted by height.
+// The actual average block time varied a lot over the years, so estimates are
+// only interpolated between the two closest checkpoints.
+var mainNetCheckpoints = []blockCheckpoint{
+	{height: 0, timestamp: 1231006505},
+	{height: 100000, timestamp: 1293623863},
+	{height: 200000, timestamp: 1348310759},
+	{height: 210000, timestamp: 1354116278},
+	{height: 300000, timestamp: 1399703554},
+	{height: 400000, timestamp: 1456417484},
+	{height: 420000, timestamp: 1468082773},
+	{height: 481824, timestamp: 1503539857},
+	{height: 500000, timestamp: 1513622125},
+	{height: 630000, timestamp: 1589225023},
+	{height: 700000, timestamp: 1631333672},
+	{height: 709632, timestamp: 1636866927},
+	{height: 800000, timestamp: 1690168629},
+	{height: 840000, timestamp: 1713571767},
+}
+
+// networkCheckpoints returns the known blocks of the current network. Test
+// networks have none, their block times have nothing to do with the wall
+// clock because of the minimum difficulty rules.
+func networkCheckpoints() []blockCheckpoint {
+	switch chainParams.Name {
+	case "mainnet":
+		return mainNetCheckpoints
+
+	default:
+		return nil
+	}
+}
+
+// checkpointHeight estimates the height of the block that was mined at the
+// given time by interpolating between the two checkpoints around it. After the
+// last checkpoint the average block time of 10 minutes is assumed.
+func checkpointHeight(checkpoints []blockCheckpoint,
+	timestamp time.Time) uint32 {
+
+	unix := timestamp.Unix()
+	idx := sort.Search(len(checkpoints), func(i int) bool {
+		return checkpoints[i].timestamp > unix
+	})
+	switch {
+	case idx == 0:
+		return 0
+
+	case idx == len(checkpoints):
+		last := checkpoints[idx-1]
+		return last.height + uint32((unix-last.timestamp)/600)
+	}
+
+	prev, next := checkpoints[idx-1], checkpoints[idx]
+	blocks := int64(next.height - prev.height)
+	return prev.height + uint32(
+		(unix-prev.timestamp)*blocks/(next.timestamp-prev.timestamp),
+	)
+}
+
+// checkpointTimestamp is the inverse of checkpointHeight and estimates the
+// timestamp of the block with the given height.
+func checkpointTimestamp(checkpoints []blockCheckpoint, height uint32) int64 {
+	idx := sort.Search(len(checkpoints), func(i int) bool {
+		return checkpoints[i].height > height
+	})
+	switch {
+	case idx == 0:
+		return checkpoints[0].timestamp
+
+	case idx == len(checkpoints):
+		last := checkpoints[idx-1]
+		return last.timestamp + int64(height-last.height)*600
+	}
+
+	prev, next := checkpoints[idx-1], checkpoints[idx]
+	duration := next.timestamp - prev.timestamp
+	return prev.timestamp + int64(height-prev.height)*duration/
+		int64(next.height-prev.height)
+}
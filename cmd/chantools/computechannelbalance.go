@@ -0,0 +1,97 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"path"
+
+	"github.com/lightningnetwork/lnd/channeldb"
+)
+
+// channelBalance is the sum of the balances of a group of channels.
+type channelBalance struct {
+	NumChannels     int    `json:"number_of_channels"`
+	LocalBalance    uint64 `json:"total_local_balance_sat"`
+	RemoteBalance   uint64 `json:"total_remote_balance_sat"`
+	UnsettledHTLCs  uint64 `json:"total_unsettled_htlcs_sat"`
+	AverageCapacity uint64 `json:"average_channel_capacity_sat"`
+}
+
+// channelBalances are the balances of all channels that are not fully closed,
+// grouped by their state.
+type channelBalances struct {
+	Open         *channelBalance `json:"open"`
+	PendingOpen  *channelBalance `json:"pending_open"`
+	PendingClose *channelBalance `json:"pending_close"`
+}
+
+type computeChannelBalanceCommand struct {
+	ChannelDB string `long:"channeldb" description:"The lnd channel.db file to sum up the channel balances of."`
+}
+
+func (c *computeChannelBalanceCommand) Execute(_ []string) error {
+	setupChainParams(cfg)
+
+	// Check that we have a channel DB.
+	if c.ChannelDB == "" {
+		return fmt.Errorf("channel DB is required")
+	}
+	db, err := channeldb.Open(
+		path.Dir(c.ChannelDB), channeldb.OptionSetSyncFreelist(true),
+		channeldb.OptionReadOnly(true),
+	)
+	if err != nil {
+		return fmt.Errorf("error opening channel DB: %v", err)
+	}
+	defer db.Close()
+
+	open, err := db.FetchAllOpenChannels()
+	if err != nil {
+		return fmt.Errorf("error fetching open channels: %v", err)
+	}
+	pendingOpen, err := db.FetchPendingChannels()
+	if err != nil {
+		return fmt.Errorf("error fetching pending channels: %v", err)
+	}
+	pendingClose, err := db.FetchWaitingCloseChannels()
+	if err != nil {
+		return fmt.Errorf("error fetching waiting close channels: %v",
+			err)
+	}
+
+	balances := &channelBalances{
+		Open:         sumChannelBalances(open),
+		PendingOpen:  sumChannelBalances(pendingOpen),
+		PendingClose: sumChannelBalances(pendingClose),
+	}
+	balanceBytes, err := json.MarshalIndent(balances, "", " ")
+	if err != nil {
+		return err
+	}
+	fmt.Println(string(balanceBytes))
+	return nil
+}
+
+// sumChannelBalances sums up the balances of the latest local commitment of
+// all given channels.
+func sumChannelBalances(channels []*channeldb.OpenChannel) *channelBalance {
+	balance := &channelBalance{NumChannels: len(channels)}
+	capacity := uint64(0)
+	for _, channel := range channels {
+		commitment := channel.LocalCommitment
+		balance.LocalBalance += uint64(
+			commitment.LocalBalance.ToSatoshis(),
+		)
+		balance.RemoteBalance += uint64(
+			commitment.RemoteBalance.ToSatoshis(),
+		)
+		for _, htlc := range commitment.Htlcs {
+			balance.UnsettledHTLCs += uint64(htlc.Amt.ToSatoshis())
+		}
+		capacity += uint64(channel.Capacity)
+	}
+	if len(channels) > 0 {
+		balance.AverageCapacity = capacity / uint64(len(channels))
+	}
+	return balance
+}
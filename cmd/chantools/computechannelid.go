@@ -0,0 +1,83 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/lightningnetwork/lnd/lnwire"
+)
+
+type computeChannelIDCommand struct {
+	Channel   string `long:"channel" description:"The funding outpoint (txid:index) of the channel."`
+	BlockHash string `long:"blockhash" description:"The hash of the block the funding transaction was confirmed in. Only needed if bitcoind runs without txindex."`
+}
+
+func (c *computeChannelIDCommand) Execute(_ []string) error {
+	setupChainParams(cfg)
+
+	if c.Channel == "" {
+		return fmt.Errorf("channel is required")
+	}
+	outPoint, err := parseOutPoint(c.Channel)
+	if err != nil {
+		return fmt.Errorf("error parsing channel: %v", err)
+	}
+
+	// Find the position of the funding transaction in the chain.
+	bitcoind := newBitcoind(cfg)
+	tx, err := bitcoind.GetRawTransaction(
+		outPoint.Hash.String(), c.BlockHash,
+	)
+	if err != nil {
+		return fmt.Errorf("error looking up funding transaction: %v",
+			err)
+	}
+	if tx.BlockHash == "" || tx.Confirmations == 0 {
+		return fmt.Errorf("funding transaction is not confirmed yet")
+	}
+	block, err := bitcoind.GetBlock(tx.BlockHash)
+	if err != nil {
+		return fmt.Errorf("error looking up block: %v", err)
+	}
+	txIndex := -1
+	for idx, txid := range block.Tx {
+		if txid == tx.TxID {
+			txIndex = idx
+			break
+		}
+	}
+	if txIndex < 0 {
+		return fmt.Errorf("funding transaction not found in block %s",
+			block.Hash)
+	}
+
+	scid := lnwire.ShortChannelID{
+		BlockHeight: block.Height,
+		TxIndex:     uint32(txIndex),
+		TxPosition:  uint16(outPoint.Index),
+	}
+	fmt.Printf("Short channel ID: %d\n", scid.ToUint64())
+	fmt.Printf("Short channel ID (block:tx:out): %s\n", scid.String())
+	fmt.Printf("Channel ID: %v\n", lnwire.NewChanIDFromOutPoint(outPoint))
+	return nil
+}
+
+// parseOutPoint parses an outpoint in the format txid:index.
+func parseOutPoint(outPoint string) (*wire.OutPoint, error) {
+	parts := strings.Split(outPoint, ":")
+	if len(parts) != 2 {
+		return nil, fmt.Errorf("expected format txid:index")
+	}
+	txHash, err := chainhash.NewHashFromStr(parts[0])
+	if err != nil {
+		return nil, fmt.Errorf("error parsing tx hash: %v", err)
+	}
+	index, err := strconv.ParseUint(parts[1], 10, 16)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing index: %v", err)
+	}
+	return wire.NewOutPoint(txHash, uint32(index)), nil
+}
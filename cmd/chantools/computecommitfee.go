@@ -0,0 +1,123 @@
+package main
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	"github.com/btcsuite/btcd/blockchain"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/btcsuite/btcutil"
+	"github.com/guggero/chantools/btc"
+	"github.com/lightningnetwork/lnd/input"
+	"github.com/lightningnetwork/lnd/lnwallet/chainfee"
+)
+
+type computeCommitFeeCommand struct {
+	Tx            string `long:"tx" description:"The commitment transaction, hex encoded."`
+	FundingAmount uint64 `long:"fundingamount" description:"The amount of the funding output in satoshis. Leave empty to look it up with the API."`
+}
+
+func (c *computeCommitFeeCommand) Execute(_ []string) error {
+	setupChainParams(cfg)
+
+	if c.Tx == "" {
+		return fmt.Errorf("tx is required")
+	}
+	rawTx, err := hex.DecodeString(strings.TrimSpace(c.Tx))
+	if err != nil {
+		return fmt.Errorf("error decoding tx hex: %v", err)
+	}
+	tx := &wire.MsgTx{}
+	if err := tx.Deserialize(bytes.NewReader(rawTx)); err != nil {
+		return fmt.Errorf("error parsing tx: %v", err)
+	}
+	if len(tx.TxIn) != 1 {
+		return fmt.Errorf("expected exactly one input, a commitment "+
+			"transaction only spends the funding output, got %d",
+			len(tx.TxIn))
+	}
+
+	totalIn := btcutil.Amount(c.FundingAmount)
+	if totalIn == 0 {
+		fundingOutpoint := tx.TxIn[0].PreviousOutPoint
+		api := &btc.ExplorerAPI{BaseURL: cfg.APIURL}
+		fundingTx, err := api.Transaction(
+			fundingOutpoint.Hash.String(),
+		)
+		if err != nil {
+			return fmt.Errorf("error looking up funding tx: %v",
+				err)
+		}
+		if int(fundingOutpoint.Index) >= len(fundingTx.Vout) {
+			return fmt.Errorf("funding tx has no output %d",
+				fundingOutpoint.Index)
+		}
+		totalIn = btcutil.Amount(
+			fundingTx.Vout[fundingOutpoint.Index].Value,
+		)
+	}
+
+	var (
+		totalOut    btcutil.Amount
+		numP2WSHOut int
+	)
+	for _, txOut := range tx.TxOut {
+		totalOut += btcutil.Amount(txOut.Value)
+		if txscript.GetScriptClass(txOut.PkScript) ==
+			txscript.WitnessV0ScriptHashTy {
+
+			numP2WSHOut++
+		}
+	}
+	fee := totalIn - totalOut
+	if fee < 0 {
+		return fmt.Errorf("outputs exceed the funding amount by %d "+
+			"sats", -fee)
+	}
+
+	// If the transaction isn't signed yet, we add the size of the 2-of-2
+	// multisig witness so the fee rate matches the published transaction.
+	weight := blockchain.GetTransactionWeight(btcutil.NewTx(tx))
+	if len(tx.TxIn[0].Witness) == 0 {
+		weight += input.WitnessHeaderSize + input.WitnessSize
+	}
+	vSize := (weight + blockchain.WitnessScaleFactor - 1) /
+		blockchain.WitnessScaleFactor
+
+	fmt.Printf("Total input value (funding amount): %d sats\n", totalIn)
+	fmt.Printf("Total output value: %d sats\n", totalOut)
+	fmt.Printf("Fee: %d sats\n", fee)
+	fmt.Printf("Fee rate: %.2f sat/vByte (%d vBytes)\n",
+		float64(fee)/float64(vSize), vSize)
+
+	// lnd doesn't use the actual size of the commitment transaction to
+	// calculate its fee but the weight estimate of BOLT#03 that depends on
+	// the number of HTLC outputs. The fee is rounded down, so there is a
+	// range of fee rates that results in the same fee. We assume all P2WSH
+	// outputs except the to_local output are HTLCs.
+	numHtlcs := 0
+	if numP2WSHOut > 0 {
+		numHtlcs = numP2WSHOut - 1
+	}
+	commitWeight := int64(input.CommitWeight + numHtlcs*input.HTLCWeight)
+	minFeeRate := chainfee.SatPerKWeight(
+		(int64(fee)*1000 + commitWeight - 1) / commitWeight,
+	)
+	maxFeeRate := chainfee.SatPerKWeight(
+		(int64(fee+1)*1000+commitWeight-1)/commitWeight - 1,
+	)
+	fmt.Printf("\nAssuming %d HTLC output(s), the expected commitment "+
+		"weight is %d.\n", numHtlcs, commitWeight)
+	if minFeeRate > maxFeeRate {
+		fmt.Printf("No fee rate results in exactly this fee, the " +
+			"number of HTLCs is probably different.\n")
+		return nil
+	}
+	fmt.Printf("Fee rate policy that produces this fee: %d to %d sat/kw "+
+		"(%d to %d sat/kvByte)\n", minFeeRate, maxFeeRate,
+		minFeeRate.FeePerKVByte(), maxFeeRate.FeePerKVByte())
+	return nil
+}
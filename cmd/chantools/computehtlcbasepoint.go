@@ -0,0 +1,119 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/btcsuite/btcd/btcec"
+	"github.com/btcsuite/btcutil/hdkeychain"
+	"github.com/guggero/chantools/lnd"
+	"github.com/lightningnetwork/lnd/input"
+	"github.com/lightningnetwork/lnd/keychain"
+)
+
+type computeHtlcBasePointCommand struct {
+	RootKey   string `long:"rootkey" description:"BIP32 HD root key of the node. Leave empty to prompt for lnd 24 word aezeed."`
+	Channel   string `long:"channel" description:"The funding outpoint (txid:index) of the channel."`
+	ChannelDB string `long:"channeldb" description:"The lnd channel.db file to look up the keys of the channel in."`
+	MultiFile string `long:"multi_file" description:"The lnd channel.backup file to look up the keys of the channel in."`
+
+	// Index is -1 if not set, see main.go.
+	Index int64 `long:"index" description:"The key index of the channel. If set, the index is not looked up and the channel, channeldb and multi_file flags are ignored. The commitpoint flag is then needed to compute the per-commitment HTLC keys."`
+
+	CommitNumber        uint64 `long:"commitnumber" description:"The number of the local commitment to compute the per-commitment HTLC keys for. The first commitment of a channel has the number 0."`
+	CommitPoint         string `long:"commitpoint" description:"The per-commitment point to compute the HTLC keys for. Use this to compute the keys of a remote commitment by specifying the remote party's per-commitment point. Overrides the commitnumber flag."`
+	RemoteHtlcBasePoint string `long:"remotehtlcbasepoint" description:"The HTLC base point of the remote party. Only needed if the channel is not looked up."`
+}
+
+func (c *computeHtlcBasePointCommand) Execute(_ []string) error {
+	setupChainParams(cfg)
+
+	var (
+		extendedKey *hdkeychain.ExtendedKey
+		err         error
+	)
+
+	// Check that root key is valid or fall back to console input.
+	switch {
+	case c.RootKey != "":
+		extendedKey, err = hdkeychain.NewKeyFromString(c.RootKey)
+
+	default:
+		extendedKey, _, err = rootKeyFromConsole()
+	}
+	if err != nil {
+		return fmt.Errorf("error reading root key: %v", err)
+	}
+
+	var (
+		keyLoc = keychain.KeyLocator{
+			Family: keychain.KeyFamilyHtlcBase,
+			Index:  uint32(c.Index),
+		}
+		commitPoint *btcec.PublicKey
+		remoteBase  *btcec.PublicKey
+	)
+	if c.Index < 0 {
+		keys, err := lookupChannelKeys(
+			extendedKey, c.Channel, c.ChannelDB, c.MultiFile,
+		)
+		if err != nil {
+			return err
+		}
+		keyLoc = keys.localCfg.HtlcBasePoint.KeyLocator
+		remoteBase = keys.remoteCfg.HtlcBasePoint.PubKey
+
+		commitSecret, err := keys.producer.AtIndex(c.CommitNumber)
+		if err != nil {
+			return fmt.Errorf("error deriving commitment secret: "+
+				"%v", err)
+		}
+		commitPoint = input.ComputeCommitmentPoint(commitSecret[:])
+	}
+	if c.CommitPoint != "" {
+		commitPoint, err = pubKeyFromHex(c.CommitPoint)
+		if err != nil {
+			return fmt.Errorf("error parsing commit point: %v", err)
+		}
+	}
+	if c.RemoteHtlcBasePoint != "" {
+		remoteBase, err = pubKeyFromHex(c.RemoteHtlcBasePoint)
+		if err != nil {
+			return fmt.Errorf("error parsing remote HTLC base "+
+				"point: %v", err)
+		}
+	}
+
+	keyRing := &lnd.HDKeyRing{
+		ExtendedKey: extendedKey,
+		ChainParams: chainParams,
+	}
+	keyDesc, err := keyRing.DeriveKey(keyLoc)
+	if err != nil {
+		return fmt.Errorf("error deriving HTLC base key: %v", err)
+	}
+
+	fmt.Printf("Deriving path %s for network %s.\n", keyLocatorPath(keyLoc),
+		chainParams.Name)
+	fmt.Printf("HTLC base point: %x\n",
+		keyDesc.PubKey.SerializeCompressed())
+	if commitPoint == nil {
+		log.Infof("No commitment point available, specify the " +
+			"commitpoint flag to compute the per-commitment " +
+			"HTLC keys.")
+		return nil
+	}
+
+	// The HTLC keys of a commitment are the base points tweaked with the
+	// per-commitment point, as defined in BOLT #3.
+	fmt.Printf("Per-commitment point: %x\n",
+		commitPoint.SerializeCompressed())
+	fmt.Printf("Local HTLC key: %x\n", input.TweakPubKey(
+		keyDesc.PubKey, commitPoint,
+	).SerializeCompressed())
+	if remoteBase != nil {
+		fmt.Printf("Remote HTLC key: %x\n", input.TweakPubKey(
+			remoteBase, commitPoint,
+		).SerializeCompressed())
+	}
+	return nil
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"encoding/hex"
+	"fmt"
+
+	"github.com/btcsuite/btcd/btcec"
+	"github.com/lightningnetwork/lnd/input"
+)
+
+type computeLocalDelayKeyCommand struct {
+	DelayBasePoint   string `long:"delaybasepoint" description:"The local delay base point of the channel (hex)."`
+	CommitPoint      string `long:"commitpoint" description:"The per-commitment point of the commitment to compute the local delay key for (hex)."`
+	DelayBasePrivKey string `long:"delaybaseprivkey" description:"The private key of the local delay base point (hex). If set, the private key of the local delay key is computed as well."`
+}
+
+func (c *computeLocalDelayKeyCommand) Execute(_ []string) error {
+	setupChainParams(cfg)
+
+	if c.DelayBasePoint == "" || c.CommitPoint == "" {
+		return fmt.Errorf("delaybasepoint and commitpoint are required")
+	}
+	basePoint, err := pubKeyFromHex(c.DelayBasePoint)
+	if err != nil {
+		return fmt.Errorf("error parsing delay base point: %v", err)
+	}
+	commitPoint, err := pubKeyFromHex(c.CommitPoint)
+	if err != nil {
+		return fmt.Errorf("error parsing commit point: %v", err)
+	}
+
+	// The local delay key of a commitment is the base point tweaked with
+	// the per-commitment point, as defined in BOLT #3:
+	// local_delayedpubkey = basepoint +
+	//     SHA256(per_commitment_point || basepoint) * G
+	tweak := input.SingleTweakBytes(commitPoint, basePoint)
+	fmt.Printf("Tweak: %x\n", tweak)
+	fmt.Printf("Local delay key: %x\n", input.TweakPubKey(
+		basePoint, commitPoint,
+	).SerializeCompressed())
+
+	if c.DelayBasePrivKey == "" {
+		return nil
+	}
+	privKeyBytes, err := hex.DecodeString(c.DelayBasePrivKey)
+	if err != nil {
+		return fmt.Errorf("error decoding private key: %v", err)
+	}
+	basePrivKey, basePubKey := btcec.PrivKeyFromBytes(
+		btcec.S256(), privKeyBytes,
+	)
+	if !basePubKey.IsEqual(basePoint) {
+		return fmt.Errorf("private key doesn't belong to delay base " +
+			"point")
+	}
+	privKey := input.TweakPrivKey(basePrivKey, tweak)
+	fmt.Printf("Local delay private key: %x\n", privKey.Serialize())
+	return nil
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/btcsuite/btcd/btcec"
+	"github.com/guggero/chantools/btc"
+)
+
+type computeMerkleRootCommand struct {
+	TapLeaves   []string `long:"tapleaf" description:"A leaf of the script tree in the format <script hex>[:<leaf version hex>]. The leaf version defaults to c0 (Tapscript). Specify multiple times for multiple leaves, the leaves are paired up in the order they are given."`
+	InternalKey string   `long:"internalkey" description:"The internal public key of the Taproot output, hex encoded as a 32 byte x-only or a 33 byte compressed key. Required to compute the control blocks."`
+}
+
+func (c *computeMerkleRootCommand) Execute(_ []string) error {
+	setupChainParams(cfg)
+
+	if len(c.TapLeaves) == 0 {
+		return fmt.Errorf("at least one tapleaf is required")
+	}
+	leafVersions := make([]byte, len(c.TapLeaves))
+	leafHashes := make([][]byte, len(c.TapLeaves))
+	for idx, tapLeaf := range c.TapLeaves {
+		parts := strings.Split(tapLeaf, ":")
+		if len(parts) > 2 {
+			return fmt.Errorf("invalid tapleaf %s", tapLeaf)
+		}
+		script, err := hex.DecodeString(parts[0])
+		if err != nil {
+			return fmt.Errorf("error decoding script of leaf "+
+				"%d: %v", idx, err)
+		}
+		leafVersions[idx] = btc.BaseLeafVersion
+		if len(parts) == 2 {
+			version, err := strconv.ParseUint(parts[1], 16, 8)
+			if err != nil {
+				return fmt.Errorf("error parsing version of "+
+					"leaf %d: %v", idx, err)
+			}
+
+			// The lowest bit of the first byte of the control block
+			// is the parity of the output key, so it can't be part
+			// of the leaf version.
+			if version&1 != 0 {
+				return fmt.Errorf("leaf version of leaf %d "+
+					"must be even", idx)
+			}
+			leafVersions[idx] = byte(version)
+		}
+		leafHashes[idx] = btc.TapLeafHash(leafVersions[idx], script)
+	}
+
+	root, proofs, err := btc.TapTreeProofs(leafHashes)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Merkle root: %x\n", root)
+
+	var internalKey, outputKey *btcec.PublicKey
+	if c.InternalKey != "" {
+		internalKey, err = taprootKeyFromHex(c.InternalKey)
+		if err != nil {
+			return fmt.Errorf("error parsing internal key: %v", err)
+		}
+		_, outputKey, err = btc.TaprootOutputKey(internalKey, root)
+		if err != nil {
+			return fmt.Errorf("error tweaking internal key: %v",
+				err)
+		}
+		fmt.Printf("Output key (x-only): %x\n",
+			outputKey.SerializeCompressed()[1:])
+	}
+
+	for idx := range leafHashes {
+		fmt.Printf("\nLeaf %d:\n", idx)
+		fmt.Printf("  Leaf version: %02x\n", leafVersions[idx])
+		fmt.Printf("  Leaf hash: %x\n", leafHashes[idx])
+		for level, hash := range proofs[idx] {
+			fmt.Printf("  Merkle proof %d: %x\n", level, hash)
+		}
+		if outputKey != nil {
+			fmt.Printf("  Control block: %x\n", btc.TapControlBlock(
+				leafVersions[idx], internalKey, outputKey,
+				proofs[idx],
+			))
+		}
+	}
+	return nil
+}
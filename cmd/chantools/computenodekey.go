@@ -0,0 +1,72 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/btcsuite/btcutil/bech32"
+	"github.com/btcsuite/btcutil/hdkeychain"
+	"github.com/guggero/chantools/lnd"
+	"github.com/lightningnetwork/lnd/keychain"
+)
+
+const (
+	// nodeIDHrp is the human readable part of the bech32 encoded node ID.
+	nodeIDHrp = "ln"
+)
+
+type computeNodeKeyCommand struct {
+	RootKey string `long:"rootkey" description:"BIP32 HD root key of the node. Leave empty to prompt for lnd 24 word aezeed."`
+}
+
+func (c *computeNodeKeyCommand) Execute(_ []string) error {
+	setupChainParams(cfg)
+
+	var (
+		extendedKey *hdkeychain.ExtendedKey
+		err         error
+	)
+
+	// Check that root key is valid or fall back to console input.
+	switch {
+	case c.RootKey != "":
+		extendedKey, err = hdkeychain.NewKeyFromString(c.RootKey)
+
+	default:
+		extendedKey, _, err = rootKeyFromConsole()
+	}
+	if err != nil {
+		return fmt.Errorf("error reading root key: %v", err)
+	}
+
+	keyRing := &lnd.HDKeyRing{
+		ExtendedKey: extendedKey,
+		ChainParams: chainParams,
+	}
+	keyLoc := keychain.KeyLocator{
+		Family: keychain.KeyFamilyNodeKey,
+		Index:  0,
+	}
+	keyDesc, err := keyRing.DeriveKey(keyLoc)
+	if err != nil {
+		return fmt.Errorf("error deriving node key: %v", err)
+	}
+	pubKey := keyDesc.PubKey.SerializeCompressed()
+
+	converted, err := bech32.ConvertBits(pubKey, 8, 5, true)
+	if err != nil {
+		return fmt.Errorf("error converting node key: %v", err)
+	}
+	nodeID, err := bech32.Encode(nodeIDHrp, converted)
+	if err != nil {
+		return fmt.Errorf("error encoding node ID: %v", err)
+	}
+
+	// The onion service key of lnd is created by Tor and stored in a file
+	// next to the wallet, it is not derived from the seed. So there is no
+	// onion address we could show here.
+	fmt.Printf("Deriving path %s for network %s.\n", keyLocatorPath(keyLoc),
+		chainParams.Name)
+	fmt.Printf("Node public key: %x\n", pubKey)
+	fmt.Printf("Node ID (bech32): %s\n", nodeID)
+	return nil
+}
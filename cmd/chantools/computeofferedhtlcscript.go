@@ -0,0 +1,176 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	"github.com/btcsuite/btcd/btcec"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcutil"
+	"github.com/lightningnetwork/lnd/input"
+)
+
+type computeOfferedHtlcScriptCommand struct {
+	LocalHtlcPubKey  string `long:"localhtlcpubkey" description:"The local HTLC public key of the commitment, the key of the party that offered the HTLC (hex)."`
+	RemoteHtlcPubKey string `long:"remotehtlcpubkey" description:"The remote HTLC public key of the commitment (hex)."`
+	RevocationPubKey string `long:"revocationpubkey" description:"The revocation public key of the commitment (hex)."`
+	PaymentHash      string `long:"paymenthash" description:"The payment hash of the HTLC (hex)."`
+}
+
+func (c *computeOfferedHtlcScriptCommand) Execute(_ []string) error {
+	setupChainParams(cfg)
+
+	keys, err := parseHtlcKeys(
+		c.LocalHtlcPubKey, c.RemoteHtlcPubKey, c.RevocationPubKey,
+	)
+	if err != nil {
+		return err
+	}
+	paymentHash, err := parsePaymentHash(c.PaymentHash)
+	if err != nil {
+		return err
+	}
+
+	script, err := input.SenderHTLCScript(
+		keys.local, keys.remote, keys.revocation, paymentHash,
+	)
+	if err != nil {
+		return fmt.Errorf("error creating offered HTLC script: %v", err)
+	}
+
+	// The purposes of all opcodes in the order of the template in BOLT 3.
+	purposes := []string{
+		"duplicate the first witness element",
+		"hash it",
+		"RIPEMD160(SHA256(revocationpubkey))",
+		"is it the revocation key?",
+		"if so, the revocation path:",
+		"  check the signature of the revocation key",
+		"otherwise:",
+		"  remote_htlcpubkey",
+		"  swap it with the next witness element",
+		"  push the size of that element",
+		"  32, the size of a payment preimage",
+		"  check the size",
+		"  timeout path if it's not a preimage:",
+		"    drop the empty element",
+		"    2 signatures",
+		"    swap with the remote signature",
+		"    local_htlcpubkey",
+		"    2 public keys",
+		"    check both signatures (HTLC-timeout transaction)",
+		"  success path of the remote party:",
+		"    hash the preimage",
+		"    RIPEMD160(payment_hash)",
+		"    make sure the preimage matches",
+		"    check the signature of the remote HTLC key",
+		"  end of timeout/success",
+		"end of revocation",
+	}
+
+	if err := printHtlcScript(script, purposes); err != nil {
+		return err
+	}
+	fmt.Printf("\nWitness to spend with the revocation key:\n")
+	fmt.Printf("  0: <signature of revocation key>\n")
+	fmt.Printf("  1: %x\n", keys.revocation.SerializeCompressed())
+	fmt.Printf("  2: %x\n", script)
+	fmt.Printf("\nWitness of the HTLC-timeout transaction of the local " +
+		"party:\n")
+	fmt.Printf("  0: <empty>\n")
+	fmt.Printf("  1: <signature of remote HTLC key>\n")
+	fmt.Printf("  2: <signature of local HTLC key>\n")
+	fmt.Printf("  3: <empty>\n")
+	fmt.Printf("  4: %x\n", script)
+	fmt.Printf("\nWitness to spend with the payment preimage by the " +
+		"remote party:\n")
+	fmt.Printf("  0: <signature of remote HTLC key>\n")
+	fmt.Printf("  1: <payment preimage>\n")
+	fmt.Printf("  2: %x\n", script)
+	return nil
+}
+
+// htlcKeys are the public keys that are used in the HTLC scripts of a
+// commitment.
+type htlcKeys struct {
+	local      *btcec.PublicKey
+	remote     *btcec.PublicKey
+	revocation *btcec.PublicKey
+}
+
+func parseHtlcKeys(local, remote, revocation string) (*htlcKeys, error) {
+	if local == "" || remote == "" || revocation == "" {
+		return nil, fmt.Errorf("localhtlcpubkey, remotehtlcpubkey " +
+			"and revocationpubkey are required")
+	}
+	localKey, err := pubKeyFromHex(local)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing local HTLC key: %v", err)
+	}
+	remoteKey, err := pubKeyFromHex(remote)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing remote HTLC key: %v", err)
+	}
+	revocationKey, err := pubKeyFromHex(revocation)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing revocation key: %v", err)
+	}
+	return &htlcKeys{
+		local:      localKey,
+		remote:     remoteKey,
+		revocation: revocationKey,
+	}, nil
+}
+
+func parsePaymentHash(paymentHash string) ([]byte, error) {
+	if paymentHash == "" {
+		return nil, fmt.Errorf("paymenthash is required")
+	}
+	hash, err := hex.DecodeString(paymentHash)
+	if err != nil {
+		return nil, fmt.Errorf("error decoding payment hash: %v", err)
+	}
+	if len(hash) != sha256.Size {
+		return nil, fmt.Errorf("payment hash must be %d bytes, got %d",
+			sha256.Size, len(hash))
+	}
+	return hash, nil
+}
+
+// printHtlcScript prints an HTLC script with its P2WSH address and every
+// opcode together with its purpose.
+func printHtlcScript(script []byte, purposes []string) error {
+	disassembled, err := txscript.DisasmString(script)
+	if err != nil {
+		return fmt.Errorf("error disassembling script: %v", err)
+	}
+	opcodes := strings.Split(disassembled, " ")
+	if len(opcodes) != len(purposes) {
+		return fmt.Errorf("script has %d opcodes but the template has "+
+			"%d", len(opcodes), len(purposes))
+	}
+	scriptHash := sha256.Sum256(script)
+	addr, err := btcutil.NewAddressWitnessScriptHash(
+		scriptHash[:], chainParams,
+	)
+	if err != nil {
+		return fmt.Errorf("error creating address: %v", err)
+	}
+
+	fmt.Printf("Witness script: %x\n", script)
+	fmt.Printf("Disassembled: %s\n", disassembled)
+	fmt.Printf("P2WSH address: %s\n", addr.EncodeAddress())
+	fmt.Printf("\nOpcodes:\n")
+	width := 0
+	for _, opcode := range opcodes {
+		if len(opcode) > width {
+			width = len(opcode)
+		}
+	}
+	for idx, opcode := range opcodes {
+		fmt.Printf("  %-*s  %s\n", width, opcode, purposes[idx])
+	}
+	return nil
+}
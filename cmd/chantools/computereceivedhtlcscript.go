@@ -0,0 +1,108 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/lightningnetwork/lnd/input"
+)
+
+type computeReceivedHtlcScriptCommand struct {
+	LocalHtlcPubKey  string `long:"localhtlcpubkey" description:"The local HTLC public key of the commitment, the key of the party that received the HTLC (hex)."`
+	RemoteHtlcPubKey string `long:"remotehtlcpubkey" description:"The remote HTLC public key of the commitment (hex)."`
+	RevocationPubKey string `long:"revocationpubkey" description:"The revocation public key of the commitment (hex)."`
+	PaymentHash      string `long:"paymenthash" description:"The payment hash of the HTLC (hex)."`
+	CLTVExpiry       uint32 `long:"cltvexpiry" description:"The absolute block height after which the HTLC times out."`
+}
+
+func (c *computeReceivedHtlcScriptCommand) Execute(_ []string) error {
+	setupChainParams(cfg)
+
+	keys, err := parseHtlcKeys(
+		c.LocalHtlcPubKey, c.RemoteHtlcPubKey, c.RevocationPubKey,
+	)
+	if err != nil {
+		return err
+	}
+	paymentHash, err := parsePaymentHash(c.PaymentHash)
+	if err != nil {
+		return err
+	}
+	if c.CLTVExpiry == 0 {
+		return fmt.Errorf("cltvexpiry is required")
+	}
+
+	script, err := input.ReceiverHTLCScript(
+		c.CLTVExpiry, keys.remote, keys.local, keys.revocation,
+		paymentHash,
+	)
+	if err != nil {
+		return fmt.Errorf("error creating received HTLC script: %v",
+			err)
+	}
+
+	// The expiry is pushed as a minimally encoded CScriptNum. Encoding it
+	// as a fixed size integer results in a different script.
+	expiryScript, err := txscript.NewScriptBuilder().
+		AddInt64(int64(c.CLTVExpiry)).
+		Script()
+	if err != nil {
+		return err
+	}
+
+	// The purposes of all opcodes in the order of the template in BOLT 3.
+	purposes := []string{
+		"duplicate the first witness element",
+		"hash it",
+		"RIPEMD160(SHA256(revocationpubkey))",
+		"is it the revocation key?",
+		"if so, the revocation path:",
+		"  check the signature of the revocation key",
+		"otherwise:",
+		"  remote_htlcpubkey",
+		"  swap it with the next witness element",
+		"  push the size of that element",
+		"  32, the size of a payment preimage",
+		"  check the size",
+		"  success path if it's a preimage:",
+		"    hash the preimage",
+		"    RIPEMD160(payment_hash)",
+		"    make sure the preimage matches",
+		"    2 signatures",
+		"    swap with the remote signature",
+		"    local_htlcpubkey",
+		"    2 public keys",
+		"    check both signatures (HTLC-success transaction)",
+		"  timeout path of the remote party:",
+		"    drop the result of the size check",
+		fmt.Sprintf("    cltv_expiry %d", c.CLTVExpiry),
+		"    make sure the lock time is at least the expiry",
+		"    drop the expiry",
+		"    check the signature of the remote HTLC key",
+		"  end of success/timeout",
+		"end of revocation",
+	}
+
+	if err := printHtlcScript(script, purposes); err != nil {
+		return err
+	}
+	fmt.Printf("\nCLTV expiry %d is encoded as: %x\n", c.CLTVExpiry,
+		expiryScript)
+	fmt.Printf("\nWitness to spend with the revocation key:\n")
+	fmt.Printf("  0: <signature of revocation key>\n")
+	fmt.Printf("  1: %x\n", keys.revocation.SerializeCompressed())
+	fmt.Printf("  2: %x\n", script)
+	fmt.Printf("\nWitness of the HTLC-success transaction of the local " +
+		"party:\n")
+	fmt.Printf("  0: <empty>\n")
+	fmt.Printf("  1: <signature of remote HTLC key>\n")
+	fmt.Printf("  2: <signature of local HTLC key>\n")
+	fmt.Printf("  3: <payment preimage>\n")
+	fmt.Printf("  4: %x\n", script)
+	fmt.Printf("\nWitness to spend after the timeout by the remote party "+
+		"(transaction lock time of at least %d):\n", c.CLTVExpiry)
+	fmt.Printf("  0: <signature of remote HTLC key>\n")
+	fmt.Printf("  1: <empty>\n")
+	fmt.Printf("  2: %x\n", script)
+	return nil
+}
@@ -0,0 +1,162 @@
+package main
+
+import (
+	"encoding/hex"
+	"fmt"
+
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcutil"
+)
+
+const (
+	redeemTypeNP2WKH = "p2sh-p2wpkh"
+)
+
+type computeRedemptionScriptCommand struct {
+	RedeemScript string `long:"redeemscript" description:"The redeem script of the P2SH output (hex)."`
+	Type         string `long:"type" description:"Derive the redeem script from a known pattern instead of specifying it. Currently only p2sh-p2wpkh is supported which requires --pubkey."`
+	PubKey       string `long:"pubkey" description:"The public key to derive the redeem script from (hex)."`
+}
+
+func (c *computeRedemptionScriptCommand) Execute(_ []string) error {
+	setupChainParams(cfg)
+
+	var (
+		redeemScript []byte
+		pubKey       []byte
+		err          error
+	)
+	switch {
+	case c.RedeemScript != "" && c.Type != "":
+		return fmt.Errorf("only one of redeemscript or type can be " +
+			"specified")
+
+	case c.RedeemScript != "":
+		redeemScript, err = hex.DecodeString(c.RedeemScript)
+		if err != nil {
+			return fmt.Errorf("error decoding redeem script: %v",
+				err)
+		}
+
+	case c.Type == redeemTypeNP2WKH:
+		if c.PubKey == "" {
+			return fmt.Errorf("pubkey is required for type %s",
+				c.Type)
+		}
+		key, err := pubKeyFromHex(c.PubKey)
+		if err != nil {
+			return fmt.Errorf("error parsing pubkey: %v", err)
+		}
+		pubKey = key.SerializeCompressed()
+		redeemScript, err = p2wkhScript(pubKey)
+		if err != nil {
+			return fmt.Errorf("error creating redeem script: %v",
+				err)
+		}
+
+	case c.Type != "":
+		return fmt.Errorf("unknown type %s", c.Type)
+
+	default:
+		return fmt.Errorf("redeemscript or type is required")
+	}
+	if len(redeemScript) > txscript.MaxScriptElementSize {
+		return fmt.Errorf("redeem script is larger than %d bytes and "+
+			"cannot be spent", txscript.MaxScriptElementSize)
+	}
+
+	disassembled, err := txscript.DisasmString(redeemScript)
+	if err != nil {
+		return fmt.Errorf("error disassembling script: %v", err)
+	}
+	addr, err := btcutil.NewAddressScriptHash(redeemScript, chainParams)
+	if err != nil {
+		return fmt.Errorf("error creating address: %v", err)
+	}
+	pkScript, err := txscript.PayToAddrScript(addr)
+	if err != nil {
+		return fmt.Errorf("error creating pk script: %v", err)
+	}
+
+	fmt.Printf("Redeem script: %x\n", redeemScript)
+	fmt.Printf("Disassembled: %s\n", disassembled)
+	fmt.Printf("P2SH address: %s\n", addr.EncodeAddress())
+	fmt.Printf("P2SH pk script: %x\n", pkScript)
+
+	return printRedemptionTemplate(redeemScript, pubKey)
+}
+
+// printRedemptionTemplate prints the signature script and witness needed to
+// spend a P2SH output with the given redeem script. Signatures and other data
+// that cannot be known in advance are printed as placeholders.
+func printRedemptionTemplate(redeemScript, pubKey []byte) error {
+	// Nested SegWit outputs only push the redeem script in the signature
+	// script, so it is complete already.
+	if txscript.IsPayToWitnessPubKeyHash(redeemScript) ||
+		txscript.IsPayToWitnessScriptHash(redeemScript) {
+
+		sigScript, err := txscript.NewScriptBuilder().
+			AddData(redeemScript).
+			Script()
+		if err != nil {
+			return err
+		}
+		fmt.Printf("\nSignature script: %x\n", sigScript)
+		fmt.Printf("Witness program: %x\n", redeemScript[2:])
+		fmt.Printf("\nWitness:\n")
+
+		switch {
+		case txscript.IsPayToWitnessScriptHash(redeemScript):
+			fmt.Printf("  ...: <inputs of witness script>\n")
+			fmt.Printf("  n: <witness script with SHA256 %x>\n",
+				redeemScript[2:])
+
+		case pubKey != nil:
+			fmt.Printf("  0: <signature>\n")
+			fmt.Printf("  1: %x\n", pubKey)
+
+		default:
+			fmt.Printf("  0: <signature>\n")
+			fmt.Printf("  1: <public key with HASH160 %x>\n",
+				redeemScript[2:])
+		}
+		return nil
+	}
+
+	fmt.Printf("\nSignature script:\n")
+	switch txscript.GetScriptClass(redeemScript) {
+	case txscript.MultiSigTy:
+		numPubKeys, numSigs, err := txscript.CalcMultiSigStats(
+			redeemScript,
+		)
+		if err != nil {
+			return fmt.Errorf("error parsing multisig script: %v",
+				err)
+		}
+
+		// OP_CHECKMULTISIG consumes one element more than it should, so
+		// an additional empty element is needed. The signatures must be
+		// in the same order as the public keys they belong to.
+		fmt.Printf("  0: OP_0\n")
+		for i := 1; i <= numSigs; i++ {
+			fmt.Printf("  %d: <signature %d of %d-of-%d>\n", i, i,
+				numSigs, numPubKeys)
+		}
+		fmt.Printf("  %d: %x\n", numSigs+1, redeemScript)
+
+	case txscript.PubKeyTy:
+		fmt.Printf("  0: <signature>\n")
+		fmt.Printf("  1: %x\n", redeemScript)
+
+	case txscript.PubKeyHashTy:
+		fmt.Printf("  0: <signature>\n")
+		fmt.Printf("  1: <public key with HASH160 %x>\n",
+			redeemScript[3:23])
+		fmt.Printf("  2: %x\n", redeemScript)
+
+	default:
+		fmt.Printf("  ...: <inputs of redeem script>\n")
+		fmt.Printf("  n: %x\n", redeemScript)
+	}
+	return nil
+}
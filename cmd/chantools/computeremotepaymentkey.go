@@ -0,0 +1,133 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/btcsuite/btcd/btcec"
+	"github.com/btcsuite/btcutil"
+	"github.com/btcsuite/btcutil/hdkeychain"
+	"github.com/lightningnetwork/lnd/input"
+)
+
+const (
+	channelTypeLegacy          = "legacy"
+	channelTypeStaticRemoteKey = "staticremotekey"
+)
+
+type computeRemotePaymentKeyCommand struct {
+	RootKey   string `long:"rootkey" description:"BIP32 HD root key of the node. Only needed if the channel is looked up. Leave empty to prompt for lnd 24 word aezeed."`
+	Channel   string `long:"channel" description:"The funding outpoint (txid:index) of the channel."`
+	ChannelDB string `long:"channeldb" description:"The lnd channel.db file to look up the keys and the type of the channel in."`
+	MultiFile string `long:"multi_file" description:"The lnd channel.backup file to look up the keys and the type of the channel in."`
+
+	CommitNumber           uint64 `long:"commitnumber" description:"The number of the local commitment to compute the remote payment key for. The first commitment of a channel has the number 0."`
+	CommitPoint            string `long:"commitpoint" description:"The per-commitment point of the local commitment. Overrides the commitnumber flag."`
+	RemotePaymentBasePoint string `long:"remotepaymentbasepoint" description:"The payment base point of the remote party. Only needed if the channel is not looked up."`
+	ChannelType            string `long:"channeltype" description:"The type of the channel, either legacy or staticremotekey. Overrides the type that is looked up. (default legacy if the channel is not looked up)"`
+}
+
+func (c *computeRemotePaymentKeyCommand) Execute(_ []string) error {
+	setupChainParams(cfg)
+
+	var (
+		commitPoint *btcec.PublicKey
+		remoteBase  *btcec.PublicKey
+		tweakless   bool
+		err         error
+	)
+
+	// The root key is only needed to look up the channel.
+	if c.Channel != "" {
+		var extendedKey *hdkeychain.ExtendedKey
+		switch {
+		case c.RootKey != "":
+			extendedKey, err = hdkeychain.NewKeyFromString(
+				c.RootKey,
+			)
+
+		default:
+			extendedKey, _, err = rootKeyFromConsole()
+		}
+		if err != nil {
+			return fmt.Errorf("error reading root key: %v", err)
+		}
+
+		keys, err := lookupChannelKeys(
+			extendedKey, c.Channel, c.ChannelDB, c.MultiFile,
+		)
+		if err != nil {
+			return err
+		}
+		remoteBase = keys.remoteCfg.PaymentBasePoint.PubKey
+		tweakless = keys.tweakless
+
+		commitSecret, err := keys.producer.AtIndex(c.CommitNumber)
+		if err != nil {
+			return fmt.Errorf("error deriving commitment secret: "+
+				"%v", err)
+		}
+		commitPoint = input.ComputeCommitmentPoint(commitSecret[:])
+	}
+	if c.CommitPoint != "" {
+		commitPoint, err = pubKeyFromHex(c.CommitPoint)
+		if err != nil {
+			return fmt.Errorf("error parsing commit point: %v", err)
+		}
+	}
+	if c.RemotePaymentBasePoint != "" {
+		remoteBase, err = pubKeyFromHex(c.RemotePaymentBasePoint)
+		if err != nil {
+			return fmt.Errorf("error parsing remote payment base "+
+				"point: %v", err)
+		}
+	}
+	switch c.ChannelType {
+	case "":
+
+	case channelTypeLegacy:
+		tweakless = false
+
+	case channelTypeStaticRemoteKey:
+		tweakless = true
+
+	default:
+		return fmt.Errorf("unknown channel type %s, must be %s or %s",
+			c.ChannelType, channelTypeLegacy,
+			channelTypeStaticRemoteKey)
+	}
+	if remoteBase == nil {
+		return fmt.Errorf("channel or remotepaymentbasepoint is " +
+			"required")
+	}
+
+	// With the static remote key commitment format the remote party is
+	// paid to its payment base point directly. Before that, the base point
+	// was tweaked with the per-commitment point, as defined in BOLT #3.
+	channelType := channelTypeStaticRemoteKey
+	remoteKey := remoteBase
+	switch {
+	case !tweakless && commitPoint == nil:
+		return fmt.Errorf("commitpoint is required for legacy channels")
+
+	case !tweakless:
+		channelType = channelTypeLegacy
+		remoteKey = input.TweakPubKey(remoteBase, commitPoint)
+	}
+	addr, err := btcutil.NewAddressWitnessPubKeyHash(
+		btcutil.Hash160(remoteKey.SerializeCompressed()), chainParams,
+	)
+	if err != nil {
+		return fmt.Errorf("error creating address: %v", err)
+	}
+
+	fmt.Printf("Channel type: %s\n", channelType)
+	fmt.Printf("Remote payment base point: %x\n",
+		remoteBase.SerializeCompressed())
+	if !tweakless {
+		fmt.Printf("Per-commitment point: %x\n",
+			commitPoint.SerializeCompressed())
+	}
+	fmt.Printf("Remote payment key: %x\n", remoteKey.SerializeCompressed())
+	fmt.Printf("to_remote P2WKH address: %s\n", addr.EncodeAddress())
+	return nil
+}
@@ -0,0 +1,185 @@
+package main
+
+import (
+	"fmt"
+	"path"
+
+	"github.com/btcsuite/btcutil/hdkeychain"
+	"github.com/guggero/chantools/lnd"
+	"github.com/lightningnetwork/lnd/chanbackup"
+	"github.com/lightningnetwork/lnd/channeldb"
+	"github.com/lightningnetwork/lnd/keychain"
+	"github.com/lightningnetwork/lnd/shachain"
+)
+
+type computeRevocationBasePointCommand struct {
+	RootKey   string `long:"rootkey" description:"BIP32 HD root key of the node. Leave empty to prompt for lnd 24 word aezeed."`
+	Channel   string `long:"channel" description:"The funding outpoint (txid:index) of the channel."`
+	ChannelDB string `long:"channeldb" description:"The lnd channel.db file to look up the key index of the channel in."`
+	MultiFile string `long:"multi_file" description:"The lnd channel.backup file to look up the key index of the channel in."`
+
+	// Index is -1 if not set, see main.go.
+	Index int64 `long:"index" description:"The key index of the channel. If set, the index is not looked up and the channel, channeldb and multi_file flags are ignored."`
+}
+
+func (c *computeRevocationBasePointCommand) Execute(_ []string) error {
+	setupChainParams(cfg)
+
+	var (
+		extendedKey *hdkeychain.ExtendedKey
+		err         error
+	)
+
+	// Check that root key is valid or fall back to console input.
+	switch {
+	case c.RootKey != "":
+		extendedKey, err = hdkeychain.NewKeyFromString(c.RootKey)
+
+	default:
+		extendedKey, _, err = rootKeyFromConsole()
+	}
+	if err != nil {
+		return fmt.Errorf("error reading root key: %v", err)
+	}
+
+	keyLoc := keychain.KeyLocator{
+		Family: keychain.KeyFamilyRevocationBase,
+		Index:  uint32(c.Index),
+	}
+	if c.Index < 0 {
+		keys, err := lookupChannelKeys(
+			extendedKey, c.Channel, c.ChannelDB, c.MultiFile,
+		)
+		if err != nil {
+			return err
+		}
+		keyLoc = keys.localCfg.RevocationBasePoint.KeyLocator
+	}
+
+	keyRing := &lnd.HDKeyRing{
+		ExtendedKey: extendedKey,
+		ChainParams: chainParams,
+	}
+	keyDesc, err := keyRing.DeriveKey(keyLoc)
+	if err != nil {
+		return fmt.Errorf("error deriving revocation base key: %v",
+			err)
+	}
+
+	fmt.Printf("Deriving path %s for network %s.\n", keyLocatorPath(keyLoc),
+		chainParams.Name)
+	fmt.Printf("Revocation base point: %x\n",
+		keyDesc.PubKey.SerializeCompressed())
+	return nil
+}
+
+// channelKeys are the key configurations and the revocation producer of a
+// channel that was found in a channel DB or a channel backup file. Tweakless
+// is set if the channel uses the static remote key commitment format.
+type channelKeys struct {
+	localCfg  *channeldb.ChannelConfig
+	remoteCfg *channeldb.ChannelConfig
+	producer  shachain.Producer
+	tweakless bool
+}
+
+// lookupChannelKeys finds the keys of a channel by its funding outpoint. lnd
+// doesn't derive the key indexes from the funding outpoint but uses the next
+// free index of each key family when a channel is opened. That's why the
+// indexes have to be looked up in the channel DB or the channel backup file.
+func lookupChannelKeys(extendedKey *hdkeychain.ExtendedKey, channel,
+	channelDB, multiFile string) (*channelKeys, error) {
+
+	switch {
+	case channel == "":
+		return nil, fmt.Errorf("either channel or index is required")
+
+	case channelDB != "":
+		db, err := channeldb.Open(
+			path.Dir(channelDB),
+			channeldb.OptionSetSyncFreelist(true),
+			channeldb.OptionReadOnly(true),
+		)
+		if err != nil {
+			return nil, fmt.Errorf("error opening channel DB: %v",
+				err)
+		}
+		defer db.Close()
+
+		channels, err := db.FetchAllChannels()
+		if err != nil {
+			return nil, fmt.Errorf("error fetching channels: %v",
+				err)
+		}
+		for _, openChannel := range channels {
+			if openChannel.FundingOutpoint.String() != channel {
+				continue
+			}
+			return &channelKeys{
+				localCfg:  &openChannel.LocalChanCfg,
+				remoteCfg: &openChannel.RemoteChanCfg,
+				producer:  openChannel.RevocationProducer,
+				tweakless: openChannel.ChanType.IsTweakless(),
+			}, nil
+		}
+
+	case multiFile != "":
+		keyRing := &lnd.HDKeyRing{
+			ExtendedKey: extendedKey,
+			ChainParams: chainParams,
+		}
+		multi, err := chanbackup.NewMultiFile(multiFile).ExtractMulti(
+			keyRing,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("could not extract multi "+
+				"file: %v", err)
+		}
+		for idx := range multi.StaticBackups {
+			single := multi.StaticBackups[idx]
+			if single.FundingOutpoint.String() != channel {
+				continue
+			}
+
+			// The shachain root is the private key of the
+			// revocation root descriptor, just like lnd restores
+			// it from a backup.
+			producer, err := revocationProducer(
+				extendedKey, single.ShaChainRootDesc.KeyLocator,
+			)
+			if err != nil {
+				return nil, err
+			}
+			return &channelKeys{
+				localCfg:  &single.LocalChanCfg,
+				remoteCfg: &single.RemoteChanCfg,
+				producer:  producer,
+				tweakless: single.Version ==
+					chanbackup.TweaklessCommitVersion,
+			}, nil
+		}
+
+	default:
+		return nil, fmt.Errorf("channeldb or multi_file is required " +
+			"to look up the keys of the channel")
+	}
+	return nil, fmt.Errorf("channel %s not found", channel)
+}
+
+// keyLocatorPath returns the BIP32 derivation path of the key that lnd derives
+// for a key locator.
+func keyLocatorPath(keyLoc keychain.KeyLocator) string {
+	return fmt.Sprintf("m/%d'/%d'/%d'/0/%d", keychain.BIP0043Purpose,
+		chainParams.HDCoinType, keyLoc.Family, keyLoc.Index)
+}
+
+// keyLocatorBip32Path is the same as keyLocatorPath but returns the path as the
+// list of child indexes that is used in PSBTs.
+func keyLocatorBip32Path(keyLoc keychain.KeyLocator) []uint32 {
+	return []uint32{
+		lnd.HardenedKeyStart + keychain.BIP0043Purpose,
+		lnd.HardenedKeyStart + chainParams.HDCoinType,
+		lnd.HardenedKeyStart + uint32(keyLoc.Family),
+		0, keyLoc.Index,
+	}
+}
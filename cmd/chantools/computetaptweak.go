@@ -0,0 +1,82 @@
+package main
+
+import (
+	"encoding/hex"
+	"fmt"
+
+	"github.com/btcsuite/btcd/btcec"
+	"github.com/guggero/chantools/btc"
+)
+
+const (
+	// taprootWitnessVersion is the segwit version of Taproot outputs.
+	taprootWitnessVersion = 1
+)
+
+type computeTapTweakCommand struct {
+	InternalKey string `long:"internalkey" description:"The internal public key of the Taproot output, hex encoded as a 32 byte x-only or a 33 byte compressed key."`
+	ScriptRoot  string `long:"scriptroot" description:"The root hash of the script tree of the output (hex)."`
+	NoScripts   bool   `long:"noscripts" description:"The output has no script tree and can only be spent with the key path."`
+}
+
+func (c *computeTapTweakCommand) Execute(_ []string) error {
+	setupChainParams(cfg)
+
+	if c.InternalKey == "" {
+		return fmt.Errorf("internalkey is required")
+	}
+	internalKey, err := taprootKeyFromHex(c.InternalKey)
+	if err != nil {
+		return fmt.Errorf("error parsing internal key: %v", err)
+	}
+
+	var scriptRoot []byte
+	switch {
+	case c.ScriptRoot != "" && c.NoScripts:
+		return fmt.Errorf("scriptroot and noscripts are mutually " +
+			"exclusive")
+
+	case c.ScriptRoot != "":
+		scriptRoot, err = hex.DecodeString(c.ScriptRoot)
+		if err != nil {
+			return fmt.Errorf("error decoding script root: %v", err)
+		}
+
+	case !c.NoScripts:
+		return fmt.Errorf("either scriptroot or noscripts is required")
+	}
+
+	tweak, outputKey, err := btc.TaprootOutputKey(internalKey, scriptRoot)
+	if err != nil {
+		return fmt.Errorf("error tweaking internal key: %v", err)
+	}
+	outputKeyBytes := outputKey.SerializeCompressed()
+	addr, err := btc.EncodeSegWitAddress(
+		chainParams.Bech32HRPSegwit, taprootWitnessVersion,
+		outputKeyBytes[1:],
+	)
+	if err != nil {
+		return fmt.Errorf("error encoding address: %v", err)
+	}
+
+	fmt.Printf("Internal key (x-only): %x\n",
+		internalKey.SerializeCompressed()[1:])
+	fmt.Printf("Tweak: %x\n", tweak)
+	fmt.Printf("Output key (x-only): %x\n", outputKeyBytes[1:])
+	fmt.Printf("Output key parity: %d\n", outputKeyBytes[0]-0x02)
+	fmt.Printf("P2TR address: %s\n", addr)
+	return nil
+}
+
+// taprootKeyFromHex parses a public key that is hex encoded either as a 32 byte
+// x-only key or as a 33 byte compressed key.
+func taprootKeyFromHex(hexKey string) (*btcec.PublicKey, error) {
+	keyBytes, err := hex.DecodeString(hexKey)
+	if err != nil {
+		return nil, err
+	}
+	if len(keyBytes) == 32 {
+		return btc.ParseXOnlyPubKey(keyBytes)
+	}
+	return btcec.ParsePubKey(keyBytes, btcec.S256())
+}
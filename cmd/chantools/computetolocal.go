@@ -0,0 +1,69 @@
+package main
+
+import (
+	"crypto/sha256"
+	"fmt"
+
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcutil"
+	"github.com/lightningnetwork/lnd/input"
+)
+
+type computeToLocalCommand struct {
+	RevocationPubKey string `long:"revocationpubkey" description:"The revocation public key of the commitment (hex)."`
+	DelayPubKey      string `long:"delaypubkey" description:"The local delay public key of the commitment (hex)."`
+	CSVDelay         uint32 `long:"csvdelay" description:"The CSV delay of the to_local output in blocks."`
+}
+
+func (c *computeToLocalCommand) Execute(_ []string) error {
+	setupChainParams(cfg)
+
+	if c.RevocationPubKey == "" || c.DelayPubKey == "" {
+		return fmt.Errorf("revocationpubkey and delaypubkey are " +
+			"required")
+	}
+	if c.CSVDelay == 0 {
+		return fmt.Errorf("csvdelay is required")
+	}
+	revocationKey, err := pubKeyFromHex(c.RevocationPubKey)
+	if err != nil {
+		return fmt.Errorf("error parsing revocation key: %v", err)
+	}
+	delayKey, err := pubKeyFromHex(c.DelayPubKey)
+	if err != nil {
+		return fmt.Errorf("error parsing delay key: %v", err)
+	}
+
+	script, err := input.CommitScriptToSelf(
+		c.CSVDelay, delayKey, revocationKey,
+	)
+	if err != nil {
+		return fmt.Errorf("error creating to_local script: %v", err)
+	}
+	disassembled, err := txscript.DisasmString(script)
+	if err != nil {
+		return fmt.Errorf("error disassembling script: %v", err)
+	}
+	scriptHash := sha256.Sum256(script)
+	addr, err := btcutil.NewAddressWitnessScriptHash(
+		scriptHash[:], chainParams,
+	)
+	if err != nil {
+		return fmt.Errorf("error creating address: %v", err)
+	}
+
+	fmt.Printf("Witness script: %x\n", script)
+	fmt.Printf("Disassembled: %s\n", disassembled)
+	fmt.Printf("P2WSH address: %s\n", addr.EncodeAddress())
+	fmt.Printf("\nWitness to spend with the delay key after %d blocks "+
+		"(transaction version 2 and input sequence of at least "+
+		"%d):\n", c.CSVDelay, c.CSVDelay)
+	fmt.Printf("  0: <signature of delay key>\n")
+	fmt.Printf("  1: <empty>\n")
+	fmt.Printf("  2: %x\n", script)
+	fmt.Printf("\nWitness to spend with the revocation key:\n")
+	fmt.Printf("  0: <signature of revocation key>\n")
+	fmt.Printf("  1: 01\n")
+	fmt.Printf("  2: %x\n", script)
+	return nil
+}
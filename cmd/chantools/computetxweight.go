@@ -0,0 +1,160 @@
+package main
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/btcsuite/btcd/blockchain"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/lightningnetwork/lnd/input"
+)
+
+const (
+	inputTypeP2PKH          = "p2pkh"
+	inputTypeP2WKH          = "p2wpkh"
+	inputTypeP2WSH          = "p2wsh"
+	inputTypeP2TRKeyPath    = "p2tr-keypath"
+	inputTypeP2TRScriptPath = "p2tr-scriptpath"
+
+	// p2trKeyPathWitnessSize is the size of the witness of a Taproot key
+	// path spend with the default sighash type:
+	//	- number_of_witness_elements: 1 byte
+	//	- signature_length: 1 byte
+	//	- signature: 64 bytes
+	p2trKeyPathWitnessSize = 1 + 1 + 64
+
+	// p2trScriptPathWitnessSize is the size of the witness of a Taproot
+	// script path spend of a single key leaf at the root of the tree:
+	//	- number_of_witness_elements: 1 byte
+	//	- signature_length: 1 byte
+	//	- signature: 64 bytes
+	//	- script_length: 1 byte
+	//	- script (<x-only pubkey> OP_CHECKSIG): 34 bytes
+	//	- control_block_length: 1 byte
+	//	- control_block: 33 bytes
+	p2trScriptPathWitnessSize = 1 + 1 + 64 + 1 + 34 + 1 + 33
+)
+
+var (
+	// estimatedInputSizes are the sizes of the signature script (P2PKH) or
+	// the witness (all other types) that are assumed for unsigned inputs.
+	estimatedInputSizes = map[string]int{
+		inputTypeP2PKH:          input.P2PKHScriptSigSize,
+		inputTypeP2WKH:          input.P2WKHWitnessSize,
+		inputTypeP2WSH:          input.WitnessSize,
+		inputTypeP2TRKeyPath:    p2trKeyPathWitnessSize,
+		inputTypeP2TRScriptPath: p2trScriptPathWitnessSize,
+	}
+)
+
+type computeTxWeightCommand struct {
+	Tx         string   `long:"tx" description:"The transaction to compute the weight of, hex encoded. The inputs can be unsigned."`
+	InputTypes []string `long:"inputtype" description:"The type of an unsigned input to estimate the size of its signature in the format <type>[:<size>], one of p2pkh, p2wpkh, p2wsh (2-of-2 multisig), p2tr-keypath or p2tr-scriptpath (single key leaf). The optional size overwrites the assumed size of the witness (or signature script for p2pkh) in bytes. Specify once for every input, in the order of the inputs."`
+}
+
+func (c *computeTxWeightCommand) Execute(_ []string) error {
+	setupChainParams(cfg)
+
+	if c.Tx == "" {
+		return fmt.Errorf("tx is required")
+	}
+	rawTx, err := hex.DecodeString(strings.TrimSpace(c.Tx))
+	if err != nil {
+		return fmt.Errorf("error decoding tx hex: %v", err)
+	}
+	tx := &wire.MsgTx{}
+	if err := tx.Deserialize(bytes.NewReader(rawTx)); err != nil {
+		return fmt.Errorf("error parsing tx: %v", err)
+	}
+	if len(c.InputTypes) > len(tx.TxIn) {
+		return fmt.Errorf("got %d input types but tx only has %d "+
+			"inputs", len(c.InputTypes), len(tx.TxIn))
+	}
+
+	var (
+		baseSize    = tx.SerializeSizeStripped()
+		witnessSize = 0
+		hasWitness  = false
+	)
+	for idx, txIn := range tx.TxIn {
+		signed := len(txIn.SignatureScript) > 0 || len(txIn.Witness) > 0
+		if idx >= len(c.InputTypes) {
+			if !signed {
+				log.Warnf("Input %d is unsigned and no input "+
+					"type was given, the weight will be "+
+					"too low", idx)
+			}
+			if len(txIn.Witness) > 0 {
+				hasWitness = true
+			}
+			witnessSize += txIn.Witness.SerializeSize()
+			continue
+		}
+
+		if signed {
+			return fmt.Errorf("input %d is already signed, don't "+
+				"specify an input type for it", idx)
+		}
+		inputType, size, err := parseUnsignedInputType(
+			c.InputTypes[idx],
+		)
+		if err != nil {
+			return fmt.Errorf("invalid input type of input %d: %v",
+				idx, err)
+		}
+		fmt.Printf("Input %d: assuming %s with %d bytes\n", idx,
+			inputType, size)
+
+		// The signature script is part of the base transaction, the
+		// length of the empty script is already counted as one byte.
+		if inputType == inputTypeP2PKH {
+			witnessSize += wire.TxWitness{}.SerializeSize()
+			baseSize += wire.VarIntSerializeSize(uint64(size)) - 1 +
+				size
+			continue
+		}
+		hasWitness = true
+		witnessSize += size
+	}
+
+	// Transactions without any witness are serialized without the marker
+	// and the flag byte and the empty witness of every input.
+	if hasWitness {
+		witnessSize += 2
+	} else {
+		witnessSize = 0
+	}
+	weight := baseSize*blockchain.WitnessScaleFactor + witnessSize
+	vSize := (weight + blockchain.WitnessScaleFactor - 1) /
+		blockchain.WitnessScaleFactor
+
+	fmt.Printf("Base size: %d bytes\n", baseSize)
+	fmt.Printf("Witness size: %d bytes\n", witnessSize)
+	fmt.Printf("Weight: %d weight units\n", weight)
+	fmt.Printf("Virtual size: %d vBytes\n", vSize)
+	return nil
+}
+
+// parseUnsignedInputType parses an input type in the format <type>[:<size>] and
+// returns the type and the size of its signature script or witness.
+func parseUnsignedInputType(inputType string) (string, int, error) {
+	parts := strings.Split(strings.ToLower(inputType), ":")
+	if len(parts) > 2 {
+		return "", 0, fmt.Errorf("invalid format %s", inputType)
+	}
+	size, ok := estimatedInputSizes[parts[0]]
+	if !ok {
+		return "", 0, fmt.Errorf("unknown type %s", parts[0])
+	}
+	if len(parts) == 2 {
+		var err error
+		size, err = strconv.Atoi(parts[1])
+		if err != nil || size <= 0 {
+			return "", 0, fmt.Errorf("invalid size %s", parts[1])
+		}
+	}
+	return parts[0], size, nil
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+)
+
+const (
+	defaultSigHash = "ALL"
+)
+
+var (
+	// sigHashTypes maps the names of the supported sighash types to their
+	// values. ANYONECANPAY is combined with the other types with a "|".
+	sigHashTypes = map[string]txscript.SigHashType{
+		"ALL":    txscript.SigHashAll,
+		"NONE":   txscript.SigHashNone,
+		"SINGLE": txscript.SigHashSingle,
+		"ALL|ANYONECANPAY": txscript.SigHashAll |
+			txscript.SigHashAnyOneCanPay,
+		"NONE|ANYONECANPAY": txscript.SigHashNone |
+			txscript.SigHashAnyOneCanPay,
+		"SINGLE|ANYONECANPAY": txscript.SigHashSingle |
+			txscript.SigHashAnyOneCanPay,
+	}
+)
+
+type computeWitnessHashCommand struct {
+	Tx         string `long:"tx" description:"The transaction to compute the sighash for, hex encoded."`
+	InputIndex uint32 `long:"inputindex" description:"The index of the input that is signed."`
+	ScriptCode string `long:"scriptcode" description:"The scriptCode of the input as defined in BIP143 (hex). This is the witness script for P2WSH inputs and the P2PKH script of the key hash for P2WPKH inputs."`
+	Value      int64  `long:"value" description:"The value in satoshis of the output the input spends."`
+	SigHash    string `long:"sighash" description:"The sighash type, one of ALL, NONE, SINGLE optionally combined with ANYONECANPAY, for example ALL|ANYONECANPAY. (default ALL)"`
+}
+
+func (c *computeWitnessHashCommand) Execute(_ []string) error {
+	setupChainParams(cfg)
+
+	// Set default values.
+	if c.SigHash == "" {
+		c.SigHash = defaultSigHash
+	}
+
+	hashType, ok := sigHashTypes[strings.ToUpper(c.SigHash)]
+	if !ok {
+		return fmt.Errorf("unknown sighash type %s", c.SigHash)
+	}
+	if c.Tx == "" {
+		return fmt.Errorf("tx is required")
+	}
+	rawTx, err := hex.DecodeString(strings.TrimSpace(c.Tx))
+	if err != nil {
+		return fmt.Errorf("error decoding tx hex: %v", err)
+	}
+	tx := &wire.MsgTx{}
+	if err := tx.Deserialize(bytes.NewReader(rawTx)); err != nil {
+		return fmt.Errorf("error parsing tx: %v", err)
+	}
+	if int(c.InputIndex) >= len(tx.TxIn) {
+		return fmt.Errorf("tx has no input %d", c.InputIndex)
+	}
+	if c.ScriptCode == "" {
+		return fmt.Errorf("scriptcode is required")
+	}
+	scriptCode, err := hex.DecodeString(c.ScriptCode)
+	if err != nil {
+		return fmt.Errorf("error decoding scriptcode: %v", err)
+	}
+	if c.Value <= 0 {
+		return fmt.Errorf("value is required")
+	}
+
+	sigHash, err := txscript.CalcWitnessSigHash(
+		scriptCode, txscript.NewTxSigHashes(tx), hashType, tx,
+		int(c.InputIndex), c.Value,
+	)
+	if err != nil {
+		return fmt.Errorf("error computing sighash: %v", err)
+	}
+	fmt.Printf("Sighash type: %s (0x%02x)\n", strings.ToUpper(c.SigHash),
+		uint32(hashType))
+	fmt.Printf("Sighash: %x\n", sigHash)
+	return nil
+}
@@ -0,0 +1,230 @@
+package main
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	"github.com/btcsuite/btcd/btcec"
+	"github.com/btcsuite/btcutil"
+	"github.com/btcsuite/btcutil/hdkeychain"
+	"github.com/guggero/chantools/lnd"
+)
+
+const (
+	keyFormatXPrv       = "xprv"
+	keyFormatWIF        = "wif"
+	keyFormatPubKeyHex  = "pubkey_hex"
+	keyFormatAddrP2PKH  = "addr_p2pkh"
+	keyFormatAddrNP2WKH = "addr_np2wkh"
+	keyFormatAddrP2WKH  = "addr_p2wpkh"
+	keyFormatAddrP2TR   = "addr_p2tr"
+)
+
+// leafKeyFormats are the formats of a single key, as opposed to an extended
+// key.
+var leafKeyFormats = []string{
+	keyFormatWIF, keyFormatPubKeyHex, keyFormatAddrP2PKH,
+	keyFormatAddrNP2WKH, keyFormatAddrP2WKH, keyFormatAddrP2TR,
+}
+
+type convertKeyCommand struct {
+	Input         string `long:"input" description:"The key to convert. Can be an extended key (xprv, xpub or any SLIP-0132 version like zpub), a WIF encoded private key or a hex encoded private or compressed public key."`
+	Path          string `long:"path" description:"The BIP32 derivation path to derive from the extended key given as input before converting it, relative to the input key. Must start with \"m/\" and is required for the single key formats of an extended key."`
+	OutputFormats string `long:"outputformats" description:"Comma separated list of formats to convert the key to. Extended keys can be converted to xprv (tprv on test networks), xpub, ypub, zpub, Ypub, Zpub or tpub, upub, vpub on test networks, single keys to wif, pubkey_hex, addr_p2pkh, addr_np2wkh, addr_p2wpkh and addr_p2tr. (default all formats of the input type)"`
+}
+
+func (c *convertKeyCommand) Execute(_ []string) error {
+	setupChainParams(cfg)
+
+	if c.Input == "" {
+		return fmt.Errorf("input is required")
+	}
+
+	extendedKey, privKey, pubKey, err := parseConvertKeyInput(c.Input)
+	if err != nil {
+		return err
+	}
+
+	if c.Path != "" {
+		if extendedKey == nil {
+			return fmt.Errorf("path can only be used with an " +
+				"extended key as input")
+		}
+		path, err := lnd.ParsePath(c.Path)
+		if err != nil {
+			return fmt.Errorf("error parsing path: %v", err)
+		}
+		extendedKey, err = lnd.DeriveChildren(extendedKey, path)
+		if err != nil {
+			return fmt.Errorf("error deriving path: %v", err)
+		}
+	}
+
+	// The single key of an extended key is only used if it was derived
+	// explicitly, the account key itself never receives any funds.
+	if extendedKey != nil && c.Path != "" {
+		if extendedKey.IsPrivate() {
+			privKey, err = extendedKey.ECPrivKey()
+			if err != nil {
+				return fmt.Errorf("error deriving private "+
+					"key: %v", err)
+			}
+		}
+		pubKey, err = extendedKey.ECPubKey()
+		if err != nil {
+			return fmt.Errorf("error deriving public key: %v", err)
+		}
+	}
+
+	formats := defaultConvertKeyFormats(extendedKey, privKey, pubKey)
+	if c.OutputFormats != "" {
+		formats = strings.Split(c.OutputFormats, ",")
+	}
+
+	var leafKey *DerivedKey
+	if pubKey != nil {
+		leafKey, err = newLeafKey(privKey, pubKey)
+		if err != nil {
+			return err
+		}
+	}
+	for _, format := range formats {
+		format = strings.TrimSpace(format)
+		value, err := convertKey(format, extendedKey, leafKey)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("%s: %s\n", format, value)
+	}
+	return nil
+}
+
+// parseConvertKeyInput detects the format of the input key and parses it.
+// Either the extended key or the public key (and for private keys the private
+// key) is returned.
+func parseConvertKeyInput(input string) (*hdkeychain.ExtendedKey,
+	*btcec.PrivateKey, *btcec.PublicKey, error) {
+
+	extendedKey, err := hdkeychain.NewKeyFromString(input)
+	if err == nil {
+		return extendedKey, nil, nil, nil
+	}
+
+	wif, err := btcutil.DecodeWIF(input)
+	if err == nil {
+		if !wif.IsForNet(chainParams) {
+			return nil, nil, nil, fmt.Errorf("WIF is not for "+
+				"network %s", chainParams.Name)
+		}
+		return nil, wif.PrivKey, wif.PrivKey.PubKey(), nil
+	}
+
+	keyBytes, err := hex.DecodeString(input)
+	switch {
+	case err == nil && len(keyBytes) == btcec.PrivKeyBytesLen:
+		privKey, pubKey := btcec.PrivKeyFromBytes(
+			btcec.S256(), keyBytes,
+		)
+		return nil, privKey, pubKey, nil
+
+	case err == nil && len(keyBytes) == btcec.PubKeyBytesLenCompressed:
+		pubKey, err := btcec.ParsePubKey(keyBytes, btcec.S256())
+		if err != nil {
+			return nil, nil, nil, fmt.Errorf("error parsing "+
+				"public key: %v", err)
+		}
+		return nil, nil, pubKey, nil
+	}
+
+	return nil, nil, nil, fmt.Errorf("unknown key format, must be an " +
+		"extended key, a WIF or a hex encoded private or compressed " +
+		"public key")
+}
+
+// defaultConvertKeyFormats returns all formats the given key can be converted
+// to.
+func defaultConvertKeyFormats(extendedKey *hdkeychain.ExtendedKey,
+	privKey *btcec.PrivateKey, pubKey *btcec.PublicKey) []string {
+
+	var formats []string
+	if extendedKey != nil {
+		if extendedKey.IsPrivate() {
+			formats = append(formats, keyFormatXPrv)
+		}
+		pubFormat := "xpub"
+		if chainParams.Name != "mainnet" {
+			pubFormat = "tpub"
+		}
+		formats = append(formats, pubFormat)
+	}
+	if pubKey != nil {
+		for _, format := range leafKeyFormats {
+			if format == keyFormatWIF && privKey == nil {
+				continue
+			}
+			formats = append(formats, format)
+		}
+	}
+	return formats
+}
+
+// convertKey returns the extended key or the single key in the given format.
+func convertKey(format string, extendedKey *hdkeychain.ExtendedKey,
+	leafKey *DerivedKey) (string, error) {
+
+	if _, ok := slip132Versions[format]; ok || format == keyFormatXPrv {
+		if extendedKey == nil {
+			return "", fmt.Errorf("format %s requires an extended "+
+				"key as input", format)
+		}
+		if format != keyFormatXPrv {
+			return slip132Encode(extendedKey, format)
+		}
+		if !extendedKey.IsPrivate() {
+			return "", fmt.Errorf("format %s requires a private "+
+				"key as input", format)
+		}
+		extendedKey.SetNet(chainParams)
+		return extendedKey.String(), nil
+	}
+
+	isLeafFormat := false
+	for _, leafFormat := range leafKeyFormats {
+		if format == leafFormat {
+			isLeafFormat = true
+		}
+	}
+	switch {
+	case !isLeafFormat:
+		return "", fmt.Errorf("unknown output format %s", format)
+
+	case leafKey == nil:
+		return "", fmt.Errorf("format %s requires a path to derive a "+
+			"single key from the extended key", format)
+	}
+
+	switch format {
+	case keyFormatWIF:
+		if leafKey.WIF == "" {
+			return "", fmt.Errorf("format %s requires a private "+
+				"key as input", format)
+		}
+		return leafKey.WIF, nil
+
+	case keyFormatPubKeyHex:
+		return leafKey.PubKeyHex, nil
+
+	case keyFormatAddrP2PKH:
+		return leafKey.AddrP2PKH, nil
+
+	case keyFormatAddrNP2WKH:
+		return leafKey.AddrNP2WKH, nil
+
+	case keyFormatAddrP2WKH:
+		return leafKey.AddrP2WKH, nil
+
+	default:
+		return leafKey.AddrP2TR, nil
+	}
+}
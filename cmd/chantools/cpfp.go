@@ -0,0 +1,50 @@
+package main
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/guggero/chantools/btc"
+)
+
+const (
+	// minRelayFeeRate is the lowest fee rate in satoshis per vbyte that
+	// nodes with the default policy relay transactions for.
+	minRelayFeeRate = 1
+)
+
+// cpfpChildFee returns the fee a child transaction needs to pay so that the
+// package of the unconfirmed parent and the child has the target fee rate. The
+// child pays at least the minimum relay fee for its own size, even if the
+// parent already pays enough.
+func cpfpChildFee(parentVSize, parentFee, childVSize int64,
+	packageFeeRate float64) int64 {
+
+	packageFee := int64(math.Ceil(
+		float64(parentVSize+childVSize) * packageFeeRate,
+	))
+	childFee := packageFee - parentFee
+	minFee := int64(math.Ceil(float64(childVSize) * minRelayFeeRate))
+	if childFee < minFee {
+		return minFee
+	}
+	return childFee
+}
+
+// cpfpFeeRate returns the lowest fee rate of the fee estimates of the chain API
+// which is the rate a package needs to at least have to be accepted by the
+// mempool.
+func cpfpFeeRate(api *btc.ExplorerAPI) (float64, error) {
+	estimates, err := api.FeeEstimates()
+	if err != nil {
+		return 0, fmt.Errorf("error fetching fee estimates: %v", err)
+	}
+	if len(estimates) == 0 {
+		return 0, fmt.Errorf("chain API returned no fee estimates")
+	}
+	feeRate := math.Inf(1)
+	for _, estimate := range estimates {
+		feeRate = math.Min(feeRate, estimate)
+	}
+	return math.Max(feeRate, minRelayFeeRate), nil
+}
@@ -0,0 +1,352 @@
+package main
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/btcsuite/btcutil"
+	"github.com/btcsuite/btcutil/hdkeychain"
+	"github.com/btcsuite/btcutil/psbt"
+	"github.com/guggero/chantools/lnd"
+	"github.com/guggero/chantools/sweep"
+)
+
+type createPsbtCommand struct {
+	UTXOs          []string `long:"utxo" description:"An input of the transaction in the format txid:vout:amount:scriptpubkey with the amount in satoshis and the hex encoded pk script. Can be specified multiple times."`
+	Outputs        []string `long:"output" description:"An output of the transaction in the format address:amount with the amount in satoshis. Can be specified multiple times."`
+	LockTime       uint32   `long:"locktime" description:"The lock time of the transaction."`
+	AddDerivation  bool     `long:"addderivation" description:"Add the BIP32 derivation paths of all inputs that belong to the wallet of the root key."`
+	RootKey        string   `long:"rootkey" description:"BIP32 HD root key to use for --addderivation. Leave empty to prompt for lnd 24 word aezeed."`
+	DerivationPath string   `long:"derivationpath" description:"The first levels of the derivation path before any internal/external branch to search the input keys in. (default m/84'/0'/0')"`
+	RecoveryWindow uint32   `long:"recoverywindow" description:"The number of keys to search per internal/external branch. (default 2500)"`
+}
+
+func (c *createPsbtCommand) Execute(_ []string) error {
+	setupChainParams(cfg)
+
+	if len(c.UTXOs) == 0 {
+		return fmt.Errorf("at least one utxo is required")
+	}
+	if len(c.Outputs) == 0 {
+		return fmt.Errorf("at least one output is required")
+	}
+
+	// Parse all inputs and outputs.
+	var (
+		outPoints = make([]*wire.OutPoint, len(c.UTXOs))
+		prevOuts  = make([]*wire.TxOut, len(c.UTXOs))
+		sequences = make([]uint32, len(c.UTXOs))
+		outputs   = make([]*wire.TxOut, len(c.Outputs))
+		totalIn   = int64(0)
+		totalOut  = int64(0)
+		err       error
+	)
+	for idx, utxo := range c.UTXOs {
+		outPoints[idx], prevOuts[idx], err = parseUTXO(utxo)
+		if err != nil {
+			return fmt.Errorf("error parsing utxo %s: %v", utxo,
+				err)
+		}
+		sequences[idx] = wire.MaxTxInSequenceNum
+		totalIn += prevOuts[idx].Value
+	}
+	for idx, output := range c.Outputs {
+		outputs[idx], err = parseOutput(output)
+		if err != nil {
+			return fmt.Errorf("error parsing output %s: %v", output,
+				err)
+		}
+		totalOut += outputs[idx].Value
+	}
+	if totalOut > totalIn {
+		return fmt.Errorf("total output amount %d exceeds total input "+
+			"amount %d", totalOut, totalIn)
+	}
+
+	creator := &psbt.Creator{}
+	err = creator.CreatePsbt(outPoints, outputs, 2, c.LockTime, sequences)
+	if err != nil {
+		return fmt.Errorf("error creating PSBT: %v", err)
+	}
+	updater, err := psbt.NewUpdater(creator.Cpsbt)
+	if err != nil {
+		return fmt.Errorf("error creating PSBT updater: %v", err)
+	}
+
+	// We only know the previous output and not the full previous
+	// transaction, so we can only add witness UTXO information.
+	for idx, prevOut := range prevOuts {
+		if !isWitnessSpendable(prevOut.PkScript) {
+			log.Warnf("Input %d is not a SegWit input, signers "+
+				"might require the full previous transaction",
+				idx)
+		}
+		err := updater.AddInWitnessUtxo(prevOut, idx)
+		if err != nil {
+			return fmt.Errorf("error adding utxo info: %v", err)
+		}
+	}
+
+	if c.AddDerivation {
+		err := c.addDerivations(updater, prevOuts)
+		if err != nil {
+			return err
+		}
+	}
+
+	log.Infof("Created PSBT with %d inputs and %d outputs, fee %d sats",
+		len(outPoints), len(outputs), totalIn-totalOut)
+	b64, err := updater.Upsbt.B64Encode()
+	if err != nil {
+		return fmt.Errorf("error encoding PSBT: %v", err)
+	}
+	fmt.Println(b64)
+	return nil
+}
+
+// addDerivations searches the wallet of the root key for the keys of all
+// inputs and adds their BIP32 derivation information to the PSBT.
+func (c *createPsbtCommand) addDerivations(updater *psbt.Updater,
+	prevOuts []*wire.TxOut) error {
+
+	var (
+		extendedKey *hdkeychain.ExtendedKey
+		err         error
+	)
+
+	// Check that root key is valid or fall back to console input.
+	switch {
+	case c.RootKey != "":
+		extendedKey, err = hdkeychain.NewKeyFromString(c.RootKey)
+
+	default:
+		extendedKey, _, err = rootKeyFromConsole()
+	}
+	if err != nil {
+		return fmt.Errorf("error reading root key: %v", err)
+	}
+
+	// Set default values.
+	if c.DerivationPath == "" {
+		c.DerivationPath = defaultDerivationPath
+	}
+	if c.RecoveryWindow == 0 {
+		c.RecoveryWindow = defaultRecoveryWindow
+	}
+	basePath, err := lnd.ParsePath(c.DerivationPath)
+	if err != nil {
+		return fmt.Errorf("error parsing path: %v", err)
+	}
+
+	fingerprint, err := rootKeyFingerprint(extendedKey)
+	if err != nil {
+		return err
+	}
+
+	// Build a lookup table of all pk scripts we can derive.
+	lookup, err := deriveWalletKeys(
+		extendedKey, basePath, c.RecoveryWindow,
+	)
+	if err != nil {
+		return err
+	}
+
+	for idx, prevOut := range prevOuts {
+		d, ok := lookup[hex.EncodeToString(prevOut.PkScript)]
+		if !ok {
+			log.Infof("Key of input %d not found in wallet", idx)
+			continue
+		}
+		err := updater.AddInBip32Derivation(
+			fingerprint, d.path, d.pubKey, idx,
+		)
+		if err != nil {
+			return fmt.Errorf("error adding derivation: %v", err)
+		}
+
+		// Nested SegWit inputs also need the redeem script.
+		if txscript.IsPayToScriptHash(prevOut.PkScript) {
+			redeemScript, err := p2wkhScript(d.pubKey)
+			if err != nil {
+				return err
+			}
+			err = updater.AddInRedeemScript(redeemScript, idx)
+			if err != nil {
+				return fmt.Errorf("error adding redeem "+
+					"script: %v", err)
+			}
+		}
+	}
+	return nil
+}
+
+// rootKeyFingerprint returns the BIP32 fingerprint of the root key that is
+// used to identify it in the derivation information of a PSBT.
+func rootKeyFingerprint(extendedKey *hdkeychain.ExtendedKey) (uint32, error) {
+	rootPubKey, err := extendedKey.ECPubKey()
+	if err != nil {
+		return 0, fmt.Errorf("error deriving root pubkey: %v", err)
+	}
+	return binary.LittleEndian.Uint32(
+		btcutil.Hash160(rootPubKey.SerializeCompressed())[:4],
+	), nil
+}
+
+// walletKey is a key of the wallet together with its derivation path and the
+// type of the pk script it was looked up by.
+type walletKey struct {
+	key        *hdkeychain.ExtendedKey
+	pubKey     []byte
+	path       []uint32
+	scriptType sweep.ScriptType
+}
+
+// deriveWalletKeys derives all keys of the internal and external branch below
+// the base path and returns them keyed by the hex encoded P2WKH, NP2WKH and
+// P2PKH pk scripts.
+func deriveWalletKeys(extendedKey *hdkeychain.ExtendedKey, basePath []uint32,
+	recoveryWindow uint32) (map[string]*walletKey, error) {
+
+	baseKey, err := lnd.DeriveChildren(extendedKey, basePath)
+	if err != nil {
+		return nil, fmt.Errorf("error deriving base key: %v", err)
+	}
+
+	scriptTypes := []sweep.ScriptType{
+		sweep.ScriptTypeP2WKH, sweep.ScriptTypeNP2WKH,
+		sweep.ScriptTypeP2PKH,
+	}
+	lookup := make(map[string]*walletKey)
+	for branch := uint32(0); branch <= 1; branch++ {
+		branchKey, err := baseKey.Child(branch)
+		if err != nil {
+			return nil, err
+		}
+		for i := uint32(0); i < recoveryWindow; i++ {
+			key, err := branchKey.Child(i)
+			if err != nil {
+				return nil, err
+			}
+			pubKey, err := key.ECPubKey()
+			if err != nil {
+				return nil, err
+			}
+			pubKeyBytes := pubKey.SerializeCompressed()
+			scripts, err := pubKeyScripts(pubKeyBytes)
+			if err != nil {
+				return nil, err
+			}
+			path := append([]uint32{}, basePath...)
+			path = append(path, branch, i)
+			for idx, script := range scripts {
+				lookup[hex.EncodeToString(script)] = &walletKey{
+					key:        key,
+					pubKey:     pubKeyBytes,
+					path:       path,
+					scriptType: scriptTypes[idx],
+				}
+			}
+		}
+	}
+	return lookup, nil
+}
+
+func parseUTXO(utxo string) (*wire.OutPoint, *wire.TxOut, error) {
+	parts := strings.Split(utxo, ":")
+	if len(parts) != 4 {
+		return nil, nil, fmt.Errorf("expected format " +
+			"txid:vout:amount:scriptpubkey")
+	}
+	txHash, err := chainhash.NewHashFromStr(parts[0])
+	if err != nil {
+		return nil, nil, fmt.Errorf("error parsing tx hash: %v", err)
+	}
+	vout, err := strconv.ParseUint(parts[1], 10, 32)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error parsing vout: %v", err)
+	}
+	amount, err := strconv.ParseInt(parts[2], 10, 64)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error parsing amount: %v", err)
+	}
+	pkScript, err := hex.DecodeString(parts[3])
+	if err != nil {
+		return nil, nil, fmt.Errorf("error parsing pk script: %v", err)
+	}
+	return wire.NewOutPoint(txHash, uint32(vout)),
+		wire.NewTxOut(amount, pkScript), nil
+}
+
+func parseOutput(output string) (*wire.TxOut, error) {
+	parts := strings.Split(output, ":")
+	if len(parts) != 2 {
+		return nil, fmt.Errorf("expected format address:amount")
+	}
+	addr, err := btcutil.DecodeAddress(parts[0], chainParams)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing address: %v", err)
+	}
+	if !addr.IsForNet(chainParams) {
+		return nil, fmt.Errorf("address %s is not valid for network "+
+			"%s", parts[0], chainParams.Name)
+	}
+	pkScript, err := txscript.PayToAddrScript(addr)
+	if err != nil {
+		return nil, fmt.Errorf("error creating pk script: %v", err)
+	}
+	amount, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing amount: %v", err)
+	}
+	return wire.NewTxOut(amount, pkScript), nil
+}
+
+func isWitnessSpendable(pkScript []byte) bool {
+	return txscript.IsPayToWitnessPubKeyHash(pkScript) ||
+		txscript.IsPayToWitnessScriptHash(pkScript) ||
+		txscript.IsPayToScriptHash(pkScript)
+}
+
+// p2wkhScript returns the P2WKH pk script of the given public key.
+func p2wkhScript(pubKey []byte) ([]byte, error) {
+	builder := txscript.NewScriptBuilder()
+	builder.AddOp(txscript.OP_0)
+	builder.AddData(btcutil.Hash160(pubKey))
+	return builder.Script()
+}
+
+// pubKeyScripts returns the P2WKH, NP2WKH and P2PKH pk scripts of the given
+// public key.
+func pubKeyScripts(pubKey []byte) ([][]byte, error) {
+	p2wkh, err := p2wkhScript(pubKey)
+	if err != nil {
+		return nil, err
+	}
+
+	builder := txscript.NewScriptBuilder()
+	builder.AddOp(txscript.OP_HASH160)
+	builder.AddData(btcutil.Hash160(p2wkh))
+	builder.AddOp(txscript.OP_EQUAL)
+	np2wkh, err := builder.Script()
+	if err != nil {
+		return nil, err
+	}
+
+	builder = txscript.NewScriptBuilder()
+	builder.AddOp(txscript.OP_DUP)
+	builder.AddOp(txscript.OP_HASH160)
+	builder.AddData(btcutil.Hash160(pubKey))
+	builder.AddOp(txscript.OP_EQUALVERIFY)
+	builder.AddOp(txscript.OP_CHECKSIG)
+	p2pkh, err := builder.Script()
+	if err != nil {
+		return nil, err
+	}
+	return [][]byte{p2wkh, np2wkh, p2pkh}, nil
+}
@@ -0,0 +1,196 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/btcsuite/btcutil/hdkeychain"
+	"github.com/btcsuite/btcwallet/waddrmgr"
+	"github.com/btcsuite/btcwallet/wallet"
+	"github.com/btcsuite/btcwallet/walletdb"
+	"github.com/lightningnetwork/lnd/aezeed"
+	"github.com/lightningnetwork/lnd/keychain"
+)
+
+const (
+	// walletInfoFileName is the name of the file in the wallet directory
+	// the account xpubs of a new wallet are written to.
+	walletInfoFileName = "wallet-info.txt"
+
+	// minWalletPasswordLen is the minimum length of a wallet password that
+	// lnd accepts.
+	minWalletPasswordLen = 8
+)
+
+var (
+	// lightningAddrSchema is the address schema lnd uses for the key scope
+	// of its own keys.
+	lightningAddrSchema = waddrmgr.ScopeAddrSchema{
+		ExternalAddrType: waddrmgr.WitnessPubKey,
+		InternalAddrType: waddrmgr.WitnessPubKey,
+	}
+)
+
+type createWalletCommand struct {
+	WalletDir string `long:"walletdir" description:"The directory to create the new lnd wallet.db file in, for example ~/.lnd/data/chain/bitcoin/mainnet. (default results/wallet-<timestamp>)"`
+}
+
+func (c *createWalletCommand) Execute(_ []string) error {
+	setupChainParams(cfg)
+
+	// Set default values.
+	if c.WalletDir == "" {
+		c.WalletDir = fmt.Sprintf("results/wallet-%s",
+			time.Now().Format("2006-01-02-15-04-05"))
+	}
+	walletDir := cleanAndExpandPath(c.WalletDir)
+	if err := os.MkdirAll(walletDir, 0700); err != nil {
+		return fmt.Errorf("error creating wallet directory: %v", err)
+	}
+	loader := wallet.NewLoader(chainParams, walletDir, true, 0)
+	exists, err := loader.WalletExists()
+	if err != nil {
+		return err
+	}
+	if exists {
+		return fmt.Errorf("wallet already exists in %s", walletDir)
+	}
+	infoFile := filepath.Join(walletDir, walletInfoFileName)
+	if _, err := os.Stat(infoFile); err == nil {
+		return fmt.Errorf("file %s already exists", infoFile)
+	}
+
+	password, err := confirmedPasswordFromConsole("wallet password")
+	if err != nil {
+		return err
+	}
+	if len(password) < minWalletPasswordLen {
+		return fmt.Errorf("wallet password must have at least %d "+
+			"characters", minWalletPasswordLen)
+	}
+	seedPassphrase, err := confirmedPasswordFromConsole(
+		"cipher seed passphrase (press enter to not use a passphrase)",
+	)
+	if err != nil {
+		return err
+	}
+
+	// The seed is created the same way as lnd does it, so the wallet can
+	// be used by lnd directly or restored from the mnemonic later.
+	cipherSeed, err := aezeed.New(
+		keychain.KeyDerivationVersion, nil, time.Now(),
+	)
+	if err != nil {
+		return fmt.Errorf("error creating cipher seed: %v", err)
+	}
+	mnemonic, err := cipherSeed.ToMnemonic(seedPassphrase)
+	if err != nil {
+		return fmt.Errorf("error encoding mnemonic: %v", err)
+	}
+	rootKey, err := hdkeychain.NewMaster(
+		cipherSeed.Entropy[:], chainParams,
+	)
+	if err != nil {
+		return fmt.Errorf("error deriving root key: %v", err)
+	}
+
+	// lnd uses the wallet password for both the public and the private
+	// passphrase of the wallet.
+	w, err := loader.CreateNewWallet(
+		password, password, cipherSeed.Entropy[:],
+		cipherSeed.BirthdayTime(),
+	)
+	if err != nil {
+		return fmt.Errorf("error creating wallet: %v", err)
+	}
+
+	// lnd creates the key scope of its own keys when it first starts the
+	// wallet. We do the same, so the wallet can be inspected directly.
+	db := w.Database()
+	err = walletdb.Update(db, func(tx walletdb.ReadWriteTx) error {
+		addrmgrNs := tx.ReadWriteBucket(waddrmgrNamespaceKey)
+		if err := w.Manager.Unlock(addrmgrNs, password); err != nil {
+			return err
+		}
+		defer w.Manager.Lock()
+
+		_, err := w.Manager.NewScopedKeyManager(
+			addrmgrNs, waddrmgr.KeyScope{
+				Purpose: keychain.BIP0043Purpose,
+				Coin:    chainParams.HDCoinType,
+			}, lightningAddrSchema,
+		)
+		return err
+	})
+	if err != nil {
+		_ = loader.UnloadWallet()
+		return fmt.Errorf("error creating lnd key scope: %v", err)
+	}
+	if err := loader.UnloadWallet(); err != nil {
+		return fmt.Errorf("error closing wallet: %v", err)
+	}
+	log.Infof("Created wallet.db in %s", walletDir)
+
+	fmt.Println("!!!YOU MUST WRITE DOWN THIS SEED TO BE ABLE TO RESTORE " +
+		"THE WALLET!!!")
+	fmt.Println()
+	for idx, word := range mnemonic {
+		fmt.Printf("%2d. %-12s", idx+1, word)
+		if (idx+1)%4 == 0 {
+			fmt.Println()
+		}
+	}
+	fmt.Println()
+	fmt.Println("!!!YOU MUST WRITE DOWN THIS SEED TO BE ABLE TO RESTORE " +
+		"THE WALLET!!!")
+	fmt.Println()
+
+	// The account xpubs only contain public keys, so they are safe to show
+	// and to store next to the wallet for setting up a watch-only wallet.
+	var info bytes.Buffer
+	if err := printAccountXPubs(&info, rootKey, true); err != nil {
+		return err
+	}
+	fmt.Print(info.String())
+
+	f, err := os.OpenFile(
+		infoFile, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600,
+	)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(f, &info)
+	_ = f.Close()
+	if err != nil {
+		return fmt.Errorf("error writing %s: %v", infoFile, err)
+	}
+	log.Infof("Wrote the account xpubs to %s", infoFile)
+	return nil
+}
+
+// confirmedPasswordFromConsole reads a password from the console twice and
+// makes sure both entries match.
+func confirmedPasswordFromConsole(name string) ([]byte, error) {
+	pw, err := passwordFromConsole(fmt.Sprintf("Input %s: ", name))
+	if err != nil {
+		return nil, err
+	}
+	confirmation, err := passwordFromConsole(
+		fmt.Sprintf("Confirm %s: ", name),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	// Without a terminal the line break is read as well.
+	pw = bytes.TrimRight(pw, "\r\n")
+	confirmation = bytes.TrimRight(confirmation, "\r\n")
+	if !bytes.Equal(pw, confirmation) {
+		return nil, fmt.Errorf("passwords don't match")
+	}
+	return pw, nil
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+
+	"github.com/btcsuite/btcutil/hdkeychain"
+	"github.com/guggero/chantools/lnd"
+)
+
+type decodeSCBCommand struct {
+	MultiFile string `long:"multi_file" description:"The lnd channel.backup file to decode."`
+	RootKey   string `long:"rootkey" description:"BIP32 HD root key of the wallet that was used to create the backup. If set, the plaintext layer is decoded as well. Leave empty to only decode the encryption layer."`
+}
+
+func (c *decodeSCBCommand) Execute(_ []string) error {
+	setupChainParams(cfg)
+
+	// Check that we have a backup file.
+	if c.MultiFile == "" {
+		return fmt.Errorf("backup file is required")
+	}
+	content, err := ioutil.ReadFile(cleanAndExpandPath(c.MultiFile))
+	if err != nil {
+		return fmt.Errorf("error reading backup file: %v", err)
+	}
+
+	packed, err := lnd.DecodePackedBackup(content)
+	if err != nil {
+		return fmt.Errorf("error decoding backup file: %v", err)
+	}
+
+	// Everything after the nonce is encrypted as a whole, so without the
+	// key this is all we can show.
+	fmt.Printf("File size:         %d bytes\n", len(content))
+	fmt.Printf("Nonce:             %x\n", packed.Nonce)
+	fmt.Printf("Ciphertext length: %d bytes\n", len(packed.Ciphertext))
+	fmt.Printf("Ciphertext:        %x\n", packed.Ciphertext)
+	fmt.Printf("Auth tag:          %x\n", packed.AuthTag)
+
+	if c.RootKey == "" {
+		return nil
+	}
+
+	extendedKey, err := hdkeychain.NewKeyFromString(c.RootKey)
+	if err != nil {
+		return fmt.Errorf("error reading root key: %v", err)
+	}
+	keyRing := &lnd.HDKeyRing{
+		ExtendedKey: extendedKey,
+		ChainParams: chainParams,
+	}
+	plaintext, err := lnd.DecryptPackedBackup(packed, keyRing)
+	if err != nil {
+		return fmt.Errorf("error decrypting backup, wrong key or "+
+			"corrupt ciphertext: %v", err)
+	}
+
+	// We only decode the framing of the entries so we can also show
+	// entries that would fail to deserialize.
+	version, numEntries, entries, err := lnd.DecodeMultiPlaintext(plaintext)
+	fmt.Printf("\nMulti version:     %d\n", version)
+	fmt.Printf("Number of entries: %d (%d found)\n", numEntries,
+		len(entries))
+	for idx, entry := range entries {
+		fmt.Printf("\nEntry %d:\n", idx)
+		fmt.Printf("  Offset:  %d\n", entry.Offset)
+		fmt.Printf("  Version: %d\n", entry.Version)
+		fmt.Printf("  Length:  %d bytes\n", entry.Length)
+		fmt.Printf("  Payload: %x\n", entry.Payload)
+	}
+	if err != nil {
+		return fmt.Errorf("error decoding plaintext: %v", err)
+	}
+	if uint32(len(entries)) != numEntries {
+		return fmt.Errorf("expected %d entries but found %d",
+			numEntries, len(entries))
+	}
+	return nil
+}
@@ -0,0 +1,21 @@
+package main
+
+import (
+	"fmt"
+)
+
+type decryptRootKeyCommand struct{}
+
+func (c *decryptRootKeyCommand) Execute(_ []string) error {
+	setupChainParams(cfg)
+
+	if cfg.EncryptedRootKeyFile == "" {
+		return fmt.Errorf("encryptedrootkeyfile is required")
+	}
+	rootKey, err := rootKeyFromEncryptedFile(cfg.EncryptedRootKeyFile)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("\nYour BIP32 HD root key is: %s\n", rootKey.String())
+	return nil
+}
@@ -8,9 +8,9 @@ import (
 )
 
 type deriveKeyCommand struct {
-	RootKey string `long:"rootkey" description:"BIP32 HD root key to derive the key from."`
-	Path    string `long:"path" description:"The BIP32 derivation path to derive. Must start with \"m/\"."`
-	Neuter  bool   `long:"neuter" description:"Do not output the private key, just the public key."`
+	RootKey     string `long:"rootkey" description:"BIP32 HD root key to derive the key from. Leave empty to prompt for lnd 24 word aezeed."`
+	Path        string `long:"path" description:"The BIP32 derivation path to derive. Must start with \"m/\"."`
+	ShowPrivate bool   `long:"showprivate" description:"Also output the private key and the extended private key. Only the public keys and addresses are shown otherwise."`
 }
 
 func (c *deriveKeyCommand) Execute(_ []string) error {
@@ -33,21 +33,40 @@ func (c *deriveKeyCommand) Execute(_ []string) error {
 		return fmt.Errorf("error reading root key: %v", err)
 	}
 
-	return deriveKey(extendedKey, c.Path, c.Neuter)
+	return deriveKey(extendedKey, c.Path, c.ShowPrivate)
 }
 
 func deriveKey(extendedKey *hdkeychain.ExtendedKey, path string,
-	neuter bool) error {
+	showPrivate bool) error {
 
 	fmt.Printf("Deriving path %s for network %s.\n", path, chainParams.Name)
-	pubKey, wif, err := lnd.DeriveKey(extendedKey, path, chainParams)
+	parsedPath, err := lnd.ParsePath(path)
+	if err != nil {
+		return fmt.Errorf("could not parse derivation path: %v", err)
+	}
+	derivedKey, err := lnd.DeriveChildren(extendedKey, parsedPath)
+	if err != nil {
+		return fmt.Errorf("could not derive children: %v", err)
+	}
+	key, err := newDerivedKey(derivedKey, path, 0, 0, !showPrivate)
 	if err != nil {
 		return fmt.Errorf("could not derive keys: %v", err)
 	}
-	fmt.Printf("Public key: %x\n", pubKey.SerializeCompressed())
+	pubExtendedKey, err := derivedKey.Neuter()
+	if err != nil {
+		return fmt.Errorf("could not neuter key: %v", err)
+	}
+
+	fmt.Printf("Public key: %s\n", key.PubKeyHex)
+	fmt.Printf("Extended public key: %s\n", pubExtendedKey.String())
+	fmt.Printf("Address P2PKH: %s\n", key.AddrP2PKH)
+	fmt.Printf("Address NP2WKH: %s\n", key.AddrNP2WKH)
+	fmt.Printf("Address P2WKH: %s\n", key.AddrP2WKH)
+	fmt.Printf("Address P2TR: %s\n", key.AddrP2TR)
 
-	if !neuter {
-		fmt.Printf("Private key (WIF): %s\n", wif.String())
+	if showPrivate {
+		fmt.Printf("Private key (WIF): %s\n", key.WIF)
+		fmt.Printf("Extended private key: %s\n", derivedKey.String())
 	}
 
 	return nil
@@ -0,0 +1,347 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"strings"
+	"syscall"
+
+	"github.com/btcsuite/btcutil/hdkeychain"
+	"github.com/guggero/chantools/lnd"
+	"github.com/lightningnetwork/lnd/channeldb"
+)
+
+const (
+	resultsDir = "results"
+
+	// minRecoveryWindow is the recovery window below which we warn that
+	// addresses might be missed.
+	minRecoveryWindow = 100
+
+	// minFreeDiskSpace is the free disk space in bytes below which we warn
+	// that writing the results might fail.
+	minFreeDiskSpace = 100 * 1024 * 1024
+)
+
+type checkStatus string
+
+const (
+	statusPass checkStatus = "PASS"
+	statusWarn checkStatus = "WARN"
+	statusFail checkStatus = "FAIL"
+)
+
+// checkResult is the outcome of a single diagnostic check.
+type checkResult struct {
+	name    string
+	status  checkStatus
+	message string
+	fix     string
+}
+
+type diagnoseCommand struct {
+	RootKey        string `long:"rootkey" description:"BIP32 HD root key to check against the selected network. Also used to verify the channel backup file."`
+	ChannelDB      string `long:"channeldb" description:"The lnd channel.db file to check."`
+	MultiFile      string `long:"multi_file" description:"The lnd channel.backup file to check."`
+	RecoveryWindow uint32 `long:"recoverywindow" description:"The recovery window that should be used for the recovery."`
+	RescanFrom     uint32 `long:"rescanfrom" description:"The block number the rescan should start from."`
+}
+
+func (c *diagnoseCommand) Execute(_ []string) error {
+	setupChainParams(cfg)
+
+	var (
+		results     []*checkResult
+		extendedKey *hdkeychain.ExtendedKey
+	)
+	if c.RootKey != "" {
+		var result *checkResult
+		extendedKey, result = checkRootKey(c.RootKey)
+		results = append(results, result)
+	}
+	if c.RecoveryWindow != 0 {
+		results = append(results, checkRecoveryWindow(c.RecoveryWindow))
+	}
+
+	bitcoindResults, height := checkBitcoind()
+	results = append(results, bitcoindResults...)
+	if c.RescanFrom != 0 {
+		results = append(results, checkRescanFrom(c.RescanFrom, height))
+	}
+
+	if c.ChannelDB != "" {
+		results = append(results, checkChannelDB(c.ChannelDB))
+	}
+	if c.MultiFile != "" {
+		results = append(results, checkMultiFile(
+			c.MultiFile, extendedKey,
+		))
+	}
+	results = append(results, checkDiskSpace())
+
+	return printCheckResults(results)
+}
+
+// printCheckResults prints the status of all checks and returns an error if
+// at least one of them failed.
+func printCheckResults(results []*checkResult) error {
+	numFailed := 0
+	for _, result := range results {
+		fmt.Printf("[%s] %s: %s\n", result.status, result.name,
+			result.message)
+		if result.status != statusPass && result.fix != "" {
+			fmt.Printf("       Fix: %s\n", result.fix)
+		}
+		if result.status == statusFail {
+			numFailed++
+		}
+	}
+	if numFailed > 0 {
+		return fmt.Errorf("%d of %d checks failed", numFailed,
+			len(results))
+	}
+	return nil
+}
+
+// checkRootKey makes sure the version bytes of the root key match the network
+// that was selected with the global flags.
+func checkRootKey(rootKey string) (*hdkeychain.ExtendedKey, *checkResult) {
+	result := &checkResult{name: "Root key network"}
+	extendedKey, err := hdkeychain.NewKeyFromString(rootKey)
+	if err != nil {
+		result.status = statusFail
+		result.message = fmt.Sprintf("cannot parse root key: %v", err)
+		result.fix = "make sure the root key is a valid extended " +
+			"private key (xprv/tprv)"
+		return nil, result
+	}
+
+	if extendedKey.IsForNet(chainParams) {
+		result.status = statusPass
+		result.message = fmt.Sprintf("root key is valid for network %s",
+			chainParams.Name)
+		return extendedKey, result
+	}
+
+	result.status = statusFail
+	result.message = fmt.Sprintf("root key is not valid for network %s",
+		chainParams.Name)
+	result.fix = "select the correct network"
+
+	// The test networks share the same key encoding, so a key can be valid
+	// for more than one of them.
+	var (
+		names []string
+		flags []string
+	)
+	for _, network := range chainNetworks {
+		if extendedKey.IsForNet(network.params) {
+			names = append(names, network.params.Name)
+			flags = append(flags, "--"+network.name)
+		}
+	}
+	if len(names) > 0 {
+		result.message = fmt.Sprintf("root key is for network %s but "+
+			"%s is selected", strings.Join(names, "/"),
+			chainParams.Name)
+		result.fix = fmt.Sprintf("use %s", strings.Join(flags, " or "))
+	}
+	return nil, result
+}
+
+func checkRecoveryWindow(recoveryWindow uint32) *checkResult {
+	result := &checkResult{
+		name:    "Recovery window",
+		status:  statusPass,
+		message: fmt.Sprintf("recovery window is %d", recoveryWindow),
+	}
+	if recoveryWindow < minRecoveryWindow {
+		result.status = statusWarn
+		result.message = fmt.Sprintf("recovery window %d is very "+
+			"small, addresses might be missed", recoveryWindow)
+		result.fix = fmt.Sprintf("use a recovery window of at least %d",
+			minRecoveryWindow)
+	}
+	return result
+}
+
+// checkBitcoind checks that the bitcoind RPC is reachable and that the wallet
+// can be used for importing keys. The current block height is returned if it
+// could be queried.
+func checkBitcoind() ([]*checkResult, uint32) {
+	bitcoind := newBitcoind(cfg)
+	rpcResult := &checkResult{name: "bitcoind RPC"}
+	height, err := bitcoind.GetBlockCount()
+	if err != nil {
+		rpcResult.status = statusFail
+		rpcResult.message = fmt.Sprintf("cannot reach bitcoind at %s: "+
+			"%v", cfg.BitcoindRPC, err)
+		rpcResult.fix = "check the --bitcoindrpc, --bitcoinduser and " +
+			"--bitcoindpass flags and that bitcoind is running " +
+			"with server=1"
+		return []*checkResult{rpcResult}, 0
+	}
+	rpcResult.status = statusPass
+	rpcResult.message = fmt.Sprintf("bitcoind at %s is at block %d",
+		cfg.BitcoindRPC, height)
+
+	walletResult := &checkResult{name: "bitcoind wallet"}
+	info, err := bitcoind.GetWalletInfo()
+	switch {
+	case err != nil:
+		walletResult.status = statusFail
+		walletResult.message = fmt.Sprintf("cannot access wallet: %v",
+			err)
+		walletResult.fix = "load or create a wallet in bitcoind and " +
+			"select it with --bitcoindwallet"
+
+	case !info.PrivateKeysEnabled:
+		walletResult.status = statusWarn
+		walletResult.message = fmt.Sprintf("wallet %s is watch-only, "+
+			"private keys cannot be imported", info.WalletName)
+		walletResult.fix = "use a wallet with private keys enabled " +
+			"or a watch-only import format"
+
+	default:
+		walletResult.status = statusPass
+		walletResult.message = fmt.Sprintf("wallet %s can import "+
+			"private keys", info.WalletName)
+	}
+	return []*checkResult{rpcResult, walletResult}, height
+}
+
+func checkRescanFrom(rescanFrom, height uint32) *checkResult {
+	result := &checkResult{name: "Rescan start"}
+	switch {
+	case height == 0:
+		result.status = statusWarn
+		result.message = "cannot check the rescan start height " +
+			"without the current block height"
+		result.fix = "make bitcoind reachable"
+
+	case rescanFrom > height:
+		result.status = statusFail
+		result.message = fmt.Sprintf("rescan start %d is after the "+
+			"current chain tip %d", rescanFrom, height)
+		result.fix = "use a block height before the wallet was created"
+
+	default:
+		result.status = statusPass
+		result.message = fmt.Sprintf("rescan starts at block %d",
+			rescanFrom)
+	}
+	return result
+}
+
+func checkChannelDB(channelDB string) *checkResult {
+	result := &checkResult{name: "Channel DB"}
+	db, err := channeldb.Open(
+		path.Dir(channelDB), channeldb.OptionSetSyncFreelist(true),
+		channeldb.OptionReadOnly(true),
+	)
+	if err != nil {
+		result.status = statusFail
+		result.message = fmt.Sprintf("cannot open channel DB: %v", err)
+		result.fix = "make sure the file exists, is readable and not " +
+			"in use by a running lnd"
+		return result
+	}
+	defer db.Close()
+
+	channels, err := db.FetchAllChannels()
+	if err != nil {
+		result.status = statusFail
+		result.message = fmt.Sprintf("cannot read channels: %v", err)
+		result.fix = "try to repair the DB with the compactdb command"
+		return result
+	}
+	result.status = statusPass
+	result.message = fmt.Sprintf("channel DB contains %d open channels",
+		len(channels))
+	return result
+}
+
+// checkMultiFile checks that the channel backup file can be decoded and, if
+// the root key is known, decrypted.
+func checkMultiFile(multiFile string,
+	extendedKey *hdkeychain.ExtendedKey) *checkResult {
+
+	result := &checkResult{name: "Channel backup"}
+	content, err := ioutil.ReadFile(cleanAndExpandPath(multiFile))
+	if err != nil {
+		result.status = statusFail
+		result.message = fmt.Sprintf("cannot read backup file: %v", err)
+		result.fix = "make sure the file exists and is readable"
+		return result
+	}
+	packed, err := lnd.DecodePackedBackup(content)
+	if err != nil {
+		result.status = statusFail
+		result.message = fmt.Sprintf("invalid backup file: %v", err)
+		result.fix = "use the decodescb command to inspect the file"
+		return result
+	}
+	if extendedKey == nil {
+		result.status = statusWarn
+		result.message = "backup file format is valid but it cannot " +
+			"be verified without a root key"
+		result.fix = "specify --rootkey to verify the backup's " +
+			"authentication tag"
+		return result
+	}
+
+	keyRing := &lnd.HDKeyRing{
+		ExtendedKey: extendedKey,
+		ChainParams: chainParams,
+	}
+	if _, err := lnd.DecryptPackedBackup(packed, keyRing); err != nil {
+		result.status = statusFail
+		result.message = "backup authentication failed, the file is " +
+			"corrupt or belongs to a different wallet"
+		result.fix = "make sure the backup and root key belong to " +
+			"the same node"
+		return result
+	}
+	result.status = statusPass
+	result.message = "backup file can be decrypted with the root key"
+	return result
+}
+
+func checkDiskSpace() *checkResult {
+	result := &checkResult{name: "Disk space"}
+	dir := resultsDir
+	if _, err := os.Stat(dir); err != nil {
+		dir = "."
+	}
+	free, err := freeDiskSpace(dir)
+	switch {
+	case err != nil:
+		result.status = statusWarn
+		result.message = fmt.Sprintf("cannot check free disk space: %v",
+			err)
+
+	case free < minFreeDiskSpace:
+		result.status = statusWarn
+		result.message = fmt.Sprintf("only %d MB free for the results",
+			free/1024/1024)
+		result.fix = "free up some disk space"
+
+	default:
+		result.status = statusPass
+		result.message = fmt.Sprintf("%d MB free for the results",
+			free/1024/1024)
+	}
+	return result
+}
+
+// freeDiskSpace returns the number of bytes available to the user in the file
+// system of the given directory.
+func freeDiskSpace(dir string) (uint64, error) {
+	var stat syscall.Statfs_t
+	if err := syscall.Statfs(dir, &stat); err != nil {
+		return 0, err
+	}
+	return stat.Bavail * uint64(stat.Bsize), nil
+}
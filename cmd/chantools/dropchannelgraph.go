@@ -0,0 +1,453 @@
+package main
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path"
+	"text/tabwriter"
+
+	"github.com/btcsuite/btcd/btcec"
+	"github.com/coreos/bbolt"
+	"github.com/lightningnetwork/lnd/channeldb"
+	"github.com/lightningnetwork/lnd/routing/route"
+)
+
+const (
+	// graphBackupSuffix is appended to the name of the channel DB file to
+	// get the name of the backup copy.
+	graphBackupSuffix = ".bak"
+)
+
+var (
+	// Bucket names from github.com/lightningnetwork/lnd/channeldb/graph.go
+	graphNodeBucket            = []byte("graph-node")
+	graphNodeUpdateIndexBucket = []byte("graph-node-update-index")
+	graphAliasIndexBucket      = []byte("alias")
+	graphEdgeBucket            = []byte("graph-edge")
+	graphEdgeIndexBucket       = []byte("edge-index")
+	graphEdgeUpdateIndexBucket = []byte("edge-update-index")
+	graphChannelPointBucket    = []byte("chan-index")
+	graphZombieBucket          = []byte("zombie-index")
+	graphDisabledPolicyBucket  = []byte("disabled-edge-policy-index")
+	graphMetaBucket            = []byte("graph-meta")
+	graphPruneLogBucket        = []byte("prune-log")
+)
+
+// graphChannel is a channel of the graph together with the policies of both
+// directions, either of which can be nil if it wasn't announced (yet).
+type graphChannel struct {
+	info    *channeldb.ChannelEdgeInfo
+	policy1 *channeldb.ChannelEdgePolicy
+	policy2 *channeldb.ChannelEdgePolicy
+}
+
+type dropChannelGraphCommand struct {
+	ChannelDB    string `long:"channeldb" description:"The lnd channel.db file to drop the channel graph from. lnd must not be running. A copy of the file is written to channel.db.bak in the same directory before anything is modified."`
+	ChannelPoint string `long:"channelpoint" description:"Only remove the channel with this channel point (txid:index) from the graph instead of dropping the whole graph."`
+	PeerPubKey   string `long:"peerpubkey" description:"Only remove all channels with the node with this identity public key from the graph instead of dropping the whole graph."`
+	ListOnly     bool   `long:"listonly" description:"Only list the channels that would be removed from the graph, don't modify the channel DB."`
+}
+
+func (c *dropChannelGraphCommand) Execute(_ []string) error {
+	// Check that we have a channel DB and at most one filter.
+	if c.ChannelDB == "" {
+		return fmt.Errorf("channel DB is required")
+	}
+	if c.ChannelPoint != "" && c.PeerPubKey != "" {
+		return fmt.Errorf("channelpoint and peerpubkey cannot be " +
+			"used at the same time")
+	}
+	dropAll := c.ChannelPoint == "" && c.PeerPubKey == ""
+
+	// The channels are looked up in read-only mode first, so nothing is
+	// written before the backup exists.
+	channels, err := c.matchingChannels()
+	if err != nil {
+		return err
+	}
+	if c.ListOnly {
+		printGraphChannels(channels)
+		return nil
+	}
+
+	// We don't want to overwrite an older backup that might be the only
+	// copy of a graph that was dropped before.
+	backupFile := c.ChannelDB + graphBackupSuffix
+	if _, err := os.Stat(backupFile); err == nil {
+		return fmt.Errorf("backup file %s already exists, move it "+
+			"somewhere else first", backupFile)
+	}
+
+	question := fmt.Sprintf("Remove %d channels from the graph in %s?",
+		len(channels), c.ChannelDB)
+	if dropAll {
+		question = fmt.Sprintf("Drop the whole channel graph with %d "+
+			"channels from %s? lnd needs to sync the full graph "+
+			"from its peers again", len(channels), c.ChannelDB)
+	} else if len(channels) == 0 {
+		return fmt.Errorf("no channels to remove found in graph")
+	}
+	err = confirmAction(question, &modificationSummary{
+		Command:    "dropchannelgraph",
+		ChannelDB:  c.ChannelDB,
+		NumRecords: len(channels),
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := copyFile(c.ChannelDB, backupFile); err != nil {
+		return fmt.Errorf("error creating backup %s: %v", backupFile,
+			err)
+	}
+	log.Infof("Wrote backup of channel DB to %s", backupFile)
+
+	db, err := channeldb.Open(
+		path.Dir(c.ChannelDB), channeldb.OptionSetSyncFreelist(true),
+		channeldb.OptionReadOnly(false),
+	)
+	if err != nil {
+		return fmt.Errorf("error opening channel DB: %v", err)
+	}
+	defer db.Close()
+
+	if dropAll {
+		if err := dropGraph(db); err != nil {
+			return fmt.Errorf("error dropping graph: %v", err)
+		}
+		log.Infof("Dropped channel graph with %d channels",
+			len(channels))
+		return nil
+	}
+
+	if err := removeGraphChannels(db, channels); err != nil {
+		return fmt.Errorf("error removing channels: %v", err)
+	}
+	numNodes, err := removeOrphanedNodes(db, channels)
+	if err != nil {
+		return fmt.Errorf("error removing nodes: %v", err)
+	}
+	log.Infof("Removed %d channels and %d nodes without any channels "+
+		"left from the graph", len(channels), numNodes)
+	return nil
+}
+
+// matchingChannels opens the channel DB in read-only mode and returns all
+// channels of the graph that match the filter of the command. Without a filter
+// all channels of the graph are returned.
+func (c *dropChannelGraphCommand) matchingChannels() ([]*graphChannel,
+	error) {
+
+	db, err := channeldb.Open(
+		path.Dir(c.ChannelDB), channeldb.OptionSetSyncFreelist(true),
+		channeldb.OptionReadOnly(true),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("error opening channel DB: %v", err)
+	}
+	defer db.Close()
+
+	var (
+		graph    = db.ChannelGraph()
+		channels []*graphChannel
+	)
+	switch {
+	case c.ChannelPoint != "":
+		outPoint, err := parseOutPoint(c.ChannelPoint)
+		if err != nil {
+			return nil, fmt.Errorf("error parsing channel point: "+
+				"%v", err)
+		}
+		info, p1, p2, err := graph.FetchChannelEdgesByOutpoint(outPoint)
+		switch {
+		case err == channeldb.ErrEdgeNotFound ||
+			err == channeldb.ErrGraphNoEdgesFound ||
+			err == channeldb.ErrGraphNotFound:
+
+			return nil, fmt.Errorf("channel point %v not found in "+
+				"graph", outPoint)
+
+		case err != nil:
+			return nil, fmt.Errorf("error fetching channel: %v",
+				err)
+		}
+		channels = append(channels, &graphChannel{
+			info:    info,
+			policy1: p1,
+			policy2: p2,
+		})
+
+	case c.PeerPubKey != "":
+		pubKeyBytes, err := hex.DecodeString(c.PeerPubKey)
+		if err != nil {
+			return nil, fmt.Errorf("error decoding peer pubkey: %v",
+				err)
+		}
+		_, err = btcec.ParsePubKey(pubKeyBytes, btcec.S256())
+		if err != nil {
+			return nil, fmt.Errorf("error parsing peer pubkey: %v",
+				err)
+		}
+		err = graph.ForEachNodeChannel(nil, pubKeyBytes, func(
+			_ *bbolt.Tx, info *channeldb.ChannelEdgeInfo, policy1,
+			policy2 *channeldb.ChannelEdgePolicy) error {
+
+			channels = append(channels, &graphChannel{
+				info:    info,
+				policy1: policy1,
+				policy2: policy2,
+			})
+			return nil
+		})
+		if err != nil && err != channeldb.ErrGraphNotFound {
+			return nil, fmt.Errorf("error fetching channels: %v",
+				err)
+		}
+		if len(channels) == 0 {
+			return nil, fmt.Errorf("no channels with peer %s "+
+				"found in graph", c.PeerPubKey)
+		}
+
+	default:
+		err := graph.ForEachChannel(func(
+			info *channeldb.ChannelEdgeInfo,
+			policy1, policy2 *channeldb.ChannelEdgePolicy) error {
+
+			channels = append(channels, &graphChannel{
+				info:    info,
+				policy1: policy1,
+				policy2: policy2,
+			})
+			return nil
+		})
+		if err != nil && err != channeldb.ErrGraphNoEdgesFound {
+			return nil, fmt.Errorf("error fetching channels: %v",
+				err)
+		}
+	}
+	return channels, nil
+}
+
+// printGraphChannels prints the given channels as a human readable table.
+func printGraphChannels(channels []*graphChannel) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "CHANNEL POINT\tCHANNEL ID\tNODE 1\tNODE 2\tCAPACITY")
+	for _, channel := range channels {
+		info := channel.info
+		fmt.Fprintf(w, "%v\t%d\t%x\t%x\t%d\n", info.ChannelPoint,
+			info.ChannelID, info.NodeKey1Bytes[:],
+			info.NodeKey2Bytes[:], info.Capacity)
+	}
+	_ = w.Flush()
+	fmt.Printf("\n%d matching channels in graph.\n", len(channels))
+}
+
+// dropGraph deletes all buckets of the channel graph and creates them again
+// empty, the same way lnd creates them for a new channel DB. The source node is
+// set again by lnd on the next start.
+func dropGraph(db *channeldb.DB) error {
+	return db.Update(func(tx *bbolt.Tx) error {
+		topLevelBuckets := [][]byte{
+			graphNodeBucket, graphEdgeBucket, graphMetaBucket,
+		}
+		for _, bucket := range topLevelBuckets {
+			err := tx.DeleteBucket(bucket)
+			if err != nil && err != bbolt.ErrBucketNotFound {
+				return err
+			}
+		}
+
+		nodes, err := tx.CreateBucket(graphNodeBucket)
+		if err != nil {
+			return err
+		}
+		subBuckets := [][]byte{
+			graphAliasIndexBucket, graphNodeUpdateIndexBucket,
+		}
+		for _, bucket := range subBuckets {
+			if _, err := nodes.CreateBucket(bucket); err != nil {
+				return err
+			}
+		}
+
+		edges, err := tx.CreateBucket(graphEdgeBucket)
+		if err != nil {
+			return err
+		}
+		subBuckets = [][]byte{
+			graphEdgeIndexBucket, graphEdgeUpdateIndexBucket,
+			graphChannelPointBucket, graphZombieBucket,
+		}
+		for _, bucket := range subBuckets {
+			if _, err := edges.CreateBucket(bucket); err != nil {
+				return err
+			}
+		}
+
+		graphMeta, err := tx.CreateBucket(graphMetaBucket)
+		if err != nil {
+			return err
+		}
+		_, err = graphMeta.CreateBucket(graphPruneLogBucket)
+		return err
+	})
+}
+
+// removeGraphChannels removes the edges and all index entries of the given
+// channels from the graph in a single transaction. This is what lnd does when
+// a channel is closed, except that we don't add the channels to the zombie
+// index. That way lnd accepts the channels again if they are still announced
+// by their nodes.
+func removeGraphChannels(db *channeldb.DB, channels []*graphChannel) error {
+	return db.Update(func(tx *bbolt.Tx) error {
+		edges := tx.Bucket(graphEdgeBucket)
+		if edges == nil {
+			return channeldb.ErrGraphNoEdgesFound
+		}
+		edgeIndex := edges.Bucket(graphEdgeIndexBucket)
+		chanIndex := edges.Bucket(graphChannelPointBucket)
+		if edgeIndex == nil || chanIndex == nil {
+			return channeldb.ErrGraphNoEdgesFound
+		}
+		updateIndex := edges.Bucket(graphEdgeUpdateIndexBucket)
+		disabledIndex := edges.Bucket(graphDisabledPolicyBucket)
+
+		for _, channel := range channels {
+			info := channel.info
+			var chanID [8]byte
+			binary.BigEndian.PutUint64(chanID[:], info.ChannelID)
+
+			// The edge policies are stored under the key
+			// nodePubKey || chanID for both directions.
+			var edgeKey [33 + 8]byte
+			copy(edgeKey[33:], chanID[:])
+			nodeKeys := [][33]byte{
+				info.NodeKey1Bytes, info.NodeKey2Bytes,
+			}
+			for _, nodeKey := range nodeKeys {
+				copy(edgeKey[:33], nodeKey[:])
+				if err := edges.Delete(edgeKey[:]); err != nil {
+					return err
+				}
+			}
+
+			// The update index has an entry updateTime || chanID
+			// for every policy that was ever stored.
+			policies := []*channeldb.ChannelEdgePolicy{
+				channel.policy1, channel.policy2,
+			}
+			for _, policy := range policies {
+				if policy == nil || updateIndex == nil {
+					continue
+				}
+				var indexKey [8 + 8]byte
+				updateUnix := uint64(policy.LastUpdate.Unix())
+				binary.BigEndian.PutUint64(
+					indexKey[:8], updateUnix,
+				)
+				copy(indexKey[8:], chanID[:])
+				err := updateIndex.Delete(indexKey[:])
+				if err != nil {
+					return err
+				}
+			}
+
+			// Disabled policies are indexed by chanID || direction.
+			if disabledIndex != nil {
+				var disabledKey [8 + 1]byte
+				copy(disabledKey[:], chanID[:])
+				for _, direction := range []byte{0, 1} {
+					disabledKey[8] = direction
+					err := disabledIndex.Delete(
+						disabledKey[:],
+					)
+					if err != nil {
+						return err
+					}
+				}
+			}
+
+			if err := edgeIndex.Delete(chanID[:]); err != nil {
+				return err
+			}
+			var outPointKey [32 + 4]byte
+			copy(outPointKey[:32], info.ChannelPoint.Hash[:])
+			binary.BigEndian.PutUint32(
+				outPointKey[32:], info.ChannelPoint.Index,
+			)
+			if err := chanIndex.Delete(outPointKey[:]); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// removeOrphanedNodes removes the nodes of the given channels from the graph
+// that don't have any channels left. Our own node is never removed. The number
+// of removed nodes is returned.
+func removeOrphanedNodes(db *channeldb.DB, channels []*graphChannel) (int,
+	error) {
+
+	graph := db.ChannelGraph()
+	var sourceKey route.Vertex
+	sourceNode, err := graph.SourceNode()
+	switch {
+	case err == nil:
+		sourceKey = sourceNode.PubKeyBytes
+
+	case err != channeldb.ErrSourceNodeNotSet &&
+		err != channeldb.ErrGraphNotFound:
+
+		return 0, fmt.Errorf("error fetching source node: %v", err)
+	}
+
+	var (
+		numRemoved int
+		visited    = make(map[route.Vertex]bool)
+	)
+	for _, channel := range channels {
+		nodeKeys := []route.Vertex{
+			channel.info.NodeKey1Bytes, channel.info.NodeKey2Bytes,
+		}
+		for _, nodeKey := range nodeKeys {
+			if visited[nodeKey] || nodeKey == sourceKey {
+				continue
+			}
+			visited[nodeKey] = true
+
+			numChannels := 0
+			err := graph.ForEachNodeChannel(
+				nil, nodeKey[:], func(*bbolt.Tx,
+					*channeldb.ChannelEdgeInfo,
+					*channeldb.ChannelEdgePolicy,
+					*channeldb.ChannelEdgePolicy) error {
+
+					numChannels++
+					return nil
+				},
+			)
+			if err != nil {
+				return numRemoved, err
+			}
+			if numChannels > 0 {
+				continue
+			}
+
+			// A node that never sent an announcement isn't stored
+			// in the graph, only its channels are.
+			err = graph.DeleteLightningNode(nodeKey)
+			switch {
+			case err == channeldb.ErrGraphNodeNotFound:
+				continue
+
+			case err != nil:
+				return numRemoved, err
+			}
+			numRemoved++
+		}
+	}
+	return numRemoved, nil
+}
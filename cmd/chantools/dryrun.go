@@ -0,0 +1,105 @@
+package main
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/btcsuite/btcd/blockchain"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/btcsuite/btcutil"
+	"github.com/guggero/chantools/btc"
+)
+
+// targetBlockTime is the average time between two blocks.
+const targetBlockTime = 10 * time.Minute
+
+// printDryRun prints a fully signed transaction that isn't published because
+// of the dryrun flag, together with a summary of its inputs, outputs and fee.
+// The input values are in the order of the transaction inputs and can be nil
+// if they aren't known, the fee can't be calculated then.
+func printDryRun(rawTxHex string, inputValues []int64) error {
+	rawTx, err := hex.DecodeString(rawTxHex)
+	if err != nil {
+		return fmt.Errorf("error decoding tx hex: %v", err)
+	}
+	tx := &wire.MsgTx{}
+	if err := tx.Deserialize(bytes.NewReader(rawTx)); err != nil {
+		return fmt.Errorf("error parsing tx: %v", err)
+	}
+	if inputValues != nil && len(inputValues) != len(tx.TxIn) {
+		return fmt.Errorf("got %d input values for %d inputs",
+			len(inputValues), len(tx.TxIn))
+	}
+
+	fmt.Printf("Dry run, not publishing transaction %s\n", tx.TxHash())
+	fmt.Println("Inputs:")
+	totalIn := int64(0)
+	for idx, in := range tx.TxIn {
+		if inputValues == nil {
+			fmt.Printf("  %d: %v\n", idx, in.PreviousOutPoint)
+			continue
+		}
+		fmt.Printf("  %d: %v (%d sats)\n", idx, in.PreviousOutPoint,
+			inputValues[idx])
+		totalIn += inputValues[idx]
+	}
+	fmt.Println("Outputs:")
+	totalOut := int64(0)
+	for idx, out := range tx.TxOut {
+		addr := pkScriptAddress(out.PkScript)
+		if addr == "" {
+			addr = fmt.Sprintf("script %x", out.PkScript)
+		}
+		fmt.Printf("  %d: %s (%d sats)\n", idx, addr, out.Value)
+		totalOut += out.Value
+	}
+
+	weight := blockchain.GetTransactionWeight(btcutil.NewTx(tx))
+	vSize := (weight + blockchain.WitnessScaleFactor - 1) /
+		blockchain.WitnessScaleFactor
+	fmt.Printf("Virtual size: %d vbytes\n", vSize)
+	if inputValues == nil {
+		fmt.Println("Fee: unknown, the input values are not known")
+	} else {
+		fee := totalIn - totalOut
+		feeRate := float64(fee) / float64(vSize)
+		fmt.Printf("Fee: %d sats (%.2f sat/vbyte)\n", fee, feeRate)
+		fmt.Printf("Estimated confirmation: %s\n",
+			estimateConfirmation(feeRate))
+	}
+	fmt.Printf("Transaction: %s\n", rawTxHex)
+	return nil
+}
+
+// estimateConfirmation describes how long it takes to confirm a transaction
+// with the given fee rate, based on the current fee estimates of the API.
+func estimateConfirmation(feeRate float64) string {
+	api := &btc.ExplorerAPI{BaseURL: cfg.APIURL}
+	estimates, err := api.FeeEstimates()
+	if err != nil {
+		return fmt.Sprintf("unknown, error fetching fee estimates: %v",
+			err)
+	}
+
+	targets := make([]uint32, 0, len(estimates))
+	for target := range estimates {
+		targets = append(targets, target)
+	}
+	sort.Slice(targets, func(i, j int) bool {
+		return targets[i] < targets[j]
+	})
+	for _, target := range targets {
+		if feeRate >= estimates[target] {
+			return fmt.Sprintf("within %d blocks (about %v)",
+				target, time.Duration(target)*targetBlockTime)
+		}
+	}
+	if len(targets) == 0 {
+		return "unknown, no fee estimates available"
+	}
+	return fmt.Sprintf("more than %d blocks, the fee rate is below the "+
+		"current estimates", targets[len(targets)-1])
+}
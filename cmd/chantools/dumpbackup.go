@@ -2,6 +2,8 @@ package main
 
 import (
 	"fmt"
+	"io/ioutil"
+	"os"
 
 	"github.com/btcsuite/btcutil/hdkeychain"
 	"github.com/davecgh/go-spew/spew"
@@ -12,8 +14,9 @@ import (
 )
 
 type dumpBackupCommand struct {
-	RootKey   string `long:"rootkey" description:"BIP32 HD root key of the wallet that was used to create the backup. Leave empty to prompt for lnd 24 word aezeed."`
-	MultiFile string `long:"multi_file" description:"The lnd channel.backup file to dump."`
+	RootKey     string `long:"rootkey" description:"BIP32 HD root key of the wallet that was used to create the backup. Leave empty to prompt for lnd 24 word aezeed."`
+	MultiFile   string `long:"multi_file" description:"The lnd channel.backup file to dump."`
+	LncliBackup string `long:"lnclibackup" description:"The JSON output of lncli exportchanbackup to dump instead of a channel.backup file. Use - to read it from stdin, the rootkey flag is required then."`
 }
 
 func (c *dumpBackupCommand) Execute(_ []string) error {
@@ -24,6 +27,13 @@ func (c *dumpBackupCommand) Execute(_ []string) error {
 		err         error
 	)
 
+	// The root key can't be entered on the console if the backup is read
+	// from stdin.
+	if c.LncliBackup == "-" && c.RootKey == "" {
+		return fmt.Errorf("rootkey is required to read the lncli " +
+			"backup from stdin")
+	}
+
 	// Check that root key is valid or fall back to console input.
 	switch {
 	case c.RootKey != "":
@@ -36,15 +46,24 @@ func (c *dumpBackupCommand) Execute(_ []string) error {
 		return fmt.Errorf("error reading root key: %v", err)
 	}
 
+	keyRing := &lnd.HDKeyRing{
+		ExtendedKey: extendedKey,
+		ChainParams: chainParams,
+	}
+	if c.LncliBackup != "" {
+		multi, err := readLncliBackup(c.LncliBackup, keyRing)
+		if err != nil {
+			return err
+		}
+		dumpMulti(multi)
+		return nil
+	}
+
 	// Check that we have a backup file.
 	if c.MultiFile == "" {
 		return fmt.Errorf("backup file is required")
 	}
 	multiFile := chanbackup.NewMultiFile(c.MultiFile)
-	keyRing := &lnd.HDKeyRing{
-		ExtendedKey: extendedKey,
-		ChainParams: chainParams,
-	}
 	return dumpChannelBackup(multiFile, keyRing)
 }
 
@@ -55,9 +74,40 @@ func dumpChannelBackup(multiFile *chanbackup.MultiFile,
 	if err != nil {
 		return fmt.Errorf("could not extract multi file: %v", err)
 	}
+	dumpMulti(multi)
+	return nil
+}
+
+func dumpMulti(multi *chanbackup.Multi) {
 	spew.Dump(dump.BackupMulti{
 		Version:       multi.Version,
 		StaticBackups: dump.BackupDump(multi, chainParams),
 	})
-	return nil
+}
+
+// readLncliBackup reads and decrypts the JSON output of lncli exportchanbackup
+// from a file or from stdin if the file name is "-".
+func readLncliBackup(fileName string, ring keychain.KeyRing) (*chanbackup.Multi,
+	error) {
+
+	var (
+		content []byte
+		err     error
+	)
+	switch {
+	case fileName == "-":
+		content, err = ioutil.ReadAll(os.Stdin)
+
+	default:
+		content, err = ioutil.ReadFile(fileName)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading lncli backup: %v", err)
+	}
+
+	multi, err := lnd.ParseLncliBackup(content, ring)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing lncli backup: %v", err)
+	}
+	return multi, nil
 }
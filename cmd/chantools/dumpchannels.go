@@ -1,16 +1,34 @@
 package main
 
 import (
+	"encoding/json"
 	"fmt"
 	"path"
 
 	"github.com/davecgh/go-spew/spew"
+	"github.com/guggero/chantools/btc"
 	"github.com/guggero/chantools/dump"
 	"github.com/lightningnetwork/lnd/channeldb"
 )
 
+const (
+	// urgentHtlcBlocks is the number of blocks before their expiry within
+	// which HTLCs are considered urgent.
+	urgentHtlcBlocks = 144
+)
+
 type dumpChannelsCommand struct {
-	ChannelDB string `long:"channeldb" description:"The lnd channel.db file to dump the channels from."`
+	ChannelDB    string `long:"channeldb" description:"The lnd channel.db file to dump the channels from."`
+	JSON         bool   `long:"json" description:"Dump the channels as JSON, for example to use them as the input of the summarize command."`
+	IncludeHtlcs bool   `long:"includehtlcs" description:"Add the HTLCs that are in flight in each channel and mark the channels with HTLCs that expire within 144 blocks as urgent."`
+	Height       uint32 `long:"height" description:"The current block height to calculate the expiry of the HTLCs. Leave empty to query it from the chain API."`
+}
+
+// channelWithHtlcs is a dumped channel with the HTLCs that are in flight in it.
+type channelWithHtlcs struct {
+	dump.OpenChannel
+	Htlcs  []dump.HTLC `json:"htlcs"`
+	Urgent bool        `json:"urgent"`
 }
 
 func (c *dumpChannelsCommand) Execute(_ []string) error {
@@ -27,10 +45,26 @@ func (c *dumpChannelsCommand) Execute(_ []string) error {
 	if err != nil {
 		return fmt.Errorf("error opening rescue DB: %v", err)
 	}
-	return dumpChannelInfo(db)
+	if !c.IncludeHtlcs {
+		return dumpChannelInfo(db, c.JSON)
+	}
+
+	if c.Height == 0 {
+		api := &btc.ExplorerAPI{
+			BaseURL: cfg.APIURL,
+			Timeout: cfg.APITimeout,
+			Retries: cfg.APIRetries,
+		}
+		c.Height, err = api.TipHeight()
+		if err != nil {
+			return fmt.Errorf("error querying block height: %v",
+				err)
+		}
+	}
+	return dumpChannelHtlcs(db, c.JSON, c.Height)
 }
 
-func dumpChannelInfo(chanDb *channeldb.DB) error {
+func dumpChannelInfo(chanDb *channeldb.DB, asJSON bool) error {
 	channels, err := chanDb.FetchAllChannels()
 	if err != nil {
 		return err
@@ -40,7 +74,57 @@ func dumpChannelInfo(chanDb *channeldb.DB) error {
 	if err != nil {
 		return fmt.Errorf("error converting to dump format: %v", err)
 	}
+	return printChannels(dumpChannels, asJSON)
+}
 
+// dumpChannelHtlcs dumps all channels together with the HTLCs that are in
+// flight in them. A channel is marked as urgent if any of its HTLCs expires
+// within urgentHtlcBlocks of the given block height.
+func dumpChannelHtlcs(chanDb *channeldb.DB, asJSON bool, height uint32) error {
+	channels, err := chanDb.FetchAllChannels()
+	if err != nil {
+		return err
+	}
+
+	dumpChannels, err := dump.ChannelDump(channels, chainParams)
+	if err != nil {
+		return fmt.Errorf("error converting to dump format: %v", err)
+	}
+	htlcChannels := make([]channelWithHtlcs, len(channels))
+	for idx, channel := range channels {
+		htlcs := dump.HtlcDump(channel, height)
+		if htlcs == nil {
+			htlcs = []dump.HTLC{}
+		}
+		htlcChannels[idx] = channelWithHtlcs{
+			OpenChannel: dumpChannels[idx],
+			Htlcs:       htlcs,
+		}
+		for _, htlc := range htlcs {
+			if htlc.BlocksUntilExpiry <= urgentHtlcBlocks {
+				htlcChannels[idx].Urgent = true
+			}
+		}
+		if htlcChannels[idx].Urgent {
+			log.Warnf("Channel %s has HTLCs that expire within %d "+
+				"blocks", dumpChannels[idx].FundingOutpoint,
+				urgentHtlcBlocks)
+		}
+	}
+	return printChannels(htlcChannels, asJSON)
+}
+
+// printChannels prints the dumped channels either as JSON or in a human
+// readable format.
+func printChannels(dumpChannels interface{}, asJSON bool) error {
+	if asJSON {
+		channelBytes, err := json.MarshalIndent(dumpChannels, "", " ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(channelBytes))
+		return nil
+	}
 	spew.Dump(dumpChannels)
 	return nil
 }
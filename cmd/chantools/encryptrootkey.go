@@ -0,0 +1,184 @@
+package main
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+
+	"github.com/btcsuite/btcutil/hdkeychain"
+)
+
+// rootKeyFileVersion is the version of the format of the encrypted root key
+// file.
+const rootKeyFileVersion = 1
+
+// encryptedRootKey is the file format of a root key that is encrypted with
+// AES-256-GCM. The key is derived from the passphrase with Argon2id, using the
+// same parameters as the encrypted key export.
+type encryptedRootKey struct {
+	Version    int    `json:"version"`
+	Salt       string `json:"salt"`
+	Nonce      string `json:"nonce"`
+	Ciphertext string `json:"ciphertext"`
+}
+
+type encryptRootKeyCommand struct {
+	RootKey    string `long:"rootkey" description:"BIP32 HD root key to encrypt. Leave empty to prompt for lnd 24 word aezeed."`
+	Passphrase string `long:"passphrase" description:"The passphrase to encrypt the root key with. Leave empty to prompt for it."`
+	OutputFile string `long:"outputfile" description:"Write the encrypted root key to this file instead of stdout."`
+	Overwrite  bool   `long:"overwrite" description:"Overwrite the output file if it already exists."`
+}
+
+func (c *encryptRootKeyCommand) Execute(_ []string) error {
+	setupChainParams(cfg)
+
+	var (
+		extendedKey *hdkeychain.ExtendedKey
+		err         error
+	)
+
+	// Check that root key is valid or fall back to console input.
+	switch {
+	case c.RootKey != "":
+		extendedKey, err = hdkeychain.NewKeyFromString(c.RootKey)
+
+	default:
+		extendedKey, _, err = rootKeyFromConsole()
+	}
+	if err != nil {
+		return fmt.Errorf("error reading root key: %v", err)
+	}
+	if !extendedKey.IsPrivate() {
+		return fmt.Errorf("root key must be an extended private key")
+	}
+
+	passphrase := []byte(c.Passphrase)
+	if len(passphrase) == 0 {
+		passphrase, err = exportPassphraseFromConsole()
+		if err != nil {
+			return fmt.Errorf("error reading passphrase: %v", err)
+		}
+	}
+
+	encrypted, err := encryptRootKeyFile(extendedKey, passphrase)
+	if err != nil {
+		return fmt.Errorf("error encrypting root key: %v", err)
+	}
+	content, err := json.Marshal(encrypted)
+	if err != nil {
+		return err
+	}
+
+	if c.OutputFile == "" {
+		fmt.Println(string(content))
+		return nil
+	}
+	f, err := createOutputFile(c.OutputFile, c.Overwrite)
+	if err != nil {
+		return err
+	}
+	if _, err := fmt.Fprintln(f, string(content)); err != nil {
+		_ = f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	fmt.Printf("Wrote the encrypted root key to %s\n", c.OutputFile)
+	return nil
+}
+
+// encryptRootKeyFile encrypts the serialized extended private key with a key
+// that is derived from the passphrase and a random salt.
+func encryptRootKeyFile(rootKey *hdkeychain.ExtendedKey,
+	passphrase []byte) (*encryptedRootKey, error) {
+
+	var salt [exportSaltSize]byte
+	if _, err := rand.Read(salt[:]); err != nil {
+		return nil, err
+	}
+	gcm, err := exportCipher(passphrase, salt[:])
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+
+	return &encryptedRootKey{
+		Version: rootKeyFileVersion,
+		Salt:    hex.EncodeToString(salt[:]),
+		Nonce:   hex.EncodeToString(nonce),
+		Ciphertext: hex.EncodeToString(
+			gcm.Seal(nil, nonce, []byte(rootKey.String()), nil),
+		),
+	}, nil
+}
+
+// decryptRootKeyFile decrypts an encrypted root key with the passphrase.
+func decryptRootKeyFile(encrypted *encryptedRootKey,
+	passphrase []byte) (*hdkeychain.ExtendedKey, error) {
+
+	if encrypted.Version != rootKeyFileVersion {
+		return nil, fmt.Errorf("unsupported version %d",
+			encrypted.Version)
+	}
+	salt, err := hex.DecodeString(encrypted.Salt)
+	if err != nil {
+		return nil, fmt.Errorf("error decoding salt: %v", err)
+	}
+	nonce, err := hex.DecodeString(encrypted.Nonce)
+	if err != nil {
+		return nil, fmt.Errorf("error decoding nonce: %v", err)
+	}
+	ciphertext, err := hex.DecodeString(encrypted.Ciphertext)
+	if err != nil {
+		return nil, fmt.Errorf("error decoding ciphertext: %v", err)
+	}
+
+	gcm, err := exportCipher(passphrase, salt)
+	if err != nil {
+		return nil, err
+	}
+	if len(nonce) != gcm.NonceSize() {
+		return nil, fmt.Errorf("invalid nonce size %d", len(nonce))
+	}
+	plaintext, err := gcm.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
+		return nil, fmt.Errorf("wrong passphrase or corrupted file")
+	}
+	return hdkeychain.NewKeyFromString(string(plaintext))
+}
+
+// rootKeyFromEncryptedFile reads the encrypted root key file and asks for the
+// passphrase to decrypt it.
+func rootKeyFromEncryptedFile(fileName string) (*hdkeychain.ExtendedKey,
+	error) {
+
+	content, err := ioutil.ReadFile(fileName)
+	if err != nil {
+		return nil, fmt.Errorf("error reading encrypted root key "+
+			"file: %v", err)
+	}
+	encrypted := &encryptedRootKey{}
+	if err := json.Unmarshal(content, encrypted); err != nil {
+		return nil, fmt.Errorf("error parsing encrypted root key "+
+			"file: %v", err)
+	}
+
+	passphrase, err := passwordFromConsole(fmt.Sprintf("Input passphrase "+
+		"of %s: ", fileName))
+	if err != nil {
+		return nil, err
+	}
+	passphrase = bytes.TrimRight(passphrase, "\r\n")
+	rootKey, err := decryptRootKeyFile(encrypted, passphrase)
+	if err != nil {
+		return nil, fmt.Errorf("error decrypting %s: %v", fileName, err)
+	}
+	return rootKey, nil
+}
@@ -0,0 +1,190 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"path"
+
+	"github.com/btcsuite/btcutil"
+	"github.com/btcsuite/btcutil/hdkeychain"
+	"github.com/guggero/chantools/btc"
+	"github.com/guggero/chantools/lnd"
+	"github.com/lightningnetwork/lnd/chanbackup"
+	"github.com/lightningnetwork/lnd/channeldb"
+)
+
+const (
+	defaultEstimateNumAddrs = 20
+)
+
+// balanceEstimate is the result of a quick balance estimation.
+type balanceEstimate struct {
+	ChannelBalance uint64 `json:"estimated_channel_balance_sat"`
+	OnChainBalance uint64 `json:"estimated_onchain_balance_sat"`
+	Total          uint64 `json:"total_estimated_sat"`
+}
+
+type estimateBalanceCommand struct {
+	ChannelDB      string `long:"channeldb" description:"The lnd channel.db file to read the channel balances from."`
+	MultiFile      string `long:"multi_file" description:"The lnd channel.backup file to read the channels from if no channel DB is available. A backup doesn't contain balances, so the channel capacity is used as an upper bound."`
+	RootKey        string `long:"rootkey" description:"BIP32 HD root key of the wallet. Leave empty to prompt for lnd 24 word aezeed."`
+	DerivationPath string `long:"derivationpath" description:"The first levels of the derivation path of the on-chain addresses before any internal/external branch. (default m/84'/0'/0')"`
+	NumAddrs       uint32 `long:"numaddrs" description:"The number of addresses per internal/external branch to check for an on-chain balance. (default 20)"`
+}
+
+func (c *estimateBalanceCommand) Execute(_ []string) error {
+	setupChainParams(cfg)
+
+	var (
+		extendedKey *hdkeychain.ExtendedKey
+		err         error
+	)
+
+	// Check that root key is valid or fall back to console input.
+	switch {
+	case c.RootKey != "":
+		extendedKey, err = hdkeychain.NewKeyFromString(c.RootKey)
+
+	default:
+		extendedKey, _, err = rootKeyFromConsole()
+	}
+	if err != nil {
+		return fmt.Errorf("error reading root key: %v", err)
+	}
+
+	// Set default values.
+	if c.DerivationPath == "" {
+		c.DerivationPath = defaultDerivationPath
+	}
+	if c.NumAddrs == 0 {
+		c.NumAddrs = defaultEstimateNumAddrs
+	}
+
+	estimate := &balanceEstimate{}
+	switch {
+	case c.ChannelDB != "":
+		estimate.ChannelBalance, err = channelDBBalance(c.ChannelDB)
+
+	case c.MultiFile != "":
+		estimate.ChannelBalance, err = backupBalance(
+			c.MultiFile, extendedKey,
+		)
+
+	default:
+		return fmt.Errorf("channel DB or backup file is required")
+	}
+	if err != nil {
+		return err
+	}
+
+	estimate.OnChainBalance, err = onChainBalance(
+		extendedKey, c.DerivationPath, c.NumAddrs,
+	)
+	if err != nil {
+		return err
+	}
+	estimate.Total = estimate.ChannelBalance + estimate.OnChainBalance
+
+	log.Infof("This is only a rough estimate, fees and pending HTLCs " +
+		"are not taken into account")
+	estimateBytes, err := json.MarshalIndent(estimate, "", " ")
+	if err != nil {
+		return err
+	}
+	fmt.Println(string(estimateBytes))
+	return nil
+}
+
+// channelDBBalance sums up our local balance of all channels that are not yet
+// fully closed.
+func channelDBBalance(channelDB string) (uint64, error) {
+	db, err := channeldb.Open(
+		path.Dir(channelDB), channeldb.OptionSetSyncFreelist(true),
+		channeldb.OptionReadOnly(true),
+	)
+	if err != nil {
+		return 0, fmt.Errorf("error opening channel DB: %v", err)
+	}
+	channels, err := db.FetchAllChannels()
+	if err != nil {
+		return 0, fmt.Errorf("error fetching channels: %v", err)
+	}
+
+	total := uint64(0)
+	for _, channel := range channels {
+		balance := channel.LocalCommitment.LocalBalance.ToSatoshis()
+		log.Debugf("Channel %v: local balance %d sats",
+			channel.FundingOutpoint, balance)
+		total += uint64(balance)
+	}
+	return total, nil
+}
+
+// backupBalance sums up the capacity of all channels in the backup file.
+func backupBalance(multiFile string, extendedKey *hdkeychain.ExtendedKey) (
+	uint64, error) {
+
+	keyRing := &lnd.HDKeyRing{
+		ExtendedKey: extendedKey,
+		ChainParams: chainParams,
+	}
+	multi, err := chanbackup.NewMultiFile(multiFile).ExtractMulti(keyRing)
+	if err != nil {
+		return 0, fmt.Errorf("could not extract multi file: %v", err)
+	}
+
+	log.Warnf("The channel backup doesn't contain any balances, using " +
+		"the channel capacity as an upper bound")
+	total := uint64(0)
+	for _, single := range multi.StaticBackups {
+		total += uint64(single.Capacity)
+	}
+	return total, nil
+}
+
+// onChainBalance sums up the confirmed balance of the first addresses of the
+// internal and external branch of the wallet.
+func onChainBalance(extendedKey *hdkeychain.ExtendedKey, derivationPath string,
+	numAddrs uint32) (uint64, error) {
+
+	basePath, err := lnd.ParsePath(derivationPath)
+	if err != nil {
+		return 0, fmt.Errorf("error parsing path: %v", err)
+	}
+	baseKey, err := lnd.DeriveChildren(extendedKey, basePath)
+	if err != nil {
+		return 0, fmt.Errorf("error deriving base key: %v", err)
+	}
+
+	api := &btc.ExplorerAPI{BaseURL: cfg.APIURL}
+	total := uint64(0)
+	for branch := uint32(0); branch <= 1; branch++ {
+		for i := uint32(0); i < numAddrs; i++ {
+			key, err := lnd.DeriveChildren(
+				baseKey, []uint32{branch, i},
+			)
+			if err != nil {
+				return 0, err
+			}
+			pubKey, err := key.ECPubKey()
+			if err != nil {
+				return 0, err
+			}
+			addr, err := btcutil.NewAddressWitnessPubKeyHash(
+				btcutil.Hash160(pubKey.SerializeCompressed()),
+				chainParams,
+			)
+			if err != nil {
+				return 0, fmt.Errorf("could not create "+
+					"address: %v", err)
+			}
+			info, err := api.Address(addr.EncodeAddress())
+			if err != nil {
+				return 0, fmt.Errorf("error querying address "+
+					"%s: %v", addr.EncodeAddress(), err)
+			}
+			total += info.Balance()
+		}
+	}
+	return total, nil
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/btcsuite/btcutil/hdkeychain"
+)
+
+// defaultExportPurposes are the BIP43 purposes of the account paths that are
+// exported by default: legacy, nested and native SegWit and Taproot.
+var defaultExportPurposes = []uint32{44, 49, 84, 86}
+
+type exportDescriptorsCommand struct {
+	RootKey        string `long:"rootkey" description:"BIP32 HD root key to use. Leave empty to prompt for lnd 24 word aezeed."`
+	Paths          string `long:"paths" description:"A comma separated list of the account derivation paths to export. The purpose field determines the script type of the descriptors (44: pkh, 49: sh(wpkh), 84: wpkh, 86: tr). (default m/44'/0'/0',m/49'/0'/0',m/84'/0'/0',m/86'/0'/0' with coin type 1' on test networks)"`
+	RecoveryWindow uint32 `long:"recoverywindow" description:"The range of keys Bitcoin Core should derive per internal/external branch. (default 2500)"`
+	RescanFrom     uint32 `long:"rescanfrom" description:"The block number to rescan from, used as the timestamp of the descriptors. Will be set automatically from the wallet birthday if the lnd 24 word aezeed is entered. (default 500000, 0 on signet)"`
+	WatchOnly      bool   `long:"watchonly" description:"Create watch-only descriptors with the account xpubs instead of the xprvs."`
+	OutputFile     string `long:"outputfile" description:"The file to write the descriptors to. The file is only readable by the current user. (default results/descriptors-<timestamp>.json)"`
+	Overwrite      bool   `long:"overwrite" description:"Overwrite the output file if it already exists."`
+}
+
+func (c *exportDescriptorsCommand) Execute(_ []string) error {
+	setupChainParams(cfg)
+
+	var (
+		extendedKey *hdkeychain.ExtendedKey
+		err         error
+		birthday    time.Time
+	)
+
+	// Check that root key is valid or fall back to console input.
+	switch {
+	case c.RootKey != "":
+		extendedKey, err = hdkeychain.NewKeyFromString(c.RootKey)
+
+	default:
+		extendedKey, birthday, err = rootKeyFromConsole()
+		if err == nil && birthday.IsZero() {
+			log.Warn(bip39BirthdayWarning)
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("error reading root key: %v", err)
+	}
+
+	// The btcwallet gives the birthday a slack of 48 hours, let's do the
+	// same.
+	if !birthday.IsZero() && c.RescanFrom == 0 {
+		c.RescanFrom = seedBirthdayToBlock(
+			birthday.Add(-48 * time.Hour),
+		)
+	}
+
+	// Set default values.
+	if c.RescanFrom == 0 {
+		c.RescanFrom = defaultRescanFromHeight()
+	}
+	if c.RecoveryWindow == 0 {
+		c.RecoveryWindow = defaultRecoveryWindow
+	}
+	if c.Paths == "" {
+		for idx, purpose := range defaultExportPurposes {
+			if idx > 0 {
+				c.Paths += ","
+			}
+			c.Paths += fmt.Sprintf("m/%d'/%d'/0'", purpose,
+				chainParams.HDCoinType)
+		}
+	}
+	if c.OutputFile == "" {
+		c.OutputFile = fmt.Sprintf("results/descriptors-%s.json",
+			time.Now().Format("2006-01-02-15-04-05"))
+	}
+
+	pathStrings, paths, err := parseDerivationPaths(c.Paths)
+	if err != nil {
+		return err
+	}
+	requests, err := descriptorRequests(
+		extendedKey, pathStrings, paths, c.RecoveryWindow,
+		blockToTimestamp(c.RescanFrom), c.WatchOnly,
+	)
+	if err != nil {
+		return err
+	}
+	content, err := json.MarshalIndent(requests, "", " ")
+	if err != nil {
+		return err
+	}
+
+	f, err := createOutputFile(c.OutputFile, c.Overwrite)
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(append(content, '\n'))
+	_ = f.Close()
+	if err != nil {
+		return fmt.Errorf("error writing descriptors: %v", err)
+	}
+
+	fmt.Printf("Wrote %d descriptors to %s. Import them into a descriptor "+
+		"wallet with:\nbitcoin-cli importdescriptors \"$(cat %s)\"\n",
+		len(requests), c.OutputFile, c.OutputFile)
+	return nil
+}
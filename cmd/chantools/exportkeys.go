@@ -0,0 +1,282 @@
+package main
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"syscall"
+	"time"
+
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcutil"
+	"github.com/btcsuite/btcutil/hdkeychain"
+	"github.com/guggero/chantools/lnd"
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/ssh/terminal"
+)
+
+const (
+	// exportVersion is the version of the format of the encrypted export
+	// file.
+	exportVersion = 1
+
+	// The Argon2id parameters recommended by RFC 9106 for memory
+	// constrained environments.
+	exportArgonTime    = 3
+	exportArgonMemory  = 64 * 1024
+	exportArgonThreads = 4
+	exportSaltSize     = 16
+	exportKeySize      = 32
+)
+
+// exportedKey is a single derived key of the wallet with all its addresses.
+type exportedKey struct {
+	DerivationPath string             `json:"derivation_path"`
+	Label          string             `json:"label"`
+	PrivKeyHex     string             `json:"privkey_hex"`
+	PubKeyHex      string             `json:"pubkey_hex"`
+	WIF            string             `json:"wif"`
+	Addresses      *exportedAddresses `json:"addresses"`
+}
+
+type exportedAddresses struct {
+	P2PKH  string `json:"p2pkh"`
+	NP2WKH string `json:"np2wkh"`
+	P2WKH  string `json:"p2wkh"`
+	P2TR   string `json:"p2tr"`
+}
+
+// encryptedExport is the file format of an export that is encrypted with
+// AES-256-GCM. The key is derived from the passphrase with Argon2id.
+type encryptedExport struct {
+	Version    int    `json:"version"`
+	KDF        string `json:"kdf"`
+	Salt       string `json:"salt"`
+	Time       uint32 `json:"time"`
+	Memory     uint32 `json:"memory"`
+	Threads    uint8  `json:"threads"`
+	Cipher     string `json:"cipher"`
+	Nonce      string `json:"nonce"`
+	Ciphertext string `json:"ciphertext"`
+}
+
+type exportKeysCommand struct {
+	RootKey        string `long:"rootkey" description:"BIP32 HD root key to use. Leave empty to prompt for lnd 24 word aezeed."`
+	DerivationPath string `long:"derivationpath" description:"The first levels of the derivation path before any internal/external branch. (default m/84'/0'/0')"`
+	RecoveryWindow uint32 `long:"recoverywindow" description:"The number of keys to export per internal/external branch. The output will consist of double this amount of keys. (default 2500)"`
+	LabelPrefix    string `long:"labelprefix" description:"A prefix that is added to the labels of all keys, for example to tell the keys of multiple wallets apart."`
+	Encrypt        bool   `long:"encrypt" description:"Encrypt the exported keys with AES-256-GCM. The key is derived from a passphrase that is asked for."`
+}
+
+func (c *exportKeysCommand) Execute(_ []string) error {
+	setupChainParams(cfg)
+
+	var (
+		extendedKey *hdkeychain.ExtendedKey
+		err         error
+	)
+
+	// Check that root key is valid or fall back to console input.
+	switch {
+	case c.RootKey != "":
+		extendedKey, err = hdkeychain.NewKeyFromString(c.RootKey)
+
+	default:
+		extendedKey, _, err = rootKeyFromConsole()
+	}
+	if err != nil {
+		return fmt.Errorf("error reading root key: %v", err)
+	}
+
+	// Set default values.
+	if c.RecoveryWindow == 0 {
+		c.RecoveryWindow = defaultRecoveryWindow
+	}
+	if c.DerivationPath == "" {
+		c.DerivationPath = defaultDerivationPath
+	}
+	derivationPath, err := lnd.ParsePath(c.DerivationPath)
+	if err != nil {
+		return fmt.Errorf("error parsing path: %v", err)
+	}
+
+	// Ask for the passphrase first so we don't derive all keys just to
+	// find out the passphrases don't match.
+	var passphrase []byte
+	if c.Encrypt {
+		passphrase, err = exportPassphraseFromConsole()
+		if err != nil {
+			return err
+		}
+	}
+
+	// Same order as genimportscript, the external branch first.
+	var keys []*exportedKey
+	for branch := uint32(0); branch <= 1; branch++ {
+		for i := uint32(0); i < c.RecoveryWindow; i++ {
+			path := append([]uint32{}, derivationPath...)
+			path = append(path, branch, i)
+			derivedKey, err := lnd.DeriveChildren(extendedKey, path)
+			if err != nil {
+				return err
+			}
+			key, err := newExportedKey(derivedKey, path)
+			if err != nil {
+				return err
+			}
+			key.Label = fmt.Sprintf("%s%s/%d/%d/", c.LabelPrefix,
+				c.DerivationPath, branch, i)
+			keys = append(keys, key)
+		}
+	}
+
+	content, err := json.MarshalIndent(keys, "", " ")
+	if err != nil {
+		return err
+	}
+	if c.Encrypt {
+		export, err := encryptExport(content, passphrase)
+		if err != nil {
+			return fmt.Errorf("error encrypting keys: %v", err)
+		}
+		content, err = json.MarshalIndent(export, "", " ")
+		if err != nil {
+			return err
+		}
+	}
+
+	fileName := fmt.Sprintf("results/exportkeys-%s.json",
+		time.Now().Format("2006-01-02-15-04-05"))
+	log.Infof("Writing %d keys to %s", len(keys), fileName)
+	return ioutil.WriteFile(fileName, content, 0600)
+}
+
+// exportPassphraseFromConsole asks for the passphrase of the export and makes
+// sure it is not empty. On a terminal it is asked twice to catch typos.
+func exportPassphraseFromConsole() ([]byte, error) {
+	passphrase, err := passwordFromConsole("Input export passphrase: ")
+	if err != nil {
+		return nil, err
+	}
+	passphrase = bytes.TrimRight(passphrase, "\r\n")
+	if len(passphrase) == 0 {
+		return nil, fmt.Errorf("passphrase must not be empty")
+	}
+	if !terminal.IsTerminal(syscall.Stdin) {
+		return passphrase, nil
+	}
+
+	confirmation, err := passwordFromConsole("Confirm export passphrase: ")
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.Equal(passphrase, confirmation) {
+		return nil, fmt.Errorf("passphrases don't match")
+	}
+	return passphrase, nil
+}
+
+// newExportedKey encodes a derived key with all its addresses.
+func newExportedKey(hdKey *hdkeychain.ExtendedKey,
+	path []uint32) (*exportedKey, error) {
+
+	privKey, err := hdKey.ECPrivKey()
+	if err != nil {
+		return nil, fmt.Errorf("could not derive private key: %v", err)
+	}
+	wif, err := btcutil.NewWIF(privKey, chainParams, true)
+	if err != nil {
+		return nil, fmt.Errorf("could not encode WIF: %v", err)
+	}
+	pubKey := privKey.PubKey()
+	pubKeyBytes := pubKey.SerializeCompressed()
+
+	hash160 := btcutil.Hash160(pubKeyBytes)
+	addrP2PKH, err := btcutil.NewAddressPubKeyHash(hash160, chainParams)
+	if err != nil {
+		return nil, fmt.Errorf("could not create address: %v", err)
+	}
+	addrP2WKH, err := btcutil.NewAddressWitnessPubKeyHash(
+		hash160, chainParams,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("could not create address: %v", err)
+	}
+	script, err := txscript.PayToAddrScript(addrP2WKH)
+	if err != nil {
+		return nil, fmt.Errorf("could not create script: %v", err)
+	}
+	addrNP2WKH, err := btcutil.NewAddressScriptHash(script, chainParams)
+	if err != nil {
+		return nil, fmt.Errorf("could not create address: %v", err)
+	}
+
+	addrP2TR, err := taprootAddress(pubKey)
+	if err != nil {
+		return nil, err
+	}
+
+	return &exportedKey{
+		DerivationPath: lnd.FormatPath(path),
+		PrivKeyHex:     hex.EncodeToString(privKey.Serialize()),
+		PubKeyHex:      hex.EncodeToString(pubKeyBytes),
+		WIF:            wif.String(),
+		Addresses: &exportedAddresses{
+			P2PKH:  addrP2PKH.EncodeAddress(),
+			NP2WKH: addrNP2WKH.EncodeAddress(),
+			P2WKH:  addrP2WKH.EncodeAddress(),
+			P2TR:   addrP2TR,
+		},
+	}, nil
+}
+
+// encryptExport encrypts the content with AES-256-GCM using a key that is
+// derived from the passphrase with Argon2id and a random salt.
+func encryptExport(content, passphrase []byte) (*encryptedExport, error) {
+	var salt [exportSaltSize]byte
+	if _, err := rand.Read(salt[:]); err != nil {
+		return nil, err
+	}
+	gcm, err := exportCipher(passphrase, salt[:])
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+
+	return &encryptedExport{
+		Version: exportVersion,
+		KDF:     "argon2id",
+		Salt:    hex.EncodeToString(salt[:]),
+		Time:    exportArgonTime,
+		Memory:  exportArgonMemory,
+		Threads: exportArgonThreads,
+		Cipher:  "aes-256-gcm",
+		Nonce:   hex.EncodeToString(nonce),
+		Ciphertext: base64.StdEncoding.EncodeToString(
+			gcm.Seal(nil, nonce, content, nil),
+		),
+	}, nil
+}
+
+// exportCipher derives the AES-256-GCM cipher of an export from the passphrase
+// and the salt with Argon2id.
+func exportCipher(passphrase, salt []byte) (cipher.AEAD, error) {
+	key := argon2.IDKey(
+		passphrase, salt, exportArgonTime, exportArgonMemory,
+		exportArgonThreads, exportKeySize,
+	)
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
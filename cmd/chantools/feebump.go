@@ -0,0 +1,156 @@
+package main
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"math"
+
+	"github.com/btcsuite/btcd/wire"
+	"github.com/guggero/chantools/btc"
+	"github.com/guggero/chantools/sweep"
+)
+
+const (
+	// maxRBFSequence is the highest sequence number of an input that still
+	// signals replaceability as defined in BIP125.
+	maxRBFSequence = wire.MaxTxInSequenceNum - 2
+
+	// incrementalRelayFeeRate is the fee rate in satoshis per vbyte that a
+	// replacement transaction needs to pay for its own size on top of the
+	// fee of the transaction it replaces.
+	incrementalRelayFeeRate = 1
+)
+
+// signalsRBF returns true if any of the inputs of the transaction signals
+// replaceability as defined in BIP125.
+func signalsRBF(tx *btc.TX) bool {
+	for _, in := range tx.Vin {
+		if in.Sequence <= maxRBFSequence {
+			return true
+		}
+	}
+	return false
+}
+
+// bumpSweepFee replaces an unconfirmed sweep transaction with one that pays the
+// given fee rate. The original transaction is the one that spends the first
+// input of the builder, it needs to signal RBF and must not spend any inputs
+// the builder doesn't know the keys for. The builder's single output is
+// reduced by the higher fee and all inputs are signed again. The replacement
+// is only published if publish is true and the global dryrun flag isn't set.
+func bumpSweepFee(api *btc.ExplorerAPI, builder *sweep.Builder,
+	feeRate float64, publish bool) error {
+
+	outPoints := builder.OutPoints()
+	if len(outPoints) == 0 {
+		return fmt.Errorf("no inputs to sweep")
+	}
+	firstInput := outPoints[0]
+	outspend, err := api.Outspend(
+		firstInput.Hash.String(), firstInput.Index,
+	)
+	if err != nil {
+		return fmt.Errorf("error checking input %v: %v", firstInput,
+			err)
+	}
+	if !outspend.Spent {
+		return fmt.Errorf("input %v isn't spent yet, there is no "+
+			"transaction to bump, run the command without "+
+			"--bumpfeerate instead", firstInput)
+	}
+	origTx, err := api.Transaction(outspend.Txid)
+	if err != nil {
+		return fmt.Errorf("error fetching original TX %s: %v",
+			outspend.Txid, err)
+	}
+	if origTx.Status != nil && origTx.Status.Confirmed {
+		return fmt.Errorf("original TX %s is already confirmed in "+
+			"block %d, its fee can't be bumped anymore",
+			outspend.Txid, origTx.Status.BlockHeight)
+	}
+	if !signalsRBF(origTx) {
+		return fmt.Errorf("original TX %s doesn't signal RBF "+
+			"(BIP125), its fee can't be bumped", outspend.Txid)
+	}
+
+	// We can only sign the replacement if we know the keys of all inputs
+	// of the original transaction.
+	known := make(map[wire.OutPoint]bool, len(outPoints))
+	for _, outPoint := range outPoints {
+		known[outPoint] = true
+	}
+	for idx, in := range origTx.Vin {
+		outPoint, err := parseOutPoint(fmt.Sprintf(
+			"%s:%d", in.Tixid, in.Vout,
+		))
+		if err != nil {
+			return fmt.Errorf("error parsing input %d of original "+
+				"TX: %v", idx, err)
+		}
+		if !known[*outPoint] {
+			return fmt.Errorf("original TX %s spends input %v "+
+				"that isn't part of the sweep", outspend.Txid,
+				outPoint)
+		}
+	}
+	if len(origTx.Vout) != 1 {
+		return fmt.Errorf("original TX %s has %d outputs, only sweep "+
+			"transactions with a single output can be bumped",
+			outspend.Txid, len(origTx.Vout))
+	}
+
+	builder.SetFeeRate(feeRate)
+	sweepTx, err := builder.Build()
+	if err != nil {
+		return fmt.Errorf("error creating replacement TX: %v", err)
+	}
+	sweepScript := sweepTx.TxOut[0].PkScript
+	if hex.EncodeToString(sweepScript) != origTx.Vout[0].ScriptPubkey {
+		return fmt.Errorf("original TX %s sweeps to %s instead of %s",
+			outspend.Txid, origTx.Vout[0].ScriptPubkeyAddr,
+			pkScriptAddress(sweepScript))
+	}
+
+	// A replacement needs to pay a higher fee rate and at least the fee of
+	// the original plus the relay fee for its own size.
+	origFeeRate := float64(origTx.Fee) / float64(origTx.VSize())
+	minFee := origTx.Fee + int64(math.Ceil(
+		float64(builder.VSize())*incrementalRelayFeeRate,
+	))
+	if feeRate <= origFeeRate || builder.Fee() < minFee {
+		return fmt.Errorf("fee rate %.2f sat/vByte is too low to "+
+			"replace TX %s that pays %d sats (%.2f sat/vByte), "+
+			"the replacement needs to pay at least %d sats",
+			feeRate, outspend.Txid, origTx.Fee, origFeeRate, minFee)
+	}
+
+	var buf bytes.Buffer
+	if err := sweepTx.Serialize(&buf); err != nil {
+		return err
+	}
+	log.Infof("Replacing TX %s that pays %d sats (%.2f sat/vByte) with "+
+		"TX %s that pays %d sats (%.2f sat/vByte)", outspend.Txid,
+		origTx.Fee, origFeeRate, sweepTx.TxHash(), builder.Fee(),
+		feeRate)
+
+	switch {
+	case cfg.DryRun:
+		return printDryRun(
+			hex.EncodeToString(buf.Bytes()), builder.InputValues(),
+		)
+
+	case publish:
+		response, err := api.PublishTx(
+			hex.EncodeToString(buf.Bytes()),
+		)
+		if err != nil {
+			return err
+		}
+		log.Infof("Published TX %s, response: %s", sweepTx.TxHash(),
+			response)
+	}
+
+	log.Infof("Transaction: %x", buf.Bytes())
+	return nil
+}
@@ -1,8 +1,11 @@
 package main
 
 import (
+	"bufio"
+	"encoding/hex"
 	"fmt"
 	"os"
+	"regexp"
 	"strings"
 	"time"
 
@@ -16,6 +19,31 @@ type filterBackupCommand struct {
 	RootKey   string `long:"rootkey" description:"BIP32 HD root key of the wallet that was used to create the backup. Leave empty to prompt for lnd 24 word aezeed."`
 	MultiFile string `long:"multi_file" description:"The lnd channel.backup file to filter."`
 	Discard   string `long:"discard" description:"A comma separated list of channel funding outpoints (format <fundingTXID>:<index>) to remove from the backup file."`
+
+	PeerPubKeyPattern string `long:"peerpubkeypattern" description:"A regular expression that is matched against the hex encoded public key of the peer of each channel, for example ^03abcd to match a prefix. Matching channels are removed from the backup file unless --keep is set."`
+	PeerPubKeyFile    string `long:"peerpubkeyfile" description:"A file with one hex encoded peer public key per line. Channels with one of these peers are removed from the backup file unless --keep is set. If combined with --peerpubkeypattern, a channel's peer must match both."`
+	Keep              bool   `long:"keep" description:"Only keep the channels whose peer matches --peerpubkeypattern and --peerpubkeyfile instead of removing them."`
+}
+
+// peerFilter selects channels by the public key of their peer. If both a
+// pattern and a list of public keys are set, a peer must match both.
+type peerFilter struct {
+	pattern *regexp.Regexp
+	pubKeys map[string]bool
+	keep    bool
+}
+
+// active returns true if any peer filter is set.
+func (f *peerFilter) active() bool {
+	return f.pattern != nil || f.pubKeys != nil
+}
+
+// matches returns true if the hex encoded public key matches the filter.
+func (f *peerFilter) matches(pubKey string) bool {
+	if f.pattern != nil && !f.pattern.MatchString(pubKey) {
+		return false
+	}
+	return f.pubKeys == nil || f.pubKeys[pubKey]
 }
 
 func (c *filterBackupCommand) Execute(_ []string) error {
@@ -40,6 +68,14 @@ func (c *filterBackupCommand) Execute(_ []string) error {
 
 	// Parse discard filter.
 	discard := strings.Split(c.Discard, ",")
+	peers, err := c.peerFilter()
+	if err != nil {
+		return err
+	}
+	if c.Keep && !peers.active() {
+		return fmt.Errorf("keep requires peerpubkeypattern or " +
+			"peerpubkeyfile")
+	}
 
 	// Check that we have a backup file.
 	if c.MultiFile == "" {
@@ -50,11 +86,54 @@ func (c *filterBackupCommand) Execute(_ []string) error {
 		ExtendedKey: extendedKey,
 		ChainParams: chainParams,
 	}
-	return filterChannelBackup(multiFile, keyRing, discard)
+	return filterChannelBackup(multiFile, keyRing, discard, peers)
+}
+
+// peerFilter parses the peer public key pattern and reads the public keys of
+// the peer file.
+func (c *filterBackupCommand) peerFilter() (*peerFilter, error) {
+	filter := &peerFilter{keep: c.Keep}
+	if c.PeerPubKeyPattern != "" {
+		pattern, err := regexp.Compile(c.PeerPubKeyPattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid peer pubkey "+
+				"pattern: %v", err)
+		}
+		filter.pattern = pattern
+	}
+	if c.PeerPubKeyFile == "" {
+		return filter, nil
+	}
+
+	f, err := os.Open(c.PeerPubKeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("error opening peer pubkey file: %v",
+			err)
+	}
+	defer f.Close()
+
+	filter.pubKeys = make(map[string]bool)
+	scanner := bufio.NewScanner(f)
+	for lineNum := 1; scanner.Scan(); lineNum++ {
+		line := strings.ToLower(strings.TrimSpace(scanner.Text()))
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if _, err := pubKeyFromHex(line); err != nil {
+			return nil, fmt.Errorf("invalid pubkey in line %d of "+
+				"peer pubkey file: %v", lineNum, err)
+		}
+		filter.pubKeys[line] = true
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("error reading peer pubkey file: %v",
+			err)
+	}
+	return filter, nil
 }
 
 func filterChannelBackup(multiFile *chanbackup.MultiFile, ring keychain.KeyRing,
-	discard []string) error {
+	discard []string, peers *peerFilter) error {
 
 	multi, err := multiFile.ExtractMulti(ring)
 	if err != nil {
@@ -72,8 +151,18 @@ func filterChannelBackup(multiFile *chanbackup.MultiFile, ring keychain.KeyRing,
 		if found {
 			continue
 		}
+		if peers.active() {
+			peer := hex.EncodeToString(
+				single.RemoteNodePub.SerializeCompressed(),
+			)
+			if peers.matches(peer) != peers.keep {
+				continue
+			}
+		}
 		keep = append(keep, single)
 	}
+	log.Infof("Keeping %d of %d channels", len(keep),
+		len(multi.StaticBackups))
 	multi.StaticBackups = keep
 
 	fileName := fmt.Sprintf("results/backup-filtered-%s.backup",
@@ -0,0 +1,149 @@
+package main
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	"github.com/btcsuite/btcd/wire"
+	"github.com/btcsuite/btcutil/psbt"
+	"github.com/guggero/chantools/btc"
+	"github.com/guggero/chantools/lnd"
+)
+
+type finalizeAndBroadcastCommand struct {
+	Psbt     string `long:"psbt" description:"The signed PSBT to finalize and broadcast, base64 encoded."`
+	PsbtFile string `long:"psbtfile" description:"A file containing signed base64 encoded PSBTs, one per line. Specify '-' to read from stdin."`
+}
+
+func (c *finalizeAndBroadcastCommand) Execute(_ []string) error {
+	setupChainParams(cfg)
+
+	var encoded []string
+	switch {
+	case c.Psbt != "":
+		encoded = []string{c.Psbt}
+
+	case c.PsbtFile != "":
+		content, err := readInput(c.PsbtFile)
+		if err != nil {
+			return fmt.Errorf("error reading PSBT file: %v", err)
+		}
+		for _, line := range strings.Split(string(content), "\n") {
+			line = strings.TrimSpace(line)
+			if line != "" {
+				encoded = append(encoded, line)
+			}
+		}
+
+	default:
+		return fmt.Errorf("psbt or psbt file is required")
+	}
+
+	api := &btc.ExplorerAPI{BaseURL: cfg.APIURL}
+	for idx, b64 := range encoded {
+		packet, err := psbt.NewPsbt([]byte(b64), true)
+		if err != nil {
+			return fmt.Errorf("error parsing PSBT %d: %v", idx, err)
+		}
+
+		// The input values are no longer known once the transaction is
+		// extracted from the PSBT.
+		inputValues := make([]int64, len(packet.Inputs))
+		for inIdx, pInput := range packet.Inputs {
+			if pInput.WitnessUtxo != nil {
+				inputValues[inIdx] = pInput.WitnessUtxo.Value
+			}
+		}
+
+		tx, err := finalizePsbt(packet)
+		if err != nil {
+			return fmt.Errorf("error finalizing PSBT %d: %v", idx,
+				err)
+		}
+		var buf bytes.Buffer
+		if err := tx.Serialize(&buf); err != nil {
+			return err
+		}
+		rawTxHex := hex.EncodeToString(buf.Bytes())
+
+		if cfg.DryRun {
+			err := printDryRun(rawTxHex, inputValues)
+			if err != nil {
+				return err
+			}
+			continue
+		}
+		response, err := api.PublishTx(rawTxHex)
+		if err != nil {
+			return fmt.Errorf("error publishing TX %s: %v",
+				tx.TxHash(), err)
+		}
+		log.Infof("Published TX %s, response: %s", tx.TxHash(),
+			response)
+	}
+	return nil
+}
+
+// finalizePsbt finalizes all inputs of a signed PSBT and extracts the final
+// transaction.
+func finalizePsbt(packet *psbt.Psbt) (*wire.MsgTx, error) {
+	for idx := range packet.Inputs {
+		if err := finalizeTimeLockInput(packet, idx); err != nil {
+			return nil, fmt.Errorf("error finalizing input %d: %v",
+				idx, err)
+		}
+		if _, err := psbt.MaybeFinalize(packet, idx); err != nil {
+			return nil, fmt.Errorf("error finalizing input %d: %v",
+				idx, err)
+		}
+	}
+
+	rawTx, err := psbt.Extract(packet)
+	if err != nil {
+		return nil, fmt.Errorf("error extracting TX: %v", err)
+	}
+	tx := &wire.MsgTx{}
+	if err := tx.Deserialize(bytes.NewReader(rawTx)); err != nil {
+		return nil, fmt.Errorf("error parsing TX: %v", err)
+	}
+	return tx, nil
+}
+
+// finalizeTimeLockInput creates the final witness of an input that spends the
+// time locked to_local output of a commitment transaction. The PSBT finalizer
+// only knows about multisig scripts and fails on any other witness script.
+func finalizeTimeLockInput(packet *psbt.Psbt, idx int) error {
+	pInput := packet.Inputs[idx]
+	if pInput.FinalScriptWitness != nil || pInput.WitnessScript == nil ||
+		len(pInput.PartialSigs) != 1 {
+
+		return nil
+	}
+	scriptType, err := lnd.ClassifyScript(pInput.WitnessScript)
+	if err != nil || scriptType != lnd.ScriptTypeToLocalCSV {
+		return nil
+	}
+
+	// The empty element selects the time locked branch of the script.
+	witness := wire.TxWitness{
+		pInput.PartialSigs[0].Signature, nil, pInput.WitnessScript,
+	}
+	var buf bytes.Buffer
+	if err := wire.WriteVarInt(&buf, 0, uint64(len(witness))); err != nil {
+		return err
+	}
+	for _, item := range witness {
+		if err := wire.WriteVarBytes(&buf, 0, item); err != nil {
+			return err
+		}
+	}
+
+	// Like the PSBT finalizer, we only keep the previous output next to the
+	// final witness.
+	finalInput := psbt.NewPsbtInput(nil, pInput.WitnessUtxo)
+	finalInput.FinalScriptWitness = buf.Bytes()
+	packet.Inputs[idx] = *finalInput
+	return nil
+}
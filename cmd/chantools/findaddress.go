@@ -0,0 +1,122 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/btcsuite/btcutil/hdkeychain"
+	"github.com/guggero/chantools/lnd"
+)
+
+const (
+	// defaultFindAddressPaths are the account paths of all address types
+	// that lnd and most other wallets use.
+	defaultFindAddressPaths = "m/84'/0'/0',m/49'/0'/0',m/86'/0'/0'," +
+		"m/44'/0'/0'"
+)
+
+type findAddressCommand struct {
+	RootKey        string `long:"rootkey" description:"BIP32 HD root key of the wallet. Leave empty to prompt for lnd 24 word aezeed."`
+	Address        string `long:"address" description:"The address to search for."`
+	DerivationPath string `long:"derivationpath" description:"The first levels of the derivation path before any internal/external branch. Multiple paths can be separated by commas. (default m/84'/0'/0',m/49'/0'/0',m/86'/0'/0',m/44'/0'/0')"`
+	RecoveryWindow uint32 `long:"recoverywindow" description:"The number of keys to search per internal/external branch of each path. (default 2500)"`
+}
+
+func (c *findAddressCommand) Execute(_ []string) error {
+	setupChainParams(cfg)
+
+	var (
+		extendedKey *hdkeychain.ExtendedKey
+		err         error
+	)
+
+	if c.Address == "" {
+		return fmt.Errorf("address is required")
+	}
+
+	// Set default values.
+	if c.DerivationPath == "" {
+		c.DerivationPath = defaultFindAddressPaths
+	}
+	if c.RecoveryWindow == 0 {
+		c.RecoveryWindow = defaultRecoveryWindow
+	}
+	pathStrings, derivationPaths, err := parseDerivationPaths(
+		c.DerivationPath,
+	)
+	if err != nil {
+		return err
+	}
+
+	// Check that root key is valid or fall back to console input.
+	switch {
+	case c.RootKey != "":
+		extendedKey, err = hdkeychain.NewKeyFromString(c.RootKey)
+
+	default:
+		extendedKey, _, err = rootKeyFromConsole()
+	}
+	if err != nil {
+		return fmt.Errorf("error reading root key: %v", err)
+	}
+
+	// Bech32 addresses can be written in upper case too.
+	target := c.Address
+	lowerTarget := strings.ToLower(target)
+	if strings.HasPrefix(lowerTarget, chainParams.Bech32HRPSegwit+"1") {
+		target = lowerTarget
+	}
+
+	for idx, derivationPath := range derivationPaths {
+		for branch := uint32(0); branch <= 1; branch++ {
+			key, err := findAddress(
+				extendedKey, derivationPath, pathStrings[idx],
+				branch, c.RecoveryWindow, target,
+			)
+			if err != nil {
+				return err
+			}
+			if key == nil {
+				continue
+			}
+
+			fmt.Printf("Found address %s at path %s/%d/%d.\n",
+				c.Address, key.Path, key.Branch, key.Index)
+			fmt.Printf("Public key: %s\n", key.PubKeyHex)
+			fmt.Printf("Private key (WIF): %s\n", key.WIF)
+			return nil
+		}
+	}
+
+	return fmt.Errorf("address %s not found in the first %d keys of "+
+		"each branch of the paths %s, try a larger recoverywindow",
+		c.Address, c.RecoveryWindow, strings.Join(pathStrings, ", "))
+}
+
+// findAddress derives the keys of one branch of a path and returns the first
+// one that has the target as one of its addresses or nil if there is none.
+func findAddress(rootKey *hdkeychain.ExtendedKey, derivationPath []uint32,
+	pathString string, branch, window uint32, target string) (*DerivedKey,
+	error) {
+
+	for i := uint32(0); i < window; i++ {
+		path := append(derivationPath, branch, i)
+		derivedKey, err := lnd.DeriveChildren(rootKey, path)
+		if err != nil {
+			return nil, err
+		}
+		key, err := newDerivedKey(
+			derivedKey, pathString, branch, i, false,
+		)
+		if err != nil {
+			return nil, err
+		}
+		switch target {
+		case key.AddrP2PKH, key.AddrNP2WKH, key.AddrP2WKH,
+			key.AddrP2TR:
+
+			return key, nil
+		}
+	}
+	return nil, nil
+}
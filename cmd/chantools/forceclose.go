@@ -10,8 +10,11 @@ import (
 	"path"
 	"time"
 
+	"github.com/btcsuite/btcd/btcec"
 	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
 	"github.com/btcsuite/btcutil/hdkeychain"
+	"github.com/btcsuite/btcutil/psbt"
 	"github.com/guggero/chantools/btc"
 	"github.com/guggero/chantools/dataformat"
 	"github.com/guggero/chantools/lnd"
@@ -20,9 +23,11 @@ import (
 )
 
 type forceCloseCommand struct {
-	RootKey   string `long:"rootkey" description:"BIP32 HD root key to use. Leave empty to prompt for lnd 24 word aezeed."`
-	ChannelDB string `long:"channeldb" description:"The lnd channel.db file to use for force-closing channels."`
-	Publish   bool   `long:"publish" description:"Should the force-closing TX be published to the chain API?"`
+	RootKey    string `long:"rootkey" description:"BIP32 HD root key to use. Leave empty to prompt for lnd 24 word aezeed."`
+	ChannelDB  string `long:"channeldb" description:"The lnd channel.db file to use for force-closing channels."`
+	Publish    bool   `long:"publish" description:"Should the force-closing TX be published to the chain API?"`
+	Psbt       bool   `long:"psbt" description:"Don't sign the force-closing TXs but create unsigned PSBTs that already contain the signature of the remote peer instead so they can be signed externally. The result file then contains the unsigned TXs."`
+	OutputFile string `long:"outputfile" description:"The file to write the PSBTs to, one per line. Leave empty to print them."`
 }
 
 func (c *forceCloseCommand) Execute(_ []string) error {
@@ -62,12 +67,14 @@ func (c *forceCloseCommand) Execute(_ []string) error {
 	if err != nil {
 		return err
 	}
-	return forceCloseChannels(extendedKey, entries, db, c.Publish)
+	return forceCloseChannels(
+		extendedKey, entries, db, c.Publish, c.Psbt, c.OutputFile,
+	)
 }
 
 func forceCloseChannels(extendedKey *hdkeychain.ExtendedKey,
 	entries []*dataformat.SummaryEntry, chanDb *channeldb.DB,
-	publish bool) error {
+	publish, createPsbt bool, psbtFile string) error {
 
 	channels, err := chanDb.FetchAllChannels()
 	if err != nil {
@@ -78,6 +85,12 @@ func forceCloseChannels(extendedKey *hdkeychain.ExtendedKey,
 		ExtendedKey: extendedKey,
 		ChainParams: chainParams,
 	}
+	fingerprint, err := rootKeyFingerprint(extendedKey)
+	if err != nil {
+		return err
+	}
+
+	var packets []*psbt.Psbt
 
 	// Go through all channels in the DB, find the still open ones and
 	// publish their local commitment TX.
@@ -95,96 +108,66 @@ func forceCloseChannels(extendedKey *hdkeychain.ExtendedKey,
 			continue
 		}
 
-		localCommit := channel.LocalCommitment
-		localCommitTx := localCommit.CommitTx
+		localCommitTx := channel.LocalCommitment.CommitTx
 		if localCommitTx == nil {
 			log.Errorf("Cannot force-close, no local commit TX "+
 				"for channel %s", channelEntry.ChannelPoint)
 			continue
 		}
 
-		// Create signed transaction.
-		lc := &lnd.LightningChannel{
-			LocalChanCfg:  channel.LocalChanCfg,
-			RemoteChanCfg: channel.RemoteChanCfg,
-			ChannelState:  channel,
-			TXSigner:      signer,
-		}
-		err := lc.CreateSignDesc()
-		if err != nil {
-			return err
-		}
-
-		// Serialize transaction.
-		signedTx, err := lc.SignedCommitTx()
-		if err != nil {
-			return err
-		}
-		var buf bytes.Buffer
-		err = signedTx.Serialize(io.Writer(&buf))
-		if err != nil {
-			return err
-		}
-		hash := signedTx.TxHash()
-		serialized := hex.EncodeToString(buf.Bytes())
-
-		// Calculate commit point.
-		basepoint := channel.LocalChanCfg.DelayBasePoint
-		revpoint := channel.RemoteChanCfg.RevocationBasePoint
-		revocationPreimage, err := channel.RevocationProducer.AtIndex(
-			localCommit.CommitHeight,
-		)
-		if err != nil {
-			return err
+		// If an external signer should sign the transaction, we only
+		// add the unsigned one to the list of PSBTs.
+		if createPsbt {
+			packet, err := commitmentPsbt(channel, fingerprint)
+			if err != nil {
+				return err
+			}
+			packets = append(packets, packet)
+			channelEntry.ForceClose, err = describeCommitment(
+				channel, localCommitTx,
+			)
+			if err != nil {
+				return err
+			}
+			continue
 		}
-		point := input.ComputeCommitmentPoint(revocationPreimage[:])
 
 		// Store all information that we collected into the channel
 		// entry file so we don't need to use the channel.db file for
 		// the next step.
-		channelEntry.ForceClose = &dataformat.ForceClose{
-			TXID:       hash.String(),
-			Serialized: serialized,
-			DelayBasePoint: &dataformat.BasePoint{
-				Family: uint16(basepoint.Family),
-				Index:  basepoint.Index,
-				PubKey: hex.EncodeToString(
-					basepoint.PubKey.SerializeCompressed(),
-				),
-			},
-			RevocationBasePoint: &dataformat.BasePoint{
-				PubKey: hex.EncodeToString(
-					revpoint.PubKey.SerializeCompressed(),
-				),
-			},
-			CommitPoint: hex.EncodeToString(
-				point.SerializeCompressed(),
-			),
-			Outs: make(
-				[]*dataformat.Out, len(localCommitTx.TxOut),
-			),
-			CSVDelay: channel.LocalChanCfg.CsvDelay,
+		channelEntry.ForceClose, err = signCommitment(channel, signer)
+		if err != nil {
+			return err
 		}
-		for idx, out := range localCommitTx.TxOut {
-			script, err := txscript.DisasmString(out.PkScript)
+		hash := channelEntry.ForceClose.TXID
+		serialized := channelEntry.ForceClose.Serialized
+
+		// Publish TX.
+		switch {
+		case cfg.DryRun:
+			err := printDryRun(
+				serialized, []int64{int64(channel.Capacity)},
+			)
 			if err != nil {
 				return err
 			}
-			channelEntry.ForceClose.Outs[idx] = &dataformat.Out{
-				Script:    hex.EncodeToString(out.PkScript),
-				ScriptAsm: script,
-				Value:     uint64(out.Value),
-			}
-		}
 
-		// Publish TX.
-		if publish {
+		case publish:
 			response, err := api.PublishTx(serialized)
 			if err != nil {
 				return err
 			}
-			log.Infof("Published TX %s, response: %s",
-				hash.String(), response)
+			log.Infof("Published TX %s, response: %s", hash,
+				response)
+		}
+	}
+
+	if createPsbt {
+		if len(packets) == 0 {
+			return fmt.Errorf("no channels to force-close")
+		}
+		if err := writePsbts(packets, psbtFile); err != nil {
+			return err
 		}
 	}
 
@@ -199,3 +182,209 @@ func forceCloseChannels(extendedKey *hdkeychain.ExtendedKey,
 	log.Infof("Writing result to %s", fileName)
 	return ioutil.WriteFile(fileName, summaryBytes, 0644)
 }
+
+// signCommitment signs the latest local commitment transaction of the channel
+// and returns it together with all information needed to sweep its outputs.
+func signCommitment(channel *channeldb.OpenChannel, signer *lnd.Signer) (
+	*dataformat.ForceClose, error) {
+
+	// Create signed transaction.
+	lc := &lnd.LightningChannel{
+		LocalChanCfg:  channel.LocalChanCfg,
+		RemoteChanCfg: channel.RemoteChanCfg,
+		ChannelState:  channel,
+		TXSigner:      signer,
+	}
+	err := lc.CreateSignDesc()
+	if err != nil {
+		return nil, err
+	}
+
+	signedTx, err := lc.SignedCommitTx()
+	if err != nil {
+		return nil, err
+	}
+	return describeCommitment(channel, signedTx)
+}
+
+// describeCommitment returns the given commitment transaction of the channel
+// together with all information needed to sweep its outputs.
+func describeCommitment(channel *channeldb.OpenChannel,
+	commitTx *wire.MsgTx) (*dataformat.ForceClose, error) {
+
+	localCommit := channel.LocalCommitment
+	localCommitTx := localCommit.CommitTx
+
+	// Serialize transaction.
+	var buf bytes.Buffer
+	err := commitTx.Serialize(io.Writer(&buf))
+	if err != nil {
+		return nil, err
+	}
+	hash := commitTx.TxHash()
+	serialized := hex.EncodeToString(buf.Bytes())
+
+	// Calculate commit point.
+	basepoint := channel.LocalChanCfg.DelayBasePoint
+	revpoint := channel.RemoteChanCfg.RevocationBasePoint
+	revocationPreimage, err := channel.RevocationProducer.AtIndex(
+		localCommit.CommitHeight,
+	)
+	if err != nil {
+		return nil, err
+	}
+	point := input.ComputeCommitmentPoint(revocationPreimage[:])
+
+	forceClose := &dataformat.ForceClose{
+		TXID:       hash.String(),
+		Serialized: serialized,
+		DelayBasePoint: &dataformat.BasePoint{
+			Family: uint16(basepoint.Family),
+			Index:  basepoint.Index,
+			PubKey: hex.EncodeToString(
+				basepoint.PubKey.SerializeCompressed(),
+			),
+		},
+		RevocationBasePoint: &dataformat.BasePoint{
+			PubKey: hex.EncodeToString(
+				revpoint.PubKey.SerializeCompressed(),
+			),
+		},
+		CommitPoint: hex.EncodeToString(point.SerializeCompressed()),
+		Outs:        make([]*dataformat.Out, len(localCommitTx.TxOut)),
+		CSVDelay:    channel.LocalChanCfg.CsvDelay,
+	}
+	for idx, out := range localCommitTx.TxOut {
+		script, err := txscript.DisasmString(out.PkScript)
+		if err != nil {
+			return nil, err
+		}
+		forceClose.Outs[idx] = &dataformat.Out{
+			Script:    hex.EncodeToString(out.PkScript),
+			ScriptAsm: script,
+			Value:     uint64(out.Value),
+			Type:      dataformat.OutTypeToRemote,
+		}
+	}
+
+	// Find out which of the outputs are ours. Everything that isn't our
+	// to_local or an HTLC output must be the remote's balance.
+	toLocalIndex, err := toLocalOutputIndex(channel, point)
+	if err != nil {
+		return nil, err
+	}
+	if toLocalIndex >= 0 {
+		forceClose.Outs[toLocalIndex].Type = dataformat.OutTypeToLocal
+	}
+	for _, htlc := range localCommit.Htlcs {
+		if htlc.OutputIndex < 0 ||
+			int(htlc.OutputIndex) >= len(forceClose.Outs) {
+
+			continue
+		}
+		out := forceClose.Outs[htlc.OutputIndex]
+		out.Type = dataformat.OutTypeHtlcOffered
+		if htlc.Incoming {
+			out.Type = dataformat.OutTypeHtlcReceived
+		}
+		out.CLTVExpiry = htlc.RefundTimeout
+		out.PaymentHash = hex.EncodeToString(htlc.RHash[:])
+	}
+	return forceClose, nil
+}
+
+// commitmentPsbt creates an unsigned PSBT of the latest local commitment
+// transaction of the channel. The signature of the remote peer is added as a
+// partial signature, so only our signature of the funding multisig output is
+// missing.
+func commitmentPsbt(channel *channeldb.OpenChannel, fingerprint uint32) (
+	*psbt.Psbt, error) {
+
+	localKey := channel.LocalChanCfg.MultiSigKey
+	localPubKey := localKey.PubKey.SerializeCompressed()
+	remoteKey := channel.RemoteChanCfg.MultiSigKey
+	remotePubKey := remoteKey.PubKey.SerializeCompressed()
+	multiSigScript, err := input.GenMultiSigScript(
+		localPubKey, remotePubKey,
+	)
+	if err != nil {
+		return nil, err
+	}
+	fundingPkScript, err := input.WitnessScriptHash(multiSigScript)
+	if err != nil {
+		return nil, err
+	}
+
+	packet, err := psbt.NewPsbtFromUnsignedTx(
+		channel.LocalCommitment.CommitTx.Copy(),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("error creating PSBT: %v", err)
+	}
+	updater, err := psbt.NewUpdater(packet)
+	if err != nil {
+		return nil, fmt.Errorf("error creating PSBT updater: %v", err)
+	}
+	err = updater.AddInWitnessUtxo(wire.NewTxOut(
+		int64(channel.Capacity), fundingPkScript,
+	), 0)
+	if err != nil {
+		return nil, err
+	}
+	if err := updater.AddInSighashType(txscript.SigHashAll, 0); err != nil {
+		return nil, err
+	}
+	if err := updater.AddInWitnessScript(multiSigScript, 0); err != nil {
+		return nil, err
+	}
+	err = updater.AddInBip32Derivation(
+		fingerprint, keyLocatorBip32Path(localKey.KeyLocator),
+		localPubKey, 0,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	theirSig := append(
+		channel.LocalCommitment.CommitSig, byte(txscript.SigHashAll),
+	)
+	packet.Inputs[0].PartialSigs = append(
+		packet.Inputs[0].PartialSigs, &psbt.PartialSig{
+			PubKey:    remotePubKey,
+			Signature: theirSig,
+		},
+	)
+	return packet, nil
+}
+
+// toLocalOutputIndex returns the index of our time locked to_local output in
+// the latest local commitment transaction of the channel or -1 if there is no
+// such output.
+func toLocalOutputIndex(channel *channeldb.OpenChannel,
+	commitPoint *btcec.PublicKey) (int, error) {
+
+	toLocalScript, err := input.CommitScriptToSelf(
+		uint32(channel.LocalChanCfg.CsvDelay),
+		input.TweakPubKey(
+			channel.LocalChanCfg.DelayBasePoint.PubKey, commitPoint,
+		),
+		input.DeriveRevocationPubkey(
+			channel.RemoteChanCfg.RevocationBasePoint.PubKey,
+			commitPoint,
+		),
+	)
+	if err != nil {
+		return -1, err
+	}
+	toLocalPkScript, err := input.WitnessScriptHash(toLocalScript)
+	if err != nil {
+		return -1, err
+	}
+	found, index := input.FindScriptOutputIndex(
+		channel.LocalCommitment.CommitTx, toLocalPkScript,
+	)
+	if !found {
+		return -1, nil
+	}
+	return int(index), nil
+}
@@ -0,0 +1,201 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcutil"
+	"github.com/btcsuite/btcutil/hdkeychain"
+	"github.com/guggero/chantools/btc"
+	"github.com/guggero/chantools/lnd"
+)
+
+const (
+	defaultAddressPath = "m/84'/0'/0'/0/0"
+
+	addrTypeP2PKH      = "p2pkh"
+	addrTypeNP2WKH     = "p2sh-p2wpkh"
+	addrTypeP2WKH      = "p2wpkh"
+	addrTypeP2TR       = "p2tr"
+	addrTypeAutoDetect = ""
+)
+
+type generateAddressCommand struct {
+	RootKey  string `long:"rootkey" description:"BIP32 HD root key to derive the address from. Leave empty to prompt for lnd 24 word aezeed."`
+	Path     string `long:"path" description:"The BIP32 derivation path of the address. (default m/84'/0'/0'/0/0)"`
+	AddrType string `long:"addrtype" description:"The type of the address to generate (p2pkh, p2sh-p2wpkh, p2wpkh or p2tr). Leave empty to detect it from the purpose of the derivation path."`
+
+	// SegWitVersion is -1 if not set, see main.go.
+	SegWitVersion int `long:"segwitversion" description:"The segwit version of the address to generate (0-16). Version 0 is p2wpkh and version 1 is p2tr. The versions 2 to 16 are not defined yet and can only be used on regtest for experiments. Leave empty to use the address type."`
+}
+
+func (c *generateAddressCommand) Execute(_ []string) error {
+	setupChainParams(cfg)
+
+	var (
+		extendedKey *hdkeychain.ExtendedKey
+		err         error
+	)
+
+	// Check that root key is valid or fall back to console input.
+	switch {
+	case c.RootKey != "":
+		extendedKey, err = hdkeychain.NewKeyFromString(c.RootKey)
+
+	default:
+		extendedKey, _, err = rootKeyFromConsole()
+	}
+	if err != nil {
+		return fmt.Errorf("error reading root key: %v", err)
+	}
+
+	// Set default values.
+	if c.Path == "" {
+		c.Path = defaultAddressPath
+	}
+	path, err := lnd.ParsePath(c.Path)
+	if err != nil {
+		return fmt.Errorf("error parsing path: %v", err)
+	}
+
+	addrType, err := c.resolveAddrType(path[0])
+	if err != nil {
+		return err
+	}
+
+	key, err := lnd.DeriveChildren(extendedKey, path)
+	if err != nil {
+		return fmt.Errorf("error deriving key: %v", err)
+	}
+	pubKey, err := key.ECPubKey()
+	if err != nil {
+		return fmt.Errorf("error deriving public key: %v", err)
+	}
+
+	var address string
+	switch {
+	case c.SegWitVersion > 1:
+		address, err = experimentalSegWitAddress(
+			pubKey.SerializeCompressed(), byte(c.SegWitVersion),
+			chainParams,
+		)
+		addrType = fmt.Sprintf("segwit v%d", c.SegWitVersion)
+
+	default:
+		var addr btcutil.Address
+		addr, err = pubKeyAddress(
+			pubKey.SerializeCompressed(), addrType, chainParams,
+		)
+		if err == nil {
+			address = addr.EncodeAddress()
+		}
+	}
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Deriving path %s for network %s.\n", c.Path,
+		chainParams.Name)
+	fmt.Printf("Address (%s): %s\n", addrType, address)
+	return nil
+}
+
+// resolveAddrType returns the address type to generate from the flags or the
+// purpose of the derivation path if no type was specified.
+func (c *generateAddressCommand) resolveAddrType(purpose uint32) (string,
+	error) {
+
+	if c.SegWitVersion < 0 {
+		if c.AddrType == addrTypeAutoDetect {
+			return addrTypeFromPurpose(purpose), nil
+		}
+		return c.AddrType, nil
+	}
+
+	if c.SegWitVersion > btc.MaxSegWitVersion {
+		return "", fmt.Errorf("invalid segwit version %d, must be "+
+			"between 0 and %d", c.SegWitVersion,
+			btc.MaxSegWitVersion)
+	}
+
+	// The segwit version determines the address type, so an explicit type
+	// must match it.
+	var versionType string
+	switch c.SegWitVersion {
+	case 0:
+		versionType = addrTypeP2WKH
+
+	case 1:
+		versionType = addrTypeP2TR
+	}
+	if c.AddrType != addrTypeAutoDetect && c.AddrType != versionType {
+		return "", fmt.Errorf("address type %s cannot be used with "+
+			"segwit version %d", c.AddrType, c.SegWitVersion)
+	}
+	return versionType, nil
+}
+
+// addrTypeFromPurpose returns the address type that belongs to the BIP43
+// purpose of a derivation path. Unknown purposes default to native SegWit.
+func addrTypeFromPurpose(purpose uint32) string {
+	switch purpose {
+	case lnd.HardenedKeyStart + 44:
+		return addrTypeP2PKH
+
+	case lnd.HardenedKeyStart + 49:
+		return addrTypeNP2WKH
+
+	case lnd.HardenedKeyStart + 86:
+		return addrTypeP2TR
+
+	default:
+		return addrTypeP2WKH
+	}
+}
+
+// pubKeyAddress returns the address of the given type for a public key.
+func pubKeyAddress(pubKey []byte, addrType string,
+	params *chaincfg.Params) (btcutil.Address, error) {
+
+	pubKeyHash := btcutil.Hash160(pubKey)
+	switch addrType {
+	case addrTypeP2PKH:
+		return btcutil.NewAddressPubKeyHash(pubKeyHash, params)
+
+	case addrTypeNP2WKH:
+		p2wkh, err := p2wkhScript(pubKey)
+		if err != nil {
+			return nil, err
+		}
+		return btcutil.NewAddressScriptHash(p2wkh, params)
+
+	case addrTypeP2WKH:
+		return btcutil.NewAddressWitnessPubKeyHash(pubKeyHash, params)
+
+	case addrTypeP2TR:
+		// The chain parameters we use don't define a Taproot
+		// deployment, so there's no network we could create a valid
+		// P2TR address for.
+		return nil, fmt.Errorf("address type %s is not supported on "+
+			"network %s, Taproot is not activated", addrType,
+			params.Name)
+
+	default:
+		return nil, fmt.Errorf("unknown address type %s", addrType)
+	}
+}
+
+// experimentalSegWitAddress returns an address of a segwit version that isn't
+// defined yet. The x-only public key is used as the witness program. Outputs of
+// undefined segwit versions can be spent by anyone, so this is only allowed on
+// regtest.
+func experimentalSegWitAddress(pubKey []byte, version byte,
+	params *chaincfg.Params) (string, error) {
+
+	if params.Net != chaincfg.RegressionNetParams.Net {
+		return "", fmt.Errorf("segwit version %d is not defined and "+
+			"can only be used on regtest", version)
+	}
+	return btc.EncodeSegWitAddress(params.Bech32HRPSegwit, version,
+		pubKey[1:])
+}
@@ -0,0 +1,320 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"time"
+
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/btcsuite/btcutil/psbt"
+	"github.com/guggero/chantools/btc"
+	"github.com/guggero/chantools/lnd"
+)
+
+const (
+	// signingRequestVersion is the version of the format of the signing
+	// request file.
+	signingRequestVersion = 1
+
+	scriptTypeP2SH    = "p2sh"
+	scriptTypeP2TR    = "p2tr"
+	scriptTypeUnknown = "unknown"
+)
+
+// signingRequest is a PSBT together with everything a signer needs to
+// compute the signature hashes of all inputs without parsing the PSBT.
+type signingRequest struct {
+	Version    int              `json:"version"`
+	Network    string           `json:"network"`
+	Psbt       string           `json:"psbt"`
+	UnsignedTx string           `json:"unsigned_tx"`
+	TXID       string           `json:"txid"`
+	Fee        int64            `json:"fee"`
+	Inputs     []*signingInput  `json:"inputs"`
+	Outputs    []*signingOutput `json:"outputs"`
+}
+
+type signingInput struct {
+	Index         int                  `json:"index"`
+	OutPoint      string               `json:"outpoint"`
+	Sequence      uint32               `json:"sequence"`
+	Value         int64                `json:"value"`
+	PkScript      string               `json:"pk_script"`
+	ScriptType    string               `json:"script_type"`
+	RedeemScript  string               `json:"redeem_script,omitempty"`
+	WitnessScript string               `json:"witness_script,omitempty"`
+	SigHashType   uint32               `json:"sighash_type"`
+	SigHash       string               `json:"sighash,omitempty"`
+	Derivations   []*signingDerivation `json:"derivations"`
+}
+
+type signingDerivation struct {
+	PubKey      string `json:"pubkey"`
+	Fingerprint string `json:"fingerprint"`
+	Path        string `json:"path"`
+}
+
+type signingOutput struct {
+	Index    int    `json:"index"`
+	Value    int64  `json:"value"`
+	PkScript string `json:"pk_script"`
+	Address  string `json:"address,omitempty"`
+}
+
+type generateHardwareSignerCommand struct {
+	Psbt string `long:"psbt" description:"The unsigned transaction as a base64 encoded PSBT, for example created with the createpsbt command. All inputs must contain the UTXO information."`
+}
+
+func (c *generateHardwareSignerCommand) Execute(_ []string) error {
+	setupChainParams(cfg)
+
+	if c.Psbt == "" {
+		return fmt.Errorf("psbt is required")
+	}
+	packet, err := psbt.NewPsbt([]byte(c.Psbt), true)
+	if err != nil {
+		return fmt.Errorf("error parsing PSBT: %v", err)
+	}
+
+	request, err := newSigningRequest(packet)
+	if err != nil {
+		return err
+	}
+	for _, in := range request.Inputs {
+		if len(in.Derivations) == 0 {
+			log.Warnf("Input %d has no derivation path, the "+
+				"signer might not be able to find its key",
+				in.Index)
+		}
+	}
+
+	requestBytes, err := json.MarshalIndent(request, "", " ")
+	if err != nil {
+		return err
+	}
+	fileName := fmt.Sprintf("results/signingrequest-%s.json",
+		time.Now().Format("2006-01-02-15-04-05"))
+	log.Infof("Writing signing request for TX %s with %d inputs to %s",
+		request.TXID, len(request.Inputs), fileName)
+	return ioutil.WriteFile(fileName, requestBytes, 0644)
+}
+
+// newSigningRequest creates a signing request from a PSBT. The signature hash
+// of every SegWit v0 and legacy input is pre-computed so the signer can verify
+// what it signs. Taproot inputs only contain the values and pk scripts of all
+// previous outputs that are needed to compute their BIP341 signature hash.
+func newSigningRequest(packet *psbt.Psbt) (*signingRequest, error) {
+	tx := packet.UnsignedTx
+	var buf bytes.Buffer
+	if err := tx.Serialize(&buf); err != nil {
+		return nil, err
+	}
+	b64, err := packet.B64Encode()
+	if err != nil {
+		return nil, fmt.Errorf("error encoding PSBT: %v", err)
+	}
+	request := &signingRequest{
+		Version:    signingRequestVersion,
+		Network:    chainParams.Name,
+		Psbt:       b64,
+		UnsignedTx: hex.EncodeToString(buf.Bytes()),
+		TXID:       tx.TxHash().String(),
+	}
+
+	sigHashes := txscript.NewTxSigHashes(tx)
+	for idx, txIn := range tx.TxIn {
+		pInput := packet.Inputs[idx]
+		prevOut, err := psbtPrevOut(pInput, txIn)
+		if err != nil {
+			return nil, fmt.Errorf("input %d: %v", idx, err)
+		}
+		request.Fee += prevOut.Value
+
+		in := &signingInput{
+			Index:         idx,
+			OutPoint:      txIn.PreviousOutPoint.String(),
+			Sequence:      txIn.Sequence,
+			Value:         prevOut.Value,
+			PkScript:      hex.EncodeToString(prevOut.PkScript),
+			ScriptType:    scriptTypeName(prevOut.PkScript),
+			RedeemScript:  hex.EncodeToString(pInput.RedeemScript),
+			WitnessScript: hex.EncodeToString(pInput.WitnessScript),
+			SigHashType:   uint32(pInput.SighashType),
+			Derivations:   []*signingDerivation{},
+		}
+		for _, derivation := range pInput.Bip32Derivation {
+			var fingerprint [4]byte
+			binary.LittleEndian.PutUint32(
+				fingerprint[:], derivation.MasterKeyFingerprint,
+			)
+			pubKey, path := derivation.PubKey, derivation.Bip32Path
+			d := &signingDerivation{
+				PubKey:      hex.EncodeToString(pubKey),
+				Fingerprint: hex.EncodeToString(fingerprint[:]),
+				Path:        lnd.FormatPath(path),
+			}
+			in.Derivations = append(in.Derivations, d)
+		}
+
+		// Taproot inputs use SIGHASH_DEFAULT, all others SIGHASH_ALL
+		// if the PSBT doesn't say otherwise.
+		if in.SigHashType == 0 && in.ScriptType != scriptTypeP2TR {
+			in.SigHashType = uint32(txscript.SigHashAll)
+		}
+		sigHash, err := inputSigHash(
+			tx, sigHashes, idx, prevOut, pInput,
+			txscript.SigHashType(in.SigHashType),
+		)
+		if err != nil {
+			return nil, fmt.Errorf("error computing sighash of "+
+				"input %d: %v", idx, err)
+		}
+		in.SigHash = hex.EncodeToString(sigHash)
+
+		request.Inputs = append(request.Inputs, in)
+	}
+
+	for idx, txOut := range tx.TxOut {
+		request.Fee -= txOut.Value
+		request.Outputs = append(request.Outputs, &signingOutput{
+			Index:    idx,
+			Value:    txOut.Value,
+			PkScript: hex.EncodeToString(txOut.PkScript),
+			Address:  pkScriptAddress(txOut.PkScript),
+		})
+	}
+	return request, nil
+}
+
+// psbtPrevOut returns the output an input of a PSBT spends, either from the
+// witness UTXO or the full previous transaction.
+func psbtPrevOut(pInput psbt.PInput, txIn *wire.TxIn) (*wire.TxOut, error) {
+	switch {
+	case pInput.WitnessUtxo != nil:
+		return pInput.WitnessUtxo, nil
+
+	case pInput.NonWitnessUtxo != nil:
+		prevTx := pInput.NonWitnessUtxo
+		if prevTx.TxHash() != txIn.PreviousOutPoint.Hash {
+			return nil, fmt.Errorf("previous transaction doesn't " +
+				"match outpoint")
+		}
+		index := txIn.PreviousOutPoint.Index
+		if int(index) >= len(prevTx.TxOut) {
+			return nil, fmt.Errorf("previous transaction has no "+
+				"output %d", index)
+		}
+		return prevTx.TxOut[index], nil
+
+	default:
+		return nil, fmt.Errorf("no UTXO information in PSBT")
+	}
+}
+
+// inputSigHash computes the signature hash of an input. Nil is returned for
+// Taproot and unknown inputs.
+func inputSigHash(tx *wire.MsgTx, sigHashes *txscript.TxSigHashes, idx int,
+	prevOut *wire.TxOut, pInput psbt.PInput,
+	hashType txscript.SigHashType) ([]byte, error) {
+
+	pkScript := prevOut.PkScript
+	if txscript.IsPayToScriptHash(pkScript) {
+		if len(pInput.RedeemScript) == 0 {
+			return nil, nil
+		}
+		pkScript = pInput.RedeemScript
+	}
+
+	switch {
+	case txscript.IsPayToWitnessPubKeyHash(pkScript):
+		// The script code of a P2WKH input is the P2PKH script of the
+		// key hash.
+		scriptCode, err := txscript.NewScriptBuilder().
+			AddOp(txscript.OP_DUP).
+			AddOp(txscript.OP_HASH160).
+			AddData(pkScript[2:]).
+			AddOp(txscript.OP_EQUALVERIFY).
+			AddOp(txscript.OP_CHECKSIG).
+			Script()
+		if err != nil {
+			return nil, err
+		}
+		return txscript.CalcWitnessSigHash(
+			scriptCode, sigHashes, hashType, tx, idx,
+			prevOut.Value,
+		)
+
+	case txscript.IsPayToWitnessScriptHash(pkScript):
+		if len(pInput.WitnessScript) == 0 {
+			return nil, nil
+		}
+		return txscript.CalcWitnessSigHash(
+			pInput.WitnessScript, sigHashes, hashType, tx, idx,
+			prevOut.Value,
+		)
+
+	case txscript.GetScriptClass(pkScript) == txscript.PubKeyHashTy,
+		txscript.GetScriptClass(pkScript) == txscript.MultiSigTy,
+		txscript.GetScriptClass(pkScript) == txscript.PubKeyTy:
+
+		return txscript.CalcSignatureHash(pkScript, hashType, tx, idx)
+
+	default:
+		return nil, nil
+	}
+}
+
+// scriptTypeName returns the name of the type of a pk script.
+func scriptTypeName(pkScript []byte) string {
+	switch {
+	case txscript.IsPayToWitnessPubKeyHash(pkScript):
+		return inputTypeP2WKH
+
+	case txscript.IsPayToWitnessScriptHash(pkScript):
+		return inputTypeP2WSH
+
+	case txscript.IsPayToScriptHash(pkScript):
+		return scriptTypeP2SH
+
+	case isTaprootScript(pkScript):
+		return scriptTypeP2TR
+
+	case txscript.GetScriptClass(pkScript) == txscript.PubKeyHashTy:
+		return inputTypeP2PKH
+
+	default:
+		return scriptTypeUnknown
+	}
+}
+
+// isTaprootScript returns true if the pk script is a SegWit v1 output with a
+// 32 byte witness program.
+func isTaprootScript(pkScript []byte) bool {
+	return len(pkScript) == 34 && pkScript[0] == txscript.OP_1 &&
+		pkScript[1] == txscript.OP_DATA_32
+}
+
+// pkScriptAddress returns the address of a pk script or an empty string if it
+// has none.
+func pkScriptAddress(pkScript []byte) string {
+	if isTaprootScript(pkScript) {
+		addr, err := btc.EncodeSegWitAddress(
+			chainParams.Bech32HRPSegwit, taprootWitnessVersion,
+			pkScript[2:],
+		)
+		if err != nil {
+			return ""
+		}
+		return addr
+	}
+	_, addrs, _, err := txscript.ExtractPkScriptAddrs(pkScript, chainParams)
+	if err != nil || len(addrs) != 1 {
+		return ""
+	}
+	return addrs[0].EncodeAddress()
+}
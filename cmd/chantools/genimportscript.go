@@ -1,28 +1,102 @@
 package main
 
 import (
+	"encoding/hex"
+	"encoding/json"
 	"fmt"
+	"io"
+	"os"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
 	"time"
 
+	"github.com/btcsuite/btcd/btcec"
 	"github.com/btcsuite/btcd/chaincfg"
 	"github.com/btcsuite/btcd/txscript"
 	"github.com/btcsuite/btcutil"
 	"github.com/btcsuite/btcutil/hdkeychain"
+	"github.com/guggero/chantools/btc"
 	"github.com/guggero/chantools/lnd"
+	"github.com/lightningnetwork/lnd/chanbackup"
 )
 
 const (
 	defaultRecoveryWindow = 2500
 	defaultRescanFrom     = 500000
 	defaultDerivationPath = "m/84'/0'/0'"
+
+	// defaultTaprootDerivationPath is the BIP86 account path that is used
+	// by default for the bitcoin-cli-taproot format.
+	defaultTaprootDerivationPath = "m/86'/0'/0'"
+
+	formatWatchOnly    = "bitcoin-cli-watchonly"
+	formatTaproot      = "bitcoin-cli-taproot"
+	formatImportWallet = "bitcoin-importwallet"
+	formatDescriptors  = "bitcoin-descriptors"
+	formatJSON         = "json"
+	formatColdcard     = "coldcard"
+
+	// addrTypeNameNP2WKH is the name of the NP2WKH address type in the
+	// addresstypes flag.
+	addrTypeNameNP2WKH = "np2wkh"
+
+	// importWalletTimeFormat is the format of the key creation time in the
+	// dumpwallet format of bitcoin core.
+	importWalletTimeFormat = "2006-01-02T15:04:05Z"
 )
 
+// DerivedKey is a single derived key with all its addresses as it is written
+// by the json format. The WIF is left out completely for watch-only output.
+type DerivedKey struct {
+	Path       string `json:"path"`
+	Branch     uint32 `json:"branch"`
+	Index      uint32 `json:"index"`
+	WIF        string `json:"wif,omitempty"`
+	PubKeyHex  string `json:"pubkey_hex"`
+	AddrP2PKH  string `json:"addr_p2pkh"`
+	AddrNP2WKH string `json:"addr_np2wkh"`
+	AddrP2WKH  string `json:"addr_p2wpkh"`
+	AddrP2TR   string `json:"addr_p2tr"`
+}
+
+// addressTypes is the set of address types that are imported. A nil set
+// contains all address types.
+type addressTypes map[string]bool
+
+// contains returns true if the given address type is part of the set.
+func (a addressTypes) contains(addrType string) bool {
+	return a == nil || a[addrType]
+}
+
+// printFunc is the type of a function that prints a single derived key in an
+// import script format to the given writer. The label of the key is the label
+// prefix followed by the derivation path.
+type printFunc func(io.Writer, *DerivedKey, string) error
+
 type genImportScriptCommand struct {
 	RootKey        string `long:"rootkey" description:"BIP32 HD root key to use. Leave empty to prompt for lnd 24 word aezeed."`
-	Format         string `long:"format" description:"The format of the generated import script. Currently supported are: bitcoin-cli, bitcoin-cli-watchonly, bitcoin-importwallet."`
-	DerivationPath string `long:"derivationpath" description:"The first levels of the derivation path before any internal/external branch. (default m/84'/0'/0')"`
-	RecoveryWindow uint32 `long:"recoverywindow" description:"The number of keys to scan per internal/external branch. The output will consist of double this amount of keys. (default 2500)"`
-	RescanFrom     uint32 `long:"rescanfrom" description:"The block number to rescan from. Will be set automatically from the wallet birthday if the lnd 24 word aezeed is entered. (default 500000)"`
+	Format         string `long:"format" description:"The format of the generated import script. Currently supported are: bitcoin-cli, bitcoin-cli-watchonly, bitcoin-cli-taproot, bitcoin-importwallet, bitcoin-descriptors, json, coldcard."`
+	DerivationPath string `long:"derivationpath" description:"The first levels of the derivation path before any internal/external branch. Multiple paths can be separated by commas. (default m/84'/0'/0', m/86'/0'/0' for the bitcoin-cli-taproot format)"`
+	RecoveryWindow uint32 `long:"recoverywindow" default:"2500" description:"The number of keys to scan per internal/external branch. The output will consist of double this amount of keys. Set to 0 together with printaccountxpub to only print the account xpub."`
+	RescanFrom     uint32 `long:"rescanfrom" description:"The block number to rescan from. Will be set automatically from the wallet birthday if the lnd 24 word aezeed is entered. (default 500000, 0 on signet)"`
+	LabelPrefix    string `long:"labelprefix" description:"A prefix that is added to the labels of all keys, for example to tell the keys of multiple wallets apart."`
+	LncliBackup    string `long:"lnclibackup" description:"The JSON output of lncli exportchanbackup. If set, the channels in the backup are listed in the script as their funds are not covered by the on-chain keys. Use - to read it from stdin, the rootkey flag is required then."`
+	GapLimit       uint32 `long:"gaplimit" description:"Instead of a fixed number of keys, derive keys of each branch until this many consecutive addresses never received any funds, as looked up with the API of --apiurl. Overrides recoverywindow."`
+	Workers        uint32 `long:"workers" description:"The number of keys to derive in parallel. (default number of CPUs)"`
+	OutputFile     string `long:"outputfile" description:"Write the script to this file instead of stdout. The file is only readable by the current user."`
+	Overwrite      bool   `long:"overwrite" description:"Overwrite the output file if it already exists."`
+	Quiet          bool   `long:"quiet" description:"Don't print the progress of the key derivation to stderr. The progress is only printed for a recoverywindow of 10000 or more."`
+	WatchOnly      bool   `long:"watchonly" description:"Only used with the bitcoin-descriptors and json formats. Create watch-only descriptors with the account xpub instead of the xprv or leave out the private keys of the json output."`
+
+	Account      *uint32 `long:"account" description:"The account index to derive the keys of. Replaces the third (account) component of every derivation path."`
+	AccountRange string  `long:"accountrange" description:"A range of account indexes to derive the keys of in a single run, for example 0-9. Replaces the third (account) component of every derivation path and adds the account to the labels of the keys."`
+
+	PrintAccountXPub   bool   `long:"printaccountxpub" description:"Print the account extended public key of each derivation path in the form xpub:<key> before the script, for setting up a watch-only wallet like Sparrow or Specter."`
+	ExtendedKeyVersion string `long:"extendedkeyversion" description:"The SLIP-0132 version to encode the account extended public key with, one of xpub, ypub, zpub, Ypub, Zpub or tpub, upub, vpub for test networks. Requires printaccountxpub. (default xpub or tpub)"`
+
+	AddressTypes string `long:"addresstypes" description:"Only used with the bitcoin-importwallet and bitcoin-descriptors formats. A comma separated list of the address types to import, any of p2pkh, np2wkh, p2wpkh and p2tr. The descriptors of derivation paths with other address types are left out. (default all)"`
 }
 
 func (c *genImportScriptCommand) Execute(_ []string) error {
@@ -34,6 +108,27 @@ func (c *genImportScriptCommand) Execute(_ []string) error {
 		birthday    time.Time
 	)
 
+	// The root key can't be entered on the console if the backup is read
+	// from stdin.
+	if c.LncliBackup == "-" && c.RootKey == "" {
+		return fmt.Errorf("rootkey is required to read the lncli " +
+			"backup from stdin")
+	}
+
+	// The output file is created before any keys are derived, so we don't
+	// do all the work for nothing if it can't be written.
+	w := io.Writer(os.Stdout)
+	if c.OutputFile != "" {
+		f, err := createOutputFile(c.OutputFile, c.Overwrite)
+		if err != nil {
+			return err
+		}
+		defer func() {
+			_ = f.Close()
+		}()
+		w = f
+	}
+
 	// Check that root key is valid or fall back to console input.
 	switch {
 	case c.RootKey != "":
@@ -47,179 +142,1159 @@ func (c *genImportScriptCommand) Execute(_ []string) error {
 		if err != nil {
 			return fmt.Errorf("error reading root key: %v", err)
 		}
+		if birthday.IsZero() {
+			log.Warn(bip39BirthdayWarning)
+			break
+		}
 		// The btcwallet gives the birthday a slack of 48 hours, let's
 		// do the same.
 		c.RescanFrom = seedBirthdayToBlock(birthday.Add(-48 * time.Hour))
 	}
 
 	// Set default values.
-	if c.RecoveryWindow == 0 {
-		c.RecoveryWindow = defaultRecoveryWindow
+	if c.Workers == 0 {
+		c.Workers = uint32(runtime.NumCPU())
 	}
 	if c.RescanFrom == 0 {
-		c.RescanFrom = defaultRescanFrom
+		c.RescanFrom = defaultRescanFromHeight()
 	}
 	if c.DerivationPath == "" {
 		c.DerivationPath = defaultDerivationPath
+		if c.Format == formatTaproot {
+			c.DerivationPath = defaultTaprootDerivationPath
+		}
 	}
 
-	derivationPath, err := lnd.ParsePath(c.DerivationPath)
+	pathStrings, derivationPaths, err := parseDerivationPaths(
+		c.DerivationPath,
+	)
 	if err != nil {
-		return fmt.Errorf("error parsing path: %v", err)
+		return err
 	}
 
-	fmt.Printf("# Wallet dump created by chantools on %s\n",
-		time.Now().UTC())
+	// The account component of the paths is replaced for every account.
+	// All accounts belong to the same seed and share its birthday, so the
+	// single rescan at the end starts early enough for all of them.
+	labelPrefixes := make([]string, len(derivationPaths))
+	for idx := range labelPrefixes {
+		labelPrefixes[idx] = c.LabelPrefix
+	}
+	if c.Account != nil && c.AccountRange != "" {
+		return fmt.Errorf("account and accountrange can't be used " +
+			"together")
+	}
+	if c.Account != nil || c.AccountRange != "" {
+		accounts := []uint32{0}
+		switch {
+		case c.Account != nil:
+			accounts[0] = *c.Account
 
-	// Determine the format.
-	var printFn func(*hdkeychain.ExtendedKey, string, uint32, uint32) error
-	switch c.Format {
-	default:
-		fallthrough
+		default:
+			accounts, err = parseAccountRange(c.AccountRange)
+			if err != nil {
+				return err
+			}
+		}
+		numPaths := len(derivationPaths)
+		pathStrings, derivationPaths, err = accountPaths(
+			derivationPaths, accounts,
+		)
+		if err != nil {
+			return err
+		}
 
-	case "bitcoin-cli":
-		printFn = printBitcoinCli
-		fmt.Println("# Paste the following lines into a command line " +
-			"window.")
-
-	case "bitcoin-cli-watchonly":
-		printFn = printBitcoinCliWatchOnly
-		fmt.Println("# Paste the following lines into a command line " +
-			"window.")
-
-	case "bitcoin-importwallet":
-		printFn = printBitcoinImportWallet
-		fmt.Println("# Save this output to a file and use the " +
-			"importwallet command of bitcoin core.")
+		// The path in the label shows the account as well, but it's
+		// easier to spot at the beginning if there are many of them.
+		labelPrefixes = labelPrefixes[:0]
+		for _, account := range accounts {
+			prefix := c.LabelPrefix
+			if c.AccountRange != "" {
+				prefix = fmt.Sprintf("%saccount%d/",
+					c.LabelPrefix, account)
+			}
+			for i := 0; i < numPaths; i++ {
+				labelPrefixes = append(labelPrefixes, prefix)
+			}
+		}
+	}
+
+	// The descriptors and keys are printed as plain JSON, so there's no
+	// room for any comments.
+	jsonFormat := c.Format == formatDescriptors || c.Format == formatJSON ||
+		c.Format == formatColdcard
+	if jsonFormat && c.LncliBackup != "" {
+		return fmt.Errorf("lnclibackup can't be used with the %s "+
+			"format", c.Format)
+	}
+	if jsonFormat && c.PrintAccountXPub {
+		return fmt.Errorf("printaccountxpub can't be used with the %s "+
+			"format", c.Format)
+	}
+	if c.ExtendedKeyVersion != "" && !c.PrintAccountXPub {
+		return fmt.Errorf("extendedkeyversion requires " +
+			"printaccountxpub")
+	}
+	if c.RecoveryWindow == 0 && !c.PrintAccountXPub && c.GapLimit == 0 {
+		return fmt.Errorf("recoverywindow must be greater than 0")
+	}
+	if c.GapLimit > 0 && (c.Format == formatDescriptors ||
+		c.Format == formatColdcard) {
+
+		return fmt.Errorf("gaplimit can't be used with the %s format",
+			c.Format)
 	}
+	var addrTypes addressTypes
+	if c.AddressTypes != "" {
+		if c.Format != formatImportWallet &&
+			c.Format != formatDescriptors {
 
-	// External branch first (<DerivationPath>/0/i).
-	for i := uint32(0); i < c.RecoveryWindow; i++ {
-		path := append(derivationPath, 0, i)
-		derivedKey, err := lnd.DeriveChildren(extendedKey, path)
+			return fmt.Errorf("addresstypes can't be used with "+
+				"the %s format", c.Format)
+		}
+		addrTypes, err = parseAddressTypes(c.AddressTypes)
 		if err != nil {
 			return err
 		}
-		err = printFn(derivedKey, c.DerivationPath, 0, i)
+	}
+	if c.GapLimit > 0 && cfg.APIURL == "" {
+		return fmt.Errorf("gaplimit requires a chain backend to look " +
+			"up the addresses, set one with --apiurl")
+	}
+	api := &btc.ExplorerAPI{
+		BaseURL: cfg.APIURL,
+		Timeout: cfg.APITimeout,
+		Retries: cfg.APIRetries,
+	}
+
+	// The private keys are only encoded for the formats that import them.
+	watchOnly := (c.Format == formatJSON && c.WatchOnly) ||
+		c.Format == formatWatchOnly || c.Format == formatTaproot
+
+	// The account xpubs are printed first. They are all that's needed to
+	// set up a watch-only wallet, so the keys can be skipped.
+	if c.PrintAccountXPub {
+		err := c.printAccountXPubs(w, extendedKey, derivationPaths)
 		if err != nil {
 			return err
 		}
+		if c.RecoveryWindow == 0 {
+			return nil
+		}
+	}
+	if c.Format == formatDescriptors {
+		pathStrings, derivationPaths = filterAddressTypePaths(
+			pathStrings, derivationPaths, addrTypes,
+		)
+		if len(derivationPaths) == 0 {
+			return fmt.Errorf("none of the derivation paths has "+
+				"one of the address types %s", c.AddressTypes)
+		}
+		return c.printDescriptors(
+			w, extendedKey, pathStrings, derivationPaths,
+		)
+	}
+	if c.Format == formatColdcard {
+		return printColdcardWallet(
+			w, extendedKey, pathStrings, derivationPaths,
+		)
 	}
 
-	// Now the internal branch (<DerivationPath>/1/i).
-	for i := uint32(0); i < c.RecoveryWindow; i++ {
-		path := append(derivationPath, 1, i)
-		derivedKey, err := lnd.DeriveChildren(extendedKey, path)
+	// The JSON output is collected and printed at the end, so it is always
+	// a valid JSON array.
+	var (
+		printFn     printFunc
+		derivedKeys []*DerivedKey
+	)
+	switch {
+	case c.Format == formatJSON:
+		printFn = func(_ io.Writer, key *DerivedKey, _ string) error {
+			derivedKeys = append(derivedKeys, key)
+			return nil
+		}
+
+	default:
+		fmt.Fprintf(w, "# Wallet dump created by chantools on %s\n",
+			time.Now().UTC())
+	}
+
+	if c.LncliBackup != "" {
+		keyRing := &lnd.HDKeyRing{
+			ExtendedKey: extendedKey,
+			ChainParams: chainParams,
+		}
+		multi, err := readLncliBackup(c.LncliBackup, keyRing)
 		if err != nil {
 			return err
 		}
-		err = printFn(derivedKey, c.DerivationPath, 1, i)
+		printBackupChannels(w, multi)
+	}
+
+	// Determine the format.
+	if printFn == nil {
+		printFn = importScriptPrintFn(
+			w, c.Format, importWalletBirthday(c.RescanFrom),
+			addrTypes,
+		)
+	}
+
+	// Deriving the addresses of a large number of keys can take minutes,
+	// so we show that we're still working on it. The progress goes to
+	// stderr, the keys might be written to stdout.
+	var progress *progressReporter
+	if c.GapLimit == 0 && c.RecoveryWindow >= progressMinKeys && !c.Quiet {
+		progress = newProgressReporter(
+			2 * uint64(c.RecoveryWindow) *
+				uint64(len(derivationPaths)),
+		)
+		progress.Start()
+		defer progress.Stop()
+	}
+
+	for idx, derivationPath := range derivationPaths {
+		var branchKeys [2][]*DerivedKey
+		switch {
+		case c.GapLimit > 0:
+			branchKeys, err = scanGapLimit(
+				api, extendedKey, derivationPath,
+				pathStrings[idx], c.GapLimit, watchOnly,
+			)
+			if err != nil {
+				return err
+			}
+
+		default:
+			keys, err := deriveBranchKeys(
+				extendedKey, derivationPath, pathStrings[idx],
+				c.RecoveryWindow, c.Workers, watchOnly,
+				progress,
+			)
+			if err != nil {
+				return err
+			}
+			branchKeys[0] = keys[:c.RecoveryWindow]
+			branchKeys[1] = keys[c.RecoveryWindow:]
+		}
+
+		// External branch first (<DerivationPath>/0/i), then the
+		// internal branch (<DerivationPath>/1/i).
+		for _, keys := range branchKeys {
+			for _, key := range keys {
+				err = printFn(w, key, labelPrefixes[idx])
+				if err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	progress.Stop()
+
+	if c.Format == formatJSON {
+		content, err := json.MarshalIndent(derivedKeys, "", " ")
 		if err != nil {
 			return err
 		}
+		fmt.Fprintln(w, string(content))
+		return nil
 	}
 
-	fmt.Printf("bitcoin-cli rescanblockchain %d\n", c.RescanFrom)
+	// All keys of all paths are imported without a rescan, so a single
+	// rescan at the end is enough.
+	fmt.Fprintf(w, "bitcoin-cli rescanblockchain %d\n", c.RescanFrom)
+
+	// The user still needs to know how to continue without having to open
+	// the file with the keys.
+	if c.OutputFile != "" {
+		fmt.Printf("Wrote the script to %s. After importing it, run:\n"+
+			"bitcoin-cli rescanblockchain %d\n", c.OutputFile,
+			c.RescanFrom)
+	}
 	return nil
 }
 
-func printBitcoinCli(hdKey *hdkeychain.ExtendedKey, path string,
-	branch, index uint32) error {
-
-	privKey, err := hdKey.ECPrivKey()
-	if err != nil {
-		return fmt.Errorf("could not derive private key: %v",
-			err)
+// createOutputFile creates a file that is only readable by the current user.
+// An existing file is only replaced if overwrite is set.
+func createOutputFile(fileName string, overwrite bool) (*os.File, error) {
+	flag := os.O_WRONLY | os.O_CREATE | os.O_EXCL
+	if overwrite {
+		flag = os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+	}
+	f, err := os.OpenFile(fileName, flag, 0600)
+	if os.IsExist(err) {
+		return nil, fmt.Errorf("output file %s already exists, use "+
+			"--overwrite to replace it", fileName)
 	}
-	wif, err := btcutil.NewWIF(privKey, chainParams, true)
 	if err != nil {
-		return fmt.Errorf("could not encode WIF: %v", err)
+		return nil, fmt.Errorf("error creating output file: %v", err)
+	}
+
+	// An existing file keeps its permissions, make sure it doesn't stay
+	// readable by others.
+	if err := f.Chmod(0600); err != nil {
+		_ = f.Close()
+		return nil, fmt.Errorf("error setting permissions of output "+
+			"file: %v", err)
+	}
+	return f, nil
+}
+
+// scanGapLimit derives the keys of the external and internal branch below the
+// given path until the given number of consecutive keys have an unused
+// address. Only the address type of the path's purpose is looked up, unless
+// it's not a known purpose.
+func scanGapLimit(api *btc.ExplorerAPI, rootKey *hdkeychain.ExtendedKey,
+	path []uint32, pathString string, gapLimit uint32,
+	watchOnly bool) ([2][]*DerivedKey, error) {
+
+	var branchKeys [2][]*DerivedKey
+	for branch := uint32(0); branch <= 1; branch++ {
+		branchPath := append(append([]uint32{}, path...), branch)
+		branchKey, err := lnd.DeriveChildren(rootKey, branchPath)
+		if err != nil {
+			return branchKeys, err
+		}
+
+		var keys []*DerivedKey
+		for gap := uint32(0); gap < gapLimit; {
+			index := uint32(len(keys))
+			hdKey, err := branchKey.Child(index)
+			if err != nil {
+				return branchKeys, err
+			}
+			key, err := newDerivedKey(
+				hdKey, pathString, branch, index, watchOnly,
+			)
+			if err != nil {
+				return branchKeys, err
+			}
+			keys = append(keys, key)
+
+			used, err := keyUsed(api, key, path)
+			if err != nil {
+				return branchKeys, err
+			}
+			gap++
+			if used {
+				gap = 0
+			}
+		}
+		branchKeys[branch] = keys
+	}
+	return branchKeys, nil
+}
+
+// keyUsed looks up whether any address of the key that fits the purpose of the
+// path ever received funds.
+func keyUsed(api *btc.ExplorerAPI, key *DerivedKey, path []uint32) (bool,
+	error) {
+
+	var purpose uint32
+	if len(path) > 0 {
+		purpose = path[0]
+	}
+
+	var addrs []string
+	switch purpose {
+	case lnd.HardenedKeyStart + 44:
+		addrs = []string{key.AddrP2PKH}
+
+	case lnd.HardenedKeyStart + 49:
+		addrs = []string{key.AddrNP2WKH}
+
+	case lnd.HardenedKeyStart + 84:
+		addrs = []string{key.AddrP2WKH}
+
+	case lnd.HardenedKeyStart + 86:
+		addrs = []string{key.AddrP2TR}
+
+	default:
+		addrs = []string{
+			key.AddrP2PKH, key.AddrNP2WKH, key.AddrP2WKH,
+			key.AddrP2TR,
+		}
+	}
+	for _, addr := range addrs {
+		info, err := api.Address(addr)
+		if err != nil {
+			return false, fmt.Errorf("error looking up address "+
+				"%s: %v", addr, err)
+		}
+		if info.Used() {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// derivationJob is the derivation of a single key of a branch.
+type derivationJob struct {
+	branch uint32
+	index  uint32
+}
+
+// deriveBranchKeys derives the keys of the external and internal branch below
+// the given path with a pool of workers. The workers also calculate the public
+// key and all addresses of each key, which takes a lot longer than the
+// derivation itself. The key with the index i of the branch b is at position
+// b*window+i of the result, so the order doesn't depend on which worker
+// finishes first.
+func deriveBranchKeys(rootKey *hdkeychain.ExtendedKey, path []uint32,
+	pathString string, window, workers uint32, watchOnly bool,
+	progress *progressReporter) ([]*DerivedKey, error) {
+
+	var branchKeys [2]*hdkeychain.ExtendedKey
+	for branch := uint32(0); branch <= 1; branch++ {
+		branchPath := append(append([]uint32{}, path...), branch)
+		branchKey, err := lnd.DeriveChildren(rootKey, branchPath)
+		if err != nil {
+			return nil, err
+		}
+
+		// The public key of a private extended key is calculated and
+		// cached when it's first needed. We make sure that happens
+		// before the workers share the key.
+		if _, err := branchKey.ECPubKey(); err != nil {
+			return nil, err
+		}
+		branchKeys[branch] = branchKey
+	}
+
+	var (
+		keys = make([]*DerivedKey, 2*window)
+		jobs = make(chan derivationJob, 2*window)
+		errs = make(chan error, workers)
+		wg   sync.WaitGroup
+	)
+	for i := uint32(0); i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			for job := range jobs {
+				hdKey, err := branchKeys[job.branch].Child(
+					job.index,
+				)
+				if err != nil {
+					errs <- err
+					return
+				}
+				key, err := newDerivedKey(
+					hdKey, pathString, job.branch,
+					job.index, watchOnly,
+				)
+				if err != nil {
+					errs <- err
+					return
+				}
+				keys[job.branch*window+job.index] = key
+				progress.done()
+			}
+		}()
+	}
+	for branch := uint32(0); branch <= 1; branch++ {
+		for i := uint32(0); i < window; i++ {
+			jobs <- derivationJob{branch: branch, index: i}
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	select {
+	case err := <-errs:
+		return nil, err
+
+	default:
+		return keys, nil
+	}
+}
+
+// printAccountXPubs prints the account extended public key of every derivation
+// path, encoded with the configured SLIP-0132 version.
+func (c *genImportScriptCommand) printAccountXPubs(w io.Writer,
+	rootKey *hdkeychain.ExtendedKey, paths [][]uint32) error {
+
+	for _, path := range paths {
+		accountKey, err := lnd.DeriveChildren(rootKey, path)
+		if err != nil {
+			return fmt.Errorf("error deriving account key: %v", err)
+		}
+
+		var encoded string
+		switch {
+		case c.ExtendedKeyVersion != "":
+			encoded, err = slip132Encode(
+				accountKey, c.ExtendedKeyVersion,
+			)
+
+		default:
+			accountKey, err = accountKey.Neuter()
+			if err == nil {
+				encoded = accountKey.String()
+			}
+		}
+		if err != nil {
+			return fmt.Errorf("error encoding account key: %v",
+				err)
+		}
+		fmt.Fprintf(w, "xpub:%s\n", encoded)
 	}
-	fmt.Printf("bitcoin-cli importprivkey %s \"%s/%d/%d/"+
-		"\" false\n", wif.String(), path, branch,
-		index)
 	return nil
 }
 
-func printBitcoinCliWatchOnly(hdKey *hdkeychain.ExtendedKey, path string,
-	branch, index uint32) error {
+// printDescriptors prints a JSON array with one ranged descriptor for the
+// external and one for the internal branch of each derivation path that can be
+// passed to the importdescriptors command of bitcoin core as is.
+func (c *genImportScriptCommand) printDescriptors(w io.Writer,
+	rootKey *hdkeychain.ExtendedKey, pathStrings []string,
+	paths [][]uint32) error {
 
-	pubKey, err := hdKey.ECPubKey()
+	requests, err := descriptorRequests(
+		rootKey, pathStrings, paths, c.RecoveryWindow,
+		blockToTimestamp(c.RescanFrom), c.WatchOnly,
+	)
+	if err != nil {
+		return err
+	}
+
+	content, err := json.MarshalIndent(requests, "", " ")
 	if err != nil {
-		return fmt.Errorf("could not derive private key: %v",
-			err)
+		return err
 	}
-	fmt.Printf("bitcoin-cli importpubkey %x \"%s/%d/%d/"+
-		"\" false\n", pubKey.SerializeCompressed(),
-		path, branch, index)
+	fmt.Fprintln(w, string(content))
 	return nil
 }
 
-func printBitcoinImportWallet(hdKey *hdkeychain.ExtendedKey, path string,
-	branch, index uint32) error {
+// coldcardAccount is a single account of a wallet file in the generic JSON
+// format of Coldcard.
+type coldcardAccount struct {
+	Deriv string `json:"deriv"`
+	XPub  string `json:"xpub"`
+}
 
-	privKey, err := hdKey.ECPrivKey()
+// printColdcardWallet prints a watch-only wallet file in the generic JSON
+// format that Coldcard exports and wallets like Specter Desktop import. The
+// account xpub of every derivation path is listed under the key of its BIP43
+// purpose, all other paths are listed under the custom key.
+func printColdcardWallet(w io.Writer, rootKey *hdkeychain.ExtendedKey,
+	pathStrings []string, paths [][]uint32) error {
+
+	xfp, err := masterFingerprint(rootKey)
 	if err != nil {
-		return fmt.Errorf("could not derive private key: %v",
-			err)
+		return err
+	}
+	wallet := map[string]interface{}{
+		"xfp": xfp,
 	}
-	wif, err := btcutil.NewWIF(privKey, chainParams, true)
+	for idx, path := range paths {
+		accountKey, err := lnd.DeriveChildren(rootKey, path)
+		if err != nil {
+			return fmt.Errorf("error deriving account key: %v", err)
+		}
+		accountKey, err = accountKey.Neuter()
+		if err != nil {
+			return fmt.Errorf("error neutering account key: %v",
+				err)
+		}
+
+		name := "custom"
+		if isBIP43AccountPath(path) {
+			name = fmt.Sprintf(
+				"bip%d", path[0]-lnd.HardenedKeyStart,
+			)
+			account := path[2] - lnd.HardenedKeyStart
+			if _, ok := wallet["account"]; !ok {
+				wallet["account"] = account
+			}
+		}
+		if _, ok := wallet[name]; ok {
+			return fmt.Errorf("the coldcard format can only "+
+				"contain one %s path", name)
+		}
+		wallet[name] = &coldcardAccount{
+			Deriv: pathStrings[idx],
+			XPub:  accountKey.String(),
+		}
+	}
+	if _, ok := wallet["account"]; !ok {
+		wallet["account"] = 0
+	}
+
+	content, err := json.MarshalIndent(wallet, "", " ")
 	if err != nil {
-		return fmt.Errorf("could not encode WIF: %v", err)
+		return err
+	}
+	fmt.Fprintln(w, string(content))
+	return nil
+}
+
+// isBIP43AccountPath returns true if the path is the account path of one of
+// the BIP44, BIP49, BIP84 or BIP86 purposes, which is purpose'/coin'/account'.
+func isBIP43AccountPath(path []uint32) bool {
+	if len(path) != 3 {
+		return false
+	}
+	for _, index := range path {
+		if index < lnd.HardenedKeyStart {
+			return false
+		}
+	}
+	switch path[0] - lnd.HardenedKeyStart {
+	case 44, 49, 84, 86:
+		return true
+
+	default:
+		return false
+	}
+}
+
+// masterFingerprint returns the fingerprint of the master key of the given key
+// as hex. Only the master key itself and its direct children know the master
+// fingerprint.
+func masterFingerprint(key *hdkeychain.ExtendedKey) (string, error) {
+	switch key.Depth() {
+	case 0:
+		pubKey, err := key.ECPubKey()
+		if err != nil {
+			return "", fmt.Errorf("error deriving root pubkey: %v",
+				err)
+		}
+		return fmt.Sprintf("%X", btcutil.Hash160(
+			pubKey.SerializeCompressed(),
+		)[:4]), nil
+
+	case 1:
+		return fmt.Sprintf("%08X", key.ParentFingerprint()), nil
+
+	default:
+		return "", fmt.Errorf("the master fingerprint of a key with "+
+			"depth %d is unknown, use the master key instead",
+			key.Depth())
+	}
+}
+
+// descriptorRequests returns the import requests of one ranged descriptor for
+// the external and one for the internal branch of each derivation path. The
+// descriptors contain the account xprv unless watchOnly is set.
+func descriptorRequests(rootKey *hdkeychain.ExtendedKey, pathStrings []string,
+	paths [][]uint32, recoveryWindow uint32, timestamp int64,
+	watchOnly bool) ([]*btc.ImportDescriptorRequest, error) {
+
+	var requests []*btc.ImportDescriptorRequest
+	for idx, path := range paths {
+		accountKey, err := lnd.DeriveChildren(rootKey, path)
+		if err != nil {
+			return nil, fmt.Errorf("error deriving account key: %v",
+				err)
+		}
+		if watchOnly {
+			accountKey, err = accountKey.Neuter()
+			if err != nil {
+				return nil, fmt.Errorf("error neutering "+
+					"account key: %v", err)
+			}
+		}
+		keyOrigin, err := descriptorKeyOrigin(rootKey, pathStrings[idx])
+		if err != nil {
+			return nil, err
+		}
+
+		for branch := uint32(0); branch <= 1; branch++ {
+			desc, err := accountDescriptor(
+				path, keyOrigin, accountKey.String(), branch,
+			)
+			if err != nil {
+				return nil, err
+			}
+			request := &btc.ImportDescriptorRequest{
+				Desc:      desc,
+				Active:    true,
+				Range:     [2]uint32{0, recoveryWindow - 1},
+				Timestamp: timestamp,
+				Internal:  branch == 1,
+			}
+			requests = append(requests, request)
+		}
+	}
+	return requests, nil
+}
+
+// parseDerivationPaths parses a comma separated list of derivation paths.
+// Paths that are listed more than once are only returned once. The warning
+// about a duplicate path goes to stderr to not end up in the import script.
+func parseDerivationPaths(paths string) ([]string, [][]uint32, error) {
+	var (
+		pathStrings []string
+		parsedPaths [][]uint32
+		seen        = make(map[string]bool)
+	)
+	for _, pathString := range strings.Split(paths, ",") {
+		path, err := lnd.ParsePath(pathString)
+		if err != nil {
+			return nil, nil, fmt.Errorf("error parsing path %s: %v",
+				strings.TrimSpace(pathString), err)
+		}
+
+		// Paths in the h notation are normalized, so they are labeled
+		// the same and duplicates are found.
+		pathString = lnd.FormatPath(path)
+		if seen[pathString] {
+			_, _ = fmt.Fprintf(os.Stderr, "Ignoring duplicate "+
+				"derivation path %s\n", pathString)
+			continue
+		}
+		seen[pathString] = true
+
+		pathStrings = append(pathStrings, pathString)
+		parsedPaths = append(parsedPaths, path)
+	}
+	return pathStrings, parsedPaths, nil
+}
+
+// parseAccountRange parses a range of account indexes in the form first-last,
+// both ends are included.
+func parseAccountRange(accountRange string) ([]uint32, error) {
+	parts := strings.Split(accountRange, "-")
+	if len(parts) != 2 {
+		return nil, fmt.Errorf("invalid account range %s, must be in "+
+			"the form first-last", accountRange)
+	}
+	var bounds [2]uint32
+	for idx, part := range parts {
+		bound, err := strconv.ParseUint(
+			strings.TrimSpace(part), 10, 31,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("invalid account range %s: %v",
+				accountRange, err)
+		}
+		bounds[idx] = uint32(bound)
+	}
+	if bounds[0] > bounds[1] {
+		return nil, fmt.Errorf("invalid account range %s, first "+
+			"account is greater than last account", accountRange)
+	}
+
+	accounts := make([]uint32, 0, bounds[1]-bounds[0]+1)
+	for account := bounds[0]; account <= bounds[1]; account++ {
+		accounts = append(accounts, account)
+	}
+	return accounts, nil
+}
+
+// accountPaths returns all derivation paths for each of the given accounts,
+// with the third component of the paths replaced by the hardened account
+// index. The paths of the first account come first.
+func accountPaths(paths [][]uint32, accounts []uint32) ([]string, [][]uint32,
+	error) {
+
+	var (
+		pathStrings []string
+		newPaths    [][]uint32
+	)
+	for _, account := range accounts {
+		if account >= lnd.HardenedKeyStart {
+			return nil, nil, fmt.Errorf("invalid account %d",
+				account)
+		}
+		for _, path := range paths {
+			if len(path) < 3 {
+				return nil, nil, fmt.Errorf("derivation path "+
+					"%s has no account component",
+					lnd.FormatPath(path))
+			}
+			newPath := append([]uint32{}, path...)
+			newPath[2] = lnd.HardenedKeyStart + account
+			pathStrings = append(
+				pathStrings, lnd.FormatPath(newPath),
+			)
+			newPaths = append(newPaths, newPath)
+		}
+	}
+	return pathStrings, newPaths, nil
+}
+
+// parseAddressTypes parses a comma separated list of address types. The NP2WKH
+// type can be given as np2wkh or p2sh-p2wpkh.
+func parseAddressTypes(types string) (addressTypes, error) {
+	addrTypes := make(addressTypes)
+	for _, addrType := range strings.Split(types, ",") {
+		addrType = strings.ToLower(strings.TrimSpace(addrType))
+		switch addrType {
+		case addrTypeNameNP2WKH:
+			addrTypes[addrTypeNP2WKH] = true
+
+		case addrTypeP2PKH, addrTypeNP2WKH, addrTypeP2WKH,
+			addrTypeP2TR:
+
+			addrTypes[addrType] = true
+
+		default:
+			return nil, fmt.Errorf("unknown address type %s, must "+
+				"be one of %s, %s, %s or %s", addrType,
+				addrTypeP2PKH, addrTypeNameNP2WKH,
+				addrTypeP2WKH, addrTypeP2TR)
+		}
+	}
+	return addrTypes, nil
+}
+
+// pathAddressType returns the address type of the descriptor of a derivation
+// path, which is determined by its purpose the same way as in
+// accountDescriptor.
+func pathAddressType(path []uint32) string {
+	switch path[0] {
+	case lnd.HardenedKeyStart + 44:
+		return addrTypeP2PKH
+
+	case lnd.HardenedKeyStart + 49:
+		return addrTypeNP2WKH
+
+	case lnd.HardenedKeyStart + 86:
+		return addrTypeP2TR
+
+	default:
+		return addrTypeP2WKH
+	}
+}
+
+// filterAddressTypePaths returns only the derivation paths with one of the
+// given address types.
+func filterAddressTypePaths(pathStrings []string, paths [][]uint32,
+	addrTypes addressTypes) ([]string, [][]uint32) {
+
+	var (
+		filteredStrings []string
+		filteredPaths   [][]uint32
+	)
+	for idx, path := range paths {
+		if !addrTypes.contains(pathAddressType(path)) {
+			continue
+		}
+		filteredStrings = append(filteredStrings, pathStrings[idx])
+		filteredPaths = append(filteredPaths, path)
+	}
+	return filteredStrings, filteredPaths
+}
+
+// importScriptPrintFn returns the function that prints a single key in the
+// given import script format and prints the instructions for that format to
+// the given writer. The birthday is used as the creation time of the keys in
+// formats that have one. Only the addresses of the given address types are
+// listed in formats that list the addresses of a key.
+func importScriptPrintFn(w io.Writer, format string, birthday time.Time,
+	addrTypes addressTypes) printFunc {
+
+	switch format {
+	default:
+		fallthrough
+
+	case "bitcoin-cli":
+		fmt.Fprintln(w, "# Paste the following lines into a command "+
+			"line window.")
+		return printBitcoinCli
+
+	case formatWatchOnly:
+		fmt.Fprintln(w, "# Paste the following lines into a command "+
+			"line window.")
+		return printBitcoinCliWatchOnly
+
+	case formatTaproot:
+		fmt.Fprintln(w, "# Paste the following lines into a command "+
+			"line window.")
+		return printBitcoinCliTaproot
+
+	case formatImportWallet:
+		fmt.Fprintln(w, "# Save this output to a file and use the "+
+			"importwallet command of bitcoin core.")
+		return func(w io.Writer, key *DerivedKey,
+			labelPrefix string) error {
+
+			return printBitcoinImportWallet(
+				w, key, labelPrefix, birthday, addrTypes,
+			)
+		}
+	}
+}
+
+// printBackupChannels prints the channels of a channel backup as comments. The
+// funds of these channels are not in the on-chain wallet and need to be
+// recovered by closing the channels. With the static remote key format, the
+// funds of a force close by the remote party are paid to the payment base
+// point of the channel.
+func printBackupChannels(w io.Writer, multi *chanbackup.Multi) {
+	fmt.Fprintf(w, "# The channel backup contains %d channels. Their "+
+		"funds are not covered by this\n# script and need to be "+
+		"recovered by closing the channels:\n",
+		len(multi.StaticBackups))
+	for _, single := range multi.StaticBackups {
+		fmt.Fprintf(w, "# %v: capacity %d sat, remote node %x",
+			single.FundingOutpoint, single.Capacity,
+			single.RemoteNodePub.SerializeCompressed())
+		if single.Version == chanbackup.TweaklessCommitVersion {
+			paymentBase := single.LocalChanCfg.PaymentBasePoint
+			fmt.Fprintf(w, ", static remote key paid to %s",
+				keyLocatorPath(paymentBase.KeyLocator))
+		}
+		fmt.Fprintln(w)
+	}
+}
+
+func printBitcoinCli(w io.Writer, key *DerivedKey, labelPrefix string) error {
+	if key.WIF == "" {
+		return fmt.Errorf("could not derive private key")
+	}
+	fmt.Fprintf(w, "bitcoin-cli importprivkey %s \"%s%s/%d/%d/"+
+		"\" false\n", key.WIF, labelPrefix, key.Path, key.Branch,
+		key.Index)
+	return printBitcoinCliTaproot(w, key, labelPrefix)
+}
+
+func printBitcoinCliWatchOnly(w io.Writer, key *DerivedKey,
+	labelPrefix string) error {
+
+	fmt.Fprintf(w, "bitcoin-cli importpubkey %s \"%s%s/%d/%d/"+
+		"\" false\n", key.PubKeyHex, labelPrefix, key.Path,
+		key.Branch, key.Index)
+	return printBitcoinCliTaproot(w, key, labelPrefix)
+}
+
+// printBitcoinCliTaproot prints the P2TR address of the key as a watch-only
+// import. The importpubkey command only watches the P2PKH, NP2WKH and P2WKH
+// scripts of a key, so the address itself needs to be imported.
+func printBitcoinCliTaproot(w io.Writer, key *DerivedKey,
+	labelPrefix string) error {
+
+	fmt.Fprintf(w, "bitcoin-cli importaddress %s \"%s%s/%d/%d/"+
+		"\" false\n", key.AddrP2TR, labelPrefix, key.Path, key.Branch,
+		key.Index)
+	return nil
+}
+
+// printBitcoinImportWallet prints a key in the dumpwallet format of bitcoin
+// core. The birthday is the creation time of the key, bitcoin core only scans
+// the blocks after it for the key's transactions. The comment lists the
+// addresses of the given address types.
+func printBitcoinImportWallet(w io.Writer, key *DerivedKey, labelPrefix string,
+	birthday time.Time, addrTypes addressTypes) error {
+
+	if key.WIF == "" {
+		return fmt.Errorf("could not derive private key")
+	}
+
+	var addrs []string
+	for _, addr := range []struct {
+		addrType string
+		address  string
+	}{
+		{addrTypeP2PKH, key.AddrP2PKH},
+		{addrTypeNP2WKH, key.AddrNP2WKH},
+		{addrTypeP2WKH, key.AddrP2WKH},
+		{addrTypeP2TR, key.AddrP2TR},
+	} {
+		if addrTypes.contains(addr.addrType) {
+			addrs = append(addrs, addr.address)
+		}
+	}
+	fmt.Fprintf(w, "%s %s label=%s%s/%d/%d/ # addr=%s\n",
+		key.WIF, birthday.UTC().Format(importWalletTimeFormat),
+		labelPrefix, key.Path, key.Branch, key.Index,
+		strings.Join(addrs, ","),
+	)
+	return nil
+}
+
+// importWalletBirthday returns the creation time of the keys of a wallet that
+// needs to be scanned from the given block. The first second after the epoch
+// means "unknown" in the dumpwallet format and is used if there is no better
+// estimate.
+func importWalletBirthday(rescanFrom uint32) time.Time {
+	timestamp := blockToTimestamp(rescanFrom)
+	if timestamp <= 0 {
+		timestamp = 1
+	}
+	return time.Unix(timestamp, 0)
+}
+
+// newDerivedKey returns the WIF, the public key and all addresses of a derived
+// key. The WIF is only set if the key isn't for watch-only use.
+func newDerivedKey(hdKey *hdkeychain.ExtendedKey, path string, branch,
+	index uint32, watchOnly bool) (*DerivedKey, error) {
+
+	var privKey *btcec.PrivateKey
+	if !watchOnly {
+		var err error
+		privKey, err = hdKey.ECPrivKey()
+		if err != nil {
+			return nil, fmt.Errorf("could not derive private "+
+				"key: %v", err)
+		}
 	}
 	pubKey, err := hdKey.ECPubKey()
 	if err != nil {
-		return fmt.Errorf("could not derive private key: %v",
-			err)
+		return nil, fmt.Errorf("could not derive public key: %v", err)
+	}
+
+	key, err := newLeafKey(privKey, pubKey)
+	if err != nil {
+		return nil, err
 	}
+	key.Path = path
+	key.Branch = branch
+	key.Index = index
+	return key, nil
+}
+
+// newLeafKey returns the WIF, the public key and all addresses of a single key
+// without any derivation information. The WIF is only set if the private key
+// is given.
+func newLeafKey(privKey *btcec.PrivateKey,
+	pubKey *btcec.PublicKey) (*DerivedKey, error) {
+
+	key := &DerivedKey{
+		PubKeyHex: hex.EncodeToString(pubKey.SerializeCompressed()),
+	}
+	if privKey != nil {
+		wif, err := btcutil.NewWIF(privKey, chainParams, true)
+		if err != nil {
+			return nil, fmt.Errorf("could not encode WIF: %v", err)
+		}
+		key.WIF = wif.String()
+	}
+
 	hash160 := btcutil.Hash160(pubKey.SerializeCompressed())
 	addrP2PKH, err := btcutil.NewAddressPubKeyHash(hash160, chainParams)
 	if err != nil {
-		return fmt.Errorf("could not create address: %v", err)
+		return nil, fmt.Errorf("could not create address: %v", err)
 	}
 	addrP2WKH, err := btcutil.NewAddressWitnessPubKeyHash(
 		hash160, chainParams,
 	)
 	if err != nil {
-		return fmt.Errorf("could not create address: %v", err)
+		return nil, fmt.Errorf("could not create address: %v", err)
 	}
 	script, err := txscript.PayToAddrScript(addrP2WKH)
 	if err != nil {
-		return fmt.Errorf("could not create script: %v", err)
+		return nil, fmt.Errorf("could not create script: %v", err)
 	}
 	addrNP2WKH, err := btcutil.NewAddressScriptHash(script, chainParams)
 	if err != nil {
-		return fmt.Errorf("could not create address: %v", err)
+		return nil, fmt.Errorf("could not create address: %v", err)
+	}
+	key.AddrP2TR, err = taprootAddress(pubKey)
+	if err != nil {
+		return nil, err
 	}
+	key.AddrP2PKH = addrP2PKH.EncodeAddress()
+	key.AddrNP2WKH = addrNP2WKH.EncodeAddress()
+	key.AddrP2WKH = addrP2WKH.EncodeAddress()
+	return key, nil
+}
 
-	fmt.Printf("%s 1970-01-01T00:00:01Z label=%s/%d/%d/ "+
-		"# addr=%s,%s,%s\n", wif.String(), path, branch, index,
-		addrP2PKH.EncodeAddress(), addrNP2WKH.EncodeAddress(),
-		addrP2WKH.EncodeAddress(),
+// taprootAddress returns the BIP86 P2TR address of a key, which is the key
+// path only output that commits to no script.
+func taprootAddress(pubKey *btcec.PublicKey) (string, error) {
+	_, outputKey, err := btc.TaprootOutputKey(pubKey, nil)
+	if err != nil {
+		return "", fmt.Errorf("error tweaking key: %v", err)
+	}
+	addr, err := btc.EncodeSegWitAddress(
+		chainParams.Bech32HRPSegwit, taprootWitnessVersion,
+		outputKey.SerializeCompressed()[1:],
 	)
-	return nil
+	if err != nil {
+		return "", fmt.Errorf("could not create address: %v", err)
+	}
+	return addr, nil
 }
 
+// seedBirthdayToBlock returns the height of the first block that was mined
+// after the given birthday of a seed. The block is looked up with the API if
+// one is configured and estimated otherwise.
 func seedBirthdayToBlock(birthdayTimestamp time.Time) uint32 {
-	var genesisTimestamp time.Time
+	if cfg.APIURL == "" {
+		log.Warnf("No API URL configured, estimating the block of " +
+			"the seed birthday instead")
+		return estimateBirthdayBlock(birthdayTimestamp)
+	}
+
+	api := &btc.ExplorerAPI{
+		BaseURL: cfg.APIURL,
+		Timeout: cfg.APITimeout,
+		Retries: cfg.APIRetries,
+	}
+	height, err := api.FirstBlockAfter(birthdayTimestamp)
+	if err != nil {
+		log.Warnf("Could not look up the block of the seed birthday "+
+			"with the API, estimating it instead: %v", err)
+		return estimateBirthdayBlock(birthdayTimestamp)
+	}
+	return height
+}
+
+// estimateBirthdayBlock estimates the height of the first block that was mined
+// after the given birthday of a seed without any API calls.
+func estimateBirthdayBlock(birthdayTimestamp time.Time) uint32 {
+	if checkpoints := networkCheckpoints(); checkpoints != nil {
+		return checkpointHeight(checkpoints, birthdayTimestamp)
+	}
+
+	genesisTimestamp, ok := genesisTimestamp()
+	if !ok {
+		return 0
+	}
+
+	// With the timestamps retrieved, we can estimate a block height by
+	// taking the difference between them and dividing by the average block
+	// time (10 minutes).
+	return uint32(birthdayTimestamp.Sub(genesisTimestamp).Seconds() / 600)
+}
+
+// defaultRescanFromHeight returns the default block to rescan from on the
+// current network. The default block is past the tip of signet, so the whole
+// chain is scanned there instead.
+func defaultRescanFromHeight() uint32 {
+	if chainParams.Name == btc.SigNetParams.Name {
+		return 0
+	}
+	return defaultRescanFrom
+}
+
+// blockToTimestamp is the inverse of estimateBirthdayBlock and estimates the
+// timestamp of a block the same way. This results in the original birthday for
+// a block number that was estimated from a birthday.
+func blockToTimestamp(height uint32) int64 {
+	if checkpoints := networkCheckpoints(); checkpoints != nil {
+		return checkpointTimestamp(checkpoints, height)
+	}
+
+	genesisTimestamp, ok := genesisTimestamp()
+	if !ok {
+		return 0
+	}
+	return genesisTimestamp.Add(time.Duration(height) * 10 * time.Minute).
+		Unix()
+}
+
+// genesisTimestamp returns the timestamp of the genesis block of the current
+// network. False is returned for test networks that don't have a meaningful
+// genesis timestamp, for which the whole chain should be scanned.
+func genesisTimestamp() (time.Time, bool) {
 	switch chainParams.Name {
 	case "mainnet":
-		genesisTimestamp =
-			chaincfg.MainNetParams.GenesisBlock.Header.Timestamp
+		return chaincfg.MainNetParams.GenesisBlock.Header.Timestamp,
+			true
 
 	case "testnet3":
-		genesisTimestamp =
-			chaincfg.TestNet3Params.GenesisBlock.Header.Timestamp
+		return chaincfg.TestNet3Params.GenesisBlock.Header.Timestamp,
+			true
+
+	case btc.SigNetParams.Name:
+		return btc.SigNetParams.GenesisBlock.Header.Timestamp, true
 
 	case "regtest", "simnet":
-		return 0
+		return time.Time{}, false
 
 	default:
 		panic(fmt.Errorf("unimplemented network %v", chainParams.Name))
 	}
-
-	// With the timestamps retrieved, we can estimate a block height by
-	// taking the difference between them and dividing by the average block
-	// time (10 minutes).
-	return uint32(birthdayTimestamp.Sub(genesisTimestamp).Seconds() / 600)
 }
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+const (
+	testRootKey = "tprv8ZgxMBicQKsPd7Uf69XL1XwhmjHopUGep8GuEiJDZmbQz6o5" +
+		"8LninorQAfcKZWARbtRtfnLcJ5MQ2AtHcQJCCRUcMRvmDUjyEmNUWwx8UbK"
+)
+
+// BenchmarkGenImportScript benchmarks the creation of an import script with
+// the default recovery window, once with a single worker and once with a
+// worker per CPU.
+func BenchmarkGenImportScript(b *testing.B) {
+	tempDir, err := ioutil.TempDir("", "chantools")
+	if err != nil {
+		b.Fatalf("error creating temp dir: %v", err)
+	}
+	defer func() {
+		_ = os.RemoveAll(tempDir)
+	}()
+
+	// The command tells us where the script was written to, which we don't
+	// need to see for every run.
+	devNull, err := os.OpenFile(os.DevNull, os.O_WRONLY, 0)
+	if err != nil {
+		b.Fatalf("error opening %s: %v", os.DevNull, err)
+	}
+	stdout := os.Stdout
+	os.Stdout = devNull
+	defer func() {
+		os.Stdout = stdout
+		_ = devNull.Close()
+	}()
+
+	for _, workers := range []uint32{1, uint32(runtime.NumCPU())} {
+		workers := workers
+		b.Run(fmt.Sprintf("workers=%d", workers), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				cmd := &genImportScriptCommand{
+					RootKey:        testRootKey,
+					Format:         "bitcoin-cli",
+					RecoveryWindow: defaultRecoveryWindow,
+					Workers:        workers,
+					OutputFile: filepath.Join(
+						tempDir, "script.txt",
+					),
+					Overwrite: true,
+				}
+				if err := cmd.Execute(nil); err != nil {
+					b.Fatalf("error creating script: %v",
+						err)
+				}
+			}
+		})
+	}
+}
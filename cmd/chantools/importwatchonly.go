@@ -0,0 +1,250 @@
+package main
+
+import (
+	"encoding/binary"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/btcsuite/btcutil"
+	"github.com/btcsuite/btcutil/hdkeychain"
+	"github.com/guggero/chantools/btc"
+	"github.com/guggero/chantools/lnd"
+)
+
+const (
+	rescanPollInterval = 5 * time.Second
+)
+
+type importWatchOnlyCommand struct {
+	XPub           string `long:"xpub" description:"The extended public key of the account to import. Leave empty to derive it from the root key."`
+	RootKey        string `long:"rootkey" description:"BIP32 HD root key to derive the account xpub from. Leave empty to prompt for lnd 24 word aezeed."`
+	DerivationPath string `long:"derivationpath" description:"The derivation path of the account. The purpose field determines the script type of the descriptors (44: pkh, 49: sh(wpkh), 84: wpkh, 86: tr). (default m/84'/0'/0')"`
+	RecoveryWindow uint32 `long:"recoverywindow" description:"The number of keys to import per internal/external branch. (default 2500)"`
+	RescanFrom     uint32 `long:"rescanfrom" description:"The block number to rescan from. Will be set automatically from the wallet birthday if the lnd 24 word aezeed is entered. (default 500000, 0 on signet)"`
+}
+
+func (c *importWatchOnlyCommand) Execute(_ []string) error {
+	setupChainParams(cfg)
+
+	// Set default values.
+	if c.DerivationPath == "" {
+		c.DerivationPath = defaultDerivationPath
+	}
+	if c.RecoveryWindow == 0 {
+		c.RecoveryWindow = defaultRecoveryWindow
+	}
+	derivationPath, err := lnd.ParsePath(c.DerivationPath)
+	if err != nil {
+		return fmt.Errorf("error parsing path: %v", err)
+	}
+
+	// Either use the given xpub directly or derive it from the root key,
+	// in which case we also know the key origin.
+	var (
+		accountKey *hdkeychain.ExtendedKey
+		keyOrigin  string
+	)
+	switch {
+	case c.XPub != "":
+		accountKey, err = hdkeychain.NewKeyFromString(c.XPub)
+		if err != nil {
+			return fmt.Errorf("error parsing xpub: %v", err)
+		}
+
+	default:
+		var birthday time.Time
+		accountKey, keyOrigin, birthday, err = c.deriveAccountKey(
+			derivationPath,
+		)
+		if err != nil {
+			return err
+		}
+
+		// The btcwallet gives the birthday a slack of 48 hours, let's
+		// do the same.
+		if !birthday.IsZero() && c.RescanFrom == 0 {
+			c.RescanFrom = seedBirthdayToBlock(
+				birthday.Add(-48 * time.Hour),
+			)
+		}
+	}
+	if c.RescanFrom == 0 {
+		c.RescanFrom = defaultRescanFromHeight()
+	}
+
+	// We only ever want to import the public key.
+	accountKey, err = accountKey.Neuter()
+	if err != nil {
+		return fmt.Errorf("error neutering account key: %v", err)
+	}
+
+	bitcoind := newBitcoind(cfg)
+	header, err := bitcoind.GetBlockHeader(c.RescanFrom)
+	if err != nil {
+		return fmt.Errorf("error fetching block %d: %v", c.RescanFrom,
+			err)
+	}
+
+	// Create one descriptor for the external and one for the internal
+	// branch.
+	var requests []*btc.ImportDescriptorRequest
+	for branch := uint32(0); branch <= 1; branch++ {
+		desc, err := accountDescriptor(
+			derivationPath, keyOrigin, accountKey.String(), branch,
+		)
+		if err != nil {
+			return err
+		}
+		log.Infof("Importing descriptor %s", desc)
+		requests = append(requests, &btc.ImportDescriptorRequest{
+			Desc:      desc,
+			Active:    true,
+			Range:     [2]uint32{0, c.RecoveryWindow - 1},
+			Timestamp: header.Time,
+			Internal:  branch == 1,
+		})
+	}
+
+	// The import call only returns after the rescan is complete, so we run
+	// it in the background and report the progress in the meantime.
+	type importResult struct {
+		results []*btc.ImportDescriptorResult
+		err     error
+	}
+	resultChan := make(chan importResult, 1)
+	go func() {
+		results, err := bitcoind.ImportDescriptors(requests)
+		resultChan <- importResult{results: results, err: err}
+	}()
+
+	log.Infof("Rescanning from block %d, this can take a while",
+		c.RescanFrom)
+	for {
+		select {
+		case result := <-resultChan:
+			if result.err != nil {
+				return fmt.Errorf("error importing "+
+					"descriptors: %v", result.err)
+			}
+			for idx, res := range result.results {
+				if !res.Success {
+					return fmt.Errorf("error importing "+
+						"descriptor %s: %s",
+						requests[idx].Desc,
+						importErrorMessage(res))
+				}
+				for _, warning := range res.Warnings {
+					log.Warnf("Import warning: %s", warning)
+				}
+			}
+			log.Infof("Import of %d keys complete",
+				2*c.RecoveryWindow)
+			return nil
+
+		case <-time.After(rescanPollInterval):
+			info, err := bitcoind.GetWalletInfo()
+			if err != nil {
+				log.Errorf("Error fetching wallet info: %v",
+					err)
+				continue
+			}
+			if progress, ok := info.ScanProgress(); ok {
+				log.Infof("Rescan progress: %.2f%%",
+					progress*100)
+			}
+		}
+	}
+}
+
+// deriveAccountKey reads the root key and derives the account key at the given
+// path. The wallet birthday is only returned if an aezeed was entered.
+func (c *importWatchOnlyCommand) deriveAccountKey(path []uint32) (
+	*hdkeychain.ExtendedKey, string, time.Time, error) {
+
+	var (
+		extendedKey *hdkeychain.ExtendedKey
+		birthday    time.Time
+		err         error
+	)
+
+	// Check that root key is valid or fall back to console input.
+	switch {
+	case c.RootKey != "":
+		extendedKey, err = hdkeychain.NewKeyFromString(c.RootKey)
+
+	default:
+		extendedKey, birthday, err = rootKeyFromConsole()
+		if err == nil && birthday.IsZero() {
+			log.Warn(bip39BirthdayWarning)
+		}
+	}
+	if err != nil {
+		return nil, "", birthday, fmt.Errorf("error reading root key: "+
+			"%v", err)
+	}
+
+	accountKey, err := lnd.DeriveChildren(extendedKey, path)
+	if err != nil {
+		return nil, "", birthday, fmt.Errorf("error deriving account "+
+			"key: %v", err)
+	}
+	keyOrigin, err := descriptorKeyOrigin(
+		extendedKey, lnd.FormatPath(path),
+	)
+	if err != nil {
+		return nil, "", birthday, err
+	}
+	return accountKey, keyOrigin, birthday, nil
+}
+
+// descriptorKeyOrigin returns the key origin information of a descriptor in
+// the format <fingerprint>/<path> for the given root key and path.
+func descriptorKeyOrigin(rootKey *hdkeychain.ExtendedKey, path string) (string,
+	error) {
+
+	rootPubKey, err := rootKey.ECPubKey()
+	if err != nil {
+		return "", fmt.Errorf("error deriving root pubkey: %v", err)
+	}
+	fingerprint := btcutil.Hash160(rootPubKey.SerializeCompressed())[:4]
+	return fmt.Sprintf(
+		"%08x%s", binary.BigEndian.Uint32(fingerprint),
+		strings.TrimPrefix(path, "m"),
+	), nil
+}
+
+// accountDescriptor returns the ranged descriptor including its checksum for
+// one branch of the given account key. The script type is determined by the
+// purpose of the derivation path.
+func accountDescriptor(path []uint32, keyOrigin, xpub string,
+	branch uint32) (string, error) {
+
+	key := fmt.Sprintf("%s/%d/*", xpub, branch)
+	if keyOrigin != "" {
+		key = fmt.Sprintf("[%s]%s", keyOrigin, key)
+	}
+
+	var desc string
+	switch path[0] {
+	case lnd.HardenedKeyStart + 44:
+		desc = fmt.Sprintf("pkh(%s)", key)
+
+	case lnd.HardenedKeyStart + 49:
+		desc = fmt.Sprintf("sh(wpkh(%s))", key)
+
+	case lnd.HardenedKeyStart + 86:
+		desc = fmt.Sprintf("tr(%s)", key)
+
+	default:
+		desc = fmt.Sprintf("wpkh(%s)", key)
+	}
+	return btc.DescriptorWithChecksum(desc)
+}
+
+func importErrorMessage(result *btc.ImportDescriptorResult) string {
+	if result.Error == nil {
+		return "unknown error"
+	}
+	return result.Error.Message
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+
+	"github.com/btcsuite/btcutil"
+	"github.com/btcsuite/btcutil/hdkeychain"
+	"github.com/guggero/chantools/dump"
+	"github.com/guggero/chantools/lnd"
+	"github.com/lightningnetwork/lnd/chanbackup"
+)
+
+// inspectedBackup is the content of a channel backup file as it is printed by
+// the inspectchanbackup command. The version and the channels are only known
+// if the backup was decrypted.
+type inspectedBackup struct {
+	Decrypted      bool                           `json:"decrypted"`
+	FileSize       int                            `json:"file_size"`
+	Nonce          string                         `json:"nonce"`
+	CiphertextSize int                            `json:"ciphertext_size"`
+	Version        *chanbackup.MultiBackupVersion `json:"version,omitempty"`
+	NumChannels    *int                           `json:"num_channels,omitempty"`
+	Channels       []*inspectedChannel            `json:"channels,omitempty"`
+}
+
+// inspectedChannel is a single channel of a decrypted channel backup.
+type inspectedChannel struct {
+	ChannelPoint string           `json:"channel_point"`
+	PeerPubKey   string           `json:"peer_pubkey"`
+	LocalKeyDesc inspectedKeyDesc `json:"local_key_desc"`
+	CapacityHint btcutil.Amount   `json:"capacity_hint,omitempty"`
+	CsvDelay     uint16           `json:"csv_delay"`
+}
+
+// inspectedKeyDesc is the descriptor of the local multisig key of a channel,
+// which is the key needed to sign the funding output.
+type inspectedKeyDesc struct {
+	Family uint32 `json:"family"`
+	Index  uint32 `json:"index"`
+	Path   string `json:"path"`
+	PubKey string `json:"pubkey"`
+}
+
+type inspectChanBackupCommand struct {
+	MultiFile string `long:"multi_file" description:"The lnd channel.backup file to inspect."`
+	RootKey   string `long:"rootkey" description:"BIP32 HD root key of the wallet that was used to create the backup. If set, the channels are decrypted and shown as well. Leave empty to only inspect the encryption envelope."`
+}
+
+func (c *inspectChanBackupCommand) Execute(_ []string) error {
+	setupChainParams(cfg)
+
+	// Check that we have a backup file.
+	if c.MultiFile == "" {
+		return fmt.Errorf("backup file is required")
+	}
+	content, err := ioutil.ReadFile(cleanAndExpandPath(c.MultiFile))
+	if err != nil {
+		return fmt.Errorf("error reading backup file: %v", err)
+	}
+
+	// The whole multi backup is encrypted, so the nonce in front of the
+	// ciphertext is the only unencrypted part of the file.
+	packed, err := lnd.DecodePackedBackup(content)
+	if err != nil {
+		return fmt.Errorf("error decoding backup file: %v", err)
+	}
+	result := &inspectedBackup{
+		FileSize:       len(content),
+		Nonce:          hex.EncodeToString(packed.Nonce),
+		CiphertextSize: len(packed.Ciphertext),
+	}
+	if c.RootKey == "" {
+		log.Infof("Decryption skipped, the version and the channels " +
+			"of the backup can only be shown with the root key")
+		return printInspectedBackup(result)
+	}
+
+	extendedKey, err := hdkeychain.NewKeyFromString(c.RootKey)
+	if err != nil {
+		return fmt.Errorf("error reading root key: %v", err)
+	}
+	keyRing := &lnd.HDKeyRing{
+		ExtendedKey: extendedKey,
+		ChainParams: chainParams,
+	}
+	_, err = lnd.DecryptPackedBackup(packed, keyRing)
+	if err != nil {
+		return fmt.Errorf("error decrypting backup, wrong key or "+
+			"corrupt ciphertext: %v", err)
+	}
+	packedMulti := chanbackup.PackedMulti(content)
+	multi, err := packedMulti.Unpack(keyRing)
+	if err != nil {
+		return fmt.Errorf("error parsing backup: %v", err)
+	}
+
+	numChannels := len(multi.StaticBackups)
+	result.Decrypted = true
+	result.Version = &multi.Version
+	result.NumChannels = &numChannels
+	for _, single := range multi.StaticBackups {
+		// Only the locators of the local keys are part of the backup,
+		// so we derive the public key ourselves.
+		multiSigKey, err := keyRing.DeriveKey(
+			single.LocalChanCfg.MultiSigKey.KeyLocator,
+		)
+		if err != nil {
+			return fmt.Errorf("error deriving multisig key of "+
+				"channel %v: %v", single.FundingOutpoint, err)
+		}
+		keyDesc := dump.ToKeyDescriptor(chainParams, multiSigKey)
+		result.Channels = append(result.Channels, &inspectedChannel{
+			ChannelPoint: single.FundingOutpoint.String(),
+			PeerPubKey: dump.PubKeyToString(
+				single.RemoteNodePub,
+			),
+			LocalKeyDesc: inspectedKeyDesc{
+				Family: uint32(multiSigKey.Family),
+				Index:  multiSigKey.Index,
+				Path:   keyDesc.Path,
+				PubKey: keyDesc.PubKey,
+			},
+			CapacityHint: single.Capacity,
+			CsvDelay:     single.LocalChanCfg.CsvDelay,
+		})
+	}
+	log.Infof("Decrypted %d channels", numChannels)
+	return printInspectedBackup(result)
+}
+
+func printInspectedBackup(result *inspectedBackup) error {
+	content, err := json.MarshalIndent(result, "", " ")
+	if err != nil {
+		return err
+	}
+	fmt.Println(string(content))
+	return nil
+}
@@ -0,0 +1,472 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcutil/hdkeychain"
+	"github.com/btcsuite/btcwallet/snacl"
+	"github.com/coreos/bbolt"
+	"github.com/guggero/chantools/lnd"
+	"github.com/lightningnetwork/lnd/chanbackup"
+	"github.com/lightningnetwork/lnd/channeldb"
+	"github.com/lightningnetwork/lnd/lnwallet"
+)
+
+const (
+	defaultLndDir = "~/.lnd"
+
+	// latestChannelDBVersion is the channel DB version of the lnd version
+	// chantools is compiled against.
+	latestChannelDBVersion = 12
+
+	// dbLockTimeout is how long we wait for the file lock of a database
+	// before assuming it is in use by a running lnd.
+	dbLockTimeout = 2 * time.Second
+)
+
+var (
+	// Key names from github.com/btcsuite/btcwallet/waddrmgr/db.go
+	masterPubKeyName = []byte("mpub")
+)
+
+type inspectLndCommand struct {
+	LndDir  string `long:"lnddir" description:"The lnd data directory to inspect. The network is selected with the global flags. (default ~/.lnd)"`
+	RootKey string `long:"rootkey" description:"BIP32 HD root key of the node. If specified, the channel backup file is decrypted and compared to the channel DB."`
+}
+
+func (c *inspectLndCommand) Execute(_ []string) error {
+	setupChainParams(cfg)
+
+	// Set default values.
+	if c.LndDir == "" {
+		c.LndDir = defaultLndDir
+	}
+	lndDir := cleanAndExpandPath(c.LndDir)
+	network := lndNetworkName(chainParams)
+	chainDir := path.Join(lndDir, "data", "chain", "bitcoin", network)
+	graphDir := path.Join(lndDir, "data", "graph", network)
+	var (
+		walletDB  = path.Join(chainDir, "wallet.db")
+		channelDB = path.Join(graphDir, "channel.db")
+		multiFile = path.Join(chainDir, "channel.backup")
+		macaroon  = path.Join(chainDir, "admin.macaroon")
+		tlsKey    = path.Join(lndDir, "tls.key")
+		tlsCert   = path.Join(lndDir, "tls.cert")
+	)
+
+	var (
+		results     []*checkResult
+		extendedKey *hdkeychain.ExtendedKey
+	)
+	if c.RootKey != "" {
+		var result *checkResult
+		extendedKey, result = checkRootKey(c.RootKey)
+		results = append(results, result)
+	}
+
+	layoutResult := checkLndLayout(
+		lndDir, []string{chainDir, graphDir}, []string{
+			walletDB, channelDB, multiFile, macaroon, tlsKey,
+			tlsCert,
+		},
+	)
+	results = append(results, layoutResult)
+	if layoutResult.status == statusFail {
+		return printCheckResults(results)
+	}
+	results = append(results, checkFilePermissions(
+		[]string{walletDB, channelDB, multiFile, macaroon, tlsKey},
+	))
+
+	// Opening a database that is in use blocks until lnd is stopped, so
+	// we only look inside if we can get the lock.
+	if fileExists(walletDB) {
+		lockResult := checkDBLock("Wallet DB lock", walletDB)
+		results = append(results, lockResult)
+		if lockResult.status == statusPass {
+			results = append(results, checkWalletDB(walletDB))
+		}
+	}
+	channelDBUnlocked := false
+	if fileExists(channelDB) {
+		lockResult := checkDBLock("Channel DB lock", channelDB)
+		results = append(results, lockResult)
+		if lockResult.status == statusPass {
+			channelDBUnlocked = true
+			results = append(
+				results, checkChannelDBVersion(channelDB),
+				checkChannelDB(channelDB),
+			)
+		}
+	}
+	if fileExists(multiFile) {
+		results = append(
+			results, checkMultiFile(multiFile, extendedKey),
+		)
+		if extendedKey != nil && channelDBUnlocked {
+			results = append(results, checkBackupCoverage(
+				multiFile, channelDB, extendedKey,
+			))
+		}
+	}
+	results = append(results, checkLndDiskSpace(lndDir, channelDB))
+
+	return printCheckResults(results)
+}
+
+// lndNetworkName returns the name lnd uses for the directories of a network.
+func lndNetworkName(params *chaincfg.Params) string {
+	if params.Name == chaincfg.TestNet3Params.Name {
+		return "testnet"
+	}
+	return params.Name
+}
+
+// checkLndLayout makes sure the directories of the selected network exist and
+// warns about missing files.
+func checkLndLayout(lndDir string, dirs, files []string) *checkResult {
+	result := &checkResult{name: "Directory layout"}
+	for _, dir := range append([]string{lndDir}, dirs...) {
+		info, err := os.Stat(dir)
+		if err != nil || !info.IsDir() {
+			result.status = statusFail
+			result.message = fmt.Sprintf("directory %s not found",
+				dir)
+			result.fix = "check --lnddir and the network flags, " +
+				"the directory must contain the data/chain " +
+				"and data/graph directories"
+			return result
+		}
+	}
+
+	var missing []string
+	for _, file := range files {
+		if !fileExists(file) {
+			missing = append(missing, path.Base(file))
+		}
+	}
+	if len(missing) > 0 {
+		result.status = statusWarn
+		result.message = fmt.Sprintf("missing files: %s",
+			strings.Join(missing, ", "))
+		result.fix = "make sure this is the data directory of an lnd " +
+			"node that was started at least once"
+		return result
+	}
+	result.status = statusPass
+	result.message = fmt.Sprintf("%s contains all expected files", lndDir)
+	return result
+}
+
+// checkFilePermissions warns about sensitive files that can be read by other
+// users and fails if we can't read them ourselves.
+func checkFilePermissions(files []string) *checkResult {
+	result := &checkResult{name: "File permissions"}
+	var (
+		unreadable []string
+		tooOpen    []string
+	)
+	for _, file := range files {
+		info, err := os.Stat(file)
+		if err != nil {
+			continue
+		}
+		f, err := os.Open(file)
+		if err != nil {
+			unreadable = append(unreadable, path.Base(file))
+			continue
+		}
+		_ = f.Close()
+		if info.Mode().Perm()&0077 != 0 {
+			tooOpen = append(tooOpen, fmt.Sprintf("%s (%v)",
+				path.Base(file), info.Mode().Perm()))
+		}
+	}
+
+	switch {
+	case len(unreadable) > 0:
+		result.status = statusFail
+		result.message = fmt.Sprintf("cannot read %s",
+			strings.Join(unreadable, ", "))
+		result.fix = "run chantools as the user that runs lnd"
+
+	case len(tooOpen) > 0:
+		result.status = statusWarn
+		result.message = fmt.Sprintf("accessible by other users: %s",
+			strings.Join(tooOpen, ", "))
+		result.fix = "restrict the permissions with chmod 600"
+
+	default:
+		result.status = statusPass
+		result.message = "sensitive files are only accessible by " +
+			"their owner"
+	}
+	return result
+}
+
+// checkDBLock makes sure the database is not locked by a running lnd.
+func checkDBLock(name, dbFile string) *checkResult {
+	result := &checkResult{name: name}
+	db, err := bbolt.Open(dbFile, dbFilePermission, &bbolt.Options{
+		ReadOnly: true,
+		Timeout:  dbLockTimeout,
+	})
+	switch {
+	case err == bbolt.ErrTimeout:
+		result.status = statusFail
+		result.message = fmt.Sprintf("%s is locked, lnd seems to be "+
+			"running", path.Base(dbFile))
+		result.fix = "stop lnd before inspecting its data directory"
+
+	case err != nil:
+		result.status = statusFail
+		result.message = fmt.Sprintf("cannot open %s: %v",
+			path.Base(dbFile), err)
+		result.fix = "the file might be corrupt, try to repair it " +
+			"with the compactdb command"
+
+	default:
+		_ = db.Close()
+		result.status = statusPass
+		result.message = fmt.Sprintf("%s is not in use",
+			path.Base(dbFile))
+	}
+	return result
+}
+
+// checkWalletDB makes sure the wallet contains a key manager and checks how its
+// private keys are encrypted.
+func checkWalletDB(walletDB string) *checkResult {
+	result := &checkResult{name: "Wallet DB"}
+	db, err := bbolt.Open(walletDB, dbFilePermission, &bbolt.Options{
+		ReadOnly: true,
+		Timeout:  dbLockTimeout,
+	})
+	if err != nil {
+		result.status = statusFail
+		result.message = fmt.Sprintf("cannot open wallet DB: %v", err)
+		return result
+	}
+	defer db.Close()
+
+	var masterPubParams, masterPrivParams []byte
+	err = db.View(func(tx *bbolt.Tx) error {
+		ns := tx.Bucket(waddrmgrNamespaceKey)
+		if ns == nil {
+			return fmt.Errorf("namespace '%s' does not exist",
+				waddrmgrNamespaceKey)
+		}
+		mainBucket := ns.Bucket(mainBucketName)
+		if mainBucket == nil {
+			return fmt.Errorf("bucket '%s' does not exist",
+				mainBucketName)
+		}
+		masterPubParams = mainBucket.Get(masterPubKeyName)
+		if masterPubParams == nil {
+			return fmt.Errorf("no master public key found")
+		}
+		if val := mainBucket.Get(masterPrivKeyName); val != nil {
+			masterPrivParams = append([]byte{}, val...)
+		}
+		return nil
+	})
+	if err != nil {
+		result.status = statusFail
+		result.message = fmt.Sprintf("wallet DB is not readable: %v",
+			err)
+		result.fix = "make sure the file is an lnd wallet.db"
+		return result
+	}
+
+	if masterPrivParams == nil {
+		result.status = statusWarn
+		result.message = "wallet is watch-only and contains no " +
+			"private keys"
+		return result
+	}
+	var masterKeyPriv snacl.SecretKey
+	if err := masterKeyPriv.Unmarshal(masterPrivParams); err != nil {
+		result.status = statusFail
+		result.message = fmt.Sprintf("cannot parse encryption "+
+			"parameters: %v", err)
+		return result
+	}
+	defaultPw := append([]byte{}, lnwallet.DefaultPrivatePassphrase...)
+	err = masterKeyPriv.DeriveKey(&defaultPw)
+	switch {
+	case err == nil:
+		result.status = statusWarn
+		result.message = "wallet is encrypted with the default " +
+			"password, anyone with the file can spend the funds"
+		result.fix = "the node was probably created with " +
+			"--noseedbackup, don't use it for real funds"
+
+	case err == snacl.ErrInvalidPassword:
+		result.status = statusPass
+		result.message = "wallet is encrypted with a custom password"
+
+	default:
+		result.status = statusFail
+		result.message = fmt.Sprintf("cannot check encryption: %v",
+			err)
+	}
+	return result
+}
+
+// checkChannelDBVersion compares the schema version of the channel DB to the
+// version chantools knows.
+func checkChannelDBVersion(channelDB string) *checkResult {
+	result := &checkResult{name: "Channel DB version"}
+	db, err := channeldb.Open(
+		path.Dir(channelDB), channeldb.OptionSetSyncFreelist(true),
+		channeldb.OptionReadOnly(true),
+	)
+	if err != nil {
+		result.status = statusFail
+		result.message = fmt.Sprintf("cannot open channel DB: %v", err)
+		return result
+	}
+	defer db.Close()
+
+	meta, err := db.FetchMeta(nil)
+	switch {
+	case err != nil:
+		result.status = statusFail
+		result.message = fmt.Sprintf("cannot read DB version: %v", err)
+		result.fix = "the file might not be an lnd channel.db"
+
+	case meta.DbVersionNumber > latestChannelDBVersion:
+		result.status = statusWarn
+		result.message = fmt.Sprintf("DB version %d is newer than the "+
+			"supported version %d", meta.DbVersionNumber,
+			latestChannelDBVersion)
+		result.fix = "use a chantools version that supports the " +
+			"lnd version the DB was created with"
+
+	case meta.DbVersionNumber < latestChannelDBVersion:
+		result.status = statusWarn
+		result.message = fmt.Sprintf("DB version %d is older than the "+
+			"supported version %d", meta.DbVersionNumber,
+			latestChannelDBVersion)
+		result.fix = "start a newer lnd once to migrate the DB or " +
+			"work on a copy, chantools might migrate it"
+
+	default:
+		result.status = statusPass
+		result.message = fmt.Sprintf("DB version %d is supported",
+			meta.DbVersionNumber)
+	}
+	return result
+}
+
+// checkBackupCoverage makes sure the channel backup file contains all open
+// channels of the channel DB.
+func checkBackupCoverage(multiFile, channelDB string,
+	extendedKey *hdkeychain.ExtendedKey) *checkResult {
+
+	result := &checkResult{name: "Channel backup coverage"}
+	content, err := ioutil.ReadFile(multiFile)
+	if err != nil {
+		result.status = statusFail
+		result.message = fmt.Sprintf("cannot read backup file: %v", err)
+		return result
+	}
+	keyRing := &lnd.HDKeyRing{
+		ExtendedKey: extendedKey,
+		ChainParams: chainParams,
+	}
+	var multi chanbackup.Multi
+	err = multi.UnpackFromReader(bytes.NewReader(content), keyRing)
+	if err != nil {
+		result.status = statusFail
+		result.message = fmt.Sprintf("cannot unpack backup file: %v",
+			err)
+		result.fix = "use the decodescb command to inspect the file"
+		return result
+	}
+
+	db, err := channeldb.Open(
+		path.Dir(channelDB), channeldb.OptionSetSyncFreelist(true),
+		channeldb.OptionReadOnly(true),
+	)
+	if err != nil {
+		result.status = statusFail
+		result.message = fmt.Sprintf("cannot open channel DB: %v", err)
+		return result
+	}
+	defer db.Close()
+	channels, err := db.FetchAllOpenChannels()
+	if err != nil {
+		result.status = statusFail
+		result.message = fmt.Sprintf("cannot read channels: %v", err)
+		return result
+	}
+
+	backedUp := make(map[string]bool, len(multi.StaticBackups))
+	for _, single := range multi.StaticBackups {
+		backedUp[single.FundingOutpoint.String()] = true
+	}
+	var missing []string
+	for _, channel := range channels {
+		chanPoint := channel.FundingOutpoint.String()
+		if !backedUp[chanPoint] {
+			missing = append(missing, chanPoint)
+		}
+	}
+	if len(missing) > 0 {
+		result.status = statusWarn
+		result.message = fmt.Sprintf("%d of %d open channels are not "+
+			"in the backup file: %s", len(missing), len(channels),
+			strings.Join(missing, ", "))
+		result.fix = "the backup file is outdated, create a new one " +
+			"with the chanbackup command"
+		return result
+	}
+	result.status = statusPass
+	result.message = fmt.Sprintf("all %d open channels are in the backup "+
+		"file", len(channels))
+	return result
+}
+
+// checkLndDiskSpace makes sure there is enough space left to compact the
+// channel DB, which needs a full copy of it.
+func checkLndDiskSpace(lndDir, channelDB string) *checkResult {
+	result := &checkResult{name: "Disk space"}
+	needed := uint64(minFreeDiskSpace)
+	if info, err := os.Stat(channelDB); err == nil {
+		needed += uint64(info.Size())
+	}
+	free, err := freeDiskSpace(lndDir)
+	switch {
+	case err != nil:
+		result.status = statusWarn
+		result.message = fmt.Sprintf("cannot check free disk space: %v",
+			err)
+
+	case free < needed:
+		result.status = statusWarn
+		result.message = fmt.Sprintf("only %d MB free but %d MB are "+
+			"needed to compact the channel DB", free/1024/1024,
+			needed/1024/1024)
+		result.fix = "free up some disk space before lnd runs out of it"
+
+	default:
+		result.status = statusPass
+		result.message = fmt.Sprintf("%d MB free in %s", free/1024/1024,
+			lndDir)
+	}
+	return result
+}
+
+// fileExists returns true if the file exists and is not a directory.
+func fileExists(file string) bool {
+	info, err := os.Stat(file)
+	return err == nil && !info.IsDir()
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+)
+
+// knownFormat is a combination of wallet software, derivation path and
+// address type that chantools knows how to recover. The placeholder <coin> in
+// the path and command is replaced with the coin type of the network.
+type knownFormat struct {
+	wallet   string
+	path     string
+	addrType string
+	command  string
+}
+
+var knownFormats = []knownFormat{{
+	wallet:   "LND on-chain (native SegWit)",
+	path:     "m/84'/<coin>'/0'",
+	addrType: addrTypeP2WKH,
+	command:  "genimportscript --derivationpath \"m/84'/<coin>'/0'\"",
+}, {
+	wallet:   "LND on-chain (nested SegWit)",
+	path:     "m/49'/<coin>'/0'",
+	addrType: addrTypeNP2WKH,
+	command:  "genimportscript --derivationpath \"m/49'/<coin>'/0'\"",
+}, {
+	wallet:   "LND channel keys",
+	path:     "m/1017'/<coin>'/<family>'/0",
+	addrType: "channel scripts",
+	command:  "derivekey --path \"m/1017'/<coin>'/<family>'/0/<index>\"",
+}, {
+	wallet:   "Electrum (BIP39 seed, native SegWit)",
+	path:     "m/84'/<coin>'/0'",
+	addrType: addrTypeP2WKH,
+	command:  "genimportscript --derivationpath \"m/84'/<coin>'/0'\"",
+}, {
+	wallet:   "Electrum (Electrum seed, native SegWit)",
+	path:     "m/0'",
+	addrType: addrTypeP2WKH,
+	command:  "genimportscript --rootkey <xprv> --derivationpath \"m/0'\"",
+}, {
+	wallet:   "Trezor BIP44",
+	path:     "m/44'/<coin>'/0'",
+	addrType: addrTypeP2PKH,
+	command:  "genimportscript --derivationpath \"m/44'/<coin>'/0'\"",
+}, {
+	wallet:   "Trezor BIP49",
+	path:     "m/49'/<coin>'/0'",
+	addrType: addrTypeNP2WKH,
+	command:  "genimportscript --derivationpath \"m/49'/<coin>'/0'\"",
+}, {
+	wallet:   "Trezor BIP84",
+	path:     "m/84'/<coin>'/0'",
+	addrType: addrTypeP2WKH,
+	command:  "genimportscript --derivationpath \"m/84'/<coin>'/0'\"",
+}, {
+	wallet:   "Coldcard BIP84",
+	path:     "m/84'/<coin>'/0'",
+	addrType: addrTypeP2WKH,
+	command:  "genimportscript --derivationpath \"m/84'/<coin>'/0'\"",
+}, {
+	wallet:   "Ledger BIP84",
+	path:     "m/84'/<coin>'/0'",
+	addrType: addrTypeP2WKH,
+	command:  "genimportscript --derivationpath \"m/84'/<coin>'/0'\"",
+}, {
+	wallet:   "Phoenix swap-in",
+	path:     "m/52'/<coin>'/0'/0",
+	addrType: "p2wsh",
+	command:  "migratephoenix --serverpubkey <pubkey>",
+}, {
+	wallet:   "c-lightning on-chain",
+	path:     "m/0/0 (from hsm_secret)",
+	addrType: addrTypeP2WKH,
+	command:  "migratefromclightning --hsmsecret <file>",
+}, {
+	wallet:   "Breez",
+	path:     "m/0/0 (from BIP39 seed)",
+	addrType: addrTypeP2WKH,
+	command:  "migratebreez",
+}}
+
+type listKnownFormatsCommand struct{}
+
+func (c *listKnownFormatsCommand) Execute(_ []string) error {
+	setupChainParams(cfg)
+
+	coinType := fmt.Sprintf("%d", chainParams.HDCoinType)
+	fmt.Printf("Known wallet formats for network %s:\n\n",
+		chainParams.Name)
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "WALLET\tPATH\tADDRESS TYPE\tCOIN TYPE\tCOMMAND")
+	for _, format := range knownFormats {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\tchantools %s\n", format.wallet,
+			strings.Replace(format.path, "<coin>", coinType, -1),
+			format.addrType, coinType,
+			strings.Replace(format.command, "<coin>", coinType, -1))
+	}
+	return w.Flush()
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/btcsuite/btcd/btcec"
+	"github.com/lightningnetwork/lnd/channeldb"
+)
+
+const (
+	listPeersFormatText = "text"
+	listPeersFormatJSON = "json"
+)
+
+// peerInfo is the summary of all channels with a single peer.
+type peerInfo struct {
+	PubKey         string   `json:"pubkey"`
+	Channels       int      `json:"num_channels"`
+	ClosedChannels int      `json:"num_closed_channels"`
+	LocalBalance   uint64   `json:"local_balance_sat"`
+	Addresses      []string `json:"addresses"`
+}
+
+type listPeersCommand struct {
+	ChannelDB string `long:"channeldb" description:"The lnd channel.db file to read the channel peers from."`
+	Format    string `long:"format" description:"The output format, either text or json. (default text)"`
+}
+
+func (c *listPeersCommand) Execute(_ []string) error {
+	setupChainParams(cfg)
+
+	// Check that we have a channel DB.
+	if c.ChannelDB == "" {
+		return fmt.Errorf("channel DB is required")
+	}
+	if c.Format == "" {
+		c.Format = listPeersFormatText
+	}
+	if c.Format != listPeersFormatText && c.Format != listPeersFormatJSON {
+		return fmt.Errorf("unknown format %s, must be %s or %s",
+			c.Format, listPeersFormatText, listPeersFormatJSON)
+	}
+	db, err := channeldb.Open(
+		path.Dir(c.ChannelDB), channeldb.OptionSetSyncFreelist(true),
+		channeldb.OptionReadOnly(true),
+	)
+	if err != nil {
+		return fmt.Errorf("error opening channel DB: %v", err)
+	}
+	defer db.Close()
+
+	// All channels that aren't fully closed yet, including the pending
+	// ones.
+	channels, err := db.FetchAllChannels()
+	if err != nil {
+		return fmt.Errorf("error fetching channels: %v", err)
+	}
+	closedChannels, err := db.FetchClosedChannels(false)
+	if err != nil {
+		return fmt.Errorf("error fetching closed channels: %v", err)
+	}
+
+	var (
+		peers    []*peerInfo
+		peerKeys []*btcec.PublicKey
+		byPubKey = make(map[string]*peerInfo)
+		seen     = make(map[string]bool)
+	)
+	peerFor := func(pubKey *btcec.PublicKey) *peerInfo {
+		key := hex.EncodeToString(pubKey.SerializeCompressed())
+		peer, ok := byPubKey[key]
+		if !ok {
+			peer = &peerInfo{PubKey: key}
+			byPubKey[key] = peer
+			peers = append(peers, peer)
+			peerKeys = append(peerKeys, pubKey)
+		}
+		return peer
+	}
+	for _, channel := range channels {
+		seen[channel.FundingOutpoint.String()] = true
+		peer := peerFor(channel.IdentityPub)
+		peer.Channels++
+		peer.LocalBalance += uint64(
+			channel.LocalCommitment.LocalBalance.ToSatoshis(),
+		)
+	}
+
+	// Channels that are waiting for their closing transaction to confirm
+	// have a close summary as well, they were already counted above.
+	for _, summary := range closedChannels {
+		if seen[summary.ChanPoint.String()] {
+			continue
+		}
+		peer := peerFor(summary.RemotePub)
+		peer.Channels++
+		peer.ClosedChannels++
+	}
+
+	for idx, peer := range peers {
+		addrs, err := db.AddrsForNode(peerKeys[idx])
+		if err != nil {
+			log.Debugf("No addresses in DB for %s: %v", peer.PubKey,
+				err)
+		}
+		peer.Addresses = make([]string, len(addrs))
+		for addrIdx, addr := range addrs {
+			peer.Addresses[addrIdx] = addr.String()
+		}
+	}
+
+	if c.Format == listPeersFormatJSON {
+		peerBytes, err := json.MarshalIndent(peers, "", " ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(peerBytes))
+		return nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "PUBKEY\tCHANNELS\tCLOSED\tLOCAL BALANCE\tADDRESSES")
+	for _, peer := range peers {
+		fmt.Fprintf(w, "%s\t%d\t%d\t%d\t%s\n", peer.PubKey,
+			peer.Channels, peer.ClosedChannels, peer.LocalBalance,
+			strings.Join(peer.Addresses, ","))
+	}
+	return w.Flush()
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/btcsuite/btcd/btcec"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcutil/hdkeychain"
+	"github.com/guggero/chantools/lnd"
+	"github.com/lightningnetwork/lnd/input"
+	"github.com/lightningnetwork/lnd/keychain"
+	"github.com/lightningnetwork/lnd/shachain"
+)
+
+type lookupRevocationCommand struct {
+	RootKey   string `long:"rootkey" description:"BIP32 HD root key of the node. Leave empty to prompt for lnd 24 word aezeed."`
+	Channel   string `long:"channel" description:"The funding outpoint (txid:index) of the channel."`
+	ChannelDB string `long:"channeldb" description:"The lnd channel.db file to look up the revocation root of the channel in."`
+	MultiFile string `long:"multi_file" description:"The lnd channel.backup file to look up the revocation root of the channel in."`
+
+	// Index is -1 if not set, see main.go.
+	Index int64 `long:"index" description:"The key index of the revocation root of the channel. If set, the revocation root is not looked up and the channel, channeldb and multi_file flags are ignored."`
+
+	CommitNumber uint64 `long:"commitnumber" description:"The number of the local commitment to derive the per-commitment secret for. The first commitment of a channel has the number 0."`
+}
+
+func (c *lookupRevocationCommand) Execute(_ []string) error {
+	setupChainParams(cfg)
+
+	var (
+		extendedKey *hdkeychain.ExtendedKey
+		err         error
+	)
+
+	// Check that root key is valid or fall back to console input.
+	switch {
+	case c.RootKey != "":
+		extendedKey, err = hdkeychain.NewKeyFromString(c.RootKey)
+
+	default:
+		extendedKey, _, err = rootKeyFromConsole()
+	}
+	if err != nil {
+		return fmt.Errorf("error reading root key: %v", err)
+	}
+
+	var (
+		producer           shachain.Producer
+		remoteRevBasePoint *btcec.PublicKey
+	)
+	switch {
+	case c.Index >= 0:
+		keyLoc := keychain.KeyLocator{
+			Family: keychain.KeyFamilyRevocationRoot,
+			Index:  uint32(c.Index),
+		}
+		producer, err = revocationProducer(extendedKey, keyLoc)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Deriving revocation root at path %s for network "+
+			"%s.\n", keyLocatorPath(keyLoc), chainParams.Name)
+
+	default:
+		keys, err := lookupChannelKeys(
+			extendedKey, c.Channel, c.ChannelDB, c.MultiFile,
+		)
+		if err != nil {
+			return err
+		}
+		producer = keys.producer
+		remoteRevBasePoint = keys.remoteCfg.RevocationBasePoint.PubKey
+	}
+
+	commitSecret, err := producer.AtIndex(c.CommitNumber)
+	if err != nil {
+		return fmt.Errorf("error deriving commitment secret: %v", err)
+	}
+	commitPoint := input.ComputeCommitmentPoint(commitSecret[:])
+
+	fmt.Printf("Per-commitment secret of commitment %d: %x\n",
+		c.CommitNumber, commitSecret[:])
+	fmt.Printf("Per-commitment point of commitment %d: %x\n",
+		c.CommitNumber, commitPoint.SerializeCompressed())
+
+	// The remote party can spend our revoked commitment with the private
+	// key of this revocation key, once we handed out the per-commitment
+	// secret.
+	if remoteRevBasePoint != nil {
+		revocationKey := input.DeriveRevocationPubkey(
+			remoteRevBasePoint, commitPoint,
+		)
+		fmt.Printf("Revocation key of commitment %d: %x\n",
+			c.CommitNumber, revocationKey.SerializeCompressed())
+	}
+	return nil
+}
+
+// revocationProducer creates the shachain producer of a channel from the
+// private key of its revocation root, the same way lnd does when opening a
+// channel.
+func revocationProducer(extendedKey *hdkeychain.ExtendedKey,
+	keyLoc keychain.KeyLocator) (shachain.Producer, error) {
+
+	signer := &lnd.Signer{
+		ExtendedKey: extendedKey,
+		ChainParams: chainParams,
+	}
+	revRoot, err := signer.FetchPrivKey(&keychain.KeyDescriptor{
+		KeyLocator: keyLoc,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error deriving revocation root: %v",
+			err)
+	}
+	rootHash, err := chainhash.NewHash(revRoot.Serialize())
+	if err != nil {
+		return nil, err
+	}
+	return shachain.NewRevocationProducer(*rootHash), nil
+}
@@ -15,7 +15,10 @@ import (
 	"github.com/btcsuite/btcd/chaincfg"
 	"github.com/btcsuite/btclog"
 	"github.com/btcsuite/btcutil/hdkeychain"
+	"github.com/guggero/chantools/bip39"
+	"github.com/guggero/chantools/btc"
 	"github.com/guggero/chantools/dataformat"
+	"github.com/guggero/chantools/lnd"
 	"github.com/jessevdk/go-flags"
 	"github.com/lightningnetwork/lnd/aezeed"
 	"github.com/lightningnetwork/lnd/build"
@@ -25,26 +28,59 @@ import (
 )
 
 const (
-	defaultAPIURL = "https://blockstream.info/api"
+	defaultAPIURL      = "https://blockstream.info/api"
+	defaultBitcoindRPC = "localhost:8332"
+	defaultAPITimeout  = 10 * time.Second
+	defaultAPIRetries  = 3
 )
 
 type config struct {
-	Testnet         bool   `long:"testnet" description:"Set to true if testnet parameters should be used."`
-	Regtest         bool   `long:"regtest" description:"Set to true if regtest parameters should be used."`
-	APIURL          string `long:"apiurl" description:"API URL to use (must be esplora compatible)."`
-	ListChannels    string `long:"listchannels" description:"The channel input is in the format of lncli's listchannels format. Specify '-' to read from stdin."`
-	PendingChannels string `long:"pendingchannels" description:"The channel input is in the format of lncli's pendingchannels format. Specify '-' to read from stdin."`
-	FromSummary     string `long:"fromsummary" description:"The channel input is in the format of this tool's channel summary. Specify '-' to read from stdin."`
-	FromChannelDB   string `long:"fromchanneldb" description:"The channel input is in the format of an lnd channel.db file."`
+	Network              string        `long:"network" description:"The network to use, one of mainnet, testnet, regtest, simnet or signet. The same as setting the flag of the network. (default mainnet)"`
+	Mainnet              bool          `long:"mainnet" description:"Set to true if mainnet parameters should be used. This is the default."`
+	Testnet              bool          `long:"testnet" description:"Set to true if testnet parameters should be used."`
+	Regtest              bool          `long:"regtest" description:"Set to true if regtest parameters should be used."`
+	Simnet               bool          `long:"simnet" description:"Set to true if simnet parameters should be used."`
+	Signet               bool          `long:"signet" description:"Set to true if signet parameters should be used."`
+	APIURL               string        `long:"apiurl" description:"API URL to use (must be esplora compatible)."`
+	APITimeout           time.Duration `long:"apitimeout" description:"The timeout of a single API request when looking up the block of a seed birthday or the addresses of a gap limit scan."`
+	APIRetries           uint32        `long:"apiretries" description:"The number of times a failed API request is retried when looking up the block of a seed birthday or the addresses of a gap limit scan."`
+	EncryptedRootKeyFile string        `long:"encryptedrootkeyfile" description:"Read the root key from a file created with the encryptrootkey command instead of prompting for the aezeed. The passphrase of the file is asked for."`
+	ListChannels         string        `long:"listchannels" description:"The channel input is in the format of lncli's listchannels format. Specify '-' to read from stdin."`
+	PendingChannels      string        `long:"pendingchannels" description:"The channel input is in the format of lncli's pendingchannels format. Specify '-' to read from stdin."`
+	FromSummary          string        `long:"fromsummary" description:"The channel input is in the format of this tool's channel summary. Specify '-' to read from stdin."`
+	FromChannelDB        string        `long:"fromchanneldb" description:"The channel input is in the format of an lnd channel.db file."`
+	BitcoindRPC          string        `long:"bitcoindrpc" description:"The host:port of the bitcoind RPC interface for commands that talk to a bitcoind node."`
+	BitcoindUser         string        `long:"bitcoinduser" description:"The bitcoind RPC user name."`
+	BitcoindPass         string        `long:"bitcoindpass" description:"The bitcoind RPC password."`
+	BitcoindWallet       string        `long:"bitcoindwallet" description:"The name of the bitcoind wallet to use for wallet RPCs. Leave empty to use the default wallet."`
+	DryRun               bool          `long:"dryrun" description:"Never publish a transaction, even if --publish is set. The fully signed transactions are printed together with a summary of their inputs, outputs and fees instead."`
+	StrictPath           bool          `long:"strictpath" description:"Only accept derivation paths that start with m/. Paths without the leading m/ are accepted otherwise. Both ' and h are accepted as the marker of hardened indexes."`
+	Yes                  bool          `short:"y" long:"yes" description:"Don't ask for confirmation before modifying or removing data. Dangerous, make sure the command does what you expect first. If stdout is not a terminal, a JSON summary of the modification is printed instead of the confirmation text."`
 }
 
 var (
 	logWriter = build.NewRotatingLogWriter()
 	log       = build.NewSubLogger("CHAN", logWriter.GenSubLogger)
 	cfg       = &config{
-		APIURL: defaultAPIURL,
+		APIURL:      defaultAPIURL,
+		APITimeout:  defaultAPITimeout,
+		APIRetries:  defaultAPIRetries,
+		BitcoindRPC: defaultBitcoindRPC,
 	}
 	chainParams = &chaincfg.MainNetParams
+
+	// chainNetworks are the networks that can be selected with --network
+	// or the flag of the same name.
+	chainNetworks = []struct {
+		name   string
+		params *chaincfg.Params
+	}{
+		{name: "mainnet", params: &chaincfg.MainNetParams},
+		{name: "testnet", params: &chaincfg.TestNet3Params},
+		{name: "regtest", params: &chaincfg.RegressionNetParams},
+		{name: "simnet", params: &chaincfg.SimNetParams},
+		{name: "signet", params: &btc.SigNetParams},
+	}
 )
 
 func main() {
@@ -53,18 +89,37 @@ func main() {
 		return
 	}
 
-	_, ok := err.(*flags.Error)
-	if !ok {
+	// Errors of the flag parser were already printed by the parser. Only
+	// showing the help isn't a failure.
+	flagErr, ok := err.(*flags.Error)
+	switch {
+	case !ok:
 		fmt.Printf("Error running chantools: %v\n", err)
+
+	case flagErr.Type == flags.ErrHelp:
+		return
 	}
-	os.Exit(0)
+	os.Exit(1)
 }
 
 func runCommandParser() error {
 	setupLogging()
 
-	// Parse command line.
+	// Parse command line. The network flags are checked before any command
+	// is executed, so the commands don't need to deal with an invalid
+	// combination of them.
 	parser := flags.NewParser(cfg, flags.Default)
+	parser.CommandHandler = func(command flags.Commander,
+		args []string) error {
+
+		if _, err := selectedNetwork(cfg); err != nil {
+			return err
+		}
+		if command == nil {
+			return nil
+		}
+		return command.Execute(args)
+	}
 	_, _ = parser.AddCommand(
 		"summary", "Compile a summary about the current state of "+
 			"channels.", "", &summaryCommand{},
@@ -82,26 +137,92 @@ func runCommandParser() error {
 		"sweeptimelock", "Sweep the force-closed state after the time "+
 			"lock has expired.", "", &sweepTimeLockCommand{},
 	)
+	_, _ = parser.AddCommand(
+		"sweeptaproot", "Sweep a Taproot output with the key path or "+
+			"the script of a leaf.", "", &sweepTaprootCommand{},
+	)
 	_, _ = parser.AddCommand(
 		"dumpchannels", "Dump all channel information from lnd's "+
 			"channel database.", "", &dumpChannelsCommand{},
 	)
+	_, _ = parser.AddCommand(
+		"summarize", "Summarize the balances and states of all "+
+			"channels in a channel DB.", "", &summarizeCommand{},
+	)
 	_, _ = parser.AddCommand(
 		"showrootkey", "Extract and show the BIP32 HD root key from "+
 			"the 24 word lnd aezeed.", "", &showRootKeyCommand{},
 	)
+	_, _ = parser.AddCommand(
+		"encryptrootkey", "Encrypt a BIP32 HD root key with a "+
+			"passphrase for safe storage.", "",
+		&encryptRootKeyCommand{},
+	)
+	_, _ = parser.AddCommand(
+		"decryptrootkey", "Decrypt and show a root key that was "+
+			"encrypted with encryptrootkey.", "",
+		&decryptRootKeyCommand{},
+	)
 	_, _ = parser.AddCommand(
 		"dumpbackup", "Dump the content of a channel.backup file.", "",
 		&dumpBackupCommand{},
 	)
+	_, _ = parser.AddCommand(
+		"convertkey", "Convert a key between the extended key, WIF, "+
+			"hex and address formats.", "", &convertKeyCommand{},
+	)
 	_, _ = parser.AddCommand(
 		"derivekey", "Derive a key with a specific derivation path "+
 			"from the BIP32 HD root key.", "", &deriveKeyCommand{},
 	)
+	_, _ = parser.AddCommand(
+		"vanitygen", "Search the keys of a wallet branch for "+
+			"addresses that match a pattern.", "",
+		&vanityGenCommand{},
+	)
+	_, _ = parser.AddCommand(
+		"bip32dump", "Dump the extended keys of every level of a "+
+			"derivation path tree.", "", &bip32DumpCommand{},
+	)
+	_, _ = parser.AddCommand(
+		"bip32xpubwatch", "Generate a watch-only import script from "+
+			"the extended public key of an account.", "",
+		&bip32XPubWatchCommand{},
+	)
+	bip85Cmd, _ := parser.AddCommand(
+		"bip85", "Derive deterministic child entropy like BIP39 "+
+			"mnemonics or private keys from the root key as "+
+			"defined in BIP85.", "", &bip85Command{},
+	)
+	_, _ = bip85Cmd.AddCommand(
+		"bip39", "Derive a child BIP39 mnemonic.", "",
+		&bip85BIP39Command{},
+	)
+	_, _ = bip85Cmd.AddCommand(
+		"wif", "Derive a child WIF encoded private key.", "",
+		&bip85WIFCommand{},
+	)
+	_, _ = bip85Cmd.AddCommand(
+		"hex", "Derive raw child entropy.", "", &bip85HexCommand{},
+	)
 	_, _ = parser.AddCommand(
 		"filterbackup", "Filter an lnd channel.backup file and "+
 			"remove certain channels.", "", &filterBackupCommand{},
 	)
+	_, _ = parser.AddCommand(
+		"verifybackup", "Verify that an lnd channel.backup file is "+
+			"intact without restoring it.", "",
+		&verifyBackupCommand{},
+	)
+	_, _ = parser.AddCommand(
+		"mergebackup", "Merge multiple lnd channel.backup files into "+
+			"one.", "", &mergeBackupCommand{},
+	)
+	_, _ = parser.AddCommand(
+		"rotatekeys", "Re-encrypt a channel.backup file with a new "+
+			"root key and show addresses of the new wallet.", "",
+		&rotateKeysCommand{},
+	)
 	_, _ = parser.AddCommand(
 		"fixoldbackup", "Fixes an old channel.backup file that is "+
 			"affected by the lnd issue #3881 (unable to derive "+
@@ -113,6 +234,21 @@ func runCommandParser() error {
 			"other software like bitcoind.", "",
 		&genImportScriptCommand{},
 	)
+	_, _ = parser.AddCommand(
+		"exportdescriptors", "Export the accounts of an lnd wallet as "+
+			"descriptors that can be imported into a Bitcoin "+
+			"Core descriptor wallet.", "",
+		&exportDescriptorsCommand{},
+	)
+	_, _ = parser.AddCommand(
+		"findaddress", "Find the derivation path of an address of a "+
+			"wallet.", "", &findAddressCommand{},
+	)
+	_, _ = parser.AddCommand(
+		"createwallet", "Create a new lnd wallet.db with a new aezeed "+
+			"and show the xpubs of its accounts.", "",
+		&createWalletCommand{},
+	)
 	_, _ = parser.AddCommand(
 		"walletinfo", "Shows relevant information about an lnd "+
 			"wallet.db file and optionally extracts the BIP32 HD "+
@@ -122,6 +258,262 @@ func runCommandParser() error {
 		"chanbackup", "Create a channel.backup file from a channel "+
 			"database.", "", &chanBackupCommand{},
 	)
+	_, _ = parser.AddCommand(
+		"importwatchonly", "Import the xpub of a wallet account as "+
+			"watch-only descriptors into bitcoind.", "",
+		&importWatchOnlyCommand{},
+	)
+	_, _ = parser.AddCommand(
+		"migratefromclightning", "Generate a script containing the "+
+			"on-chain keys of a c-lightning wallet that can be "+
+			"imported into other software like bitcoind.", "",
+		&migrateFromCLightningCommand{},
+	)
+	_, _ = parser.AddCommand(
+		"migratebreez", "Generate a script containing the on-chain "+
+			"keys of a Breez (Greenlight) wallet and derive the "+
+			"keys of its channels.", "", &migrateBreezCommand{},
+	)
+	_, _ = parser.AddCommand(
+		"migratephoenix", "Generate a script containing the swap-in "+
+			"addresses of a Phoenix wallet and optionally sweep "+
+			"them.", "", &migratePhoenixCommand{},
+	)
+	_, _ = parser.AddCommand(
+		"createpsbt", "Create an unsigned PSBT from a list of UTXOs "+
+			"and outputs.", "", &createPsbtCommand{},
+	)
+	_, _ = parser.AddCommand(
+		"decodescb", "Decode the format layers of a channel.backup "+
+			"file for debugging corrupt backups.", "",
+		&decodeSCBCommand{},
+	)
+	_, _ = parser.AddCommand(
+		"inspectchanbackup", "Show the channels of a channel.backup "+
+			"file as JSON without restoring it.", "",
+		&inspectChanBackupCommand{},
+	)
+	_, _ = parser.AddCommand(
+		"checkpeerconnectivity", "Check whether the peers of the "+
+			"channels in a channel DB are still reachable.", "",
+		&checkPeerConnectivityCommand{},
+	)
+	_, _ = parser.AddCommand(
+		"checkpointblock", "Find the block to rescan from for a "+
+			"wallet birthday date.", "", &checkpointBlockCommand{},
+	)
+	_, _ = parser.AddCommand(
+		"listpeers", "List all peers of the channels in a channel DB "+
+			"together with their stored addresses.", "",
+		&listPeersCommand{},
+	)
+	_, _ = parser.AddCommand(
+		"estimatebalance", "Quickly estimate the total recoverable "+
+			"balance of channels and on-chain wallet.", "",
+		&estimateBalanceCommand{},
+	)
+	_, _ = parser.AddCommand(
+		"recoverytimeline", "Create a time ordered plan of the "+
+			"actions needed to recover the funds of all channels.",
+		"", &recoveryTimelineCommand{},
+	)
+	_, _ = parser.AddCommand(
+		"rebroadcast", "Re-broadcast a transaction that dropped out "+
+			"of the mempool.", "", &rebroadcastCommand{},
+	)
+	_, _ = parser.AddCommand(
+		"finalizeandbroadcast", "Finalize externally signed PSBTs "+
+			"and broadcast their transactions.", "",
+		&finalizeAndBroadcastCommand{},
+	)
+	_, _ = parser.AddCommand(
+		"unilateralclose", "Force-close one or all channels of a "+
+			"channel DB and list the outputs to sweep.", "",
+		&unilateralCloseCommand{},
+	)
+	_, _ = parser.AddCommand(
+		"generateaddress", "Generate an address of the wallet from a "+
+			"derivation path.", "", &generateAddressCommand{
+			SegWitVersion: -1,
+		},
+	)
+	_, _ = parser.AddCommand(
+		"signmessage", "Sign a message with a key of the wallet "+
+			"using the Bitcoin message signing standard.", "",
+		&signMessageCommand{},
+	)
+	_, _ = parser.AddCommand(
+		"verifymessage", "Verify that a message was signed by the "+
+			"key of an address.", "", &verifyMessageCommand{},
+	)
+	_, _ = parser.AddCommand(
+		"listknownformats", "List all known combinations of wallets, "+
+			"derivation paths and address types and how to "+
+			"recover them.", "", &listKnownFormatsCommand{},
+	)
+	_, _ = parser.AddCommand(
+		"diagnose", "Check for common misconfigurations before "+
+			"running a recovery.", "", &diagnoseCommand{},
+	)
+	_, _ = parser.AddCommand(
+		"computerevocationbasepoint", "Derive the revocation base "+
+			"point of a channel.", "",
+		&computeRevocationBasePointCommand{Index: -1},
+	)
+	_, _ = parser.AddCommand(
+		"computehtlcbasepoint", "Derive the HTLC base point of a "+
+			"channel and its per-commitment HTLC keys.", "",
+		&computeHtlcBasePointCommand{Index: -1},
+	)
+	_, _ = parser.AddCommand(
+		"computelocaldelaykey", "Derive the local delay key of a "+
+			"commitment from the delay base point.", "",
+		&computeLocalDelayKeyCommand{},
+	)
+	_, _ = parser.AddCommand(
+		"computeremotepaymentkey", "Derive the remote payment key of "+
+			"a commitment for legacy and static remote key "+
+			"channels.", "", &computeRemotePaymentKeyCommand{},
+	)
+	_, _ = parser.AddCommand(
+		"lookuprevocation", "Derive the per-commitment secret of a "+
+			"commitment from the revocation root of a channel.", "",
+		&lookupRevocationCommand{Index: -1},
+	)
+	_, _ = parser.AddCommand(
+		"computetolocal", "Construct the to_local output script of a "+
+			"commitment and show how to spend it.", "",
+		&computeToLocalCommand{},
+	)
+	_, _ = parser.AddCommand(
+		"computenodekey", "Derive the identity key of an lnd node.",
+		"", &computeNodeKeyCommand{},
+	)
+	_, _ = parser.AddCommand(
+		"signclosing", "Sign the funding input of a cooperative close "+
+			"transaction.", "", &signClosingCommand{},
+	)
+	_, _ = parser.AddCommand(
+		"rescuefunding", "Rescue the funds of a channel with a "+
+			"stuck funding output by force closing it or by "+
+			"creating a cooperative close PSBT.", "",
+		&rescueFundingCommand{},
+	)
+	_, _ = parser.AddCommand(
+		"signrescuefunding", "Add our signature to a cooperative "+
+			"close PSBT created by rescuefunding.", "",
+		&signRescueFundingCommand{},
+	)
+	_, _ = parser.AddCommand(
+		"verifyclosingtx", "Verify a cooperative close transaction "+
+			"before signing it.", "", &verifyClosingTxCommand{},
+	)
+	_, _ = parser.AddCommand(
+		"analyzebackuphistory", "Find the best channel.backup file "+
+			"in a directory of backups.", "",
+		&analyzeBackupHistoryCommand{},
+	)
+	_, _ = parser.AddCommand(
+		"computecommitfee", "Calculate the miner fee of a commitment "+
+			"transaction.", "", &computeCommitFeeCommand{},
+	)
+	_, _ = parser.AddCommand(
+		"tracepath", "Find the derivation path of a public key.", "",
+		&tracePathCommand{},
+	)
+	_, _ = parser.AddCommand(
+		"computewitnesshash", "Compute the BIP143 sighash of a "+
+			"transaction input.", "", &computeWitnessHashCommand{},
+	)
+	_, _ = parser.AddCommand(
+		"verifypubkey", "Check that a public key is a valid point on "+
+			"the secp256k1 curve.", "", &verifyPubKeyCommand{},
+	)
+	_, _ = parser.AddCommand(
+		"computetaptweak", "Apply the Taproot tweak to an internal "+
+			"key.", "", &computeTapTweakCommand{},
+	)
+	_, _ = parser.AddCommand(
+		"computemerkleroot", "Compute the merkle root and control "+
+			"blocks of a Taproot script tree.", "",
+		&computeMerkleRootCommand{},
+	)
+	_, _ = parser.AddCommand(
+		"computetxweight", "Compute the weight and virtual size of a "+
+			"transaction.", "", &computeTxWeightCommand{},
+	)
+	_, _ = parser.AddCommand(
+		"recoverfromseed", "Interactive wizard that guides through "+
+			"the recovery of a node.", "",
+		&recoverFromSeedCommand{},
+	)
+	_, _ = parser.AddCommand(
+		"monitor", "Watch the channels of a channel.db for "+
+			"breaches and force closes and send alerts.", "",
+		&monitorCommand{},
+	)
+	_, _ = parser.AddCommand(
+		"generatehardwaresigner", "Create a signing request "+
+			"for an air-gapped signer from a PSBT.", "",
+		&generateHardwareSignerCommand{},
+	)
+	_, _ = parser.AddCommand(
+		"purgepayments", "Move old succeeded and failed payments "+
+			"from a channel.db to an archive database.", "",
+		&purgePaymentsCommand{},
+	)
+	_, _ = parser.AddCommand(
+		"dropchannelgraph", "Remove all or only selected channels "+
+			"from the channel graph in a channel.db.", "",
+		&dropChannelGraphCommand{},
+	)
+	_, _ = parser.AddCommand(
+		"purgesettled", "Move old settled invoices from a "+
+			"channel.db to an archive database.", "",
+		&purgeSettledCommand{},
+	)
+	_, _ = parser.AddCommand(
+		"computechannelid", "Compute the short channel ID and "+
+			"channel ID of a channel from its funding "+
+			"outpoint.", "", &computeChannelIDCommand{},
+	)
+	_, _ = parser.AddCommand(
+		"recoverchangeoutput", "Find and sweep wallet change "+
+			"outputs of previously published transactions.", "",
+		&recoverChangeOutputCommand{},
+	)
+	_, _ = parser.AddCommand(
+		"computeredemptionscript", "Compute the P2SH address and "+
+			"the spending script of a redeem script.", "",
+		&computeRedemptionScriptCommand{},
+	)
+	_, _ = parser.AddCommand(
+		"inspectlnd", "Check the health of an lnd data directory.", "",
+		&inspectLndCommand{},
+	)
+	_, _ = parser.AddCommand(
+		"exportkeys", "Export all derived keys of the wallet as JSON, "+
+			"optionally encrypted.", "", &exportKeysCommand{},
+	)
+	_, _ = parser.AddCommand(
+		"computeofferedhtlcscript", "Compute the BOLT 3 script of an "+
+			"offered HTLC output.", "",
+		&computeOfferedHtlcScriptCommand{},
+	)
+	_, _ = parser.AddCommand(
+		"computereceivedhtlcscript", "Compute the BOLT 3 script of a "+
+			"received HTLC output.", "",
+		&computeReceivedHtlcScriptCommand{},
+	)
+	_, _ = parser.AddCommand(
+		"verifyhtlcscript", "Verify that a transaction output is "+
+			"an HTLC with the given parameters.", "",
+		&verifyHtlcScriptCommand{},
+	)
+	_, _ = parser.AddCommand(
+		"computechannelbalance", "Sum up the balances of all channels "+
+			"in a channel DB.", "", &computeChannelBalanceCommand{},
+	)
 	_, _ = parser.AddCommand(
 		"compactdb", "Open a source channel.db database file in safe/"+
 			"read-only mode and copy it to a fresh database, "+
@@ -188,9 +580,30 @@ func readInput(input string) ([]byte, error) {
 	return ioutil.ReadFile(input)
 }
 
+// bip39BirthdayWarning is logged by commands that use the wallet birthday to
+// find the block to rescan from if a BIP39 mnemonic or an encrypted root key
+// file was used.
+const bip39BirthdayWarning = "A BIP39 mnemonic or an encrypted root key " +
+	"file doesn't contain a wallet birthday, the rescan starts at the " +
+	"default block or the one set with --rescanfrom. Funds received " +
+	"before that block are missed."
+
 func rootKeyFromConsole() (*hdkeychain.ExtendedKey, time.Time, error) {
+	// An encrypted root key file replaces the seed input. Like a BIP39
+	// mnemonic it doesn't contain a birthday.
+	if cfg.EncryptedRootKeyFile != "" {
+		rootKey, err := rootKeyFromEncryptedFile(
+			cfg.EncryptedRootKeyFile,
+		)
+		if err != nil {
+			return nil, time.Unix(0, 0), err
+		}
+		return rootKey, time.Time{}, nil
+	}
+
 	// We'll now prompt the user to enter in their 24-word mnemonic.
-	fmt.Printf("Input your 24-word mnemonic separated by spaces: ")
+	fmt.Printf("Input your 24-word aezeed or 12 to 24 word BIP39 " +
+		"mnemonic separated by spaces: ")
 	reader := bufio.NewReader(os.Stdin)
 	mnemonicStr, err := reader.ReadString('\n')
 	if err != nil {
@@ -206,10 +619,32 @@ func rootKeyFromConsole() (*hdkeychain.ExtendedKey, time.Time, error) {
 
 	fmt.Println()
 
-	if len(cipherSeedMnemonic) != 24 {
-		return nil, time.Unix(0, 0), fmt.Errorf("wrong cipher seed "+
-			"mnemonic length: got %v words, expecting %v words",
-			len(cipherSeedMnemonic), 24)
+	// Both formats use the same word list, so we can only tell them apart
+	// by their checksum. The aezeed checksum is checked first as it is a
+	// lot stronger than the one of a 24 word BIP39 mnemonic.
+	var mnemonic aezeed.Mnemonic
+	copy(mnemonic[:], cipherSeedMnemonic)
+	if len(cipherSeedMnemonic) != 24 || !isAezeed(&mnemonic) {
+		if !bip39.IsMnemonicValid(mnemonicStr) {
+			return nil, time.Unix(0, 0), fmt.Errorf("mnemonic "+
+				"with %v words is neither a valid aezeed nor "+
+				"a valid BIP39 mnemonic",
+				len(cipherSeedMnemonic))
+		}
+
+		// A BIP39 mnemonic doesn't contain a birthday, so we return
+		// the zero time and let the caller decide where to start
+		// scanning.
+		seed, err := bip39SeedFromMnemonic(mnemonicStr)
+		if err != nil {
+			return nil, time.Unix(0, 0), err
+		}
+		rootKey, err := hdkeychain.NewMaster(seed, chainParams)
+		if err != nil {
+			return nil, time.Unix(0, 0), fmt.Errorf("failed to "+
+				"derive master extended key: %v", err)
+		}
+		return rootKey, time.Time{}, nil
 	}
 
 	// Additionally, the user may have a passphrase, that will also
@@ -222,9 +657,6 @@ func rootKeyFromConsole() (*hdkeychain.ExtendedKey, time.Time, error) {
 		return nil, time.Unix(0, 0), err
 	}
 
-	var mnemonic aezeed.Mnemonic
-	copy(mnemonic[:], cipherSeedMnemonic)
-
 	// If we're unable to map it back into the ciphertext, then either the
 	// mnemonic is wrong, or the passphrase is wrong.
 	cipherSeed, err := mnemonic.ToCipherSeed(passphrase)
@@ -240,6 +672,53 @@ func rootKeyFromConsole() (*hdkeychain.ExtendedKey, time.Time, error) {
 	return rootKey, cipherSeed.BirthdayTime(), nil
 }
 
+// isAezeed returns true if the mnemonic has a valid aezeed version and
+// checksum. Both are checked before the seed is deciphered, so a wrong
+// passphrase still identifies an aezeed.
+func isAezeed(mnemonic *aezeed.Mnemonic) bool {
+	_, err := mnemonic.ToCipherSeed(nil)
+	return err == nil || err == aezeed.ErrInvalidPass
+}
+
+func bip39SeedFromConsole() ([]byte, error) {
+	// We'll now prompt the user to enter in their BIP39 mnemonic.
+	fmt.Printf("Input your 12 to 24 word BIP39 mnemonic separated by " +
+		"spaces: ")
+	reader := bufio.NewReader(os.Stdin)
+	mnemonicStr, err := reader.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+
+	// We'll trim off extra spaces, and ensure the mnemonic is all
+	// lower case.
+	mnemonicStr = strings.TrimSpace(mnemonicStr)
+	mnemonicStr = strings.ToLower(mnemonicStr)
+
+	fmt.Println()
+
+	if !bip39.IsMnemonicValid(mnemonicStr) {
+		return nil, fmt.Errorf("invalid BIP39 mnemonic")
+	}
+	return bip39SeedFromMnemonic(mnemonicStr)
+}
+
+// bip39SeedFromMnemonic asks for the passphrase of a valid BIP39 mnemonic and
+// derives the 64 byte seed from both.
+func bip39SeedFromMnemonic(mnemonicStr string) ([]byte, error) {
+	// The mnemonic might be protected by a passphrase that is needed to
+	// derive the correct seed.
+	fmt.Printf("Input your BIP39 passphrase (press enter if your seed " +
+		"doesn't have a passphrase): ")
+	passphrase, err := terminal.ReadPassword(syscall.Stdin)
+	if err != nil {
+		return nil, err
+	}
+	fmt.Println()
+
+	return bip39.NewSeed(mnemonicStr, string(passphrase)), nil
+}
+
 func passwordFromConsole(userQuery string) ([]byte, error) {
 	// Read from terminal (if there is one).
 	if terminal.IsTerminal(syscall.Stdin) {
@@ -261,19 +740,73 @@ func passwordFromConsole(userQuery string) ([]byte, error) {
 	return pw, nil
 }
 
-func setupChainParams(cfg *config) {
-	switch {
-	case cfg.Testnet:
-		chainParams = &chaincfg.TestNet3Params
+func newBitcoind(cfg *config) *btc.Bitcoind {
+	return &btc.Bitcoind{
+		Host:     cfg.BitcoindRPC,
+		User:     cfg.BitcoindUser,
+		Password: cfg.BitcoindPass,
+		Wallet:   cfg.BitcoindWallet,
+	}
+}
+
+// selectedNetwork returns the name of the network selected with --network or
+// one of the network flags. Selecting more than one network is an error.
+func selectedNetwork(cfg *config) (string, error) {
+	flagSet := map[string]bool{
+		"mainnet": cfg.Mainnet,
+		"testnet": cfg.Testnet,
+		"regtest": cfg.Regtest,
+		"simnet":  cfg.Simnet,
+		"signet":  cfg.Signet,
+	}
+	var (
+		selected []string
+		names    []string
+		known    bool
+	)
+	network := strings.ToLower(strings.TrimSpace(cfg.Network))
+	for _, chainNetwork := range chainNetworks {
+		names = append(names, chainNetwork.name)
+		if chainNetwork.name == network {
+			known = true
+		}
+		if chainNetwork.name == network || flagSet[chainNetwork.name] {
+			selected = append(selected, chainNetwork.name)
+		}
+	}
+	if network != "" && !known {
+		return "", fmt.Errorf("unknown network %s, must be one of %s",
+			cfg.Network, strings.Join(names, ", "))
+	}
+	switch len(selected) {
+	case 0:
+		return "mainnet", nil
 
-	case cfg.Regtest:
-		chainParams = &chaincfg.RegressionNetParams
+	case 1:
+		return selected[0], nil
 
 	default:
-		chainParams = &chaincfg.MainNetParams
+		return "", fmt.Errorf("only one network can be selected, got "+
+			"%s", strings.Join(selected, " and "))
 	}
 }
 
+func setupChainParams(cfg *config) {
+	// The network flags were already checked by the command handler, so
+	// an error can't happen here and mainnet is kept in that case.
+	network, _ := selectedNetwork(cfg)
+	chainParams = &chaincfg.MainNetParams
+	for _, chainNetwork := range chainNetworks {
+		if chainNetwork.name == network {
+			chainParams = chainNetwork.params
+		}
+	}
+
+	// The derivation paths are parsed by the lnd package, so all commands
+	// follow the same rules.
+	lnd.StrictPath = cfg.StrictPath
+}
+
 func setupLogging() {
 	setSubLogger("CHAN", log)
 	addSubLogger("CHDB", channeldb.UseLogger)
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/guggero/chantools/btc"
+	"github.com/jessevdk/go-flags"
+)
+
+// TestNetworkFlags tests that the network flags and --network select the same
+// chain parameters and that more than one network can't be selected.
+func TestNetworkFlags(t *testing.T) {
+	testCases := []struct {
+		name     string
+		args     []string
+		expected *chaincfg.Params
+		err      string
+	}{{
+		name:     "default",
+		expected: &chaincfg.MainNetParams,
+	}, {
+		name:     "mainnet flag",
+		args:     []string{"--mainnet"},
+		expected: &chaincfg.MainNetParams,
+	}, {
+		name:     "testnet flag",
+		args:     []string{"--testnet"},
+		expected: &chaincfg.TestNet3Params,
+	}, {
+		name:     "regtest flag",
+		args:     []string{"--regtest"},
+		expected: &chaincfg.RegressionNetParams,
+	}, {
+		name:     "simnet flag",
+		args:     []string{"--simnet"},
+		expected: &chaincfg.SimNetParams,
+	}, {
+		name:     "signet flag",
+		args:     []string{"--signet"},
+		expected: &btc.SigNetParams,
+	}, {
+		name:     "network",
+		args:     []string{"--network", "testnet"},
+		expected: &chaincfg.TestNet3Params,
+	}, {
+		name:     "network upper case",
+		args:     []string{"--network", "Signet"},
+		expected: &btc.SigNetParams,
+	}, {
+		name:     "network and same flag",
+		args:     []string{"--network", "regtest", "--regtest"},
+		expected: &chaincfg.RegressionNetParams,
+	}, {
+		name: "unknown network",
+		args: []string{"--network", "testnet3"},
+		err: "unknown network testnet3, must be one of mainnet, " +
+			"testnet, regtest, simnet, signet",
+	}, {
+		name: "two flags",
+		args: []string{"--testnet", "--signet"},
+		err: "only one network can be selected, got testnet and " +
+			"signet",
+	}, {
+		name: "network and other flag",
+		args: []string{"--network", "mainnet", "--testnet"},
+		err: "only one network can be selected, got mainnet and " +
+			"testnet",
+	}}
+
+	oldChainParams := chainParams
+	defer func() {
+		chainParams = oldChainParams
+	}()
+
+	for _, tc := range testCases {
+		testCfg := &config{}
+		parser := flags.NewParser(testCfg, flags.None)
+		if _, err := parser.ParseArgs(tc.args); err != nil {
+			t.Fatalf("%s: error parsing flags: %v", tc.name, err)
+		}
+
+		_, err := selectedNetwork(testCfg)
+		switch {
+		case tc.err != "" && (err == nil || err.Error() != tc.err):
+			t.Fatalf("%s: expected error '%s', got %v", tc.name,
+				tc.err, err)
+
+		case tc.err == "" && err != nil:
+			t.Fatalf("%s: unexpected error: %v", tc.name, err)
+
+		case tc.err != "":
+			continue
+		}
+
+		setupChainParams(testCfg)
+		if chainParams != tc.expected {
+			t.Fatalf("%s: unexpected network %s, wanted %s",
+				tc.name, chainParams.Name, tc.expected.Name)
+		}
+	}
+}
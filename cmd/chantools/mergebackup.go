@@ -0,0 +1,169 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"time"
+
+	"github.com/btcsuite/btcutil/hdkeychain"
+	"github.com/guggero/chantools/lnd"
+	"github.com/lightningnetwork/lnd/chanbackup"
+	"github.com/lightningnetwork/lnd/keychain"
+)
+
+type mergeBackupCommand struct {
+	RootKey    string   `long:"rootkey" description:"BIP32 HD root key of the wallet that was used to create the backups. Leave empty to prompt for lnd 24 word aezeed."`
+	Inputs     []string `long:"input" description:"An lnd channel.backup file to merge. Glob patterns like backups/*.backup are expanded. Can be specified multiple times."`
+	OutputFile string   `long:"outputfile" description:"The file to write the merged backup to. (default results/backup-merged-<timestamp>.backup)"`
+	Overwrite  bool     `long:"overwrite" description:"Overwrite the output file if it already exists."`
+	Strict     bool     `long:"strict" description:"Abort if an input file can't be read instead of skipping it with a warning."`
+}
+
+func (c *mergeBackupCommand) Execute(_ []string) error {
+	setupChainParams(cfg)
+
+	var (
+		extendedKey *hdkeychain.ExtendedKey
+		err         error
+	)
+
+	// Check that root key is valid or fall back to console input.
+	switch {
+	case c.RootKey != "":
+		extendedKey, err = hdkeychain.NewKeyFromString(c.RootKey)
+
+	default:
+		extendedKey, _, err = rootKeyFromConsole()
+	}
+	if err != nil {
+		return fmt.Errorf("error reading root key: %v", err)
+	}
+
+	// Check that we have backup files.
+	if len(c.Inputs) == 0 {
+		return fmt.Errorf("at least one input file is required")
+	}
+	fileNames, err := c.inputFiles()
+	if err != nil {
+		return err
+	}
+
+	keyRing := &lnd.HDKeyRing{
+		ExtendedKey: extendedKey,
+		ChainParams: chainParams,
+	}
+	multi, err := c.mergeChannelBackups(fileNames, keyRing)
+	if err != nil {
+		return err
+	}
+
+	fileName := c.OutputFile
+	if fileName == "" {
+		fileName = fmt.Sprintf("results/backup-merged-%s.backup",
+			time.Now().Format("2006-01-02-15-04-05"))
+	}
+	log.Infof("Writing %d channels to %s", len(multi.StaticBackups),
+		fileName)
+	f, err := createOutputFile(fileName, c.Overwrite)
+	if err != nil {
+		return err
+	}
+	err = multi.PackToWriter(f, keyRing)
+	_ = f.Close()
+	return err
+}
+
+// inputFiles expands the glob patterns of the input flags. A file that is
+// matched by multiple patterns is only returned once.
+func (c *mergeBackupCommand) inputFiles() ([]string, error) {
+	var (
+		fileNames []string
+		seen      = make(map[string]bool)
+	)
+	for _, input := range c.Inputs {
+		matches, err := filepath.Glob(cleanAndExpandPath(input))
+		if err != nil {
+			return nil, fmt.Errorf("invalid input pattern %s: %v",
+				input, err)
+		}
+		if len(matches) == 0 {
+			err := c.skipInput(input, fmt.Errorf("no such file"))
+			if err != nil {
+				return nil, err
+			}
+		}
+		for _, match := range matches {
+			if seen[match] {
+				continue
+			}
+			seen[match] = true
+			fileNames = append(fileNames, match)
+		}
+	}
+	return fileNames, nil
+}
+
+// mergeChannelBackups decrypts all files and merges their channels. If the
+// same channel is contained in multiple files, the backup with the higher
+// short channel ID is kept as it reflects the more recent state.
+func (c *mergeBackupCommand) mergeChannelBackups(fileNames []string,
+	ring keychain.KeyRing) (*chanbackup.Multi, error) {
+
+	var (
+		chanPoints []string
+		singles    = make(map[string]chanbackup.Single)
+		numFiles   int
+	)
+	for _, fileName := range fileNames {
+		multiFile := chanbackup.NewMultiFile(fileName)
+		multi, err := multiFile.ExtractMulti(ring)
+		if err != nil {
+			if err := c.skipInput(fileName, err); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		numFiles++
+
+		for _, single := range multi.StaticBackups {
+			chanPoint := single.FundingOutpoint.String()
+			existing, ok := singles[chanPoint]
+			switch {
+			case !ok:
+				chanPoints = append(chanPoints, chanPoint)
+
+			case single.ShortChannelID.ToUint64() <=
+				existing.ShortChannelID.ToUint64():
+
+				continue
+			}
+			singles[chanPoint] = single
+		}
+	}
+	if numFiles == 0 {
+		return nil, fmt.Errorf("none of the input files could be read")
+	}
+	log.Infof("Merged %d channels from %d files", len(chanPoints),
+		numFiles)
+
+	multi := &chanbackup.Multi{
+		Version:       chanbackup.DefaultMultiVersion,
+		StaticBackups: make([]chanbackup.Single, 0, len(chanPoints)),
+	}
+	for _, chanPoint := range chanPoints {
+		multi.StaticBackups = append(
+			multi.StaticBackups, singles[chanPoint],
+		)
+	}
+	return multi, nil
+}
+
+// skipInput warns about an input that can't be read or returns the error if
+// the strict flag is set.
+func (c *mergeBackupCommand) skipInput(input string, err error) error {
+	if c.Strict {
+		return fmt.Errorf("error reading %s: %v", input, err)
+	}
+	log.Warnf("Skipping %s: %v", input, err)
+	return nil
+}
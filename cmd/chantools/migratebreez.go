@@ -0,0 +1,166 @@
+package main
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"time"
+
+	"github.com/btcsuite/btcd/btcec"
+	"github.com/btcsuite/btcutil"
+	"github.com/guggero/chantools/cln"
+)
+
+const (
+	defaultMaxDBID = 50
+)
+
+// breezChannelKeys are all keys of a single Breez (Greenlight) channel.
+type breezChannelKeys struct {
+	PeerPubKey               string
+	DBID                     uint64
+	FundingPubKey            string
+	FundingPrivKey           string
+	RevocationBasePoint      string
+	PaymentBasePoint         string
+	PaymentBasePointPrivKey  string
+	HtlcBasePoint            string
+	DelayedPaymentBasePoint  string
+	DelayedPaymentBaseSecret string
+	ShaChainSeed             string
+}
+
+type migrateBreezCommand struct {
+	Format         string   `long:"format" description:"The format of the generated import script. Currently supported are: bitcoin-cli, bitcoin-cli-watchonly, bitcoin-cli-taproot, bitcoin-importwallet."`
+	RecoveryWindow uint32   `long:"recoverywindow" description:"The number of on-chain keys to scan. (default 2500)"`
+	RescanFrom     uint32   `long:"rescanfrom" description:"The block number to rescan from. (default 500000, 0 on signet)"`
+	Peers          []string `long:"peer" description:"The public key of a peer (usually the Breez LSP) to derive the channel keys for. Can be specified multiple times."`
+	MaxDBID        uint64   `long:"maxdbid" description:"The highest channel database ID to derive the channel keys for. (default 50)"`
+	LabelPrefix    string   `long:"labelprefix" description:"A prefix that is added to the labels of all keys, for example to tell the keys of multiple wallets apart."`
+}
+
+func (c *migrateBreezCommand) Execute(_ []string) error {
+	setupChainParams(cfg)
+
+	// Breez creates the Greenlight node from a BIP39 mnemonic and uses the
+	// first 32 bytes of the seed as the c-lightning hsm_secret.
+	seed, err := bip39SeedFromConsole()
+	if err != nil {
+		return fmt.Errorf("error reading mnemonic: %v", err)
+	}
+	secret, err := cln.HsmSecretFromSeed(seed)
+	if err != nil {
+		return err
+	}
+
+	// Set default values.
+	if c.RecoveryWindow == 0 {
+		c.RecoveryWindow = defaultRecoveryWindow
+	}
+	if c.RescanFrom == 0 {
+		c.RescanFrom = defaultRescanFromHeight()
+	}
+	if c.MaxDBID == 0 {
+		c.MaxDBID = defaultMaxDBID
+	}
+
+	// The on-chain wallet is a plain c-lightning wallet.
+	err = printCLightningImportScript(
+		secret, c.Format, c.LabelPrefix, c.RecoveryWindow, c.RescanFrom,
+	)
+	if err != nil {
+		return err
+	}
+
+	if len(c.Peers) == 0 {
+		return nil
+	}
+
+	// We don't know the database IDs of the channels, so we derive the
+	// keys for all of them up to the given maximum. The IDs are assigned
+	// sequentially, starting at 1.
+	peerSeed, err := cln.PeerSeed(secret)
+	if err != nil {
+		return fmt.Errorf("error deriving peer seed: %v", err)
+	}
+	var channels []*breezChannelKeys
+	for _, peer := range c.Peers {
+		peerBytes, err := hex.DecodeString(peer)
+		if err != nil {
+			return fmt.Errorf("error decoding peer pubkey: %v", err)
+		}
+		peerID, err := btcec.ParsePubKey(peerBytes, btcec.S256())
+		if err != nil {
+			return fmt.Errorf("error parsing peer pubkey: %v", err)
+		}
+
+		for dbid := uint64(1); dbid <= c.MaxDBID; dbid++ {
+			keys, err := breezChannel(peerSeed, peerID, dbid)
+			if err != nil {
+				return err
+			}
+			channels = append(channels, keys)
+		}
+	}
+
+	channelBytes, err := json.MarshalIndent(channels, "", " ")
+	if err != nil {
+		return err
+	}
+	fileName := fmt.Sprintf("results/migratebreez-%s.json",
+		time.Now().Format("2006-01-02-15-04-05"))
+	log.Infof("Writing channel keys to %s", fileName)
+	return ioutil.WriteFile(fileName, channelBytes, 0600)
+}
+
+// breezChannel derives all keys of the channel with the given peer and
+// database ID.
+func breezChannel(peerSeed [32]byte, peerID *btcec.PublicKey,
+	dbid uint64) (*breezChannelKeys, error) {
+
+	channelSeed, err := cln.ChannelSeed(peerSeed, peerID, dbid)
+	if err != nil {
+		return nil, fmt.Errorf("error deriving channel seed: %v", err)
+	}
+	basepoints, err := cln.DeriveBasepoints(channelSeed)
+	if err != nil {
+		return nil, fmt.Errorf("error deriving basepoints: %v", err)
+	}
+
+	// The funding and payment keys are exported as WIF so they can be
+	// imported into bitcoind directly. With option_static_remotekey the
+	// remote party pays our balance directly to the payment basepoint.
+	fundingWIF, err := btcutil.NewWIF(basepoints.Funding, chainParams, true)
+	if err != nil {
+		return nil, fmt.Errorf("could not encode WIF: %v", err)
+	}
+	paymentWIF, err := btcutil.NewWIF(basepoints.Payment, chainParams, true)
+	if err != nil {
+		return nil, fmt.Errorf("could not encode WIF: %v", err)
+	}
+
+	return &breezChannelKeys{
+		PeerPubKey:     hex.EncodeToString(peerID.SerializeCompressed()),
+		DBID:           dbid,
+		FundingPubKey:  pubKeyHex(basepoints.Funding),
+		FundingPrivKey: fundingWIF.String(),
+		RevocationBasePoint: pubKeyHex(
+			basepoints.Revocation,
+		),
+		PaymentBasePoint:        pubKeyHex(basepoints.Payment),
+		PaymentBasePointPrivKey: paymentWIF.String(),
+		HtlcBasePoint:           pubKeyHex(basepoints.Htlc),
+		DelayedPaymentBasePoint: pubKeyHex(
+			basepoints.DelayedPayment,
+		),
+		DelayedPaymentBaseSecret: hex.EncodeToString(
+			basepoints.DelayedPayment.Serialize(),
+		),
+		ShaChainSeed: hex.EncodeToString(basepoints.ShaSeed[:]),
+	}, nil
+}
+
+func pubKeyHex(privKey *btcec.PrivateKey) string {
+	return hex.EncodeToString(privKey.PubKey().SerializeCompressed())
+}
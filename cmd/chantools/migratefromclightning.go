@@ -0,0 +1,98 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/guggero/chantools/cln"
+)
+
+type migrateFromCLightningCommand struct {
+	HsmSecret      string `long:"hsmsecret" description:"The c-lightning hsm_secret file to read the wallet secret from. Leave empty to prompt for a BIP39 mnemonic."`
+	Format         string `long:"format" description:"The format of the generated import script. Currently supported are: bitcoin-cli, bitcoin-cli-watchonly, bitcoin-cli-taproot, bitcoin-importwallet."`
+	RecoveryWindow uint32 `long:"recoverywindow" description:"The number of keys to scan. (default 2500)"`
+	RescanFrom     uint32 `long:"rescanfrom" description:"The block number to rescan from. (default 500000, 0 on signet)"`
+	LabelPrefix    string `long:"labelprefix" description:"A prefix that is added to the labels of all keys, for example to tell the keys of multiple wallets apart."`
+}
+
+func (c *migrateFromCLightningCommand) Execute(_ []string) error {
+	setupChainParams(cfg)
+
+	var (
+		secret [cln.HsmSecretSize]byte
+		err    error
+	)
+
+	// Read the hsm_secret from the file or fall back to deriving it from
+	// the BIP39 mnemonic entered on the console.
+	switch {
+	case c.HsmSecret != "":
+		secret, err = cln.ReadHsmSecret(cleanAndExpandPath(c.HsmSecret))
+		if err != nil {
+			return fmt.Errorf("error reading hsm_secret: %v", err)
+		}
+
+	default:
+		seed, err := bip39SeedFromConsole()
+		if err != nil {
+			return fmt.Errorf("error reading mnemonic: %v", err)
+		}
+		secret, err = cln.HsmSecretFromSeed(seed)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Set default values.
+	if c.RecoveryWindow == 0 {
+		c.RecoveryWindow = defaultRecoveryWindow
+	}
+	if c.RescanFrom == 0 {
+		c.RescanFrom = defaultRescanFromHeight()
+	}
+
+	return printCLightningImportScript(
+		secret, c.Format, c.LabelPrefix, c.RecoveryWindow, c.RescanFrom,
+	)
+}
+
+// printCLightningImportScript prints the import script for all on-chain keys
+// of the c-lightning wallet with the given hsm_secret.
+func printCLightningImportScript(secret [cln.HsmSecretSize]byte, format,
+	labelPrefix string, recoveryWindow, rescanFrom uint32) error {
+
+	masterKey, err := cln.MasterKey(secret, chainParams)
+	if err != nil {
+		return fmt.Errorf("error deriving master key: %v", err)
+	}
+	baseKey, err := cln.WalletBaseKey(masterKey)
+	if err != nil {
+		return fmt.Errorf("error deriving wallet base key: %v", err)
+	}
+
+	fmt.Printf("# Wallet dump of c-lightning wallet created by chantools "+
+		"on %s\n", time.Now().UTC())
+
+	// c-lightning doesn't use separate internal and external branches,
+	// all keys are direct children of the base key at m/0/0.
+	printFn := importScriptPrintFn(
+		os.Stdout, format, importWalletBirthday(rescanFrom), nil,
+	)
+	for i := uint32(0); i < recoveryWindow; i++ {
+		hdKey, err := baseKey.Child(i)
+		if err != nil {
+			return err
+		}
+		key, err := newDerivedKey(hdKey, "m/0", 0, i, false)
+		if err != nil {
+			return err
+		}
+		if err := printFn(os.Stdout, key, labelPrefix); err != nil {
+			return err
+		}
+	}
+
+	fmt.Printf("bitcoin-cli rescanblockchain %d\n", rescanFrom)
+	return nil
+}
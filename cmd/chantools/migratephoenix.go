@@ -0,0 +1,278 @@
+package main
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"time"
+
+	"github.com/btcsuite/btcd/btcec"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/btcsuite/btcutil"
+	"github.com/btcsuite/btcutil/hdkeychain"
+	"github.com/guggero/chantools/btc"
+	"github.com/guggero/chantools/phoenix"
+	"github.com/guggero/chantools/sweep"
+	"github.com/lightningnetwork/lnd/input"
+)
+
+const (
+	defaultPhoenixRecoveryWindow = 20
+)
+
+// phoenixSwapIn is a single derived swap-in address of a Phoenix wallet.
+type phoenixSwapIn struct {
+	index         uint32
+	userKey       *btcec.PrivateKey
+	witnessScript []byte
+	address       *btcutil.AddressWitnessScriptHash
+}
+
+type migratePhoenixCommand struct {
+	ServerPubKey   string   `long:"serverpubkey" description:"The public key of the ACINQ swap-in server that is part of the swap-in script."`
+	RefundDelay    uint32   `long:"refunddelay" description:"The relative time lock in blocks of the timeout path of the swap-in script. (default 25920)"`
+	RecoveryWindow uint32   `long:"recoverywindow" description:"The number of swap-in addresses to derive. (default 20)"`
+	SweepAddr      string   `long:"sweepaddr" description:"The address the funds of all swap-in addresses should be sweeped to. Leave empty to only generate the import script."`
+	ServerSigs     []string `long:"serversig" description:"The hex encoded DER signature of the swap-in server to spend an input through the cooperative path. Must be specified once for each input, in the order the inputs are listed. Leave empty to use the timeout path."`
+	Publish        bool     `long:"publish" description:"Should the sweep TX be published to the chain API?"`
+	LabelPrefix    string   `long:"labelprefix" description:"A prefix that is added to the labels of all keys, for example to tell the keys of multiple wallets apart."`
+}
+
+func (c *migratePhoenixCommand) Execute(_ []string) error {
+	setupChainParams(cfg)
+
+	if c.ServerPubKey == "" {
+		return fmt.Errorf("server pubkey is required")
+	}
+	serverKey, err := pubKeyFromHex(c.ServerPubKey)
+	if err != nil {
+		return fmt.Errorf("error parsing server pubkey: %v", err)
+	}
+
+	// Phoenix uses a 12 word BIP39 mnemonic, the root key is derived from
+	// the seed the same way as in any other BIP39 wallet.
+	seed, err := bip39SeedFromConsole()
+	if err != nil {
+		return fmt.Errorf("error reading mnemonic: %v", err)
+	}
+	rootKey, err := hdkeychain.NewMaster(seed, chainParams)
+	if err != nil {
+		return fmt.Errorf("failed to derive master extended key: %v",
+			err)
+	}
+
+	// Set default values.
+	if c.RefundDelay == 0 {
+		c.RefundDelay = phoenix.DefaultRefundDelay
+	}
+	if c.RecoveryWindow == 0 {
+		c.RecoveryWindow = defaultPhoenixRecoveryWindow
+	}
+
+	swapIns := make([]*phoenixSwapIn, c.RecoveryWindow)
+	for i := uint32(0); i < c.RecoveryWindow; i++ {
+		swapIns[i], err = deriveSwapIn(
+			rootKey, serverKey, c.RefundDelay, i,
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	fmt.Printf("# Wallet dump of Phoenix swap-in addresses created by "+
+		"chantools on %s\n", time.Now().UTC())
+	fmt.Println("# Save this output to a file and use the importwallet " +
+		"command of bitcoin core.")
+	for _, swapIn := range swapIns {
+		err := printPhoenixImportWallet(swapIn, c.LabelPrefix)
+		if err != nil {
+			return err
+		}
+	}
+
+	if c.SweepAddr == "" {
+		return nil
+	}
+	return sweepPhoenix(
+		cfg.APIURL, swapIns, c.SweepAddr, c.RefundDelay, c.ServerSigs,
+		c.Publish,
+	)
+}
+
+func deriveSwapIn(rootKey *hdkeychain.ExtendedKey, serverKey *btcec.PublicKey,
+	refundDelay, index uint32) (*phoenixSwapIn, error) {
+
+	userKey, err := phoenix.UserKey(rootKey, chainParams, index)
+	if err != nil {
+		return nil, fmt.Errorf("could not derive user key: %v", err)
+	}
+	script, err := phoenix.SwapInScript(
+		userKey.PubKey(), serverKey, refundDelay,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("could not create script: %v", err)
+	}
+	addr, err := phoenix.SwapInAddress(script, chainParams)
+	if err != nil {
+		return nil, err
+	}
+	return &phoenixSwapIn{
+		index:         index,
+		userKey:       userKey,
+		witnessScript: script,
+		address:       addr,
+	}, nil
+}
+
+func printPhoenixImportWallet(swapIn *phoenixSwapIn, labelPrefix string) error {
+	wif, err := btcutil.NewWIF(swapIn.userKey, chainParams, true)
+	if err != nil {
+		return fmt.Errorf("could not encode WIF: %v", err)
+	}
+
+	// The user key itself is imported together with the witness script of
+	// the swap-in output so bitcoind can watch the P2WSH address.
+	fmt.Printf("%s 1970-01-01T00:00:01Z label=%sm/52'/%d'/0'/0/%d/\n",
+		wif.String(), labelPrefix, chainParams.HDCoinType, swapIn.index)
+	fmt.Printf("%x 1970-01-01T00:00:01Z script=1 # addr=%s\n",
+		swapIn.witnessScript, swapIn.address.EncodeAddress())
+	return nil
+}
+
+func sweepPhoenix(apiURL string, swapIns []*phoenixSwapIn, sweepAddr string,
+	refundDelay uint32, serverSigs []string, publish bool) error {
+
+	api := &btc.ExplorerAPI{BaseURL: apiURL}
+	cooperative := len(serverSigs) > 0
+
+	// Find all unspent outputs of the derived swap-in addresses.
+	type swapInUTXO struct {
+		swapIn *phoenixSwapIn
+		utxo   *btc.UTXO
+	}
+	var (
+		utxos            []*swapInUTXO
+		totalOutputValue = int64(0)
+	)
+	for _, swapIn := range swapIns {
+		addrUTXOs, err := api.Unspent(swapIn.address.EncodeAddress())
+		if err != nil {
+			return fmt.Errorf("error querying unspent outputs of "+
+				"%s: %v", swapIn.address.EncodeAddress(), err)
+		}
+		for _, utxo := range addrUTXOs {
+			log.Infof("Found %d sats in %s:%d of address %s",
+				utxo.Value, utxo.Txid, utxo.Vout,
+				swapIn.address.EncodeAddress())
+
+			totalOutputValue += int64(utxo.Value)
+			utxos = append(utxos, &swapInUTXO{
+				swapIn: swapIn,
+				utxo:   utxo,
+			})
+		}
+	}
+	if len(utxos) == 0 {
+		return fmt.Errorf("no unspent swap-in outputs found")
+	}
+	if cooperative && len(serverSigs) != len(utxos) {
+		return fmt.Errorf("got %d server signatures for %d inputs",
+			len(serverSigs), len(utxos))
+	}
+
+	builder := sweep.NewSweepBuilder(chainParams)
+	builder.SetFeeRate(feeSatPerByte)
+	for idx, u := range utxos {
+		txHash, err := chainhash.NewHashFromStr(u.utxo.Txid)
+		if err != nil {
+			return fmt.Errorf("error parsing tx hash: %v", err)
+		}
+		witnessScript := u.swapIn.witnessScript
+		utxo := sweep.UTXO{
+			OutPoint: wire.OutPoint{
+				Hash:  *txHash,
+				Index: u.utxo.Vout,
+			},
+			Value:         int64(u.utxo.Value),
+			WitnessScript: witnessScript,
+		}
+
+		// The timeout path requires the input's sequence to be set to
+		// the refund delay. The server's signatures commit to the
+		// final sequence so we can't signal RBF in the cooperative
+		// case.
+		switch {
+		case cooperative:
+			serverSig, err := hex.DecodeString(serverSigs[idx])
+			if err != nil {
+				return fmt.Errorf("error decoding server "+
+					"signature: %v", err)
+			}
+			serverSig = append(serverSig, byte(txscript.SigHashAll))
+			utxo.Sequence = wire.MaxTxInSequenceNum
+			utxo.WitnessSize = phoenix.CooperativeWitnessSize
+			utxo.Witness = func(sig []byte) wire.TxWitness {
+				return phoenix.CooperativeWitness(
+					sig, serverSig, witnessScript,
+				)
+			}
+
+		default:
+			utxo.Sequence = input.LockTimeToSequence(
+				false, refundDelay,
+			)
+			utxo.WitnessSize = phoenix.TimeoutWitnessSize
+			utxo.Witness = func(sig []byte) wire.TxWitness {
+				return phoenix.TimeoutWitness(
+					sig, witnessScript,
+				)
+			}
+		}
+		builder.AddInput(utxo, u.swapIn.userKey, sweep.ScriptTypeP2WSH)
+	}
+
+	// Add our sweep destination output. Because the server's signatures
+	// commit to the whole transaction, the fee is estimated up front by
+	// the builder instead of signing twice.
+	addr, err := btcutil.DecodeAddress(sweepAddr, chainParams)
+	if err != nil {
+		return fmt.Errorf("error parsing sweep addr: %v", err)
+	}
+	builder.SetOutput(addr)
+	sweepTx, err := builder.Build()
+	if err != nil {
+		return fmt.Errorf("error creating sweep TX: %v", err)
+	}
+	fee := builder.Fee()
+
+	var buf bytes.Buffer
+	err = sweepTx.Serialize(&buf)
+	if err != nil {
+		return err
+	}
+	log.Infof("Fee %d sats of %d total amount (for size %d)",
+		fee, totalOutputValue, sweepTx.SerializeSize())
+
+	// Publish TX.
+	switch {
+	case cfg.DryRun:
+		return printDryRun(
+			hex.EncodeToString(buf.Bytes()), builder.InputValues(),
+		)
+
+	case publish:
+		response, err := api.PublishTx(
+			hex.EncodeToString(buf.Bytes()),
+		)
+		if err != nil {
+			return err
+		}
+		log.Infof("Published TX %s, response: %s",
+			sweepTx.TxHash().String(), response)
+	}
+
+	log.Infof("Transaction: %x", buf.Bytes())
+	return nil
+}
@@ -0,0 +1,508 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path"
+	"runtime"
+	"time"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/btcsuite/btcutil/hdkeychain"
+	"github.com/guggero/chantools/btc"
+	"github.com/guggero/chantools/dataformat"
+	"github.com/guggero/chantools/lnd"
+	"github.com/lightninglabs/gozmq"
+	"github.com/lightningnetwork/lnd/channeldb"
+	"github.com/lightningnetwork/lnd/input"
+	"github.com/lightningnetwork/lnd/lnwallet"
+)
+
+const (
+	alertInfo     = "INFO"
+	alertWarning  = "WARNING"
+	alertCritical = "CRITICAL"
+
+	// zmqTopicRawTx and zmqTopicHashBlock are the topics bitcoind
+	// publishes new transactions and blocks on.
+	zmqTopicRawTx     = "rawtx"
+	zmqTopicHashBlock = "hashblock"
+
+	zmqReadTimeout = 5 * time.Second
+)
+
+// alerter delivers alerts to all configured destinations.
+type alerter struct {
+	webhook string
+	desktop bool
+}
+
+// alert is the JSON body that is posted to the webhook.
+type alert struct {
+	Level        string `json:"level"`
+	ChannelPoint string `json:"channel_point"`
+	Message      string `json:"message"`
+	Time         string `json:"time"`
+}
+
+func (a *alerter) send(level, channelPoint, message string) {
+	now := time.Now()
+	_, _ = fmt.Fprintf(os.Stderr, "%s [%s] %s: %s\n",
+		now.Format(time.RFC3339), level, channelPoint, message)
+
+	if a.webhook != "" {
+		body, err := json.Marshal(&alert{
+			Level:        level,
+			ChannelPoint: channelPoint,
+			Message:      message,
+			Time:         now.Format(time.RFC3339),
+		})
+		if err == nil {
+			err = postWebhook(a.webhook, body)
+		}
+		if err != nil {
+			log.Errorf("Could not deliver alert to webhook: %v",
+				err)
+		}
+	}
+
+	if a.desktop {
+		title := fmt.Sprintf("chantools: %s", level)
+		text := fmt.Sprintf("%s: %s", channelPoint, message)
+		if err := desktopNotification(title, text); err != nil {
+			log.Errorf("Could not show desktop notification: %v",
+				err)
+		}
+	}
+}
+
+// timeLockedOutput is the to_local output of one of our own commitment
+// transactions that can be swept once its CSV delay has expired.
+type timeLockedOutput struct {
+	channel  *channeldb.OpenChannel
+	outpoint wire.OutPoint
+	notified bool
+}
+
+type monitorCommand struct {
+	ChannelDB    string `long:"channeldb" description:"The lnd channel.db file to load the channels to monitor from."`
+	ZMQRawTx     string `long:"zmqpubrawtx" description:"The address of the ZMQ rawtx publisher of bitcoind, for example tcp://127.0.0.1:28333."`
+	ZMQHashBlock string `long:"zmqpubhashblock" description:"The address of the ZMQ hashblock publisher of bitcoind. Needed to track the CSV delay of force-closed channels, can be the same as zmqpubrawtx."`
+	Webhook      string `long:"webhook" description:"A URL to post alerts to as JSON."`
+	Desktop      bool   `long:"desktop" description:"Show alerts as desktop notifications (notify-send on Linux, osascript on macOS)."`
+	AutoSweep    bool   `long:"autosweep" description:"Sweep the time locked output of our own force-closed channels once the CSV delay expired. Requires the root key."`
+	SweepAddr    string `long:"sweepaddr" description:"The address to sweep the time locked outputs to if autosweep is set."`
+	RootKey      string `long:"rootkey" description:"BIP32 HD root key of the node, only needed for autosweep. Leave empty to prompt for lnd 24 word aezeed."`
+}
+
+func (c *monitorCommand) Execute(_ []string) error {
+	setupChainParams(cfg)
+
+	var extendedKey *hdkeychain.ExtendedKey
+	if c.AutoSweep {
+		if c.SweepAddr == "" {
+			return fmt.Errorf("sweep addr is required for " +
+				"autosweep")
+		}
+		if c.ZMQHashBlock == "" {
+			return fmt.Errorf("zmqpubhashblock is required for " +
+				"autosweep")
+		}
+
+		var err error
+		switch {
+		case c.RootKey != "":
+			extendedKey, err = hdkeychain.NewKeyFromString(
+				c.RootKey,
+			)
+
+		default:
+			extendedKey, _, err = rootKeyFromConsole()
+		}
+		if err != nil {
+			return fmt.Errorf("error reading root key: %v", err)
+		}
+	}
+
+	if c.ZMQRawTx == "" {
+		return fmt.Errorf("zmqpubrawtx is required")
+	}
+
+	// Check that we have a channel DB.
+	if c.ChannelDB == "" {
+		return fmt.Errorf("channel DB is required")
+	}
+	db, err := channeldb.Open(
+		path.Dir(c.ChannelDB), channeldb.OptionSetSyncFreelist(true),
+		channeldb.OptionReadOnly(true),
+	)
+	if err != nil {
+		return fmt.Errorf("error opening channel DB: %v", err)
+	}
+	channels, err := db.FetchAllChannels()
+	db.Close()
+	if err != nil {
+		return fmt.Errorf("error fetching channels: %v", err)
+	}
+	if len(channels) == 0 {
+		return fmt.Errorf("no channels to monitor in channel DB")
+	}
+
+	m := &monitor{
+		alerter:     &alerter{webhook: c.Webhook, desktop: c.Desktop},
+		bitcoind:    newBitcoind(cfg),
+		extendedKey: extendedKey,
+		sweepAddr:   c.SweepAddr,
+		funding:     make(map[wire.OutPoint]*channeldb.OpenChannel),
+		seenTxs:     make(map[string]bool),
+	}
+	for _, channel := range channels {
+		m.funding[channel.FundingOutpoint] = channel
+	}
+	m.checkFundingOutputs()
+
+	return m.run(c.ZMQRawTx, c.ZMQHashBlock)
+}
+
+// monitor watches the funding outputs of the channels and the time locked
+// outputs of our own commitment transactions.
+type monitor struct {
+	alerter     *alerter
+	bitcoind    *btc.Bitcoind
+	extendedKey *hdkeychain.ExtendedKey
+	sweepAddr   string
+
+	funding    map[wire.OutPoint]*channeldb.OpenChannel
+	timeLocked []*timeLockedOutput
+	seenTxs    map[string]bool
+}
+
+// checkFundingOutputs warns about channels that were already closed before
+// the monitoring started.
+func (m *monitor) checkFundingOutputs() {
+	for outpoint, channel := range m.funding {
+		txOut, err := m.bitcoind.GetTxOut(
+			outpoint.Hash.String(), outpoint.Index,
+		)
+		switch {
+		case err != nil:
+			log.Warnf("Could not check funding output of channel "+
+				"%s: %v", outpoint, err)
+
+		case txOut == nil:
+			m.alerter.send(alertWarning, outpoint.String(),
+				"funding output is already spent, run "+
+					"chantools summary to find out how "+
+					"the channel was closed")
+			delete(m.funding, outpoint)
+
+		default:
+			localBalance := channel.LocalCommitment.LocalBalance
+			log.Infof("Monitoring channel %s with a local "+
+				"balance of %d sats", outpoint,
+				localBalance.ToSatoshis())
+		}
+	}
+}
+
+func (m *monitor) run(rawTxAddr, hashBlockAddr string) error {
+	txs := make(chan []byte)
+	blocks := make(chan []byte)
+	errs := make(chan error, 2)
+	topics := map[string][]string{rawTxAddr: {zmqTopicRawTx}}
+	if hashBlockAddr != "" {
+		topics[hashBlockAddr] = append(
+			topics[hashBlockAddr], zmqTopicHashBlock,
+		)
+	}
+	for addr, addrTopics := range topics {
+		conn, err := gozmq.Subscribe(addr, addrTopics, zmqReadTimeout)
+		if err != nil {
+			return fmt.Errorf("error subscribing to %s: %v", addr,
+				err)
+		}
+		defer conn.Close()
+		go receiveZMQ(conn, txs, blocks, errs)
+	}
+
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt)
+
+	log.Infof("Waiting for transactions, press Ctrl+C to stop")
+	for {
+		select {
+		case rawTx := <-txs:
+			tx := &wire.MsgTx{}
+			err := tx.Deserialize(bytes.NewReader(rawTx))
+			if err != nil {
+				log.Errorf("Could not parse tx: %v", err)
+				continue
+			}
+			m.handleTx(tx)
+
+		case <-blocks:
+			m.handleBlock()
+
+		case err := <-errs:
+			return err
+
+		case <-interrupt:
+			log.Infof("Stopping monitor")
+			return nil
+		}
+	}
+}
+
+// receiveZMQ reads all messages of a ZMQ connection and sends them to the
+// channel of their topic.
+func receiveZMQ(conn *gozmq.Conn, txs, blocks chan []byte, errs chan error) {
+	for {
+		msg, err := conn.Receive(nil)
+		if netErr, ok := err.(net.Error); ok && netErr.Timeout() {
+			continue
+		}
+		if err != nil {
+			errs <- fmt.Errorf("error receiving from ZMQ: %v", err)
+			return
+		}
+		if len(msg) < 2 {
+			continue
+		}
+		switch string(msg[0]) {
+		case zmqTopicRawTx:
+			txs <- msg[1]
+
+		case zmqTopicHashBlock:
+			blocks <- msg[1]
+		}
+	}
+}
+
+// handleTx checks whether a transaction spends the funding output of one of
+// the channels and sends the matching alert. bitcoind publishes a transaction
+// once when it enters the mempool and once when it's mined, so we only look at
+// it the first time.
+func (m *monitor) handleTx(tx *wire.MsgTx) {
+	txid := tx.TxHash()
+	if m.seenTxs[txid.String()] {
+		return
+	}
+	m.seenTxs[txid.String()] = true
+
+	for _, txIn := range tx.TxIn {
+		channel, ok := m.funding[txIn.PreviousOutPoint]
+		if !ok {
+			continue
+		}
+		chanPoint := channel.FundingOutpoint.String()
+		localCommit := channel.LocalCommitment
+		remoteCommit := channel.RemoteCommitment
+
+		switch {
+		case !isCommitmentTx(tx):
+			m.alerter.send(alertInfo, chanPoint, fmt.Sprintf(
+				"channel was cooperatively closed in tx %v",
+				txid,
+			))
+
+		case localCommit.CommitTx != nil &&
+			localCommit.CommitTx.TxHash() == txid:
+
+			m.alerter.send(alertWarning, chanPoint, fmt.Sprintf(
+				"our commitment tx %v was published, the "+
+					"local balance is time locked for %d "+
+					"blocks", txid,
+				channel.LocalChanCfg.CsvDelay,
+			))
+			m.trackTimeLock(channel, txid)
+
+		case commitHeight(channel, tx) < remoteCommit.CommitHeight:
+			m.alerter.send(alertCritical, chanPoint, fmt.Sprintf(
+				"BREACH: the remote party published the "+
+					"revoked state %d in tx %v, start lnd "+
+					"with the channel.db immediately to "+
+					"claim all funds before the CSV delay "+
+					"of %d blocks expires",
+				commitHeight(channel, tx), txid,
+				channel.RemoteChanCfg.CsvDelay,
+			))
+
+		default:
+			m.alerter.send(alertWarning, chanPoint, fmt.Sprintf(
+				"the remote party force-closed the channel "+
+					"in tx %v, run chantools rescueclosed "+
+					"if the funds don't arrive in the "+
+					"wallet", txid,
+			))
+		}
+		delete(m.funding, txIn.PreviousOutPoint)
+	}
+}
+
+// trackTimeLock starts tracking the CSV delay of the to_local output of our
+// own commitment transaction.
+func (m *monitor) trackTimeLock(channel *channeldb.OpenChannel,
+	txid chainhash.Hash) {
+
+	revocationPreimage, err := channel.RevocationProducer.AtIndex(
+		channel.LocalCommitment.CommitHeight,
+	)
+	if err != nil {
+		log.Errorf("Could not derive commit point: %v", err)
+		return
+	}
+	commitPoint := input.ComputeCommitmentPoint(revocationPreimage[:])
+	index, err := toLocalOutputIndex(channel, commitPoint)
+	if err != nil || index < 0 {
+		log.Infof("No time locked output in commitment of channel %s",
+			channel.FundingOutpoint)
+		return
+	}
+	m.timeLocked = append(m.timeLocked, &timeLockedOutput{
+		channel: channel,
+		outpoint: wire.OutPoint{
+			Hash:  txid,
+			Index: uint32(index),
+		},
+	})
+}
+
+// handleBlock updates the CSV countdown of all time locked outputs and sweeps
+// them if the delay expired and autosweep is enabled.
+func (m *monitor) handleBlock() {
+	var remaining []*timeLockedOutput
+	for _, output := range m.timeLocked {
+		chanPoint := output.channel.FundingOutpoint.String()
+		csvDelay := uint32(output.channel.LocalChanCfg.CsvDelay)
+		txOut, err := m.bitcoind.GetTxOut(
+			output.outpoint.Hash.String(), output.outpoint.Index,
+		)
+		switch {
+		case err != nil:
+			log.Errorf("Could not check time locked output %v: %v",
+				output.outpoint, err)
+			remaining = append(remaining, output)
+			continue
+
+		case txOut == nil:
+			m.alerter.send(alertInfo, chanPoint, fmt.Sprintf(
+				"time locked output %v was swept",
+				output.outpoint,
+			))
+			continue
+		}
+		remaining = append(remaining, output)
+
+		if txOut.Confirmations < csvDelay {
+			log.Infof("Time locked output %v of channel %s can be "+
+				"swept in %d blocks", output.outpoint,
+				chanPoint, csvDelay-txOut.Confirmations)
+			continue
+		}
+		if output.notified {
+			continue
+		}
+		output.notified = true
+
+		if m.extendedKey == nil {
+			m.alerter.send(alertWarning, chanPoint, fmt.Sprintf(
+				"CSV delay of time locked output %v expired, "+
+					"sweep it with chantools sweeptimelock",
+				output.outpoint,
+			))
+			continue
+		}
+		if err := m.sweep(output); err != nil {
+			m.alerter.send(alertWarning, chanPoint, fmt.Sprintf(
+				"CSV delay of time locked output %v expired "+
+					"but sweeping failed: %v",
+				output.outpoint, err,
+			))
+			continue
+		}
+		m.alerter.send(alertInfo, chanPoint, fmt.Sprintf(
+			"CSV delay of time locked output %v expired, sweep "+
+				"transaction published", output.outpoint,
+		))
+	}
+	m.timeLocked = remaining
+}
+
+// sweep publishes a transaction that sweeps the time locked output to the
+// sweep address.
+func (m *monitor) sweep(output *timeLockedOutput) error {
+	signer := &lnd.Signer{
+		ExtendedKey: m.extendedKey,
+		ChainParams: chainParams,
+	}
+	forceClose, err := signCommitment(output.channel, signer)
+	if err != nil {
+		return err
+	}
+	localBalance := output.channel.LocalCommitment.LocalBalance
+	entry := &dataformat.SummaryEntry{
+		ChannelPoint: output.channel.FundingOutpoint.String(),
+		LocalBalance: uint64(localBalance.ToSatoshis()),
+		ForceClose:   forceClose,
+	}
+	return sweepTimeLock(
+		m.extendedKey, cfg.APIURL, []*dataformat.SummaryEntry{entry},
+		m.sweepAddr, int(output.channel.LocalChanCfg.CsvDelay), true,
+		false, "", 0,
+	)
+}
+
+// isCommitmentTx returns true if the transaction has the lock time and
+// sequence encoding that lnd uses for the obfuscated commitment number.
+func isCommitmentTx(tx *wire.MsgTx) bool {
+	return len(tx.TxIn) == 1 && tx.LockTime>>24 == 0x20 &&
+		tx.TxIn[0].Sequence>>24 == 0x80
+}
+
+// commitHeight decodes the obfuscated commitment number of a commitment
+// transaction of the channel.
+func commitHeight(channel *channeldb.OpenChannel, tx *wire.MsgTx) uint64 {
+	localBase := channel.LocalChanCfg.PaymentBasePoint.PubKey
+	remoteBase := channel.RemoteChanCfg.PaymentBasePoint.PubKey
+	obfuscator := lnwallet.DeriveStateHintObfuscator(localBase, remoteBase)
+	if !channel.IsInitiator {
+		obfuscator = lnwallet.DeriveStateHintObfuscator(
+			remoteBase, localBase,
+		)
+	}
+	return lnwallet.GetStateNumHint(tx, obfuscator)
+}
+
+// postWebhook posts the JSON body to the webhook URL.
+func postWebhook(url string, body []byte) error {
+	client := &http.Client{Timeout: 10 * time.Second}
+	resp, err := client.Post(url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("webhook returned status %s", resp.Status)
+	}
+	return nil
+}
+
+// desktopNotification shows a notification with the tool of the operating
+// system.
+func desktopNotification(title, text string) error {
+	if runtime.GOOS == "darwin" {
+		script := fmt.Sprintf("display notification %q with title %q",
+			text, title)
+		return exec.Command("osascript", "-e", script).Run()
+	}
+
+	// notify-send talks to the notification daemon over dbus.
+	return exec.Command("notify-send", title, text).Run()
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// progressMinKeys is the recovery window from which on the progress of
+	// the key derivation is reported.
+	progressMinKeys = 10000
+
+	// progressInterval is the interval in which the progress is printed.
+	progressInterval = 500 * time.Millisecond
+
+	// progressRateWindow is the time span the throughput for the ETA is
+	// averaged over.
+	progressRateWindow = 5 * time.Second
+)
+
+// progressSample is the number of completed keys at a point in time.
+type progressSample struct {
+	time      time.Time
+	completed uint64
+}
+
+// progressReporter periodically prints the progress of the key derivation to
+// stderr, so it doesn't get mixed up with the keys that are written to stdout.
+// A nil reporter can be used to not report anything.
+type progressReporter struct {
+	// completed is the number of keys derived so far, it must be accessed
+	// atomically and is the first field to be 64-bit aligned.
+	completed uint64
+
+	total   uint64
+	start   time.Time
+	samples []progressSample
+
+	quit     chan struct{}
+	quitOnce sync.Once
+	wg       sync.WaitGroup
+}
+
+// newProgressReporter creates a reporter for the given total number of keys.
+func newProgressReporter(total uint64) *progressReporter {
+	return &progressReporter{
+		total: total,
+		quit:  make(chan struct{}),
+	}
+}
+
+// Start starts printing the progress until the reporter is stopped.
+func (p *progressReporter) Start() {
+	p.start = time.Now()
+	p.samples = []progressSample{{time: p.start}}
+
+	p.wg.Add(1)
+	go func() {
+		defer p.wg.Done()
+
+		ticker := time.NewTicker(progressInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				p.print(time.Now())
+
+			case <-p.quit:
+				return
+			}
+		}
+	}()
+}
+
+// Stop stops the reporter and prints the final progress. It is safe to call on
+// a nil reporter and more than once.
+func (p *progressReporter) Stop() {
+	if p == nil {
+		return
+	}
+	p.quitOnce.Do(func() {
+		close(p.quit)
+		p.wg.Wait()
+		_, _ = fmt.Fprintf(os.Stderr, "Derived %d keys in %v\n",
+			atomic.LoadUint64(&p.completed),
+			time.Since(p.start).Round(time.Second))
+	})
+}
+
+// done marks a single key as derived. It is safe to call on a nil reporter.
+func (p *progressReporter) done() {
+	if p == nil {
+		return
+	}
+	atomic.AddUint64(&p.completed, 1)
+}
+
+// print prints the current progress. The ETA is calculated from the throughput
+// of the last few seconds, so it adapts quickly if the machine gets busy.
+func (p *progressReporter) print(now time.Time) {
+	completed := atomic.LoadUint64(&p.completed)
+	p.samples = append(p.samples, progressSample{
+		time:      now,
+		completed: completed,
+	})
+	for len(p.samples) > 2 &&
+		now.Sub(p.samples[1].time) >= progressRateWindow {
+
+		p.samples = p.samples[1:]
+	}
+
+	eta := "--:--:--"
+	oldest := p.samples[0]
+	elapsed := now.Sub(oldest.time).Seconds()
+	if rate := float64(completed-oldest.completed) / elapsed; rate > 0 {
+		remaining := float64(p.total-completed) / rate
+		eta = formatETA(time.Duration(remaining * float64(time.Second)))
+	}
+	_, _ = fmt.Fprintf(os.Stderr, "Deriving keys: %d/%d (%d%%) ETA %s\n",
+		completed, p.total, completed*100/p.total, eta)
+}
+
+// formatETA formats a duration as hours, minutes and seconds.
+func formatETA(d time.Duration) string {
+	seconds := int64(d.Round(time.Second).Seconds())
+	return fmt.Sprintf("%02d:%02d:%02d", seconds/3600, seconds/60%60,
+		seconds%60)
+}
@@ -0,0 +1,30 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"strings"
+
+	"github.com/btcsuite/btcutil/psbt"
+)
+
+// writePsbts encodes the PSBTs in base64 and writes them to the given file,
+// one per line. If no file name is given, they are printed instead.
+func writePsbts(packets []*psbt.Psbt, fileName string) error {
+	lines := make([]string, len(packets))
+	for idx, packet := range packets {
+		b64, err := packet.B64Encode()
+		if err != nil {
+			return fmt.Errorf("error encoding PSBT: %v", err)
+		}
+		lines[idx] = b64
+	}
+	content := strings.Join(lines, "\n") + "\n"
+
+	if fileName == "" {
+		fmt.Print(content)
+		return nil
+	}
+	log.Infof("Writing %d unsigned PSBTs to %s", len(packets), fileName)
+	return ioutil.WriteFile(fileName, []byte(content), 0644)
+}
@@ -0,0 +1,274 @@
+package main
+
+import (
+	"fmt"
+	"path"
+	"time"
+
+	"github.com/coreos/bbolt"
+	"github.com/lightningnetwork/lnd/channeldb"
+)
+
+var (
+	// Bucket names from github.com/lightningnetwork/lnd/channeldb/
+	// payments.go
+	paymentsRootBucket = []byte("payments-root-bucket")
+)
+
+type purgePaymentsCommand struct {
+	ChannelDB  string `long:"channeldb" description:"The lnd channel.db file to remove the payments from. lnd must not be running."`
+	ArchiveDir string `long:"archivedir" description:"The directory to create the archive channel.db file in that the removed payments are written to. If it already exists, the payments are added to it."`
+	OlderThan  string `long:"olderthan" description:"Only remove payments that were created longer ago than this, for example 30d or 720h."`
+}
+
+func (c *purgePaymentsCommand) Execute(_ []string) error {
+	// Check that we have a channel DB and an archive.
+	if c.ChannelDB == "" {
+		return fmt.Errorf("channel DB is required")
+	}
+	if c.ArchiveDir == "" {
+		return fmt.Errorf("archive dir is required")
+	}
+	if c.OlderThan == "" {
+		return fmt.Errorf("olderthan is required")
+	}
+	age, err := parseAge(c.OlderThan)
+	if err != nil {
+		return fmt.Errorf("error parsing olderthan: %v", err)
+	}
+	err = checkArchiveDir(c.ChannelDB, c.ArchiveDir)
+	if err != nil {
+		return err
+	}
+
+	db, err := channeldb.Open(
+		path.Dir(c.ChannelDB), channeldb.OptionSetSyncFreelist(true),
+		channeldb.OptionReadOnly(false),
+	)
+	if err != nil {
+		return fmt.Errorf("error opening channel DB: %v", err)
+	}
+	defer db.Close()
+
+	payments, err := db.FetchPayments()
+	if err != nil {
+		return fmt.Errorf("error fetching payments: %v", err)
+	}
+
+	// Older versions of lnd allowed multiple payments to the same hash
+	// which are stored in the same bucket. We can only move the bucket if
+	// all of them can be archived.
+	cutoff := time.Now().Add(-age)
+	archivable := make(map[string]bool)
+	numInFlight := 0
+	for _, payment := range payments {
+		hash := string(payment.Info.PaymentHash[:])
+		if _, ok := archivable[hash]; !ok {
+			archivable[hash] = true
+		}
+
+		switch {
+		case payment.Status == channeldb.StatusInFlight:
+			numInFlight++
+			archivable[hash] = false
+
+		case !isTerminalPayment(payment):
+			archivable[hash] = false
+
+		case !payment.Info.CreationTime.Before(cutoff):
+			archivable[hash] = false
+		}
+	}
+	var purge [][]byte
+	for hash, ok := range archivable {
+		if ok {
+			purge = append(purge, []byte(hash))
+		}
+	}
+	numPayments := 0
+	for _, payment := range payments {
+		if archivable[string(payment.Info.PaymentHash[:])] {
+			numPayments++
+		}
+	}
+	log.Infof("Found %d payments (%d in flight), %d payments to %d "+
+		"payment hashes were created before %v", len(payments),
+		numInFlight, numPayments, len(purge),
+		cutoff.Format(time.RFC3339))
+	if len(purge) == 0 {
+		return nil
+	}
+
+	err = confirmAction(fmt.Sprintf("Move %d payments to the archive in "+
+		"%s and remove them from %s?", numPayments, c.ArchiveDir,
+		c.ChannelDB), &modificationSummary{
+		Command:    "purgepayments",
+		ChannelDB:  c.ChannelDB,
+		ArchiveDir: c.ArchiveDir,
+		Before:     cutoff.Format(time.RFC3339),
+		NumRecords: numPayments,
+	})
+	if err != nil {
+		return err
+	}
+
+	archive, err := channeldb.Open(
+		c.ArchiveDir, channeldb.OptionSetSyncFreelist(true),
+		channeldb.OptionReadOnly(false),
+	)
+	if err != nil {
+		return fmt.Errorf("error opening archive DB: %v", err)
+	}
+	defer archive.Close()
+
+	// Just like the invoices, the payments are first written to the
+	// archive and only removed from the channel DB after that succeeded.
+	var removedBytes int
+	for start := 0; start < len(purge); start += purgeBatchSize {
+		end := start + purgeBatchSize
+		if end > len(purge) {
+			end = len(purge)
+		}
+		batch := purge[start:end]
+
+		err := db.View(func(srcTx *bbolt.Tx) error {
+			return archive.Update(func(dstTx *bbolt.Tx) error {
+				size, err := copyPaymentBuckets(
+					dstTx, srcTx, batch,
+				)
+				removedBytes += size
+				return err
+			})
+		})
+		if err != nil {
+			return fmt.Errorf("error archiving payments: %v", err)
+		}
+		err = db.Update(func(tx *bbolt.Tx) error {
+			payments := tx.Bucket(paymentsRootBucket)
+			for _, hash := range batch {
+				err := payments.DeleteBucket(hash)
+				if err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return fmt.Errorf("error removing payments: %v", err)
+		}
+		log.Infof("Archived %d of %d payment hashes", end, len(purge))
+	}
+
+	log.Infof("Removed %d payments with a total of %d bytes. Run "+
+		"chantools compactdb to actually reduce the size of the file.",
+		numPayments, removedBytes)
+	return nil
+}
+
+// isTerminalPayment returns true if the payment either succeeded or failed and
+// its records are consistent with that. A warning is logged for all other
+// payments.
+func isTerminalPayment(payment *channeldb.MPPayment) bool {
+	hash := payment.Info.PaymentHash
+	switch payment.Status {
+	case channeldb.StatusSucceeded:
+		for _, htlc := range payment.HTLCs {
+			if htlc.Settle != nil {
+				return true
+			}
+		}
+		log.Warnf("Payment %v succeeded but has no preimage, "+
+			"skipping it", hash)
+		return false
+
+	case channeldb.StatusFailed:
+		if payment.FailureReason == nil {
+			log.Warnf("Payment %v failed but has no failure "+
+				"reason, skipping it", hash)
+			return false
+		}
+		return true
+
+	default:
+		log.Warnf("Payment %v has unexpected status %v, skipping it",
+			hash, payment.Status)
+		return false
+	}
+}
+
+// copyPaymentBuckets copies the buckets of the given payment hashes with all
+// their duplicate payments from the source to the destination database and
+// returns the number of bytes copied.
+func copyPaymentBuckets(dstTx, srcTx *bbolt.Tx, hashes [][]byte) (int,
+	error) {
+
+	srcPayments := srcTx.Bucket(paymentsRootBucket)
+	if srcPayments == nil {
+		return 0, fmt.Errorf("no payments in channel DB")
+	}
+	dstPayments, err := dstTx.CreateBucketIfNotExists(paymentsRootBucket)
+	if err != nil {
+		return 0, err
+	}
+
+	// The sequence of the root bucket is used to number the payments, keep
+	// it ahead of all archived payments.
+	if srcPayments.Sequence() > dstPayments.Sequence() {
+		err := dstPayments.SetSequence(srcPayments.Sequence())
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	size := 0
+	for _, hash := range hashes {
+		src := srcPayments.Bucket(hash)
+		if src == nil {
+			return 0, fmt.Errorf("payment %x not found", hash)
+		}
+
+		// A previous run might have archived the payment already but
+		// failed to remove it.
+		if dstPayments.Bucket(hash) != nil {
+			err := dstPayments.DeleteBucket(hash)
+			if err != nil {
+				return 0, err
+			}
+		}
+		dst, err := dstPayments.CreateBucket(hash)
+		if err != nil {
+			return 0, err
+		}
+		bucketSize, err := copyBucket(dst, src)
+		if err != nil {
+			return 0, err
+		}
+		size += bucketSize
+	}
+	return size, nil
+}
+
+// copyBucket recursively copies all keys and nested buckets.
+func copyBucket(dst, src *bbolt.Bucket) (int, error) {
+	size := 0
+	err := src.ForEach(func(k, v []byte) error {
+		size += len(k) + len(v)
+		if v != nil {
+			return dst.Put(k, v)
+		}
+
+		srcChild := src.Bucket(k)
+		dstChild, err := dst.CreateBucket(k)
+		if err != nil {
+			return err
+		}
+		err = dstChild.SetSequence(srcChild.Sequence())
+		if err != nil {
+			return err
+		}
+		childSize, err := copyBucket(dstChild, srcChild)
+		size += childSize
+		return err
+	})
+	return size, err
+}
@@ -0,0 +1,400 @@
+package main
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/coreos/bbolt"
+	"github.com/lightningnetwork/lnd/channeldb"
+	"golang.org/x/crypto/ssh/terminal"
+)
+
+const (
+	// purgeBatchSize is the number of records that are moved to the
+	// archive in a single database transaction.
+	purgeBatchSize = 1000
+)
+
+var (
+	// Bucket and key names from github.com/lightningnetwork/lnd/channeldb/
+	// invoices.go
+	invoiceBucket      = []byte("invoices")
+	invoiceIndexBucket = []byte("paymenthashes")
+	numInvoicesKey     = []byte("nik")
+	addIndexBucket     = []byte("invoice-add-index")
+	settleIndexBucket  = []byte("invoice-settle-index")
+)
+
+// archivedInvoice is a settled invoice together with all keys it is stored
+// under in the invoice bucket and its indexes.
+type archivedInvoice struct {
+	paymentHash []byte
+	addIndex    uint64
+	settleIndex uint64
+}
+
+type purgeSettledCommand struct {
+	ChannelDB  string `long:"channeldb" description:"The lnd channel.db file to remove the settled invoices from. lnd must not be running."`
+	ArchiveDir string `long:"archivedir" description:"The directory to create the archive channel.db file in that the removed invoices are written to. If it already exists, the invoices are added to it."`
+	OlderThan  string `long:"olderthan" description:"Only remove invoices that were settled longer ago than this, for example 30d or 720h."`
+}
+
+func (c *purgeSettledCommand) Execute(_ []string) error {
+	// Check that we have a channel DB and an archive.
+	if c.ChannelDB == "" {
+		return fmt.Errorf("channel DB is required")
+	}
+	if c.ArchiveDir == "" {
+		return fmt.Errorf("archive dir is required")
+	}
+	if c.OlderThan == "" {
+		return fmt.Errorf("olderthan is required")
+	}
+	age, err := parseAge(c.OlderThan)
+	if err != nil {
+		return fmt.Errorf("error parsing olderthan: %v", err)
+	}
+	err = checkArchiveDir(c.ChannelDB, c.ArchiveDir)
+	if err != nil {
+		return err
+	}
+
+	db, err := channeldb.Open(
+		path.Dir(c.ChannelDB), channeldb.OptionSetSyncFreelist(true),
+		channeldb.OptionReadOnly(false),
+	)
+	if err != nil {
+		return fmt.Errorf("error opening channel DB: %v", err)
+	}
+	defer db.Close()
+
+	// Find all invoices that are settled for long enough.
+	allInvoices, err := db.FetchAllInvoicesWithPaymentHash(false)
+	if err != nil && err != channeldb.ErrNoInvoicesCreated {
+		return fmt.Errorf("error fetching invoices: %v", err)
+	}
+	cutoff := time.Now().Add(-age)
+	var purge []*archivedInvoice
+	for _, invoice := range allInvoices {
+		if invoice.Invoice.State != channeldb.ContractSettled ||
+			!invoice.Invoice.SettleDate.Before(cutoff) {
+
+			continue
+		}
+		hash := invoice.PaymentHash
+		purge = append(purge, &archivedInvoice{
+			paymentHash: append([]byte{}, hash[:]...),
+			addIndex:    invoice.Invoice.AddIndex,
+			settleIndex: invoice.Invoice.SettleIndex,
+		})
+	}
+	log.Infof("Found %d invoices of which %d were settled before %v",
+		len(allInvoices), len(purge), cutoff.Format(time.RFC3339))
+	if len(purge) == 0 {
+		return nil
+	}
+
+	err = confirmAction(fmt.Sprintf("Move %d settled invoices to the "+
+		"archive in %s and remove them from %s?", len(purge),
+		c.ArchiveDir, c.ChannelDB), &modificationSummary{
+		Command:    "purgesettled",
+		ChannelDB:  c.ChannelDB,
+		ArchiveDir: c.ArchiveDir,
+		Before:     cutoff.Format(time.RFC3339),
+		NumRecords: len(purge),
+	})
+	if err != nil {
+		return err
+	}
+
+	archive, err := channeldb.Open(
+		c.ArchiveDir, channeldb.OptionSetSyncFreelist(true),
+		channeldb.OptionReadOnly(false),
+	)
+	if err != nil {
+		return fmt.Errorf("error opening archive DB: %v", err)
+	}
+	defer archive.Close()
+
+	// The invoices are first written to the archive and only removed from
+	// the channel DB after that succeeded. If anything goes wrong in
+	// between, an invoice ends up in both databases, which is harmless and
+	// fixed by running the command again.
+	var removedBytes int
+	for start := 0; start < len(purge); start += purgeBatchSize {
+		end := start + purgeBatchSize
+		if end > len(purge) {
+			end = len(purge)
+		}
+		batch := purge[start:end]
+
+		var records map[string][]byte
+		err := db.View(func(tx *bbolt.Tx) error {
+			var err error
+			records, err = readInvoiceRecords(tx, batch)
+			return err
+		})
+		if err != nil {
+			return fmt.Errorf("error reading invoices: %v", err)
+		}
+		err = archive.Update(func(tx *bbolt.Tx) error {
+			return writeInvoiceRecords(tx, batch, records)
+		})
+		if err != nil {
+			return fmt.Errorf("error archiving invoices: %v", err)
+		}
+		err = db.Update(func(tx *bbolt.Tx) error {
+			return deleteInvoiceRecords(tx, batch)
+		})
+		if err != nil {
+			return fmt.Errorf("error removing invoices: %v", err)
+		}
+		for _, record := range records {
+			removedBytes += len(record)
+		}
+		log.Infof("Archived %d of %d invoices", end, len(purge))
+	}
+
+	log.Infof("Removed %d invoices with a total of %d bytes. Run "+
+		"chantools compactdb to actually reduce the size of the file.",
+		len(purge), removedBytes)
+	return nil
+}
+
+// readInvoiceRecords reads the serialized invoices of the batch, keyed by
+// their payment hash.
+func readInvoiceRecords(tx *bbolt.Tx, batch []*archivedInvoice) (
+	map[string][]byte, error) {
+
+	invoices := tx.Bucket(invoiceBucket)
+	if invoices == nil {
+		return nil, channeldb.ErrNoInvoicesCreated
+	}
+	invoiceIndex := invoices.Bucket(invoiceIndexBucket)
+	if invoiceIndex == nil {
+		return nil, channeldb.ErrNoInvoicesCreated
+	}
+
+	records := make(map[string][]byte, len(batch))
+	for _, invoice := range batch {
+		invoiceKey := invoiceIndex.Get(invoice.paymentHash)
+		if invoiceKey == nil {
+			return nil, fmt.Errorf("invoice %x not found",
+				invoice.paymentHash)
+		}
+		record := invoices.Get(invoiceKey)
+		if record == nil {
+			return nil, fmt.Errorf("invoice %x not found",
+				invoice.paymentHash)
+		}
+		records[string(invoice.paymentHash)] = append(
+			append([]byte{}, invoiceKey...), record...,
+		)
+	}
+	return records, nil
+}
+
+// writeInvoiceRecords stores the invoices of the batch under the same invoice
+// number and index keys as in the original database. That way the archive can
+// be filled by multiple runs and stays readable by lnd.
+func writeInvoiceRecords(tx *bbolt.Tx, batch []*archivedInvoice,
+	records map[string][]byte) error {
+
+	invoices, err := tx.CreateBucketIfNotExists(invoiceBucket)
+	if err != nil {
+		return err
+	}
+	invoiceIndex, err := invoices.CreateBucketIfNotExists(
+		invoiceIndexBucket,
+	)
+	if err != nil {
+		return err
+	}
+	addIndex, err := invoices.CreateBucketIfNotExists(addIndexBucket)
+	if err != nil {
+		return err
+	}
+	settleIndex, err := invoices.CreateBucketIfNotExists(settleIndexBucket)
+	if err != nil {
+		return err
+	}
+
+	numInvoices := uint32(0)
+	if counter := invoiceIndex.Get(numInvoicesKey); counter != nil {
+		numInvoices = binary.BigEndian.Uint32(counter)
+	}
+	for _, invoice := range batch {
+		record := records[string(invoice.paymentHash)]
+		invoiceKey, serialized := record[:4], record[4:]
+
+		if err := invoices.Put(invoiceKey, serialized); err != nil {
+			return err
+		}
+		err := invoiceIndex.Put(invoice.paymentHash, invoiceKey)
+		if err != nil {
+			return err
+		}
+		err = addIndex.Put(uint64Key(invoice.addIndex), invoiceKey)
+		if err != nil {
+			return err
+		}
+		if invoice.settleIndex != 0 {
+			err = settleIndex.Put(
+				uint64Key(invoice.settleIndex), invoiceKey,
+			)
+			if err != nil {
+				return err
+			}
+		}
+
+		// Keep the counters ahead of all archived invoices in case lnd
+		// ever adds an invoice to the archive.
+		num := binary.BigEndian.Uint32(invoiceKey)
+		if num >= numInvoices {
+			numInvoices = num + 1
+		}
+		if invoice.addIndex > addIndex.Sequence() {
+			err := addIndex.SetSequence(invoice.addIndex)
+			if err != nil {
+				return err
+			}
+		}
+		if invoice.settleIndex > settleIndex.Sequence() {
+			err := settleIndex.SetSequence(invoice.settleIndex)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	var counter [4]byte
+	binary.BigEndian.PutUint32(counter[:], numInvoices)
+	return invoiceIndex.Put(numInvoicesKey, counter[:])
+}
+
+// deleteInvoiceRecords removes the invoices of the batch and all their index
+// entries. The counters are left untouched so lnd never re-uses an invoice
+// number or index.
+func deleteInvoiceRecords(tx *bbolt.Tx, batch []*archivedInvoice) error {
+	invoices := tx.Bucket(invoiceBucket)
+	if invoices == nil {
+		return channeldb.ErrNoInvoicesCreated
+	}
+	invoiceIndex := invoices.Bucket(invoiceIndexBucket)
+	addIndex := invoices.Bucket(addIndexBucket)
+	settleIndex := invoices.Bucket(settleIndexBucket)
+	if invoiceIndex == nil || addIndex == nil {
+		return channeldb.ErrNoInvoicesCreated
+	}
+
+	for _, invoice := range batch {
+		invoiceKey := invoiceIndex.Get(invoice.paymentHash)
+		if invoiceKey == nil {
+			continue
+		}
+		if err := invoices.Delete(invoiceKey); err != nil {
+			return err
+		}
+		if err := invoiceIndex.Delete(invoice.paymentHash); err != nil {
+			return err
+		}
+		err := addIndex.Delete(uint64Key(invoice.addIndex))
+		if err != nil {
+			return err
+		}
+		if settleIndex != nil && invoice.settleIndex != 0 {
+			err = settleIndex.Delete(uint64Key(invoice.settleIndex))
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// uint64Key returns the big endian encoding of an index number.
+func uint64Key(index uint64) []byte {
+	var key [8]byte
+	binary.BigEndian.PutUint64(key[:], index)
+	return key[:]
+}
+
+// checkArchiveDir makes sure the archive is not the channel DB itself.
+func checkArchiveDir(channelDB, archiveDir string) error {
+	dbDir, err := filepath.Abs(path.Dir(channelDB))
+	if err != nil {
+		return err
+	}
+	archiveDir, err = filepath.Abs(archiveDir)
+	if err != nil {
+		return err
+	}
+	if dbDir == archiveDir {
+		return fmt.Errorf("archive dir must not be the directory of " +
+			"the channel DB")
+	}
+	return nil
+}
+
+// parseAge parses a duration that can also be given in days, for example 30d.
+func parseAge(age string) (time.Duration, error) {
+	if strings.HasSuffix(age, "d") {
+		days, err := strconv.ParseUint(
+			strings.TrimSuffix(age, "d"), 10, 32,
+		)
+		if err != nil {
+			return 0, err
+		}
+		return time.Duration(days) * 24 * time.Hour, nil
+	}
+	return time.ParseDuration(age)
+}
+
+// modificationSummary describes what a command is about to change in a
+// database. It is printed instead of the confirmation text if the
+// confirmation is skipped in a script.
+type modificationSummary struct {
+	Command    string `json:"command"`
+	ChannelDB  string `json:"channel_db"`
+	ArchiveDir string `json:"archive_dir,omitempty"`
+	Before     string `json:"before,omitempty"`
+	NumRecords int    `json:"num_records"`
+}
+
+// confirmAction asks the user to confirm an action that modifies a database
+// and returns an error if they don't. With the global --yes flag the question
+// is skipped and, if stdout is not a terminal, the summary is printed as JSON.
+func confirmAction(question string, summary *modificationSummary) error {
+	if cfg.Yes {
+		if terminal.IsTerminal(int(os.Stdout.Fd())) {
+			log.Infof("%s Confirmation skipped because of --yes",
+				question)
+			return nil
+		}
+		summaryBytes, err := json.Marshal(summary)
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(summaryBytes))
+		return nil
+	}
+
+	answer, err := promptLine(
+		bufio.NewReader(os.Stdin), question+" Type yes to continue",
+	)
+	if err != nil {
+		return err
+	}
+	if answer != "yes" {
+		return fmt.Errorf("aborted by user")
+	}
+	return nil
+}
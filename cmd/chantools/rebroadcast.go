@@ -0,0 +1,109 @@
+package main
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	"github.com/btcsuite/btcd/wire"
+	"github.com/guggero/chantools/btc"
+)
+
+type rebroadcastCommand struct {
+	Tx     string `long:"tx" description:"The raw transaction to re-broadcast, hex encoded."`
+	TxFile string `long:"txfile" description:"A file containing the hex encoded raw transaction to re-broadcast. Specify '-' to read from stdin."`
+}
+
+func (c *rebroadcastCommand) Execute(_ []string) error {
+	setupChainParams(cfg)
+
+	var rawTxHex string
+	switch {
+	case c.Tx != "":
+		rawTxHex = c.Tx
+
+	case c.TxFile != "":
+		content, err := readInput(c.TxFile)
+		if err != nil {
+			return fmt.Errorf("error reading tx file: %v", err)
+		}
+		rawTxHex = string(content)
+
+	default:
+		return fmt.Errorf("tx or tx file is required")
+	}
+	rawTxHex = strings.TrimSpace(rawTxHex)
+
+	rawTx, err := hex.DecodeString(rawTxHex)
+	if err != nil {
+		return fmt.Errorf("error decoding tx hex: %v", err)
+	}
+	tx := &wire.MsgTx{}
+	if err := tx.Deserialize(bytes.NewReader(rawTx)); err != nil {
+		return fmt.Errorf("error parsing tx: %v", err)
+	}
+
+	// Test the transaction first so we can give a more helpful error
+	// message than the one returned by sendrawtransaction.
+	bitcoind := newBitcoind(cfg)
+	result, err := bitcoind.TestMempoolAccept(rawTxHex)
+	if err != nil {
+		return err
+	}
+	if !result.Allowed {
+		return rejectError(bitcoind, tx, result.RejectReason)
+	}
+	if cfg.DryRun {
+		return printDryRun(rawTxHex, nil)
+	}
+
+	txid, err := bitcoind.SendRawTransaction(rawTxHex)
+	if err != nil {
+		return fmt.Errorf("error broadcasting tx: %v", err)
+	}
+	log.Infof("Transaction %s re-broadcast successfully", txid)
+	return nil
+}
+
+// rejectError turns the reject reason of a transaction into an error that
+// tells the user what to do about it.
+func rejectError(bitcoind *btc.Bitcoind, tx *wire.MsgTx, reason string) error {
+	switch reason {
+	case "txn-already-in-mempool", "txn-already-known":
+		log.Infof("Transaction %s is already in the mempool",
+			tx.TxHash())
+		return nil
+
+	case "missing-inputs", "bad-txns-inputs-missingorspent",
+		"txn-mempool-conflict":
+
+		for idx, in := range tx.TxIn {
+			prevOut := in.PreviousOutPoint
+			txOut, err := bitcoind.GetTxOut(
+				prevOut.Hash.String(), prevOut.Index,
+			)
+			if err != nil {
+				return fmt.Errorf("error checking input %d: %v",
+					idx, err)
+			}
+			if txOut == nil {
+				return fmt.Errorf("transaction rejected (%s), "+
+					"input %d (%v) is already spent or "+
+					"doesn't exist", reason, idx, prevOut)
+			}
+		}
+		return fmt.Errorf("transaction rejected: %s", reason)
+
+	case "min relay fee not met", "mempool min fee not met",
+		"insufficient fee":
+
+		return fmt.Errorf("transaction rejected (%s), the fee is too "+
+			"low for the current mempool, use the --bumpfeerate "+
+			"flag of the sweep command to create a transaction "+
+			"with a higher fee", reason)
+
+	default:
+		return fmt.Errorf("transaction rejected: %s", reason)
+	}
+}
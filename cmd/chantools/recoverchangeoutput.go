@@ -0,0 +1,208 @@
+package main
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	"github.com/btcsuite/btcd/wire"
+	"github.com/btcsuite/btcutil"
+	"github.com/btcsuite/btcutil/hdkeychain"
+	"github.com/btcsuite/btcutil/psbt"
+	"github.com/guggero/chantools/lnd"
+	"github.com/guggero/chantools/sweep"
+)
+
+type recoverChangeOutputCommand struct {
+	RootKey        string   `long:"rootkey" description:"BIP32 HD root key to use. Leave empty to prompt for lnd 24 word aezeed."`
+	Txs            []string `long:"tx" description:"A previously published transaction that might have change outputs, hex encoded. Can be specified multiple times."`
+	TxIDs          []string `long:"txid" description:"The ID of a previously published transaction that might have change outputs. The transaction is looked up in bitcoind which needs the transaction index for that. Can be specified multiple times."`
+	DerivationPath string   `long:"derivationpath" description:"The first levels of the derivation path before any internal/external branch to search the change keys in. (default m/84'/0'/0')"`
+	RecoveryWindow uint32   `long:"recoverywindow" description:"The number of keys to search per internal/external branch. (default 2500)"`
+	SweepAddr      string   `long:"sweepaddr" description:"The address all unspent change outputs should be swept to. Leave empty to only list the outputs."`
+	Publish        bool     `long:"publish" description:"Should the sweep TX be published to bitcoind?"`
+	Psbt           bool     `long:"psbt" description:"Don't sign the sweep TX but create an unsigned PSBT with the derivation information of all inputs instead so it can be signed externally."`
+	OutputFile     string   `long:"outputfile" description:"The file to write the PSBT to. Leave empty to print it."`
+}
+
+func (c *recoverChangeOutputCommand) Execute(_ []string) error {
+	setupChainParams(cfg)
+
+	if len(c.Txs) == 0 && len(c.TxIDs) == 0 {
+		return fmt.Errorf("at least one tx or txid is required")
+	}
+
+	var (
+		extendedKey *hdkeychain.ExtendedKey
+		err         error
+	)
+
+	// Check that root key is valid or fall back to console input.
+	switch {
+	case c.RootKey != "":
+		extendedKey, err = hdkeychain.NewKeyFromString(c.RootKey)
+
+	default:
+		extendedKey, _, err = rootKeyFromConsole()
+	}
+	if err != nil {
+		return fmt.Errorf("error reading root key: %v", err)
+	}
+
+	// Set default values.
+	if c.DerivationPath == "" {
+		c.DerivationPath = defaultDerivationPath
+	}
+	if c.RecoveryWindow == 0 {
+		c.RecoveryWindow = defaultRecoveryWindow
+	}
+	basePath, err := lnd.ParsePath(c.DerivationPath)
+	if err != nil {
+		return fmt.Errorf("error parsing path: %v", err)
+	}
+
+	// Collect all transactions, the ones given by their ID are looked up
+	// in bitcoind.
+	bitcoind := newBitcoind(cfg)
+	rawTxs := append([]string{}, c.Txs...)
+	for _, txid := range c.TxIDs {
+		tx, err := bitcoind.GetRawTransaction(txid, "")
+		if err != nil {
+			return fmt.Errorf("error looking up tx %s: %v", txid,
+				err)
+		}
+		rawTxs = append(rawTxs, tx.Hex)
+	}
+	txs := make([]*wire.MsgTx, len(rawTxs))
+	for idx, rawTx := range rawTxs {
+		txBytes, err := hex.DecodeString(strings.TrimSpace(rawTx))
+		if err != nil {
+			return fmt.Errorf("error decoding tx hex: %v", err)
+		}
+		txs[idx] = &wire.MsgTx{}
+		err = txs[idx].Deserialize(bytes.NewReader(txBytes))
+		if err != nil {
+			return fmt.Errorf("error parsing tx: %v", err)
+		}
+	}
+
+	lookup, err := deriveWalletKeys(
+		extendedKey, basePath, c.RecoveryWindow,
+	)
+	if err != nil {
+		return err
+	}
+
+	fingerprint, err := rootKeyFingerprint(extendedKey)
+	if err != nil {
+		return err
+	}
+
+	// Find all outputs of the transactions that belong to our wallet and
+	// are still unspent.
+	builder := sweep.NewSweepBuilder(chainParams)
+	builder.SetFeeRate(feeSatPerByte)
+	var numOutputs, totalValue int64
+	for _, tx := range txs {
+		txHash := tx.TxHash()
+		for idx, txOut := range tx.TxOut {
+			key, ok := lookup[hex.EncodeToString(txOut.PkScript)]
+			if !ok {
+				continue
+			}
+			outPoint := wire.OutPoint{
+				Hash:  txHash,
+				Index: uint32(idx),
+			}
+			unspent, err := bitcoind.GetTxOut(
+				txHash.String(), uint32(idx),
+			)
+			if err != nil {
+				return fmt.Errorf("error checking output %v: "+
+					"%v", outPoint, err)
+			}
+			if unspent == nil {
+				log.Infof("Change output %v (%s) is already "+
+					"spent", outPoint,
+					lnd.FormatPath(key.path))
+				continue
+			}
+			log.Infof("Found unspent change output %v of %d sats "+
+				"to %s (%s)", outPoint, txOut.Value,
+				pkScriptAddress(txOut.PkScript),
+				lnd.FormatPath(key.path))
+
+			privKey, err := key.key.ECPrivKey()
+			if err != nil {
+				return fmt.Errorf("could not derive private "+
+					"key: %v", err)
+			}
+			builder.AddInput(sweep.UTXO{
+				OutPoint: outPoint,
+				Value:    txOut.Value,
+				Derivation: &psbt.Bip32Derivation{
+					PubKey:               key.pubKey,
+					MasterKeyFingerprint: fingerprint,
+					Bip32Path:            key.path,
+				},
+			}, privKey, key.scriptType)
+			numOutputs++
+			totalValue += txOut.Value
+		}
+	}
+	log.Infof("Found %d unspent change outputs with a total of %d sats",
+		numOutputs, totalValue)
+	if numOutputs == 0 || c.SweepAddr == "" {
+		return nil
+	}
+
+	// Sweep all change outputs into a single output.
+	addr, err := btcutil.DecodeAddress(c.SweepAddr, chainParams)
+	if err != nil {
+		return fmt.Errorf("error parsing sweep addr: %v", err)
+	}
+	builder.SetOutput(addr)
+
+	// If an external signer should sign the transaction, we stop here.
+	if c.Psbt {
+		packet, err := builder.BuildPsbt()
+		if err != nil {
+			return fmt.Errorf("error creating sweep PSBT: %v", err)
+		}
+		log.Infof("Fee %d sats of %d total amount", builder.Fee(),
+			totalValue)
+		return writePsbts([]*psbt.Psbt{packet}, c.OutputFile)
+	}
+
+	sweepTx, err := builder.Build()
+	if err != nil {
+		return fmt.Errorf("error creating sweep TX: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := sweepTx.Serialize(&buf); err != nil {
+		return err
+	}
+	log.Infof("Fee %d sats of %d total amount (for size %d)",
+		builder.Fee(), totalValue, sweepTx.SerializeSize())
+
+	switch {
+	case cfg.DryRun:
+		return printDryRun(
+			hex.EncodeToString(buf.Bytes()), builder.InputValues(),
+		)
+
+	case c.Publish:
+		txid, err := bitcoind.SendRawTransaction(
+			hex.EncodeToString(buf.Bytes()),
+		)
+		if err != nil {
+			return fmt.Errorf("error publishing TX: %v", err)
+		}
+		log.Infof("Published TX %s", txid)
+	}
+
+	log.Infof("Transaction: %x", buf.Bytes())
+	return nil
+}
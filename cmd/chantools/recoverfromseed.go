@@ -0,0 +1,372 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/btcsuite/btcutil/hdkeychain"
+	"github.com/guggero/chantools/dataformat"
+	"github.com/guggero/chantools/lnd"
+	"github.com/lightningnetwork/lnd/chanbackup"
+	"github.com/lightningnetwork/lnd/channeldb"
+)
+
+const (
+	lossChannelDB  = 1
+	lossCrash      = 2
+	lossForceClose = 3
+)
+
+// recoveryReport collects what the wizard was able to recover and what the
+// user still needs to do manually.
+type recoveryReport struct {
+	recovered []string
+	manual    []string
+}
+
+func (r *recoveryReport) addRecovered(format string, args ...interface{}) {
+	r.recovered = append(r.recovered, fmt.Sprintf(format, args...))
+}
+
+func (r *recoveryReport) addManual(format string, args ...interface{}) {
+	r.manual = append(r.manual, fmt.Sprintf(format, args...))
+}
+
+type recoverFromSeedCommand struct{}
+
+func (c *recoverFromSeedCommand) Execute(_ []string) error {
+	setupChainParams(cfg)
+
+	fmt.Printf("This wizard guides you through the recovery of the funds " +
+		"of an lnd node. Nothing is published to the network, all " +
+		"results are written to the results directory.\n\n")
+
+	// Step 1: The seed is needed for everything else.
+	extendedKey, _, err := rootKeyFromConsole()
+	if err != nil {
+		return fmt.Errorf("error reading root key: %v", err)
+	}
+
+	// Step 2: What happened to the node?
+	reader := bufio.NewReader(os.Stdin)
+	loss, err := promptChoice(
+		reader, "What happened to your node?", []string{
+			"The channel.db file is lost, only the seed and " +
+				"maybe a channel.backup file are left",
+			"The node crashed or can't be started, but the " +
+				"channel.db file is still there",
+			"Channels were force-closed by the remote party",
+		},
+	)
+	if err != nil {
+		return err
+	}
+
+	// Step 3: Which files and information do we have?
+	var (
+		channelDB    string
+		multiFile    string
+		closingTxids []string
+	)
+	switch loss {
+	case lossChannelDB:
+		multiFile, err = promptFile(
+			reader, "Path to the channel.backup file (leave empty "+
+				"if you don't have one)",
+		)
+
+	default:
+		channelDB, err = promptFile(
+			reader, "Path to the channel.db file",
+		)
+		if err == nil && channelDB == "" {
+			err = fmt.Errorf("channel DB is required")
+		}
+	}
+	if err != nil {
+		return err
+	}
+	if loss == lossForceClose {
+		answer, err := promptLine(
+			reader, "Comma separated list of the closing "+
+				"transaction IDs (leave empty to check all "+
+				"channels)",
+		)
+		if err != nil {
+			return err
+		}
+		for _, txid := range strings.Split(answer, ",") {
+			if strings.TrimSpace(txid) != "" {
+				closingTxids = append(
+					closingTxids, strings.TrimSpace(txid),
+				)
+			}
+		}
+	}
+
+	// Step 4: Run the commands that apply to the situation.
+	report := &recoveryReport{}
+	recoverOnChain(extendedKey, report)
+	switch loss {
+	case lossChannelDB:
+		err = recoverWithoutChannelDB(extendedKey, multiFile, report)
+
+	case lossCrash:
+		err = recoverFromCrash(extendedKey, channelDB, report)
+
+	case lossForceClose:
+		err = recoverForceClosed(
+			extendedKey, channelDB, closingTxids, report,
+		)
+	}
+	if err != nil {
+		return err
+	}
+
+	// Step 5: Summarize the results.
+	fmt.Printf("\nRecovered:\n")
+	for _, line := range report.recovered {
+		fmt.Printf("  - %s\n", line)
+	}
+	if len(report.recovered) == 0 {
+		fmt.Printf("  - nothing\n")
+	}
+	fmt.Printf("\nManual action needed:\n")
+	for _, line := range report.manual {
+		fmt.Printf("  - %s\n", line)
+	}
+	if len(report.manual) == 0 {
+		fmt.Printf("  - none\n")
+	}
+	return nil
+}
+
+// recoverOnChain estimates the on-chain balance of the wallet, it is the same
+// for all kinds of losses.
+func recoverOnChain(extendedKey *hdkeychain.ExtendedKey,
+	report *recoveryReport) {
+
+	log.Infof("Estimating on-chain balance")
+	balance, err := onChainBalance(
+		extendedKey, defaultDerivationPath, defaultEstimateNumAddrs,
+	)
+	if err != nil {
+		report.addManual("Could not query the on-chain balance (%v), "+
+			"run chantools estimatebalance later", err)
+		return
+	}
+	report.addRecovered("On-chain balance of approximately %d sats, "+
+		"restore the seed in lnd or run chantools genimportscript to "+
+		"access it", balance)
+}
+
+// recoverWithoutChannelDB handles the case where only the seed and maybe a
+// channel.backup file are left.
+func recoverWithoutChannelDB(extendedKey *hdkeychain.ExtendedKey,
+	multiFile string, report *recoveryReport) error {
+
+	if multiFile == "" {
+		report.addManual("Without a channel.db or channel.backup the " +
+			"channels can only be found through the remote " +
+			"parties, ask them to force-close and then run " +
+			"chantools rescueclosed")
+		return nil
+	}
+
+	keyRing := &lnd.HDKeyRing{
+		ExtendedKey: extendedKey,
+		ChainParams: chainParams,
+	}
+	multi, err := chanbackup.NewMultiFile(multiFile).ExtractMulti(keyRing)
+	if err != nil {
+		return fmt.Errorf("could not extract multi file: %v", err)
+	}
+	total := uint64(0)
+	for _, single := range multi.StaticBackups {
+		total += uint64(single.Capacity)
+	}
+	report.addRecovered("%d channels with a total capacity of %d sats "+
+		"found in %s", len(multi.StaticBackups), total, multiFile)
+	report.addManual("Restore the seed in a new lnd node and run lncli " +
+		"restorechanbackup with the channel.backup file to ask the " +
+		"remote parties to force-close the channels")
+	report.addManual("Run chantools rescueclosed for channels that were " +
+		"force-closed but whose funds didn't arrive in the wallet")
+	return nil
+}
+
+// recoverFromCrash handles the case where the channel.db is still there. A
+// fresh channel.backup and a summary of all channels are created from it.
+func recoverFromCrash(extendedKey *hdkeychain.ExtendedKey, channelDB string,
+	report *recoveryReport) error {
+
+	db, err := channeldb.Open(
+		path.Dir(channelDB), channeldb.OptionSetSyncFreelist(true),
+		channeldb.OptionReadOnly(true),
+	)
+	if err != nil {
+		return fmt.Errorf("error opening channel DB: %v", err)
+	}
+	defer db.Close()
+
+	fileName := fmt.Sprintf("results/channel-%s.backup",
+		time.Now().Format("2006-01-02-15-04-05"))
+	keyRing := &lnd.HDKeyRing{
+		ExtendedKey: extendedKey,
+		ChainParams: chainParams,
+	}
+	err = lnd.CreateChannelBackup(
+		db, chanbackup.NewMultiFile(fileName), keyRing,
+	)
+	if err != nil {
+		return fmt.Errorf("error creating channel backup: %v", err)
+	}
+	report.addRecovered("Channel backup of all open channels written to "+
+		"%s", fileName)
+
+	entries, err := (&dataformat.ChannelDBFile{DB: db}).AsSummaryEntries()
+	if err != nil {
+		return err
+	}
+	if err := summarizeChannels(cfg.APIURL, entries); err != nil {
+		report.addManual("Could not create the channel summary (%v), "+
+			"run chantools summary later", err)
+		return nil
+	}
+
+	var open, closed int
+	var localBalance uint64
+	for _, entry := range entries {
+		switch {
+		case entry.ClosingTX != nil:
+			closed++
+
+		case entry.ChanExists:
+			open++
+			localBalance += entry.LocalBalance
+		}
+	}
+	report.addRecovered("%d open channels with a local balance of %d "+
+		"sats, %d channels are already closed", open, localBalance,
+		closed)
+	report.addManual("Try to start lnd again with the channel.db. If " +
+		"that is not possible, run chantools forceclose and " +
+		"chantools sweeptimelock with the summary file")
+	return nil
+}
+
+// recoverForceClosed handles channels that were force-closed by the remote
+// party. The keys of our outputs are searched with the information in the
+// channel.db.
+func recoverForceClosed(extendedKey *hdkeychain.ExtendedKey, channelDB string,
+	closingTxids []string, report *recoveryReport) error {
+
+	db, err := channeldb.Open(
+		path.Dir(channelDB), channeldb.OptionSetSyncFreelist(true),
+		channeldb.OptionReadOnly(true),
+	)
+	if err != nil {
+		return fmt.Errorf("error opening channel DB: %v", err)
+	}
+	defer db.Close()
+
+	entries, err := (&dataformat.ChannelDBFile{DB: db}).AsSummaryEntries()
+	if err != nil {
+		return err
+	}
+	if err := summarizeChannels(cfg.APIURL, entries); err != nil {
+		return fmt.Errorf("error creating channel summary: %v", err)
+	}
+
+	// Only look at the channels the user told us about.
+	if len(closingTxids) > 0 {
+		var filtered []*dataformat.SummaryEntry
+		for _, entry := range entries {
+			if entry.ClosingTX == nil {
+				continue
+			}
+			for _, txid := range closingTxids {
+				if entry.ClosingTX.TXID == txid {
+					filtered = append(filtered, entry)
+				}
+			}
+		}
+		if len(filtered) < len(closingTxids) {
+			report.addManual("%d of the closing transactions "+
+				"don't belong to a channel in the channel.db",
+				len(closingTxids)-len(filtered))
+		}
+		entries = filtered
+	}
+
+	if err := rescueClosedChannels(extendedKey, entries, db); err != nil {
+		return fmt.Errorf("error rescuing closed channels: %v", err)
+	}
+	for _, entry := range entries {
+		if entry.ClosingTX == nil || entry.ClosingTX.AllOutsSpent ||
+			entry.ClosingTX.OurAddr == "" {
+
+			continue
+		}
+		if entry.ClosingTX.SweepPrivkey != "" {
+			report.addRecovered("Private key of output %s of "+
+				"channel %s found, import it into a wallet to "+
+				"sweep it", entry.ClosingTX.OurAddr,
+				entry.ChannelPoint)
+			continue
+		}
+		report.addManual("Private key of output %s of channel %s not "+
+			"found, see the chantools rescueclosed documentation",
+			entry.ClosingTX.OurAddr, entry.ChannelPoint)
+	}
+	return nil
+}
+
+// promptLine asks the user a question and returns the trimmed answer.
+func promptLine(reader *bufio.Reader, question string) (string, error) {
+	fmt.Printf("%s: ", question)
+	answer, err := reader.ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(answer), nil
+}
+
+// promptFile asks the user for the path of a file and makes sure it exists
+// unless the answer is empty.
+func promptFile(reader *bufio.Reader, question string) (string, error) {
+	answer, err := promptLine(reader, question)
+	if err != nil || answer == "" {
+		return answer, err
+	}
+	fileName := cleanAndExpandPath(answer)
+	if _, err := os.Stat(fileName); err != nil {
+		return "", fmt.Errorf("cannot access %s: %v", fileName, err)
+	}
+	return fileName, nil
+}
+
+// promptChoice asks the user to pick one of the options and returns its
+// number, starting at 1.
+func promptChoice(reader *bufio.Reader, question string,
+	options []string) (int, error) {
+
+	fmt.Printf("\n%s\n", question)
+	for idx, option := range options {
+		fmt.Printf("  %d) %s\n", idx+1, option)
+	}
+	answer, err := promptLine(reader, "Choice")
+	if err != nil {
+		return 0, err
+	}
+	choice, err := strconv.Atoi(answer)
+	if err != nil || choice < 1 || choice > len(options) {
+		return 0, fmt.Errorf("invalid choice %s", answer)
+	}
+	return choice, nil
+}
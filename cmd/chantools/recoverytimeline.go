@@ -0,0 +1,220 @@
+package main
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"time"
+
+	"github.com/lightningnetwork/lnd/channeldb"
+)
+
+const (
+	blockInterval = 10 * time.Minute
+)
+
+// timelineEvent is a single action of the recovery plan that can be performed
+// at the given block height.
+type timelineEvent struct {
+	height uint32
+	action string
+}
+
+type recoveryTimelineCommand struct {
+	ChannelDB string `long:"channeldb" description:"The lnd channel.db file to create the recovery plan for."`
+	Height    uint32 `long:"height" description:"The current block height. Leave empty to query it from the bitcoind RPC."`
+}
+
+func (c *recoveryTimelineCommand) Execute(_ []string) error {
+	setupChainParams(cfg)
+
+	// Check that we have a channel DB.
+	if c.ChannelDB == "" {
+		return fmt.Errorf("channel DB is required")
+	}
+	db, err := channeldb.Open(
+		path.Dir(c.ChannelDB), channeldb.OptionSetSyncFreelist(true),
+		channeldb.OptionReadOnly(true),
+	)
+	if err != nil {
+		return fmt.Errorf("error opening channel DB: %v", err)
+	}
+
+	if c.Height == 0 {
+		c.Height, err = newBitcoind(cfg).GetBlockCount()
+		if err != nil {
+			return fmt.Errorf("error querying block height: %v",
+				err)
+		}
+	}
+
+	channels, err := db.FetchAllChannels()
+	if err != nil {
+		return fmt.Errorf("error fetching channels: %v", err)
+	}
+	closed, err := db.FetchClosedChannels(true)
+	if err != nil {
+		return fmt.Errorf("error fetching closed channels: %v", err)
+	}
+
+	var events []*timelineEvent
+	for _, channel := range channels {
+		events = append(events, openChannelEvents(channel, c.Height)...)
+	}
+	for _, summary := range closed {
+		events = append(events, closedChannelEvents(summary)...)
+	}
+
+	// Everything that is already possible is done now, the rest is sorted
+	// by the height it becomes possible at.
+	for _, event := range events {
+		if event.height < c.Height {
+			event.height = c.Height
+		}
+	}
+	sort.SliceStable(events, func(i, j int) bool {
+		return events[i].height < events[j].height
+	})
+
+	fmt.Printf("Recovery timeline at block height %d:\n", c.Height)
+	if len(events) == 0 {
+		fmt.Println("Nothing to do.")
+	}
+	for _, event := range events {
+		fmt.Printf("%s: %s\n", formatBlocksFromNow(
+			event.height-c.Height,
+		), event.action)
+	}
+	return nil
+}
+
+// openChannelEvents returns the actions needed to recover the funds of a
+// channel that is not closed yet. We assume the force-close transaction
+// confirms in the next block.
+func openChannelEvents(channel *channeldb.OpenChannel,
+	height uint32) []*timelineEvent {
+
+	var (
+		events     []*timelineEvent
+		chanPoint  = channel.FundingOutpoint.String()
+		closeConf  = height + 1
+		csvDelay   = uint32(channel.LocalChanCfg.CsvDelay)
+		commitment = channel.LocalCommitment
+	)
+	switch {
+	case channel.HasChanStatus(channeldb.ChanStatusCoopBroadcasted):
+		return []*timelineEvent{{
+			height: height,
+			action: fmt.Sprintf("wait for cooperative close of "+
+				"channel %s to confirm", chanPoint),
+		}}
+
+	case channel.HasChanStatus(channeldb.ChanStatusCommitBroadcasted):
+		events = append(events, &timelineEvent{
+			height: height,
+			action: fmt.Sprintf("wait for force-close of channel "+
+				"%s to confirm", chanPoint),
+		})
+
+	default:
+		events = append(events, &timelineEvent{
+			height: height,
+			action: fmt.Sprintf("broadcast force-close for "+
+				"channel %s", chanPoint),
+		})
+	}
+
+	localBalance := commitment.LocalBalance.ToSatoshis()
+	if localBalance > 0 {
+		events = append(events, &timelineEvent{
+			height: closeConf + csvDelay,
+			action: fmt.Sprintf("sweep to_local output (%d sats) "+
+				"of channel %s", localBalance, chanPoint),
+		})
+	}
+
+	for _, htlc := range commitment.Htlcs {
+		amt := htlc.Amt.ToSatoshis()
+		if htlc.Incoming {
+			events = append(events, &timelineEvent{
+				height: height,
+				action: fmt.Sprintf("claim incoming HTLC (%d "+
+					"sats) of channel %s with preimage "+
+					"before block %d", amt, chanPoint,
+					htlc.RefundTimeout),
+			})
+			continue
+		}
+
+		// Outgoing HTLCs first need to be timed out with a second
+		// level transaction which is time locked again.
+		timeout := htlc.RefundTimeout
+		if timeout < closeConf {
+			timeout = closeConf
+		}
+		events = append(events, &timelineEvent{
+			height: timeout,
+			action: fmt.Sprintf("broadcast HTLC timeout (%d sats) "+
+				"for channel %s", amt, chanPoint),
+		}, &timelineEvent{
+			height: timeout + 1 + csvDelay,
+			action: fmt.Sprintf("sweep HTLC timeout output (%d "+
+				"sats) of channel %s", amt, chanPoint),
+		})
+	}
+	return events
+}
+
+// closedChannelEvents returns the actions needed to recover the funds of a
+// channel that is closed but not fully resolved yet.
+func closedChannelEvents(
+	summary *channeldb.ChannelCloseSummary) []*timelineEvent {
+
+	chanPoint := summary.ChanPoint.String()
+	switch summary.CloseType {
+	case channeldb.LocalForceClose:
+		if summary.TimeLockedBalance == 0 {
+			return nil
+		}
+		csvDelay := uint32(summary.LocalChanConfig.CsvDelay)
+		return []*timelineEvent{{
+			height: summary.CloseHeight + csvDelay,
+			action: fmt.Sprintf("sweep to_local output (%d sats) "+
+				"of force-closed channel %s",
+				summary.TimeLockedBalance, chanPoint),
+		}}
+
+	case channeldb.RemoteForceClose:
+		return []*timelineEvent{{
+			height: summary.CloseHeight,
+			action: fmt.Sprintf("sweep to_remote output (%d sats) "+
+				"of remotely force-closed channel %s",
+				summary.SettledBalance, chanPoint),
+		}}
+
+	default:
+		return nil
+	}
+}
+
+// formatBlocksFromNow returns a human readable description of the time until
+// the given number of blocks are mined.
+func formatBlocksFromNow(blocks uint32) string {
+	if blocks == 0 {
+		return "Now"
+	}
+
+	duration := time.Duration(blocks) * blockInterval
+	var approx string
+	switch {
+	case duration >= 48*time.Hour:
+		approx = fmt.Sprintf("~%dd", int(duration.Hours()/24))
+
+	case duration >= time.Hour:
+		approx = fmt.Sprintf("~%dh", int(duration.Hours()))
+
+	default:
+		approx = fmt.Sprintf("~%dmin", int(duration.Minutes()))
+	}
+	return fmt.Sprintf("In %d blocks (%s)", blocks, approx)
+}
@@ -1,7 +1,9 @@
 package main
 
 import (
+	"bytes"
 	"crypto/subtle"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -10,10 +12,14 @@ import (
 	"time"
 
 	"github.com/btcsuite/btcd/btcec"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/wire"
 	"github.com/btcsuite/btcutil"
 	"github.com/btcsuite/btcutil/hdkeychain"
+	"github.com/guggero/chantools/btc"
 	"github.com/guggero/chantools/dataformat"
 	"github.com/guggero/chantools/lnd"
+	"github.com/guggero/chantools/sweep"
 	"github.com/lightningnetwork/lnd/channeldb"
 	"github.com/lightningnetwork/lnd/input"
 	"github.com/lightningnetwork/lnd/keychain"
@@ -32,8 +38,13 @@ type cacheEntry struct {
 }
 
 type rescueClosedCommand struct {
-	RootKey   string `long:"rootkey" description:"BIP32 HD root key to use. Leave empty to prompt for lnd 24 word aezeed."`
-	ChannelDB string `long:"channeldb" description:"The lnd channel.db file to use for rescuing force-closed channels."`
+	RootKey     string  `long:"rootkey" description:"BIP32 HD root key to use. Leave empty to prompt for lnd 24 word aezeed."`
+	ChannelDB   string  `long:"channeldb" description:"The lnd channel.db file to use for rescuing force-closed channels."`
+	SweepAddr   string  `long:"sweepaddr" description:"The address the rescued outputs should be swept to. Leave empty to only find the private keys."`
+	Publish     bool    `long:"publish" description:"Should the sweep TXs be published to the chain API?"`
+	CpfpFeeRate float64 `long:"cpfpfeerate" description:"The fee rate in sat/vByte the package of an unconfirmed closing TX and its sweep TX should have together. (default the lowest fee estimate of the chain API)"`
+	BumpFeeRate float64 `long:"bumpfeerate" description:"Replace the unconfirmed sweep TXs of the rescued outputs with ones that pay this fee rate in sat/vByte instead of sweeping the unspent outputs. The original TXs must signal RBF. The replacements are only published with --publish."`
+	Force       bool    `long:"force" description:"Sweep to the address of --sweepaddr even if it isn't a P2WPKH or P2TR address."`
 }
 
 func (c *rescueClosedCommand) Execute(_ []string) error {
@@ -60,6 +71,16 @@ func (c *rescueClosedCommand) Execute(_ []string) error {
 	if c.ChannelDB == "" {
 		return fmt.Errorf("rescue DB is required")
 	}
+
+	// The sweep address is checked before the keys are searched, which
+	// can take a while.
+	var sweepAddr btcutil.Address
+	if c.SweepAddr != "" {
+		sweepAddr, err = checkSweepAddr(c.SweepAddr, c.Force)
+		if err != nil {
+			return err
+		}
+	}
 	db, err := channeldb.Open(
 		path.Dir(c.ChannelDB), channeldb.OptionSetSyncFreelist(true),
 		channeldb.OptionReadOnly(true),
@@ -73,7 +94,65 @@ func (c *rescueClosedCommand) Execute(_ []string) error {
 	if err != nil {
 		return err
 	}
-	return rescueClosedChannels(extendedKey, entries, db)
+	err = rescueClosedChannels(extendedKey, entries, db)
+	if err != nil || sweepAddr == nil {
+		return err
+	}
+	return sweepRescuedOutputs(
+		entries, sweepAddr, c.CpfpFeeRate, c.BumpFeeRate, c.Publish,
+	)
+}
+
+// checkSweepAddr parses the address the rescued outputs are swept to and makes
+// sure it belongs to the current network. P2WPKH and P2TR addresses result in
+// the smallest sweep transactions, any other address type needs to be
+// confirmed with force.
+func checkSweepAddr(sweepAddr string, force bool) (btcutil.Address, error) {
+	addr, err := btc.DecodeAddress(sweepAddr, chainParams)
+	if err == nil && !addr.IsForNet(chainParams) {
+		err = fmt.Errorf("address is for another network")
+	}
+	if err != nil {
+		for _, network := range chainNetworks {
+			other, decodeErr := btc.DecodeAddress(
+				sweepAddr, network.params,
+			)
+			if decodeErr == nil && other.IsForNet(network.params) {
+				return nil, fmt.Errorf("sweep address %s is "+
+					"for network %s but %s is selected",
+					sweepAddr, network.params.Name,
+					chainParams.Name)
+			}
+		}
+		return nil, fmt.Errorf("error parsing sweep addr: %v", err)
+	}
+
+	var addrType string
+	switch addr.(type) {
+	case *btcutil.AddressWitnessPubKeyHash, *btc.AddressTaproot:
+		return addr, nil
+
+	case *btcutil.AddressPubKeyHash:
+		addrType = "P2PKH"
+
+	case *btcutil.AddressScriptHash:
+		addrType = "P2SH"
+
+	case *btcutil.AddressWitnessScriptHash:
+		addrType = "P2WSH"
+
+	default:
+		addrType = "P2PK"
+	}
+	log.Warnf("!!! The sweep address %s is a %s address and not a "+
+		"standard P2WPKH or P2TR address. Make sure you can spend "+
+		"from it, sweeping to it also costs more fees !!!", sweepAddr,
+		addrType)
+	if !force {
+		return nil, fmt.Errorf("unusual %s sweep address, set --force "+
+			"to sweep to it anyway", addrType)
+	}
+	return addr, nil
 }
 
 func rescueClosedChannels(extendedKey *hdkeychain.ExtendedKey,
@@ -153,6 +232,152 @@ func rescueClosedChannels(extendedKey *hdkeychain.ExtendedKey,
 	return ioutil.WriteFile(fileName, summaryBytes, 0644)
 }
 
+// sweepRescuedOutputs sweeps the outputs of all closing transactions we found
+// the private key for. Each output is swept with its own transaction. If the
+// closing transaction is still unconfirmed, the sweep transaction pays enough
+// fees for both of them to confirm (CPFP).
+func sweepRescuedOutputs(entries []*dataformat.SummaryEntry,
+	addr btcutil.Address, cpfpRate, bumpFeeRate float64,
+	publish bool) error {
+
+	api := &btc.ExplorerAPI{BaseURL: cfg.APIURL}
+
+	for _, entry := range entries {
+		closingTx := entry.ClosingTX
+		if closingTx == nil || closingTx.SweepPrivkey == "" ||
+			closingTx.AllOutsSpent {
+
+			continue
+		}
+
+		wif, err := btcutil.DecodeWIF(closingTx.SweepPrivkey)
+		if err != nil {
+			return fmt.Errorf("error parsing private key of %s: %v",
+				entry.ChannelPoint, err)
+		}
+		tx, err := api.Transaction(closingTx.TXID)
+		if err != nil {
+			return fmt.Errorf("error fetching closing TX %s: %v",
+				closingTx.TXID, err)
+		}
+		txHash, err := chainhash.NewHashFromStr(closingTx.TXID)
+		if err != nil {
+			return fmt.Errorf("error parsing tx hash: %v", err)
+		}
+
+		builder := sweep.NewSweepBuilder(chainParams)
+		builder.SetFeeRate(feeSatPerByte)
+		builder.SetOutput(addr)
+		totalValue := int64(0)
+		for idx, vout := range tx.Vout {
+			if vout.ScriptPubkeyAddr != closingTx.OurAddr {
+				continue
+			}
+
+			// When bumping the fee, we're looking for the outputs
+			// a stuck sweep TX spends instead of the unspent ones.
+			outspend := vout.Outspend
+			unconfirmedSpend := outspend.Spent &&
+				(outspend.Status == nil ||
+					!outspend.Status.Confirmed)
+			skip := outspend.Spent
+			if bumpFeeRate != 0 {
+				skip = !unconfirmedSpend
+			}
+			if skip {
+				continue
+			}
+			builder.AddInput(sweep.UTXO{
+				OutPoint: wire.OutPoint{
+					Hash:  *txHash,
+					Index: uint32(idx),
+				},
+				Value: int64(vout.Value),
+			}, wif.PrivKey, sweep.ScriptTypeP2WKH)
+			totalValue += int64(vout.Value)
+		}
+		switch {
+		case totalValue == 0 && bumpFeeRate != 0:
+			log.Infof("Output of %s isn't spent by an unconfirmed "+
+				"TX", entry.ChannelPoint)
+			continue
+
+		case totalValue == 0:
+			log.Infof("Output of %s is already spent",
+				entry.ChannelPoint)
+			continue
+
+		case bumpFeeRate != 0:
+			err := bumpSweepFee(api, builder, bumpFeeRate, publish)
+			if err != nil {
+				return fmt.Errorf("error bumping sweep of %s: "+
+					"%v", entry.ChannelPoint, err)
+			}
+			continue
+		}
+
+		if _, err := builder.Build(); err != nil {
+			return fmt.Errorf("error creating sweep TX: %v", err)
+		}
+
+		// A sweep transaction can't confirm before its parent, so an
+		// unconfirmed closing transaction that might pay too little
+		// fees needs to be bumped by the sweep transaction.
+		if tx.Status != nil && !tx.Status.Confirmed {
+			if cpfpRate == 0 {
+				cpfpRate, err = cpfpFeeRate(api)
+				if err != nil {
+					return err
+				}
+			}
+			childFee := cpfpChildFee(
+				tx.VSize(), tx.Fee, builder.VSize(), cpfpRate,
+			)
+			log.Infof("Closing TX %s is unconfirmed, paying %d "+
+				"sats for a package fee rate of %.2f "+
+				"sat/vByte", closingTx.TXID, childFee,
+				cpfpRate)
+			builder.SetFeeRate(
+				float64(childFee) / float64(builder.VSize()),
+			)
+		}
+
+		sweepTx, err := builder.Build()
+		if err != nil {
+			return fmt.Errorf("error creating sweep TX: %v", err)
+		}
+		var buf bytes.Buffer
+		if err := sweepTx.Serialize(&buf); err != nil {
+			return err
+		}
+		log.Infof("Fee %d sats of %d total amount (for size %d)",
+			builder.Fee(), totalValue, sweepTx.SerializeSize())
+
+		switch {
+		case cfg.DryRun:
+			err := printDryRun(
+				hex.EncodeToString(buf.Bytes()),
+				builder.InputValues(),
+			)
+			if err != nil {
+				return err
+			}
+
+		case publish:
+			response, err := api.PublishTx(
+				hex.EncodeToString(buf.Bytes()),
+			)
+			if err != nil {
+				return err
+			}
+			log.Infof("Published TX %s, response: %s",
+				sweepTx.TxHash(), response)
+		}
+		log.Infof("Transaction: %x", buf.Bytes())
+	}
+	return nil
+}
+
 func addrInCache(addr string, perCommitPoint *btcec.PublicKey) (string, error) {
 	targetPubKeyHash, err := parseAddr(addr)
 	if err != nil {
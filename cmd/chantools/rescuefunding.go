@@ -0,0 +1,262 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"path"
+
+	"github.com/btcsuite/btcd/blockchain"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/btcsuite/btcutil"
+	"github.com/btcsuite/btcutil/hdkeychain"
+	"github.com/btcsuite/btcutil/psbt"
+	"github.com/guggero/chantools/btc"
+	"github.com/guggero/chantools/lnd"
+	"github.com/lightningnetwork/lnd/channeldb"
+	"github.com/lightningnetwork/lnd/input"
+)
+
+type rescueFundingCommand struct {
+	RootKey      string  `long:"rootkey" description:"BIP32 HD root key of the node. Leave empty to prompt for lnd 24 word aezeed."`
+	ChannelDB    string  `long:"channeldb" description:"The lnd channel.db file that contains the channel with the stuck funding output."`
+	ChannelPoint string  `long:"channelpoint" description:"The funding outpoint of the channel to rescue, in the format txid:index."`
+	Publish      bool    `long:"publish" description:"Should the commitment TX be published to the chain API? Not used with --cooperative."`
+	Cooperative  bool    `long:"cooperative" description:"Don't force close the channel but create a PSBT of a cooperative close transaction that the remote party signs with signrescuefunding."`
+	SweepAddr    string  `long:"sweepaddr" description:"The address our balance should be paid to in the cooperative close transaction."`
+	RemoteAddr   string  `long:"remoteaddr" description:"The address the balance of the remote party should be paid to in the cooperative close transaction. Only required if the remote party has a balance above the dust limit."`
+	FeeRate      float64 `long:"feerate" description:"The fee rate in sat/vByte of the cooperative close transaction. The fee is paid by the initiator of the channel. (default 2)"`
+}
+
+func (c *rescueFundingCommand) Execute(_ []string) error {
+	setupChainParams(cfg)
+
+	var (
+		extendedKey *hdkeychain.ExtendedKey
+		err         error
+	)
+
+	// Check that root key is valid or fall back to console input.
+	switch {
+	case c.RootKey != "":
+		extendedKey, err = hdkeychain.NewKeyFromString(c.RootKey)
+
+	default:
+		extendedKey, _, err = rootKeyFromConsole()
+	}
+	if err != nil {
+		return fmt.Errorf("error reading root key: %v", err)
+	}
+
+	// Set default values.
+	if c.FeeRate == 0 {
+		c.FeeRate = feeSatPerByte
+	}
+
+	// Check that we have a channel DB and a channel to rescue.
+	if c.ChannelDB == "" {
+		return fmt.Errorf("channel DB is required")
+	}
+	if c.ChannelPoint == "" {
+		return fmt.Errorf("channel point is required")
+	}
+	if c.Cooperative && c.SweepAddr == "" {
+		return fmt.Errorf("sweep address is required for a " +
+			"cooperative close")
+	}
+	chanPoint, err := parseOutPoint(c.ChannelPoint)
+	if err != nil {
+		return fmt.Errorf("error parsing channel point: %v", err)
+	}
+	db, err := channeldb.Open(
+		path.Dir(c.ChannelDB), channeldb.OptionSetSyncFreelist(true),
+		channeldb.OptionReadOnly(true),
+	)
+	if err != nil {
+		return fmt.Errorf("error opening channel DB: %v", err)
+	}
+	defer db.Close()
+	channels, err := db.FetchAllChannels()
+	if err != nil {
+		return fmt.Errorf("error fetching channels: %v", err)
+	}
+	var channel *channeldb.OpenChannel
+	for _, dbChannel := range channels {
+		if dbChannel.FundingOutpoint == *chanPoint {
+			channel = dbChannel
+		}
+	}
+	if channel == nil {
+		return fmt.Errorf("channel %v not found in channel DB",
+			chanPoint)
+	}
+
+	signer := &lnd.Signer{
+		ExtendedKey: extendedKey,
+		ChainParams: chainParams,
+	}
+	if c.Cooperative {
+		fingerprint, err := rootKeyFingerprint(extendedKey)
+		if err != nil {
+			return err
+		}
+		packet, err := cooperativeRescuePsbt(
+			channel, signer, fingerprint, c.SweepAddr, c.RemoteAddr,
+			c.FeeRate,
+		)
+		if err != nil {
+			return err
+		}
+		b64, err := packet.B64Encode()
+		if err != nil {
+			return fmt.Errorf("error encoding PSBT: %v", err)
+		}
+		log.Infof("Send the PSBT to the remote party, it can be " +
+			"signed with signrescuefunding")
+		fmt.Println(b64)
+		return nil
+	}
+
+	// Without the remote party we can only publish our latest commitment.
+	forceClose, err := signCommitment(channel, signer)
+	if err != nil {
+		return err
+	}
+	api := &btc.ExplorerAPI{BaseURL: cfg.APIURL}
+	switch {
+	case cfg.DryRun:
+		return printDryRun(
+			forceClose.Serialized, []int64{int64(channel.Capacity)},
+		)
+
+	case c.Publish:
+		response, err := api.PublishTx(forceClose.Serialized)
+		if err != nil {
+			return err
+		}
+		log.Infof("Published TX %s, response: %s", forceClose.TXID,
+			response)
+	}
+
+	log.Infof("Commitment transaction %s: %s", forceClose.TXID,
+		forceClose.Serialized)
+	return nil
+}
+
+// cooperativeRescuePsbt creates a PSBT of a cooperative close transaction that
+// spends the funding output of the channel and pays both parties their balance
+// of the latest local commitment. The initiator gets back the commitment fee
+// and pays the fee of the close transaction instead. The PSBT contains our
+// signature and the BIP32 derivation of our multisig key.
+func cooperativeRescuePsbt(channel *channeldb.OpenChannel, signer *lnd.Signer,
+	fingerprint uint32, sweepAddr, remoteAddr string,
+	feeRate float64) (*psbt.Psbt, error) {
+
+	sweepScript, err := addressScript(sweepAddr)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing sweep address: %v", err)
+	}
+	var remoteScript []byte
+	if remoteAddr != "" {
+		remoteScript, err = addressScript(remoteAddr)
+		if err != nil {
+			return nil, fmt.Errorf("error parsing remote address: "+
+				"%v", err)
+		}
+	}
+
+	// The fee of the closing transaction is paid by the initiator of the
+	// channel who gets back the fee of the commitment transaction instead.
+	// The remote party is only paid if its balance is above its dust limit,
+	// so we need to know that before we can estimate the size.
+	var (
+		localCommit   = channel.LocalCommitment
+		localBalance  = localCommit.LocalBalance.ToSatoshis()
+		remoteBalance = localCommit.RemoteBalance.ToSatoshis()
+		localDust     = channel.LocalChanCfg.DustLimit
+		remoteDust    = channel.RemoteChanCfg.DustLimit
+	)
+	payRemote := remoteBalance >= remoteDust
+	if payRemote && remoteScript == nil {
+		return nil, fmt.Errorf("remote address is required, the "+
+			"remote party has a balance of %d sats", remoteBalance)
+	}
+
+	// The size of the transaction only depends on the output scripts, so
+	// we can add the outputs first and set their values afterwards.
+	tx := wire.NewMsgTx(2)
+	tx.AddTxIn(&wire.TxIn{
+		PreviousOutPoint: channel.FundingOutpoint,
+		Sequence:         wire.MaxTxInSequenceNum,
+	})
+	tx.AddTxOut(wire.NewTxOut(0, sweepScript))
+	if payRemote {
+		tx.AddTxOut(wire.NewTxOut(0, remoteScript))
+	}
+	weight := tx.SerializeSizeStripped()*blockchain.WitnessScaleFactor +
+		input.WitnessHeaderSize + input.WitnessSize
+	vSize := (weight + blockchain.WitnessScaleFactor - 1) /
+		blockchain.WitnessScaleFactor
+	fee := btcutil.Amount(math.Ceil(float64(vSize) * feeRate))
+	if channel.IsInitiator {
+		localBalance = localBalance - fee + localCommit.CommitFee
+	} else {
+		remoteBalance = remoteBalance - fee + localCommit.CommitFee
+	}
+	if localBalance < localDust {
+		return nil, fmt.Errorf("our balance of %d sats is below the "+
+			"dust limit after paying a fee of %d sats",
+			localBalance, fee)
+	}
+
+	// If the remote party is the initiator, its balance might not be
+	// enough to pay the fee. Its output is left out then and the rest of
+	// its balance goes to the fee as well.
+	tx.TxOut[0].Value = int64(localBalance)
+	if payRemote && remoteBalance < remoteDust {
+		tx.TxOut = tx.TxOut[:1]
+	}
+	if len(tx.TxOut) > 1 {
+		tx.TxOut[1].Value = int64(remoteBalance)
+	} else {
+		remoteBalance = 0
+	}
+	fee = channel.Capacity - localBalance - remoteBalance
+	log.Infof("Created cooperative close TX %s paying %d sats to us and "+
+		"%d sats to the remote party with a fee of %d sats (%.2f "+
+		"sat/vByte)", tx.TxHash(), localBalance, remoteBalance, fee,
+		float64(fee)/float64(vSize))
+
+	packet, err := psbt.NewPsbtFromUnsignedTx(tx)
+	if err != nil {
+		return nil, fmt.Errorf("error creating PSBT: %v", err)
+	}
+	updater, err := psbt.NewUpdater(packet)
+	if err != nil {
+		return nil, fmt.Errorf("error creating PSBT updater: %v", err)
+	}
+	if err := updater.AddInSighashType(txscript.SigHashAll, 0); err != nil {
+		return nil, err
+	}
+	localKey := channel.LocalChanCfg.MultiSigKey
+	err = updater.AddInBip32Derivation(
+		fingerprint, keyLocatorBip32Path(localKey.KeyLocator),
+		localKey.PubKey.SerializeCompressed(), 0,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := signFundingInput(updater, 0, channel, signer); err != nil {
+		return nil, err
+	}
+	return packet, nil
+}
+
+// addressScript returns the output script of an address of the current chain.
+func addressScript(address string) ([]byte, error) {
+	addr, err := btcutil.DecodeAddress(address, chainParams)
+	if err != nil {
+		return nil, err
+	}
+	return txscript.PayToAddrScript(addr)
+}
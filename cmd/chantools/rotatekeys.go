@@ -0,0 +1,130 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/btcsuite/btcutil/hdkeychain"
+	"github.com/guggero/chantools/lnd"
+	"github.com/lightningnetwork/lnd/chanbackup"
+)
+
+const (
+	defaultRotateNumAddresses = 5
+)
+
+type rotateKeysCommand struct {
+	RootKey      string `long:"rootkey" description:"BIP32 HD root key of the old wallet that was used to create the backup. Leave empty to prompt for lnd 24 word aezeed."`
+	NewRootKey   string `long:"newrootkey" description:"BIP32 HD root key of the new wallet to encrypt the backup with. Leave empty to prompt for lnd 24 word aezeed."`
+	MultiFile    string `long:"multi_file" description:"The lnd channel.backup file to re-encrypt."`
+	NumAddresses uint32 `long:"numaddresses" description:"The number of addresses of the new wallet to show that the funds can be migrated to. (default 5)"`
+}
+
+func (c *rotateKeysCommand) Execute(_ []string) error {
+	setupChainParams(cfg)
+
+	// Check that we have a backup file.
+	if c.MultiFile == "" {
+		return fmt.Errorf("backup file is required")
+	}
+
+	// Set default values.
+	if c.NumAddresses == 0 {
+		c.NumAddresses = defaultRotateNumAddresses
+	}
+
+	oldKey, err := rotateRootKey(c.RootKey, "old")
+	if err != nil {
+		return err
+	}
+	newKey, err := rotateRootKey(c.NewRootKey, "new")
+	if err != nil {
+		return err
+	}
+	if oldKey.String() == newKey.String() {
+		return fmt.Errorf("old and new root key are the same")
+	}
+
+	oldRing := &lnd.HDKeyRing{
+		ExtendedKey: oldKey,
+		ChainParams: chainParams,
+	}
+	newRing := &lnd.HDKeyRing{
+		ExtendedKey: newKey,
+		ChainParams: chainParams,
+	}
+	multiFile := chanbackup.NewMultiFile(c.MultiFile)
+	multi, err := multiFile.ExtractMulti(oldRing)
+	if err != nil {
+		return fmt.Errorf("could not extract multi file: %v", err)
+	}
+
+	fileName := fmt.Sprintf("results/backup-rotated-%s.backup",
+		time.Now().Format("2006-01-02-15-04-05"))
+	log.Infof("Writing %d channels encrypted with the new root key to %s",
+		len(multi.StaticBackups), fileName)
+	f, err := os.OpenFile(
+		fileName, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644,
+	)
+	if err != nil {
+		return err
+	}
+	err = multi.PackToWriter(f, newRing)
+	_ = f.Close()
+	if err != nil {
+		return err
+	}
+
+	// The channels can't be moved to the new wallet, only the on-chain
+	// funds can be sent to one of its addresses.
+	path, err := lnd.ParsePath(defaultDerivationPath)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Addresses of the new wallet to migrate the on-chain "+
+		"funds to (%s/0/i):\n", defaultDerivationPath)
+	for i := uint32(0); i < c.NumAddresses; i++ {
+		key, err := lnd.DeriveChildren(newKey, append(path, 0, i))
+		if err != nil {
+			return fmt.Errorf("error deriving key: %v", err)
+		}
+		pubKey, err := key.ECPubKey()
+		if err != nil {
+			return fmt.Errorf("error deriving public key: %v", err)
+		}
+		addr, err := pubKeyAddress(
+			pubKey.SerializeCompressed(), addrTypeP2WKH,
+			chainParams,
+		)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("  %d: %s\n", i, addr.EncodeAddress())
+	}
+	return nil
+}
+
+// rotateRootKey parses the given root key or asks for the aezeed of the wallet
+// with the given name on the console.
+func rotateRootKey(rootKey, name string) (*hdkeychain.ExtendedKey, error) {
+	var (
+		extendedKey *hdkeychain.ExtendedKey
+		err         error
+	)
+
+	// Check that root key is valid or fall back to console input.
+	switch {
+	case rootKey != "":
+		extendedKey, err = hdkeychain.NewKeyFromString(rootKey)
+
+	default:
+		fmt.Printf("Enter the seed of the %s wallet.\n", name)
+		extendedKey, _, err = rootKeyFromConsole()
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading %s root key: %v", name,
+			err)
+	}
+	return extendedKey, nil
+}
@@ -0,0 +1,252 @@
+package main
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"path"
+
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/btcsuite/btcutil/hdkeychain"
+	"github.com/btcsuite/btcutil/psbt"
+	"github.com/guggero/chantools/lnd"
+	"github.com/lightningnetwork/lnd/channeldb"
+	"github.com/lightningnetwork/lnd/input"
+)
+
+type signClosingCommand struct {
+	RootKey   string `long:"rootkey" description:"BIP32 HD root key of the node. Leave empty to prompt for lnd 24 word aezeed."`
+	ChannelDB string `long:"channeldb" description:"The lnd channel.db file to look up the channel of the closing transaction in."`
+	Psbt      string `long:"psbt" description:"The partially signed closing transaction as a base64 encoded PSBT."`
+	Tx        string `long:"tx" description:"The unsigned closing transaction as a hex encoded raw transaction. Use this if the remote party didn't create a PSBT."`
+}
+
+func (c *signClosingCommand) Execute(_ []string) error {
+	setupChainParams(cfg)
+
+	var (
+		extendedKey *hdkeychain.ExtendedKey
+		err         error
+	)
+
+	// Check that root key is valid or fall back to console input.
+	switch {
+	case c.RootKey != "":
+		extendedKey, err = hdkeychain.NewKeyFromString(c.RootKey)
+
+	default:
+		extendedKey, _, err = rootKeyFromConsole()
+	}
+	if err != nil {
+		return fmt.Errorf("error reading root key: %v", err)
+	}
+
+	packet, err := parseClosingTx(c.Psbt, c.Tx)
+	if err != nil {
+		return err
+	}
+
+	// Check that we have a channel DB.
+	if c.ChannelDB == "" {
+		return fmt.Errorf("channel DB is required")
+	}
+	db, err := channeldb.Open(
+		path.Dir(c.ChannelDB), channeldb.OptionSetSyncFreelist(true),
+		channeldb.OptionReadOnly(true),
+	)
+	if err != nil {
+		return fmt.Errorf("error opening channel DB: %v", err)
+	}
+	defer db.Close()
+	channels, err := db.FetchAllChannels()
+	if err != nil {
+		return fmt.Errorf("error fetching channels: %v", err)
+	}
+	inputIndex, channel, err := findFundingInput(
+		packet.UnsignedTx, channels,
+	)
+	if err != nil {
+		return err
+	}
+	log.Infof("Input %d spends the funding output of channel %s",
+		inputIndex, channel.FundingOutpoint)
+
+	signer := &lnd.Signer{
+		ExtendedKey: extendedKey,
+		ChainParams: chainParams,
+	}
+	updater, err := psbt.NewUpdater(packet)
+	if err != nil {
+		return fmt.Errorf("error creating PSBT updater: %v", err)
+	}
+	complete, err := signFundingInput(updater, inputIndex, channel, signer)
+	if err != nil {
+		return err
+	}
+
+	if !complete {
+		b64, err := packet.B64Encode()
+		if err != nil {
+			return fmt.Errorf("error encoding PSBT: %v", err)
+		}
+		log.Infof("The remote party did not sign yet, send the PSBT " +
+			"back to them")
+		fmt.Println(b64)
+		return nil
+	}
+
+	// Both signatures are there, so we can create the final transaction.
+	if err := psbt.MaybeFinalizeAll(packet); err != nil {
+		return fmt.Errorf("error finalizing PSBT: %v", err)
+	}
+	signedTx, err := psbt.Extract(packet)
+	if err != nil {
+		return fmt.Errorf("error extracting transaction: %v", err)
+	}
+	log.Infof("The closing transaction is fully signed and can be " +
+		"published")
+	fmt.Println(hex.EncodeToString(signedTx))
+	return nil
+}
+
+// parseClosingTx parses a closing transaction that is either given as a PSBT
+// or as a raw transaction.
+func parseClosingTx(psbtB64, txHex string) (*psbt.Psbt, error) {
+	switch {
+	case psbtB64 != "":
+		packet, err := psbt.NewPsbt([]byte(psbtB64), true)
+		if err != nil {
+			return nil, fmt.Errorf("error parsing PSBT: %v", err)
+		}
+		return packet, nil
+
+	case txHex != "":
+		txBytes, err := hex.DecodeString(txHex)
+		if err != nil {
+			return nil, fmt.Errorf("error decoding tx: %v", err)
+		}
+		tx := &wire.MsgTx{}
+		if err := tx.Deserialize(bytes.NewReader(txBytes)); err != nil {
+			return nil, fmt.Errorf("error parsing tx: %v", err)
+		}
+
+		// A PSBT can only be created from an unsigned transaction.
+		for _, txIn := range tx.TxIn {
+			txIn.SignatureScript = nil
+			txIn.Witness = nil
+		}
+		packet, err := psbt.NewPsbtFromUnsignedTx(tx)
+		if err != nil {
+			return nil, fmt.Errorf("error creating PSBT: %v", err)
+		}
+		return packet, nil
+
+	default:
+		return nil, fmt.Errorf("either psbt or tx is required")
+	}
+}
+
+// findFundingInput returns the index of the input that spends the funding
+// output of one of the channels.
+func findFundingInput(tx *wire.MsgTx, channels []*channeldb.OpenChannel) (
+	int, *channeldb.OpenChannel, error) {
+
+	for idx, txIn := range tx.TxIn {
+		for _, channel := range channels {
+			if txIn.PreviousOutPoint == channel.FundingOutpoint {
+				return idx, channel, nil
+			}
+		}
+	}
+	return 0, nil, fmt.Errorf("transaction doesn't spend the funding " +
+		"output of any channel in the channel DB")
+}
+
+// fundingOutput returns the 2-of-2 multisig witness script and the funding
+// output of a channel.
+func fundingOutput(channel *channeldb.OpenChannel) ([]byte, *wire.TxOut,
+	error) {
+
+	witnessScript, err := input.GenMultiSigScript(
+		channel.LocalChanCfg.MultiSigKey.PubKey.SerializeCompressed(),
+		channel.RemoteChanCfg.MultiSigKey.PubKey.SerializeCompressed(),
+	)
+	if err != nil {
+		return nil, nil, err
+	}
+	pkScript, err := input.WitnessScriptHash(witnessScript)
+	if err != nil {
+		return nil, nil, err
+	}
+	return witnessScript, wire.NewTxOut(
+		int64(channel.Capacity), pkScript,
+	), nil
+}
+
+// signFundingInput adds our signature for the funding input to the PSBT. The
+// returned boolean is true if the PSBT also contains the signature of the
+// remote party.
+func signFundingInput(updater *psbt.Updater, inputIndex int,
+	channel *channeldb.OpenChannel, signer *lnd.Signer) (bool, error) {
+
+	var (
+		packet    = updater.Upsbt
+		localKey  = channel.LocalChanCfg.MultiSigKey.PubKey
+		remoteKey = channel.RemoteChanCfg.MultiSigKey.PubKey
+	)
+	witnessScript, fundingOut, err := fundingOutput(channel)
+	if err != nil {
+		return false, fmt.Errorf("error creating funding script: %v",
+			err)
+	}
+	pInput := &packet.Inputs[inputIndex]
+	if pInput.WitnessUtxo != nil &&
+		(pInput.WitnessUtxo.Value != fundingOut.Value ||
+			!bytes.Equal(pInput.WitnessUtxo.PkScript,
+				fundingOut.PkScript)) {
+
+		return false, fmt.Errorf("UTXO information of input %d "+
+			"doesn't match the funding output", inputIndex)
+	}
+	if err := updater.AddInWitnessUtxo(fundingOut, inputIndex); err != nil {
+		return false, fmt.Errorf("error adding UTXO info: %v", err)
+	}
+
+	// Cooperative close transactions are always signed with SIGHASH_ALL.
+	tx := packet.UnsignedTx
+	signDesc := &input.SignDescriptor{
+		KeyDesc:       channel.LocalChanCfg.MultiSigKey,
+		WitnessScript: witnessScript,
+		Output:        fundingOut,
+		HashType:      txscript.SigHashAll,
+		SigHashes:     txscript.NewTxSigHashes(tx),
+		InputIndex:    inputIndex,
+	}
+	sig, err := signer.SignOutputRaw(tx, signDesc)
+	if err != nil {
+		return false, fmt.Errorf("error signing funding input: %v", err)
+	}
+	sig = append(sig, byte(txscript.SigHashAll))
+
+	outcome, err := updater.Sign(
+		inputIndex, sig, localKey.SerializeCompressed(), nil,
+		witnessScript,
+	)
+	switch {
+	case err != nil:
+		return false, fmt.Errorf("error adding signature: %v", err)
+
+	// A finalized input already contains both signatures.
+	case outcome == 1:
+		return true, nil
+	}
+
+	remoteKeyBytes := remoteKey.SerializeCompressed()
+	for _, partialSig := range packet.Inputs[inputIndex].PartialSigs {
+		if bytes.Equal(partialSig.PubKey, remoteKeyBytes) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
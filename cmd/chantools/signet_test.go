@@ -0,0 +1,141 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/btcsuite/btcutil/hdkeychain"
+	"github.com/guggero/chantools/btc"
+	"github.com/guggero/chantools/lnd"
+)
+
+const (
+	testSignetPath = "m/84'/1'/0'/0/0"
+	testSignetWIF  = "cNaYgv5U1xDV9LVMsdAJDSn3xow3vxcdRAB92F6aQXePzEgPnQnr"
+	testSignetP2TR = "tb1pndxzyh2j2dhxk24969pk2dycps0jz9c0khpzrgpe4" +
+		"09qawg8h53saa8c9q"
+)
+
+// useSignet selects signet with the network flag and returns the function
+// that restores the previous network.
+func useSignet(t *testing.T) func() {
+	oldCfg, oldChainParams := cfg, chainParams
+	restore := func() {
+		cfg, chainParams = oldCfg, oldChainParams
+	}
+
+	cfg = &config{Network: "signet"}
+	setupChainParams(cfg)
+	if chainParams != &btc.SigNetParams {
+		restore()
+		t.Fatalf("unexpected chain params %s", chainParams.Name)
+	}
+	return restore
+}
+
+// TestSignetDeriveKey tests that keys are derived and encoded with the signet
+// parameters, the same way derivekey shows them.
+func TestSignetDeriveKey(t *testing.T) {
+	defer useSignet(t)()
+
+	rootKey, err := hdkeychain.NewKeyFromString(testRootKey)
+	if err != nil {
+		t.Fatalf("error parsing root key: %v", err)
+	}
+	path, err := lnd.ParsePath(testSignetPath)
+	if err != nil {
+		t.Fatalf("error parsing path: %v", err)
+	}
+	hdKey, err := lnd.DeriveChildren(rootKey, path)
+	if err != nil {
+		t.Fatalf("error deriving key: %v", err)
+	}
+	key, err := newDerivedKey(hdKey, testSignetPath, 0, 0, false)
+	if err != nil {
+		t.Fatalf("error creating derived key: %v", err)
+	}
+
+	expected := &DerivedKey{
+		Path: testSignetPath,
+		WIF:  testSignetWIF,
+		PubKeyHex: "024a207c16be72daa7e7faf535133060a325b84d488ca23c3" +
+			"6d9cae33e777795a6",
+		AddrP2PKH:  "mu5XnEiVNXyE2vVzirPGUPPa4WkY34WhHA",
+		AddrNP2WKH: "2MuyeRtdg4FArFNzc4uoNU14o4iUG42VhSF",
+		AddrP2WKH:  "tb1qjnpwvzk7n82kkskh4ur99q3zux9677qtshswyy",
+		AddrP2TR:   testSignetP2TR,
+	}
+	if *key != *expected {
+		t.Fatalf("unexpected key, got %+v wanted %+v", key, expected)
+	}
+
+	pubKey, err := hdKey.Neuter()
+	if err != nil {
+		t.Fatalf("error neutering key: %v", err)
+	}
+	if !strings.HasPrefix(pubKey.String(), "tpub") {
+		t.Fatalf("unexpected extended public key %s", pubKey)
+	}
+}
+
+// TestSignetGenImportScript tests that genimportscript creates the script of a
+// signet wallet with the signet keys and scans the whole chain by default.
+func TestSignetGenImportScript(t *testing.T) {
+	defer useSignet(t)()
+
+	tempDir, err := ioutil.TempDir("", "chantools")
+	if err != nil {
+		t.Fatalf("error creating temp dir: %v", err)
+	}
+	defer func() {
+		_ = os.RemoveAll(tempDir)
+	}()
+
+	fileName := filepath.Join(tempDir, "script.txt")
+	cmd := &genImportScriptCommand{
+		RootKey:        testRootKey,
+		Format:         "bitcoin-cli",
+		DerivationPath: "m/84'/1'/0'",
+		RecoveryWindow: 1,
+		OutputFile:     fileName,
+	}
+	if err := cmd.Execute(nil); err != nil {
+		t.Fatalf("error creating script: %v", err)
+	}
+	content, err := ioutil.ReadFile(fileName)
+	if err != nil {
+		t.Fatalf("error reading script: %v", err)
+	}
+
+	for _, line := range []string{
+		"bitcoin-cli importprivkey " + testSignetWIF +
+			" \"m/84'/1'/0'/0/0/\" false",
+		"bitcoin-cli importaddress " + testSignetP2TR +
+			" \"m/84'/1'/0'/0/0/\" false",
+		"bitcoin-cli rescanblockchain 0",
+	} {
+		if !strings.Contains(string(content), line+"\n") {
+			t.Fatalf("script doesn't contain '%s':\n%s", line,
+				content)
+		}
+	}
+}
+
+// TestSignetBirthdayBlock tests that the block of a seed birthday on signet is
+// estimated from the signet genesis block.
+func TestSignetBirthdayBlock(t *testing.T) {
+	defer useSignet(t)()
+
+	genesis := btc.SigNetParams.GenesisBlock.Header.Timestamp
+	birthday := genesis.Add(1000 * 10 * time.Minute)
+	if height := estimateBirthdayBlock(birthday); height != 1000 {
+		t.Fatalf("unexpected birthday block %d", height)
+	}
+	if timestamp := blockToTimestamp(1000); timestamp != birthday.Unix() {
+		t.Fatalf("unexpected block timestamp %d", timestamp)
+	}
+}
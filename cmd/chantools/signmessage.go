@@ -0,0 +1,126 @@
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+
+	"github.com/btcsuite/btcd/btcec"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/btcsuite/btcutil/hdkeychain"
+	"github.com/guggero/chantools/lnd"
+)
+
+const (
+	// bitcoinMessageMagic is the prefix of a message that is signed with
+	// the Bitcoin message signing standard.
+	bitcoinMessageMagic = "Bitcoin Signed Message:\n"
+
+	// The first byte of a compact signature is the recovery ID plus a
+	// header that tells the verifier which public key and address type
+	// was used, as defined in BIP137.
+	sigHeaderUncompressed = 27
+	sigHeaderP2PKH        = 31
+	sigHeaderNP2WKH       = 35
+	sigHeaderP2WKH        = 39
+)
+
+// sigHeaders are the BIP137 signature headers of the address types that can be
+// used to sign messages.
+var sigHeaders = map[string]byte{
+	addrTypeP2PKH:  sigHeaderP2PKH,
+	addrTypeNP2WKH: sigHeaderNP2WKH,
+	addrTypeP2WKH:  sigHeaderP2WKH,
+}
+
+type signMessageCommand struct {
+	RootKey  string `long:"rootkey" description:"BIP32 HD root key to derive the signing key from. Leave empty to prompt for lnd 24 word aezeed."`
+	Path     string `long:"path" description:"The BIP32 derivation path of the signing key. (default m/84'/0'/0'/0/0)"`
+	Message  string `long:"message" description:"The message to sign."`
+	AddrType string `long:"addrtype" description:"The type of the address the signature is created for (p2pkh, p2sh-p2wpkh or p2wpkh). Leave empty to detect it from the purpose of the derivation path."`
+}
+
+func (c *signMessageCommand) Execute(_ []string) error {
+	setupChainParams(cfg)
+
+	var (
+		extendedKey *hdkeychain.ExtendedKey
+		err         error
+	)
+
+	// Check that root key is valid or fall back to console input.
+	switch {
+	case c.RootKey != "":
+		extendedKey, err = hdkeychain.NewKeyFromString(c.RootKey)
+
+	default:
+		extendedKey, _, err = rootKeyFromConsole()
+	}
+	if err != nil {
+		return fmt.Errorf("error reading root key: %v", err)
+	}
+
+	// Check that we have a message.
+	if c.Message == "" {
+		return fmt.Errorf("message is required")
+	}
+
+	// Set default values.
+	if c.Path == "" {
+		c.Path = defaultAddressPath
+	}
+	path, err := lnd.ParsePath(c.Path)
+	if err != nil {
+		return fmt.Errorf("error parsing path: %v", err)
+	}
+	addrType := c.AddrType
+	if addrType == addrTypeAutoDetect {
+		addrType = addrTypeFromPurpose(path[0])
+	}
+	header, ok := sigHeaders[addrType]
+	if !ok {
+		return fmt.Errorf("messages can't be signed for address type "+
+			"%s", addrType)
+	}
+
+	key, err := lnd.DeriveChildren(extendedKey, path)
+	if err != nil {
+		return fmt.Errorf("error deriving key: %v", err)
+	}
+	privKey, err := key.ECPrivKey()
+	if err != nil {
+		return fmt.Errorf("error deriving private key: %v", err)
+	}
+	addr, err := pubKeyAddress(
+		privKey.PubKey().SerializeCompressed(), addrType, chainParams,
+	)
+	if err != nil {
+		return err
+	}
+
+	sig, err := btcec.SignCompact(
+		btcec.S256(), privKey, bitcoinMessageHash(c.Message), true,
+	)
+	if err != nil {
+		return fmt.Errorf("error signing message: %v", err)
+	}
+
+	// SignCompact always uses the header of a compressed P2PKH key, the
+	// recovery ID stays the same for the other address types.
+	sig[0] = sig[0] - sigHeaderP2PKH + header
+
+	fmt.Printf("Address (%s): %s\n", addrType, addr.EncodeAddress())
+	fmt.Printf("Signature: %s\n", base64.StdEncoding.EncodeToString(sig))
+	return nil
+}
+
+// bitcoinMessageHash returns the hash of a message that is signed with the
+// Bitcoin message signing standard. The magic prefix and the message are both
+// serialized with their length as a var int.
+func bitcoinMessageHash(message string) []byte {
+	var buf bytes.Buffer
+	_ = wire.WriteVarString(&buf, 0, bitcoinMessageMagic)
+	_ = wire.WriteVarString(&buf, 0, message)
+	return chainhash.DoubleHashB(buf.Bytes())
+}
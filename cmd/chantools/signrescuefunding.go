@@ -0,0 +1,227 @@
+package main
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+
+	"github.com/btcsuite/btcd/btcec"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcutil/hdkeychain"
+	"github.com/btcsuite/btcutil/psbt"
+	"github.com/guggero/chantools/lnd"
+	"github.com/lightningnetwork/lnd/input"
+	"github.com/lightningnetwork/lnd/keychain"
+)
+
+const (
+	// multiSigScriptLen is the length of a 2-of-2 multisig witness script
+	// with two compressed public keys.
+	multiSigScriptLen = 1 + 1 + 33 + 1 + 33 + 1 + 1
+)
+
+type signRescueFundingCommand struct {
+	RootKey     string `long:"rootkey" description:"BIP32 HD root key of the node. Leave empty to prompt for lnd 24 word aezeed."`
+	Psbt        string `long:"psbt" description:"The PSBT of the cooperative close transaction created by rescuefunding --cooperative, base64 encoded."`
+	MaxKeyIndex uint32 `long:"maxkeyindex" description:"The highest index of the multisig key family to search for our funding key. (default 2500)"`
+}
+
+func (c *signRescueFundingCommand) Execute(_ []string) error {
+	setupChainParams(cfg)
+
+	var (
+		extendedKey *hdkeychain.ExtendedKey
+		err         error
+	)
+
+	// Check that root key is valid or fall back to console input.
+	switch {
+	case c.RootKey != "":
+		extendedKey, err = hdkeychain.NewKeyFromString(c.RootKey)
+
+	default:
+		extendedKey, _, err = rootKeyFromConsole()
+	}
+	if err != nil {
+		return fmt.Errorf("error reading root key: %v", err)
+	}
+
+	// Set default values.
+	if c.MaxKeyIndex == 0 {
+		c.MaxKeyIndex = defaultRecoveryWindow
+	}
+
+	if c.Psbt == "" {
+		return fmt.Errorf("psbt is required")
+	}
+	packet, err := psbt.NewPsbt([]byte(c.Psbt), true)
+	if err != nil {
+		return fmt.Errorf("error parsing PSBT: %v", err)
+	}
+	if len(packet.Inputs) != 1 {
+		return fmt.Errorf("expected a single funding input, got %d "+
+			"inputs", len(packet.Inputs))
+	}
+	pInput := &packet.Inputs[0]
+	pubKeys, err := fundingMultiSigKeys(pInput)
+	if err != nil {
+		return err
+	}
+
+	// We sign with the key of the script that has no signature yet.
+	var ourPubKey []byte
+	for _, pubKey := range pubKeys {
+		signed := false
+		for _, partialSig := range pInput.PartialSigs {
+			if bytes.Equal(partialSig.PubKey, pubKey) {
+				signed = true
+			}
+		}
+		if !signed {
+			ourPubKey = pubKey
+		}
+	}
+	if ourPubKey == nil {
+		return fmt.Errorf("funding input is already signed with both " +
+			"keys")
+	}
+	keyDesc, err := findMultiSigKey(extendedKey, ourPubKey, c.MaxKeyIndex)
+	if err != nil {
+		return err
+	}
+	log.Infof("Found our funding key %x at index %d", ourPubKey,
+		keyDesc.Index)
+
+	// Show what we are about to sign so it can be compared with what was
+	// agreed on with the remote party.
+	tx := packet.UnsignedTx
+	totalOut := int64(0)
+	for idx, txOut := range tx.TxOut {
+		totalOut += txOut.Value
+		log.Infof("Output %d pays %d sats to %s", idx, txOut.Value,
+			pkScriptAddress(txOut.PkScript))
+	}
+	log.Infof("Funding output %v of %d sats, fee %d sats",
+		tx.TxIn[0].PreviousOutPoint, pInput.WitnessUtxo.Value,
+		pInput.WitnessUtxo.Value-totalOut)
+
+	fingerprint, err := rootKeyFingerprint(extendedKey)
+	if err != nil {
+		return err
+	}
+	updater, err := psbt.NewUpdater(packet)
+	if err != nil {
+		return fmt.Errorf("error creating PSBT updater: %v", err)
+	}
+	err = updater.AddInBip32Derivation(
+		fingerprint, keyLocatorBip32Path(keyDesc.KeyLocator), ourPubKey,
+		0,
+	)
+	if err != nil && err != psbt.ErrDuplicateKey {
+		return fmt.Errorf("error adding BIP32 derivation: %v", err)
+	}
+
+	// Cooperative close transactions are always signed with SIGHASH_ALL.
+	signer := &lnd.Signer{
+		ExtendedKey: extendedKey,
+		ChainParams: chainParams,
+	}
+	signDesc := &input.SignDescriptor{
+		KeyDesc:       *keyDesc,
+		WitnessScript: pInput.WitnessScript,
+		Output:        pInput.WitnessUtxo,
+		HashType:      txscript.SigHashAll,
+		SigHashes:     txscript.NewTxSigHashes(tx),
+		InputIndex:    0,
+	}
+	sig, err := signer.SignOutputRaw(tx, signDesc)
+	if err != nil {
+		return fmt.Errorf("error signing funding input: %v", err)
+	}
+	sig = append(sig, byte(txscript.SigHashAll))
+	_, err = updater.Sign(0, sig, ourPubKey, nil, pInput.WitnessScript)
+	if err != nil {
+		return fmt.Errorf("error adding signature: %v", err)
+	}
+
+	if len(pInput.PartialSigs) < len(pubKeys) {
+		b64, err := packet.B64Encode()
+		if err != nil {
+			return fmt.Errorf("error encoding PSBT: %v", err)
+		}
+		log.Infof("The remote party did not sign yet, send the PSBT " +
+			"back to them")
+		fmt.Println(b64)
+		return nil
+	}
+
+	// Both signatures are there, so we can create the final transaction.
+	if err := psbt.MaybeFinalizeAll(packet); err != nil {
+		return fmt.Errorf("error finalizing PSBT: %v", err)
+	}
+	signedTx, err := psbt.Extract(packet)
+	if err != nil {
+		return fmt.Errorf("error extracting transaction: %v", err)
+	}
+	log.Infof("The closing transaction is fully signed and can be " +
+		"published")
+	fmt.Println(hex.EncodeToString(signedTx))
+	return nil
+}
+
+// fundingMultiSigKeys returns the two public keys of the 2-of-2 multisig
+// funding output that is spent by the PSBT input.
+func fundingMultiSigKeys(pInput *psbt.PInput) ([][]byte, error) {
+	script := pInput.WitnessScript
+	if pInput.WitnessUtxo == nil || len(script) == 0 {
+		return nil, fmt.Errorf("PSBT input is missing the funding " +
+			"output or its witness script")
+	}
+	if len(script) != multiSigScriptLen || script[0] != txscript.OP_2 ||
+		script[1] != txscript.OP_DATA_33 ||
+		script[35] != txscript.OP_DATA_33 ||
+		script[69] != txscript.OP_2 ||
+		script[70] != txscript.OP_CHECKMULTISIG {
+
+		return nil, fmt.Errorf("witness script is not a 2-of-2 " +
+			"multisig script")
+	}
+	pkScript, err := input.WitnessScriptHash(script)
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.Equal(pkScript, pInput.WitnessUtxo.PkScript) {
+		return nil, fmt.Errorf("witness script doesn't match the " +
+			"funding output")
+	}
+	return [][]byte{script[2:35], script[36:69]}, nil
+}
+
+// findMultiSigKey searches the multisig key family of the wallet for the given
+// public key.
+func findMultiSigKey(extendedKey *hdkeychain.ExtendedKey, pubKey []byte,
+	maxIndex uint32) (*keychain.KeyDescriptor, error) {
+
+	target, err := btcec.ParsePubKey(pubKey, btcec.S256())
+	if err != nil {
+		return nil, fmt.Errorf("error parsing public key: %v", err)
+	}
+	keyRing := &lnd.HDKeyRing{
+		ExtendedKey: extendedKey,
+		ChainParams: chainParams,
+	}
+	for index := uint32(0); index <= maxIndex; index++ {
+		keyDesc, err := keyRing.DeriveKey(keychain.KeyLocator{
+			Family: keychain.KeyFamilyMultiSig,
+			Index:  index,
+		})
+		if err != nil {
+			return nil, err
+		}
+		if keyDesc.PubKey.IsEqual(target) {
+			return &keyDesc, nil
+		}
+	}
+	return nil, fmt.Errorf("funding key %x not found in the first %d "+
+		"multisig keys of the wallet", pubKey, maxIndex+1)
+}
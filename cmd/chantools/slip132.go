@@ -0,0 +1,120 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/btcsuite/btcutil/base58"
+	"github.com/btcsuite/btcutil/hdkeychain"
+)
+
+// slip132Version is the version of an extended public key as registered in
+// SLIP-0132. The version tells wallets which script type to use for the keys
+// derived from it.
+type slip132Version struct {
+	version [4]byte
+	testnet bool
+}
+
+// slip132Versions are the SLIP-0132 versions of extended public keys by their
+// prefix.
+var slip132Versions = map[string]slip132Version{
+	"xpub": {version: [4]byte{0x04, 0x88, 0xb2, 0x1e}},
+	"ypub": {version: [4]byte{0x04, 0x9d, 0x7c, 0xb2}},
+	"zpub": {version: [4]byte{0x04, 0xb2, 0x47, 0x46}},
+	"Ypub": {version: [4]byte{0x02, 0x95, 0xb4, 0x3f}},
+	"Zpub": {version: [4]byte{0x02, 0xaa, 0x7e, 0xd3}},
+	"tpub": {version: [4]byte{0x04, 0x35, 0x87, 0xcf}, testnet: true},
+	"upub": {version: [4]byte{0x04, 0x4a, 0x52, 0x62}, testnet: true},
+	"vpub": {version: [4]byte{0x04, 0x5f, 0x1c, 0xf6}, testnet: true},
+}
+
+// slip132Encode encodes the public part of an extended key with the version
+// of the given SLIP-0132 prefix.
+func slip132Encode(key *hdkeychain.ExtendedKey, prefix string) (string,
+	error) {
+
+	version, ok := slip132Versions[prefix]
+	if !ok {
+		prefixes := make([]string, 0, len(slip132Versions))
+		for name := range slip132Versions {
+			prefixes = append(prefixes, name)
+		}
+		sort.Strings(prefixes)
+		return "", fmt.Errorf("unknown extended key version %s, must "+
+			"be one of %v", prefix, prefixes)
+	}
+	if version.testnet != (chainParams.Name != "mainnet") {
+		return "", fmt.Errorf("extended key version %s can't be used "+
+			"on %s", prefix, chainParams.Name)
+	}
+
+	pubKey, err := key.Neuter()
+	if err != nil {
+		return "", fmt.Errorf("error neutering key: %v", err)
+	}
+
+	// Neuter returns a public key itself, so we need a copy to not change
+	// the version of the caller's key.
+	pubKey, err = hdkeychain.NewKeyFromString(pubKey.String())
+	if err != nil {
+		return "", fmt.Errorf("error copying key: %v", err)
+	}
+
+	// The version is the only difference to the network's own encoding.
+	params := *chainParams
+	params.HDPublicKeyID = version.version
+	pubKey.SetNet(&params)
+	return pubKey.String(), nil
+}
+
+// slip132Decode parses an extended public key that is encoded with any of the
+// SLIP-0132 versions and returns it with the standard version of the network
+// together with the prefix of its SLIP-0132 version. If no prefix is given, it
+// is detected from the key itself.
+func slip132Decode(xpub, prefix string) (*hdkeychain.ExtendedKey, string,
+	error) {
+
+	key, err := hdkeychain.NewKeyFromString(xpub)
+	if err != nil {
+		return nil, "", fmt.Errorf("error parsing xpub: %v", err)
+	}
+	if key.IsPrivate() {
+		return nil, "", fmt.Errorf("xpub must be an extended public " +
+			"key")
+	}
+
+	detected := prefix == ""
+	if detected {
+		prefix = xpub[:4]
+	}
+	version, ok := slip132Versions[prefix]
+	if !ok {
+		return nil, "", fmt.Errorf("unknown extended key version %s",
+			prefix)
+	}
+	if version.testnet != (chainParams.Name != "mainnet") {
+		return nil, "", fmt.Errorf("extended key version %s can't be "+
+			"used on %s", prefix, chainParams.Name)
+	}
+	if prefix == "Ypub" || prefix == "Zpub" {
+		return nil, "", fmt.Errorf("multisig extended key version %s "+
+			"is not supported", prefix)
+	}
+
+	// An explicitly given version overrides whatever version the key was
+	// encoded with.
+	if detected {
+		var keyVersion [4]byte
+		copy(keyVersion[:], base58.Decode(xpub))
+		if keyVersion != version.version {
+			return nil, "", fmt.Errorf("xpub has version %x "+
+				"which is not the one of %s", keyVersion,
+				prefix)
+		}
+	}
+
+	// The version is the only difference to the network's own encoding.
+	key.SetNet(chainParams)
+	return key, prefix, nil
+}
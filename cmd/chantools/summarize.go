@@ -0,0 +1,333 @@
+package main
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"sort"
+	"text/tabwriter"
+
+	"github.com/guggero/chantools/dump"
+	"github.com/lightningnetwork/lnd/channeldb"
+)
+
+const (
+	defaultSummarizeTopPeers = 10
+)
+
+// stateSummary is the sum of the balances of all channels in one state.
+type stateSummary struct {
+	NumChannels    int    `json:"number_of_channels"`
+	Capacity       uint64 `json:"total_capacity_sat"`
+	LocalBalance   uint64 `json:"total_local_balance_sat"`
+	RemoteBalance  uint64 `json:"total_remote_balance_sat"`
+	UnsettledHTLCs uint64 `json:"total_unsettled_htlcs_sat"`
+}
+
+// peerSummary is the sum of all channels with a single peer that are not fully
+// closed yet.
+type peerSummary struct {
+	PubKey       string `json:"pubkey"`
+	NumChannels  int    `json:"number_of_channels"`
+	Capacity     uint64 `json:"total_capacity_sat"`
+	LocalBalance uint64 `json:"total_local_balance_sat"`
+}
+
+// htlcSummary is an HTLC that is still pending on the latest local commitment
+// of a channel.
+type htlcSummary struct {
+	ChannelPoint string `json:"channel_point"`
+	PaymentHash  string `json:"payment_hash"`
+	Amount       uint64 `json:"amount_sat"`
+	Expiry       uint32 `json:"expiry_height"`
+	Incoming     bool   `json:"incoming"`
+}
+
+// channelStateSummary is the inventory of all channels of a node, grouped by
+// their state.
+type channelStateSummary struct {
+	Open         *stateSummary  `json:"open"`
+	PendingOpen  *stateSummary  `json:"pending_open"`
+	PendingClose *stateSummary  `json:"pending_close"`
+	Closed       *stateSummary  `json:"closed"`
+	TopPeers     []*peerSummary `json:"top_peers"`
+	PendingHTLCs []*htlcSummary `json:"pending_htlcs"`
+}
+
+type summarizeCommand struct {
+	ChannelDB string `long:"channeldb" description:"The lnd channel.db file to summarize. The file is copied to a temporary directory first, the original file is never modified."`
+	FromDump  string `long:"fromdump" description:"The file containing the output of the dumpchannels command with the --json flag to summarize instead of a channel.db. The dump doesn't contain closed channels. Specify '-' to read from stdin."`
+	Top       int    `long:"top" description:"The number of peers with the highest total capacity to list. (default 10)"`
+	JSON      bool   `long:"json" description:"Also print the summary as JSON to stdout. The tables are always printed to stderr."`
+}
+
+func (c *summarizeCommand) Execute(_ []string) error {
+	setupChainParams(cfg)
+
+	// Set default values.
+	if c.Top == 0 {
+		c.Top = defaultSummarizeTopPeers
+	}
+
+	var (
+		channels       []dump.OpenChannel
+		closedChannels []*channeldb.ChannelCloseSummary
+		err            error
+	)
+	switch {
+	case c.ChannelDB != "":
+		channels, closedChannels, err = readChannelDBCopy(c.ChannelDB)
+		if err != nil {
+			return err
+		}
+
+	case c.FromDump != "":
+		content, err := readInput(c.FromDump)
+		if err != nil {
+			return fmt.Errorf("error reading dump file: %v", err)
+		}
+		decoder := json.NewDecoder(bytes.NewReader(content))
+		if err := decoder.Decode(&channels); err != nil {
+			return fmt.Errorf("error parsing dump file: %v", err)
+		}
+
+	default:
+		return fmt.Errorf("channel DB or dump file is required")
+	}
+
+	summary := summarizeChannelStates(channels, closedChannels, c.Top)
+	printChannelStateSummary(os.Stderr, summary)
+
+	if c.JSON {
+		summaryBytes, err := json.MarshalIndent(summary, "", " ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(summaryBytes))
+	}
+	return nil
+}
+
+// readChannelDBCopy copies the given channel DB to a temporary directory and
+// reads all channels and close summaries from the copy. That way a channel DB
+// that is still locked by lnd can be read and the original file is never
+// modified.
+func readChannelDBCopy(channelDB string) ([]dump.OpenChannel,
+	[]*channeldb.ChannelCloseSummary, error) {
+
+	tempDir, err := ioutil.TempDir("", "chantools-summarize")
+	if err != nil {
+		return nil, nil, fmt.Errorf("error creating temp dir: %v", err)
+	}
+	defer func() {
+		_ = os.RemoveAll(tempDir)
+	}()
+
+	dbCopy := path.Join(tempDir, path.Base(channelDB))
+	if err := copyFile(channelDB, dbCopy); err != nil {
+		return nil, nil, fmt.Errorf("error copying channel DB: %v", err)
+	}
+	db, err := channeldb.Open(
+		tempDir, channeldb.OptionSetSyncFreelist(true),
+		channeldb.OptionReadOnly(true),
+	)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error opening channel DB: %v", err)
+	}
+	defer db.Close()
+
+	// All channels that aren't fully closed yet, including the pending
+	// ones.
+	openChannels, err := db.FetchAllChannels()
+	if err != nil {
+		return nil, nil, fmt.Errorf("error fetching channels: %v", err)
+	}
+	channels, err := dump.ChannelDump(openChannels, chainParams)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error converting to dump format: "+
+			"%v", err)
+	}
+	closedChannels, err := db.FetchClosedChannels(false)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error fetching closed channels: "+
+			"%v", err)
+	}
+	return channels, closedChannels, nil
+}
+
+// copyFile copies the content of the source file to a new destination file.
+func copyFile(src, dst string) error {
+	srcFile, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer srcFile.Close()
+
+	dstFile, err := os.OpenFile(
+		dst, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600,
+	)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(dstFile, srcFile); err != nil {
+		_ = dstFile.Close()
+		return err
+	}
+	return dstFile.Close()
+}
+
+// summarizeChannelStates groups the channels by their state and lists the top
+// peers and all pending HTLCs. Only our balance is stored for closed channels,
+// their remote balance is always zero.
+func summarizeChannelStates(channels []dump.OpenChannel,
+	closedChannels []*channeldb.ChannelCloseSummary,
+	topPeers int) *channelStateSummary {
+
+	var (
+		summary = &channelStateSummary{
+			Open:         &stateSummary{},
+			PendingOpen:  &stateSummary{},
+			PendingClose: &stateSummary{},
+			Closed:       &stateSummary{},
+			TopPeers:     []*peerSummary{},
+			PendingHTLCs: []*htlcSummary{},
+		}
+		byPubKey = make(map[string]*peerSummary)
+		seen     = make(map[string]bool)
+	)
+	for _, channel := range channels {
+		seen[channel.FundingOutpoint] = true
+
+		// This is the same distinction lnd makes, any channel that has
+		// a status other than the default is waiting to be closed.
+		state := summary.Open
+		switch {
+		case channel.ChanStatus != channeldb.ChanStatusDefault:
+			state = summary.PendingClose
+
+		case channel.IsPending:
+			state = summary.PendingOpen
+		}
+
+		commitment := channel.LocalCommitment
+		localBalance := uint64(commitment.LocalBalance.ToSatoshis())
+		state.NumChannels++
+		state.Capacity += uint64(channel.Capacity)
+		state.LocalBalance += localBalance
+		state.RemoteBalance += uint64(
+			commitment.RemoteBalance.ToSatoshis(),
+		)
+		for _, htlc := range commitment.Htlcs {
+			amount := uint64(htlc.Amt.ToSatoshis())
+			state.UnsettledHTLCs += amount
+			summary.PendingHTLCs = append(
+				summary.PendingHTLCs, &htlcSummary{
+					ChannelPoint: channel.FundingOutpoint,
+					PaymentHash: hex.EncodeToString(
+						htlc.RHash[:],
+					),
+					Amount:   amount,
+					Expiry:   htlc.RefundTimeout,
+					Incoming: htlc.Incoming,
+				},
+			)
+		}
+
+		peer, ok := byPubKey[channel.IdentityPub]
+		if !ok {
+			peer = &peerSummary{PubKey: channel.IdentityPub}
+			byPubKey[channel.IdentityPub] = peer
+			summary.TopPeers = append(summary.TopPeers, peer)
+		}
+		peer.NumChannels++
+		peer.Capacity += uint64(channel.Capacity)
+		peer.LocalBalance += localBalance
+	}
+
+	// Channels that are waiting for their closing transaction to confirm
+	// have a close summary as well, they were already counted above. The
+	// close summaries that are still pending wait for all outputs to be
+	// swept.
+	for _, closed := range closedChannels {
+		if seen[closed.ChanPoint.String()] {
+			continue
+		}
+		state := summary.Closed
+		if closed.IsPending {
+			state = summary.PendingClose
+		}
+		state.NumChannels++
+		state.Capacity += uint64(closed.Capacity)
+		state.LocalBalance += uint64(
+			closed.SettledBalance + closed.TimeLockedBalance,
+		)
+	}
+
+	sort.SliceStable(summary.TopPeers, func(i, j int) bool {
+		return summary.TopPeers[i].Capacity >
+			summary.TopPeers[j].Capacity
+	})
+	if len(summary.TopPeers) > topPeers {
+		summary.TopPeers = summary.TopPeers[:topPeers]
+	}
+	sort.SliceStable(summary.PendingHTLCs, func(i, j int) bool {
+		return summary.PendingHTLCs[i].Expiry <
+			summary.PendingHTLCs[j].Expiry
+	})
+	return summary
+}
+
+// printChannelStateSummary prints the summary as human readable tables.
+func printChannelStateSummary(out io.Writer, summary *channelStateSummary) {
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "STATE\tCHANNELS\tCAPACITY\tLOCAL BALANCE\t"+
+		"REMOTE BALANCE\tUNSETTLED HTLCS")
+	states := []struct {
+		name  string
+		state *stateSummary
+	}{
+		{"open", summary.Open},
+		{"pending-open", summary.PendingOpen},
+		{"pending-close", summary.PendingClose},
+		{"closed", summary.Closed},
+	}
+	for _, s := range states {
+		fmt.Fprintf(w, "%s\t%d\t%d\t%d\t%d\t%d\n", s.name,
+			s.state.NumChannels, s.state.Capacity,
+			s.state.LocalBalance, s.state.RemoteBalance,
+			s.state.UnsettledHTLCs)
+	}
+	_ = w.Flush()
+
+	fmt.Fprintf(out, "\nTop %d peers by capacity:\n", len(summary.TopPeers))
+	w = tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "PUBKEY\tCHANNELS\tCAPACITY\tLOCAL BALANCE")
+	for _, peer := range summary.TopPeers {
+		fmt.Fprintf(w, "%s\t%d\t%d\t%d\n", peer.PubKey,
+			peer.NumChannels, peer.Capacity, peer.LocalBalance)
+	}
+	_ = w.Flush()
+
+	if len(summary.PendingHTLCs) == 0 {
+		fmt.Fprintln(out, "\nNo pending HTLCs.")
+		return
+	}
+	fmt.Fprintf(out, "\n%d pending HTLCs:\n", len(summary.PendingHTLCs))
+	w = tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "CHANNEL POINT\tPAYMENT HASH\tAMOUNT\tEXPIRY\t"+
+		"DIRECTION")
+	for _, htlc := range summary.PendingHTLCs {
+		direction := "outgoing"
+		if htlc.Incoming {
+			direction = "incoming"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%d\t%d\t%s\n", htlc.ChannelPoint,
+			htlc.PaymentHash, htlc.Amount, htlc.Expiry, direction)
+	}
+	_ = w.Flush()
+}
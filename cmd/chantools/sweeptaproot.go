@@ -0,0 +1,254 @@
+package main
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+
+	"github.com/btcsuite/btcd/btcec"
+	"github.com/btcsuite/btcutil/hdkeychain"
+	"github.com/guggero/chantools/btc"
+	"github.com/guggero/chantools/lnd"
+	"github.com/guggero/chantools/sweep"
+)
+
+type sweepTaprootCommand struct {
+	RootKey        string  `long:"rootkey" description:"BIP32 HD root key to use. Leave empty to prompt for lnd 24 word aezeed."`
+	UTXO           string  `long:"utxo" description:"The Taproot output to sweep, in the format txid:index."`
+	DerivationPath string  `long:"derivationpath" description:"The derivation path of the key that can spend the output. For a key path spend this is the internal key of the output."`
+	SweepAddr      string  `long:"sweepaddr" description:"The address the funds should be swept to."`
+	TapScript      string  `long:"tapscript" description:"The leaf script to spend the output with instead of the key path (hex). The script must only need a single signature of the derived key, time locks of the script are added to the sweep TX automatically."`
+	ControlBlock   string  `long:"controlblock" description:"The control block of the leaf script (hex), as printed by computemerkleroot. Only needed if the output has other leaves or a different internal key. (default a tree with only the leaf script and the derived key as internal key)"`
+	FeeRate        float64 `long:"feerate" description:"The fee rate of the sweep TX in sat/vByte. (default 2)"`
+	Publish        bool    `long:"publish" description:"Should the sweep TX be published to the chain API?"`
+}
+
+func (c *sweepTaprootCommand) Execute(_ []string) error {
+	setupChainParams(cfg)
+
+	var (
+		extendedKey *hdkeychain.ExtendedKey
+		err         error
+	)
+
+	// Check that root key is valid or fall back to console input.
+	switch {
+	case c.RootKey != "":
+		extendedKey, err = hdkeychain.NewKeyFromString(c.RootKey)
+
+	default:
+		extendedKey, _, err = rootKeyFromConsole()
+	}
+	if err != nil {
+		return fmt.Errorf("error reading root key: %v", err)
+	}
+
+	// Set default values.
+	if c.FeeRate == 0 {
+		c.FeeRate = feeSatPerByte
+	}
+
+	if c.UTXO == "" {
+		return fmt.Errorf("utxo is required")
+	}
+	if c.DerivationPath == "" {
+		return fmt.Errorf("derivation path is required")
+	}
+	if c.SweepAddr == "" {
+		return fmt.Errorf("sweep addr is required")
+	}
+	if c.ControlBlock != "" && c.TapScript == "" {
+		return fmt.Errorf("controlblock requires tapscript")
+	}
+	outPoint, err := parseOutPoint(c.UTXO)
+	if err != nil {
+		return fmt.Errorf("error parsing utxo: %v", err)
+	}
+	path, err := lnd.ParsePath(c.DerivationPath)
+	if err != nil {
+		return fmt.Errorf("error parsing path: %v", err)
+	}
+	derivedKey, err := lnd.DeriveChildren(extendedKey, path)
+	if err != nil {
+		return fmt.Errorf("error deriving key: %v", err)
+	}
+	privKey, err := derivedKey.ECPrivKey()
+	if err != nil {
+		return fmt.Errorf("error deriving private key: %v", err)
+	}
+	sweepAddr, err := btc.DecodeAddress(c.SweepAddr, chainParams)
+	if err != nil {
+		return fmt.Errorf("error parsing sweep addr: %v", err)
+	}
+
+	// The output script and value are needed for the signature, so we
+	// look them up with the API.
+	api := &btc.ExplorerAPI{BaseURL: cfg.APIURL}
+	txid := outPoint.Hash.String()
+	tx, err := api.Transaction(txid)
+	if err != nil {
+		return fmt.Errorf("error looking up transaction %s: %v", txid,
+			err)
+	}
+	if int(outPoint.Index) >= len(tx.Vout) {
+		return fmt.Errorf("transaction %s has no output %d", txid,
+			outPoint.Index)
+	}
+	pkScript, err := hex.DecodeString(tx.Vout[outPoint.Index].ScriptPubkey)
+	if err != nil {
+		return fmt.Errorf("error decoding output script: %v", err)
+	}
+	if !isTaprootScript(pkScript) {
+		return fmt.Errorf("output %v is not a Taproot output", outPoint)
+	}
+	outspend, err := api.Outspend(txid, outPoint.Index)
+	if err != nil {
+		return fmt.Errorf("error looking up outspend: %v", err)
+	}
+	if outspend.Spent {
+		return fmt.Errorf("output %v is already spent by %s", outPoint,
+			outspend.Txid)
+	}
+
+	utxo := sweep.UTXO{
+		OutPoint: *outPoint,
+		Value:    int64(tx.Vout[outPoint.Index].Value),
+	}
+	builder := sweep.NewSweepBuilder(chainParams)
+	builder.SetFeeRate(c.FeeRate)
+	builder.SetOutput(sweepAddr)
+
+	// Before signing anything, we make sure the output really commits to
+	// our key or the leaf script.
+	scriptType := sweep.ScriptTypeP2TR
+	switch {
+	case c.TapScript == "":
+		_, outputKey, err := btc.TaprootOutputKey(
+			privKey.PubKey(), nil,
+		)
+		if err != nil {
+			return err
+		}
+		if !taprootOutputKeyMatches(pkScript, outputKey) {
+			return fmt.Errorf("output %v is not the BIP86 key "+
+				"path output of the key %s, use tapscript for "+
+				"a script path spend", outPoint,
+				c.DerivationPath)
+		}
+
+	default:
+		scriptType = sweep.ScriptTypeP2TRScriptPath
+		err := c.addTapScript(
+			&utxo, builder, privKey.PubKey(), pkScript,
+		)
+		if err != nil {
+			return err
+		}
+	}
+	builder.AddInput(utxo, privKey, scriptType)
+
+	sweepTx, err := builder.Build()
+	if err != nil {
+		return fmt.Errorf("error creating sweep TX: %v", err)
+	}
+	var buf bytes.Buffer
+	if err := sweepTx.Serialize(&buf); err != nil {
+		return err
+	}
+	log.Infof("Fee %d sats of %d total amount (for vsize %d)",
+		builder.Fee(), utxo.Value, builder.VSize())
+
+	// Publish TX.
+	switch {
+	case cfg.DryRun:
+		return printDryRun(
+			hex.EncodeToString(buf.Bytes()), builder.InputValues(),
+		)
+
+	case c.Publish:
+		response, err := api.PublishTx(
+			hex.EncodeToString(buf.Bytes()),
+		)
+		if err != nil {
+			return err
+		}
+		log.Infof("Published TX %s, response: %s",
+			sweepTx.TxHash().String(), response)
+	}
+
+	log.Infof("Transaction: %x", buf.Bytes())
+	return nil
+}
+
+// addTapScript adds the leaf script and its control block to the UTXO of a
+// script path spend and applies the time locks of the script.
+func (c *sweepTaprootCommand) addTapScript(utxo *sweep.UTXO,
+	builder *sweep.Builder, pubKey *btcec.PublicKey,
+	pkScript []byte) error {
+
+	leafScript, err := hex.DecodeString(c.TapScript)
+	if err != nil {
+		return fmt.Errorf("error decoding tapscript: %v", err)
+	}
+
+	var controlBlock []byte
+	switch {
+	case c.ControlBlock != "":
+		controlBlock, err = hex.DecodeString(c.ControlBlock)
+		if err != nil {
+			return fmt.Errorf("error decoding control block: %v",
+				err)
+		}
+
+	// Without a control block, we assume the leaf script is the only leaf
+	// of the tree and our key is the internal key.
+	default:
+		leafHash := btc.TapLeafHash(btc.BaseLeafVersion, leafScript)
+		_, outputKey, err := btc.TaprootOutputKey(pubKey, leafHash)
+		if err != nil {
+			return err
+		}
+		controlBlock = btc.TapControlBlock(
+			btc.BaseLeafVersion, pubKey, outputKey, nil,
+		)
+	}
+	outputKey, err := btc.ParseControlBlock(controlBlock, leafScript)
+	if err != nil {
+		return fmt.Errorf("error parsing control block: %v", err)
+	}
+	if !taprootOutputKeyMatches(pkScript, outputKey) {
+		return fmt.Errorf("output %v doesn't commit to the leaf "+
+			"script with the control block %x", utxo.OutPoint,
+			controlBlock)
+	}
+	utxo.TapLeafScript = leafScript
+	utxo.ControlBlock = controlBlock
+
+	// The sweep TX must satisfy the time locks of the script, otherwise
+	// it's not valid.
+	csv, cltv, err := lnd.ScriptTimeLocks(leafScript)
+	if err != nil {
+		return fmt.Errorf("error parsing tapscript: %v", err)
+	}
+	if csv > 0 {
+		utxo.Sequence = csv
+		log.Infof("Leaf script has a relative time lock of %d, the "+
+			"sweep TX can only be published once it has passed",
+			csv)
+	}
+	if cltv > 0 {
+		builder.SetLockTime(cltv)
+		log.Infof("Leaf script has an absolute time lock of %d, the "+
+			"sweep TX can only be published once it has passed",
+			cltv)
+	}
+	return nil
+}
+
+// taprootOutputKeyMatches returns true if the Taproot output script has the
+// given output key as its witness program.
+func taprootOutputKeyMatches(pkScript []byte,
+	outputKey *btcec.PublicKey) bool {
+
+	return bytes.Equal(pkScript[2:], outputKey.SerializeCompressed()[1:])
+}
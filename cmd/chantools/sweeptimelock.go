@@ -7,12 +7,14 @@ import (
 
 	"github.com/btcsuite/btcd/btcec"
 	"github.com/btcsuite/btcd/chaincfg/chainhash"
-	"github.com/btcsuite/btcd/txscript"
 	"github.com/btcsuite/btcd/wire"
+	"github.com/btcsuite/btcutil"
 	"github.com/btcsuite/btcutil/hdkeychain"
+	"github.com/btcsuite/btcutil/psbt"
 	"github.com/guggero/chantools/btc"
 	"github.com/guggero/chantools/dataformat"
 	"github.com/guggero/chantools/lnd"
+	"github.com/guggero/chantools/sweep"
 	"github.com/lightningnetwork/lnd/input"
 )
 
@@ -21,10 +23,13 @@ const (
 )
 
 type sweepTimeLockCommand struct {
-	RootKey     string `long:"rootkey" description:"BIP32 HD root key to use. Leave empty to prompt for lnd 24 word aezeed."`
-	Publish     bool   `long:"publish" description:"Should the sweep TX be published to the chain API?"`
-	SweepAddr   string `long:"sweepaddr" description:"The address the funds should be sweeped to"`
-	MaxCsvLimit int    `long:"maxcsvlimit" description:"Maximum CSV limit to use. (default 2000)"`
+	RootKey     string  `long:"rootkey" description:"BIP32 HD root key to use. Leave empty to prompt for lnd 24 word aezeed."`
+	Publish     bool    `long:"publish" description:"Should the sweep TX be published to the chain API?"`
+	SweepAddr   string  `long:"sweepaddr" description:"The address the funds should be sweeped to"`
+	MaxCsvLimit int     `long:"maxcsvlimit" description:"Maximum CSV limit to use. (default 2000)"`
+	Psbt        bool    `long:"psbt" description:"Don't sign the sweep TX but create an unsigned PSBT with the derivation information of all inputs instead so it can be signed externally."`
+	OutputFile  string  `long:"outputfile" description:"The file to write the PSBT to. Leave empty to print it."`
+	BumpFeeRate float64 `long:"bumpfeerate" description:"Replace the unconfirmed sweep TX of the same time locked outputs with one that pays this fee rate in sat/vByte. The original TX must signal RBF. The replacement is only published with --publish."`
 }
 
 func (c *sweepTimeLockCommand) Execute(_ []string) error {
@@ -62,27 +67,34 @@ func (c *sweepTimeLockCommand) Execute(_ []string) error {
 	if c.MaxCsvLimit == 0 {
 		c.MaxCsvLimit = 2000
 	}
+	if c.BumpFeeRate != 0 && c.Psbt {
+		return fmt.Errorf("bumpfeerate cannot be used with psbt")
+	}
 	return sweepTimeLock(
 		extendedKey, cfg.APIURL, entries, c.SweepAddr, c.MaxCsvLimit,
-		c.Publish,
+		c.Publish, c.Psbt, c.OutputFile, c.BumpFeeRate,
 	)
 }
 
 func sweepTimeLock(extendedKey *hdkeychain.ExtendedKey, apiURL string,
 	entries []*dataformat.SummaryEntry, sweepAddr string, maxCsvTimeout int,
-	publish bool) error {
+	publish, createPsbt bool, psbtFile string, bumpFeeRate float64) error {
 
-	// Create signer and transaction template.
+	// Create signer and transaction builder.
 	signer := &lnd.Signer{
 		ExtendedKey: extendedKey,
 		ChainParams: chainParams,
 	}
 	api := &btc.ExplorerAPI{BaseURL: apiURL}
+	builder := sweep.NewSweepBuilder(chainParams)
+	builder.SetFeeRate(feeSatPerByte)
+	fingerprint, err := rootKeyFingerprint(extendedKey)
+	if err != nil {
+		return err
+	}
 
-	sweepTx := wire.NewMsgTx(2)
 	totalOutputValue := int64(0)
-	signDescs := make([]*input.SignDescriptor, 0)
-
+	numInputs := 0
 	for _, entry := range entries {
 		// Skip entries that can't be swept.
 		if entry.ForceClose == nil ||
@@ -97,22 +109,7 @@ func sweepTimeLock(extendedKey *hdkeychain.ExtendedKey, apiURL string,
 		fc := entry.ForceClose
 
 		// Find index of sweepable output of commitment TX.
-		txindex := -1
-		if len(fc.Outs) == 1 {
-			txindex = 0
-			if fc.Outs[0].Value != entry.LocalBalance {
-				log.Errorf("Potential value mismatch! %d vs "+
-					"%d (%s)",
-					fc.Outs[0].Value, entry.LocalBalance,
-					entry.ChannelPoint)
-			}
-		} else {
-			for idx, out := range fc.Outs {
-				if out.Value == entry.LocalBalance {
-					txindex = idx
-				}
-			}
-		}
+		txindex := findToLocalOutput(entry)
 		if txindex == -1 {
 			log.Errorf("Could not find sweep output for chan %s",
 				entry.ChannelPoint)
@@ -134,10 +131,12 @@ func sweepTimeLock(extendedKey *hdkeychain.ExtendedKey, apiURL string,
 			return fmt.Errorf("error getting private key: %v", err)
 		}
 		delayBase := delayPrivKey.PubKey()
+		delayBasePubKey := delayBase.SerializeCompressed()
+		delayTweak := input.SingleTweakBytes(commitPoint, delayBase)
 
 		// We can't rely on the CSV delay of the channel DB to be
 		// correct. But it doesn't cost us a lot to just brute force it.
-		csvTimeout, script, scriptHash, err := bruteForceDelay(
+		csvTimeout, script, _, err := bruteForceDelay(
 			input.TweakPubKey(delayBase, commitPoint),
 			input.DeriveRevocationPubkey(revBase, commitPoint),
 			fc.Outs[txindex].Script, maxCsvTimeout,
@@ -149,79 +148,72 @@ func sweepTimeLock(extendedKey *hdkeychain.ExtendedKey, apiURL string,
 			continue
 		}
 
-		// Create the transaction input.
+		// Add the time locked output with the tweaked delay key to
+		// the sweep transaction.
 		txHash, err := chainhash.NewHashFromStr(fc.TXID)
 		if err != nil {
 			return fmt.Errorf("error parsing tx hash: %v", err)
 		}
-		sweepTx.TxIn = append(sweepTx.TxIn, &wire.TxIn{
-			PreviousOutPoint: wire.OutPoint{
+		builder.AddInput(sweep.UTXO{
+			OutPoint: wire.OutPoint{
 				Hash:  *txHash,
 				Index: uint32(txindex),
 			},
+			Value: int64(fc.Outs[txindex].Value),
 			Sequence: input.LockTimeToSequence(
 				false, uint32(csvTimeout),
 			),
-		})
-
-		// Create the sign descriptor for the input.
-		signDesc := &input.SignDescriptor{
-			KeyDesc: *delayDesc,
-			SingleTweak: input.SingleTweakBytes(
-				commitPoint, delayBase,
-			),
 			WitnessScript: script,
-			Output: &wire.TxOut{
-				PkScript: scriptHash,
-				Value:    int64(fc.Outs[txindex].Value),
+			WitnessSize:   input.ToLocalTimeoutWitnessSize,
+			Witness: func(sig []byte) wire.TxWitness {
+				return wire.TxWitness{sig, nil, script}
 			},
-			HashType: txscript.SigHashAll,
-		}
+			Derivation: &psbt.Bip32Derivation{
+				PubKey:               delayBasePubKey,
+				MasterKeyFingerprint: fingerprint,
+				Bip32Path: keyLocatorBip32Path(
+					delayDesc.KeyLocator,
+				),
+			},
+			SingleTweak: delayTweak,
+		}, input.TweakPrivKey(
+			delayPrivKey, delayTweak,
+		), sweep.ScriptTypeP2WSH)
 		totalOutputValue += int64(fc.Outs[txindex].Value)
-		signDescs = append(signDescs, signDesc)
+		numInputs++
+	}
+	if numInputs == 0 {
+		return fmt.Errorf("no time locked outputs to sweep")
 	}
 
 	// Add our sweep destination output.
-	sweepScript, err := getWP2PKHScript(sweepAddr)
+	addr, err := btcutil.DecodeAddress(sweepAddr, chainParams)
 	if err != nil {
-		return err
+		return fmt.Errorf("error parsing sweep addr: %v", err)
 	}
-	sweepTx.TxOut = []*wire.TxOut{{
-		Value:    totalOutputValue,
-		PkScript: sweepScript,
-	}}
-
-	// Very naive fee estimation algorithm: Sign a first time as if we would
-	// send the whole amount with zero fee, just to estimate how big the
-	// transaction would get in bytes. Then adjust the fee and sign again.
-	sigHashes := txscript.NewTxSigHashes(sweepTx)
-	for idx, desc := range signDescs {
-		desc.SigHashes = sigHashes
-		desc.InputIndex = idx
-		witness, err := input.CommitSpendTimeout(signer, desc, sweepTx)
-		if err != nil {
-			return err
-		}
-		sweepTx.TxIn[idx].Witness = witness
+	builder.SetOutput(addr)
+
+	if bumpFeeRate != 0 {
+		return bumpSweepFee(api, builder, bumpFeeRate, publish)
 	}
 
-	// Calculate a fee. This won't be very accurate so the feeSatPerByte
-	// should at least be 2 to not risk falling below the 1 sat/byte limit.
-	size := sweepTx.SerializeSize()
-	fee := int64(size * feeSatPerByte)
-	sweepTx.TxOut[0].Value = totalOutputValue - fee
-
-	// Sign again after output fixing.
-	sigHashes = txscript.NewTxSigHashes(sweepTx)
-	for idx, desc := range signDescs {
-		desc.SigHashes = sigHashes
-		witness, err := input.CommitSpendTimeout(signer, desc, sweepTx)
+	// If an external signer should sign the transaction, we stop here.
+	if createPsbt {
+		packet, err := builder.BuildPsbt()
 		if err != nil {
-			return err
+			return fmt.Errorf("error creating sweep PSBT: %v", err)
 		}
-		sweepTx.TxIn[idx].Witness = witness
+		log.Infof("Fee %d sats of %d total amount", builder.Fee(),
+			totalOutputValue)
+		return writePsbts([]*psbt.Psbt{packet}, psbtFile)
 	}
 
+	sweepTx, err := builder.Build()
+	if err != nil {
+		return fmt.Errorf("error creating sweep TX: %v", err)
+	}
+	fee := builder.Fee()
+
 	var buf bytes.Buffer
 	err = sweepTx.Serialize(&buf)
 	if err != nil {
@@ -231,7 +223,13 @@ func sweepTimeLock(extendedKey *hdkeychain.ExtendedKey, apiURL string,
 		fee, totalOutputValue, sweepTx.SerializeSize())
 
 	// Publish TX.
-	if publish {
+	switch {
+	case cfg.DryRun:
+		return printDryRun(
+			hex.EncodeToString(buf.Bytes()), builder.InputValues(),
+		)
+
+	case publish:
 		response, err := api.PublishTx(
 			hex.EncodeToString(buf.Bytes()),
 		)
@@ -256,16 +254,47 @@ func pubKeyFromHex(pubKeyHex string) (*btcec.PublicKey, error) {
 	)
 }
 
-func getWP2PKHScript(addr string) ([]byte, error) {
-	targetPubKeyHash, err := parseAddr(addr)
-	if err != nil {
-		return nil, err
+// findToLocalOutput returns the index of the time locked to_local output of
+// the force-close transaction of the channel or -1 if it cannot be found.
+func findToLocalOutput(entry *dataformat.SummaryEntry) int {
+	fc := entry.ForceClose
+
+	// Newer force-close results already know the type of each output.
+	for idx, out := range fc.Outs {
+		if out.Type == dataformat.OutTypeToLocal {
+			return idx
+		}
 	}
-	builder := txscript.NewScriptBuilder()
-	builder.AddOp(txscript.OP_0)
-	builder.AddData(targetPubKeyHash)
 
-	return builder.Script()
+	// Otherwise we look at the output scripts. Only a P2WSH output can be
+	// our time locked output.
+	var candidates []int
+	for idx, out := range fc.Outs {
+		script, err := hex.DecodeString(out.Script)
+		if err != nil {
+			continue
+		}
+		scriptType, err := lnd.ClassifyScript(script)
+		if err != nil || scriptType != lnd.ScriptTypeP2WSH {
+			continue
+		}
+		candidates = append(candidates, idx)
+	}
+	if len(candidates) == 1 {
+		out := fc.Outs[candidates[0]]
+		if out.Value != entry.LocalBalance {
+			log.Errorf("Potential value mismatch! %d vs %d (%s)",
+				out.Value, entry.LocalBalance,
+				entry.ChannelPoint)
+		}
+		return candidates[0]
+	}
+	for _, idx := range candidates {
+		if fc.Outs[idx].Value == entry.LocalBalance {
+			return idx
+		}
+	}
+	return -1
 }
 
 func bruteForceDelay(delayPubkey, revocationPubkey *btcec.PublicKey,
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"github.com/btcsuite/btcutil"
+	"github.com/btcsuite/btcutil/hdkeychain"
+	"github.com/guggero/chantools/lnd"
+	"github.com/lightningnetwork/lnd/keychain"
+)
+
+const (
+	defaultTraceMaxFamily = 9
+	defaultTraceMaxIndex  = 100
+
+	// indexPlaceholder is replaced by the index in a path template.
+	indexPlaceholder = "{index}"
+)
+
+type tracePathCommand struct {
+	RootKey     string `long:"rootkey" description:"BIP32 HD root key of the wallet. Leave empty to prompt for lnd 24 word aezeed."`
+	PubKey      string `long:"pubkey" description:"The public key to find the derivation path of (hex)."`
+	Paths       string `long:"paths" description:"A comma separated list of additional path templates to search, the last level must be {index}, for example m/84'/0'/0'/0/{index}."`
+	MaxFamily   uint32 `long:"maxfamily" description:"The highest lnd key family to search in m/1017'/coinType'/family'/0/index. (default 9)"`
+	MaxIndex    uint32 `long:"maxindex" description:"The highest index to search in every path template. (default 100)"`
+	ShowPrivKey bool   `long:"showprivkey" description:"Also print the private key in the WIF format if the path is found."`
+}
+
+func (c *tracePathCommand) Execute(_ []string) error {
+	setupChainParams(cfg)
+
+	var (
+		extendedKey *hdkeychain.ExtendedKey
+		err         error
+	)
+
+	// Check that root key is valid or fall back to console input.
+	switch {
+	case c.RootKey != "":
+		extendedKey, err = hdkeychain.NewKeyFromString(c.RootKey)
+
+	default:
+		extendedKey, _, err = rootKeyFromConsole()
+	}
+	if err != nil {
+		return fmt.Errorf("error reading root key: %v", err)
+	}
+
+	// Set default values.
+	if c.MaxFamily == 0 {
+		c.MaxFamily = defaultTraceMaxFamily
+	}
+	if c.MaxIndex == 0 {
+		c.MaxIndex = defaultTraceMaxIndex
+	}
+
+	if c.PubKey == "" {
+		return fmt.Errorf("pubkey is required")
+	}
+	targetKey, err := pubKeyFromHex(c.PubKey)
+	if err != nil {
+		return fmt.Errorf("error parsing pubkey: %v", err)
+	}
+
+	var templates []string
+	for family := uint32(0); family <= c.MaxFamily; family++ {
+		templates = append(templates, fmt.Sprintf(
+			"m/%d'/%d'/%d'/0/%s", keychain.BIP0043Purpose,
+			chainParams.HDCoinType, family, indexPlaceholder,
+		))
+	}
+	if c.Paths != "" {
+		templates = append(templates, strings.Split(c.Paths, ",")...)
+	}
+
+	target := targetKey.SerializeCompressed()
+	for _, template := range templates {
+		template = strings.TrimSpace(template)
+		path, key, err := searchPathTemplate(
+			extendedKey, template, c.MaxIndex, target,
+		)
+		if err != nil {
+			return err
+		}
+		if key == nil {
+			continue
+		}
+
+		fmt.Printf("Found derivation path: %s\n", path)
+		if c.ShowPrivKey {
+			privKey, err := key.ECPrivKey()
+			if err != nil {
+				return fmt.Errorf("could not derive private "+
+					"key: %v", err)
+			}
+			wif, err := btcutil.NewWIF(privKey, chainParams, true)
+			if err != nil {
+				return fmt.Errorf("could not encode WIF: %v",
+					err)
+			}
+			fmt.Printf("Private key (WIF): %s\n", wif.String())
+		}
+		return nil
+	}
+
+	return fmt.Errorf("public key not found in %d path templates with "+
+		"indexes 0 to %d", len(templates), c.MaxIndex)
+}
+
+// searchPathTemplate derives all keys of a path template up to the maximum
+// index and returns the path and key that matches the target public key. The
+// returned key is nil if there is no match.
+func searchPathTemplate(extendedKey *hdkeychain.ExtendedKey, template string,
+	maxIndex uint32, target []byte) (string, *hdkeychain.ExtendedKey,
+	error) {
+
+	if !strings.HasSuffix(template, "/"+indexPlaceholder) {
+		return "", nil, fmt.Errorf("path template %s must end with /%s",
+			template, indexPlaceholder)
+	}
+	basePath := strings.TrimSuffix(template, "/"+indexPlaceholder)
+
+	// Deriving the hardened levels is expensive, so we only do it once per
+	// template.
+	parsedPath, err := lnd.ParsePath(basePath)
+	if err != nil {
+		return "", nil, fmt.Errorf("could not parse path template %s: "+
+			"%v", template, err)
+	}
+	baseKey, err := lnd.DeriveChildren(extendedKey, parsedPath)
+	if err != nil {
+		return "", nil, fmt.Errorf("could not derive %s: %v", basePath,
+			err)
+	}
+
+	for index := uint32(0); index <= maxIndex; index++ {
+		key, err := baseKey.Child(index)
+		if err != nil {
+			return "", nil, fmt.Errorf("could not derive %s/%d: %v",
+				basePath, index, err)
+		}
+		pubKey, err := key.ECPubKey()
+		if err != nil {
+			return "", nil, fmt.Errorf("could not derive public "+
+				"key: %v", err)
+		}
+		if bytes.Equal(pubKey.SerializeCompressed(), target) {
+			return fmt.Sprintf("%s/%d", basePath, index), key, nil
+		}
+	}
+	return "", nil, nil
+}
@@ -0,0 +1,162 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path"
+	"time"
+
+	"github.com/btcsuite/btcutil/hdkeychain"
+	"github.com/guggero/chantools/btc"
+	"github.com/guggero/chantools/dataformat"
+	"github.com/guggero/chantools/lnd"
+	"github.com/lightningnetwork/lnd/channeldb"
+)
+
+type unilateralCloseCommand struct {
+	RootKey   string `long:"rootkey" description:"BIP32 HD root key to use. Leave empty to prompt for lnd 24 word aezeed."`
+	ChannelDB string `long:"channeldb" description:"The lnd channel.db file to read the latest commitment transactions from."`
+	Channel   string `long:"channel" description:"The channel point (txid:index) of the channel to close."`
+	All       bool   `long:"all" description:"Close all channels in the channel DB."`
+	Publish   bool   `long:"publish" description:"Should the commitment TXs be published to the chain API?"`
+}
+
+func (c *unilateralCloseCommand) Execute(_ []string) error {
+	setupChainParams(cfg)
+
+	var (
+		extendedKey *hdkeychain.ExtendedKey
+		err         error
+	)
+
+	// Check that root key is valid or fall back to console input.
+	switch {
+	case c.RootKey != "":
+		extendedKey, err = hdkeychain.NewKeyFromString(c.RootKey)
+
+	default:
+		extendedKey, _, err = rootKeyFromConsole()
+	}
+	if err != nil {
+		return fmt.Errorf("error reading root key: %v", err)
+	}
+
+	if c.Channel == "" && !c.All {
+		return fmt.Errorf("either channel or all is required")
+	}
+
+	// Check that we have a channel DB.
+	if c.ChannelDB == "" {
+		return fmt.Errorf("channel DB is required")
+	}
+	db, err := channeldb.Open(
+		path.Dir(c.ChannelDB), channeldb.OptionSetSyncFreelist(true),
+		channeldb.OptionReadOnly(true),
+	)
+	if err != nil {
+		return fmt.Errorf("error opening channel DB: %v", err)
+	}
+	entries, err := (&dataformat.ChannelDBFile{DB: db}).AsSummaryEntries()
+	if err != nil {
+		return err
+	}
+	channels, err := db.FetchAllChannels()
+	if err != nil {
+		return fmt.Errorf("error fetching channels: %v", err)
+	}
+
+	api := &btc.ExplorerAPI{BaseURL: cfg.APIURL}
+	signer := &lnd.Signer{
+		ExtendedKey: extendedKey,
+		ChainParams: chainParams,
+	}
+
+	// The summary entries are created in the same order as the channels
+	// are returned from the DB.
+	var closed []*dataformat.SummaryEntry
+	for idx, channel := range channels {
+		entry := entries[idx]
+		if !c.All && entry.ChannelPoint != c.Channel {
+			continue
+		}
+		if channel.LocalCommitment.CommitTx == nil {
+			log.Errorf("Cannot force-close, no local commit TX "+
+				"for channel %s", entry.ChannelPoint)
+			continue
+		}
+
+		entry.ForceClose, err = signCommitment(channel, signer)
+		if err != nil {
+			return fmt.Errorf("error signing commitment of "+
+				"channel %s: %v", entry.ChannelPoint, err)
+		}
+		closed = append(closed, entry)
+		logSweepParams(entry)
+
+		switch {
+		case cfg.DryRun:
+			err := printDryRun(
+				entry.ForceClose.Serialized,
+				[]int64{int64(channel.Capacity)},
+			)
+			if err != nil {
+				return err
+			}
+
+		case c.Publish:
+			response, err := api.PublishTx(
+				entry.ForceClose.Serialized,
+			)
+			if err != nil {
+				return err
+			}
+			log.Infof("Published TX %s, response: %s",
+				entry.ForceClose.TXID, response)
+		}
+	}
+	if len(closed) == 0 {
+		return fmt.Errorf("no matching channel found")
+	}
+
+	summaryBytes, err := json.MarshalIndent(&dataformat.SummaryEntryFile{
+		Channels: closed,
+	}, "", " ")
+	if err != nil {
+		return err
+	}
+	fileName := fmt.Sprintf("results/unilateralclose-%s.json",
+		time.Now().Format("2006-01-02-15-04-05"))
+	log.Infof("Writing result to %s", fileName)
+	return ioutil.WriteFile(fileName, summaryBytes, 0644)
+}
+
+// logSweepParams prints all outputs of the commitment transaction of a channel
+// together with what is needed to sweep them.
+func logSweepParams(entry *dataformat.SummaryEntry) {
+	fc := entry.ForceClose
+	log.Infof("Commitment TX %s of channel %s:", fc.TXID,
+		entry.ChannelPoint)
+	for idx, out := range fc.Outs {
+		switch out.Type {
+		case dataformat.OutTypeToLocal:
+			log.Infof("  Output %d: to_local, %d sats, sweepable "+
+				"%d blocks after confirmation", idx, out.Value,
+				fc.CSVDelay)
+
+		case dataformat.OutTypeHtlcOffered:
+			log.Infof("  Output %d: offered HTLC, %d sats, "+
+				"timeout at block %d", idx, out.Value,
+				out.CLTVExpiry)
+
+		case dataformat.OutTypeHtlcReceived:
+			log.Infof("  Output %d: received HTLC, %d sats, needs "+
+				"preimage of %s before block %d", idx,
+				out.Value, out.PaymentHash, out.CLTVExpiry)
+
+		default:
+			log.Infof("  Output %d: to_remote, %d sats, belongs "+
+				"to the remote peer", idx, out.Value)
+		}
+	}
+}
@@ -0,0 +1,361 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"runtime"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/btcsuite/btcd/btcec"
+	"github.com/btcsuite/btcutil/hdkeychain"
+	"github.com/guggero/chantools/lnd"
+)
+
+const (
+	defaultVanityPath     = "m/84'/0'/0'/0"
+	defaultVanityMaxIndex = hdkeychain.HardenedKeyStart - 1
+
+	// vanityProgressInterval is the interval in which the search progress
+	// is printed.
+	vanityProgressInterval = 5 * time.Second
+)
+
+// vanityMatch is a key of the searched branch with an address that matches one
+// of the patterns. It is written to the results file as a single JSON line.
+type vanityMatch struct {
+	Path      string `json:"path"`
+	Index     uint32 `json:"index"`
+	PubKeyHex string `json:"pubkey_hex"`
+	Address   string `json:"address"`
+	Pattern   string `json:"pattern"`
+}
+
+type vanityGenCommand struct {
+	RootKey        string `long:"rootkey" description:"BIP32 HD root key of the wallet to search. Leave empty to prompt for lnd 24 word aezeed."`
+	Path           string `long:"path" description:"The BIP32 derivation path of the branch to search the key indexes of. (default m/84'/0'/0'/0)"`
+	AddrType       string `long:"addrtype" description:"The type of the addresses to match (p2pkh, p2sh-p2wpkh, p2wpkh or p2tr). Leave empty to detect it from the purpose of the derivation path."`
+	Pattern        string `long:"pattern" description:"A regular expression the address must match, for example ^bc1qdead."`
+	PatternFile    string `long:"patternfile" description:"A file with one regular expression per line. An address that matches any of the patterns is a match."`
+	Workers        uint32 `long:"workers" description:"The number of keys to derive in parallel. (default number of CPUs)"`
+	MaxIndex       uint32 `long:"maxindex" description:"The highest key index to search. (default 2147483647)"`
+	StopAfterFirst bool   `long:"stopafterfirst" description:"Stop the search as soon as the first match is found."`
+	OutputFile     string `long:"outputfile" description:"The file to write the matches to, one JSON object per line. (default results/vanitygen-<timestamp>.json)"`
+}
+
+func (c *vanityGenCommand) Execute(_ []string) error {
+	setupChainParams(cfg)
+
+	var (
+		extendedKey *hdkeychain.ExtendedKey
+		err         error
+	)
+
+	// Check that root key is valid or fall back to console input.
+	switch {
+	case c.RootKey != "":
+		extendedKey, err = hdkeychain.NewKeyFromString(c.RootKey)
+
+	default:
+		extendedKey, _, err = rootKeyFromConsole()
+	}
+	if err != nil {
+		return fmt.Errorf("error reading root key: %v", err)
+	}
+
+	// Set default values.
+	if c.Path == "" {
+		c.Path = defaultVanityPath
+	}
+	if c.Workers == 0 {
+		c.Workers = uint32(runtime.NumCPU())
+	}
+	if c.MaxIndex == 0 || c.MaxIndex > defaultVanityMaxIndex {
+		c.MaxIndex = defaultVanityMaxIndex
+	}
+	if c.OutputFile == "" {
+		c.OutputFile = fmt.Sprintf("results/vanitygen-%s.json",
+			time.Now().Format("2006-01-02-15-04-05"))
+	}
+
+	patterns, err := c.compilePatterns()
+	if err != nil {
+		return err
+	}
+	path, err := lnd.ParsePath(c.Path)
+	if err != nil {
+		return fmt.Errorf("error parsing path: %v", err)
+	}
+	addrType := c.AddrType
+	if addrType == addrTypeAutoDetect {
+		addrType = addrTypeFromPurpose(path[0])
+	}
+	branchKey, err := lnd.DeriveChildren(extendedKey, path)
+	if err != nil {
+		return fmt.Errorf("error deriving branch key: %v", err)
+	}
+
+	// The public key of a private extended key is calculated and cached
+	// when it's first needed. We make sure that happens before the workers
+	// share the key.
+	if _, err := branchKey.ECPubKey(); err != nil {
+		return err
+	}
+
+	f, err := createOutputFile(c.OutputFile, false)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+
+	search := &vanitySearch{
+		branchKey: branchKey,
+		path:      lnd.FormatPath(path),
+		addrType:  addrType,
+		patterns:  patterns,
+		maxIndex:  c.MaxIndex,
+		workers:   c.Workers,
+		stopFirst: c.StopAfterFirst,
+		quit:      make(chan struct{}),
+	}
+	log.Infof("Searching %d key indexes of %s with %d workers for %d "+
+		"patterns, writing matches to %s.", uint64(c.MaxIndex)+1,
+		search.path, c.Workers, len(patterns), c.OutputFile)
+	numMatches, err := search.run(f)
+	if err != nil {
+		return err
+	}
+
+	log.Infof("Found %d matches after deriving %d keys in %v.",
+		numMatches, atomic.LoadUint64(&search.numKeys),
+		time.Since(search.start).Round(time.Second))
+	return nil
+}
+
+// compilePatterns compiles the pattern of the flag and all patterns of the
+// pattern file, empty lines are skipped.
+func (c *vanityGenCommand) compilePatterns() ([]*regexp.Regexp, error) {
+	var expressions []string
+	if c.Pattern != "" {
+		expressions = append(expressions, c.Pattern)
+	}
+	if c.PatternFile != "" {
+		content, err := readInput(c.PatternFile)
+		if err != nil {
+			return nil, fmt.Errorf("error reading pattern file: %v",
+				err)
+		}
+		scanner := bufio.NewScanner(bytes.NewReader(content))
+		for scanner.Scan() {
+			line := strings.TrimSpace(scanner.Text())
+			if line != "" {
+				expressions = append(expressions, line)
+			}
+		}
+		if err := scanner.Err(); err != nil {
+			return nil, fmt.Errorf("error reading pattern file: %v",
+				err)
+		}
+	}
+	if len(expressions) == 0 {
+		return nil, fmt.Errorf("pattern or pattern file is required")
+	}
+
+	patterns := make([]*regexp.Regexp, len(expressions))
+	for idx, expression := range expressions {
+		pattern, err := regexp.Compile(expression)
+		if err != nil {
+			return nil, fmt.Errorf("invalid pattern %s: %v",
+				expression, err)
+		}
+		patterns[idx] = pattern
+	}
+	return patterns, nil
+}
+
+// vanitySearch is a concurrent search for key indexes of a branch with an
+// address that matches a pattern.
+type vanitySearch struct {
+	// numKeys is the number of keys derived so far, it must be accessed
+	// atomically and is the first field to be 64-bit aligned.
+	numKeys uint64
+
+	branchKey *hdkeychain.ExtendedKey
+	path      string
+	addrType  string
+	patterns  []*regexp.Regexp
+	maxIndex  uint32
+	workers   uint32
+	stopFirst bool
+	start     time.Time
+
+	quit     chan struct{}
+	quitOnce sync.Once
+}
+
+// run starts the workers and writes all matches they find to the given file
+// until all indexes are searched or the search is stopped. Only this goroutine
+// writes to the file, one match per write, so the lines of the workers never
+// get mixed up.
+func (s *vanitySearch) run(f *os.File) (int, error) {
+	var (
+		matches = make(chan *vanityMatch)
+		errs    = make(chan error, s.workers)
+		wg      sync.WaitGroup
+	)
+	s.start = time.Now()
+	for i := uint32(0); i < s.workers; i++ {
+		wg.Add(1)
+		go func(offset uint32) {
+			defer wg.Done()
+
+			if err := s.searchFrom(offset, matches); err != nil {
+				errs <- err
+				s.stop()
+			}
+		}(i)
+	}
+	go func() {
+		wg.Wait()
+		close(matches)
+	}()
+
+	ticker := time.NewTicker(vanityProgressInterval)
+	defer ticker.Stop()
+
+	var (
+		numMatches int
+		stopped    bool
+	)
+	for {
+		select {
+		case match, ok := <-matches:
+			if !ok {
+				select {
+				case err := <-errs:
+					return numMatches, err
+
+				default:
+					return numMatches, nil
+				}
+			}
+
+			// Other workers might have found a match at the same
+			// time, but we only want the first one.
+			if stopped {
+				continue
+			}
+
+			line, err := json.Marshal(match)
+			if err != nil {
+				return numMatches, err
+			}
+			if _, err := f.Write(append(line, '\n')); err != nil {
+				s.stop()
+				return numMatches, fmt.Errorf("error writing "+
+					"match: %v", err)
+			}
+			numMatches++
+			log.Infof("Found match %s at index %d for pattern %s.",
+				match.Address, match.Index, match.Pattern)
+			if s.stopFirst {
+				s.stop()
+				stopped = true
+			}
+
+		case <-ticker.C:
+			numKeys := atomic.LoadUint64(&s.numKeys)
+			elapsed := time.Since(s.start)
+			_, _ = fmt.Fprintf(os.Stderr, "Derived %d keys in %v "+
+				"(%.0f keys/sec), %d matches so far.\n",
+				numKeys, elapsed.Round(time.Second),
+				float64(numKeys)/elapsed.Seconds(), numMatches)
+		}
+	}
+}
+
+// searchFrom derives every key of the branch starting at the given offset with
+// the number of workers as the step, until the last index is reached or the
+// search is stopped.
+func (s *vanitySearch) searchFrom(offset uint32,
+	matches chan<- *vanityMatch) error {
+
+	maxIndex, step := uint64(s.maxIndex), uint64(s.workers)
+	for index := uint64(offset); index <= maxIndex; index += step {
+		select {
+		case <-s.quit:
+			return nil
+
+		default:
+		}
+
+		key, err := s.branchKey.Child(uint32(index))
+		if err != nil {
+			return fmt.Errorf("error deriving key %d: %v", index,
+				err)
+		}
+		pubKey, err := key.ECPubKey()
+		if err != nil {
+			return fmt.Errorf("error deriving public key %d: %v",
+				index, err)
+		}
+		address, err := vanityAddress(pubKey, s.addrType)
+		if err != nil {
+			return err
+		}
+		atomic.AddUint64(&s.numKeys, 1)
+
+		for _, pattern := range s.patterns {
+			if !pattern.MatchString(address) {
+				continue
+			}
+			match := &vanityMatch{
+				Path:  fmt.Sprintf("%s/%d", s.path, index),
+				Index: uint32(index),
+				PubKeyHex: hex.EncodeToString(
+					pubKey.SerializeCompressed(),
+				),
+				Address: address,
+				Pattern: pattern.String(),
+			}
+			select {
+			case matches <- match:
+			case <-s.quit:
+				return nil
+			}
+			break
+		}
+	}
+	return nil
+}
+
+// stop signals all workers to stop the search. It can be called more than
+// once.
+func (s *vanitySearch) stop() {
+	s.quitOnce.Do(func() {
+		close(s.quit)
+	})
+}
+
+// vanityAddress returns the address of the given type for a public key.
+func vanityAddress(pubKey *btcec.PublicKey, addrType string) (string, error) {
+	if addrType == addrTypeP2TR {
+		return taprootAddress(pubKey)
+	}
+	addr, err := pubKeyAddress(
+		pubKey.SerializeCompressed(), addrType, chainParams,
+	)
+	if err != nil {
+		return "", err
+	}
+	return addr.EncodeAddress(), nil
+}
@@ -0,0 +1,282 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"io/ioutil"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcutil/hdkeychain"
+	"github.com/guggero/chantools/lnd"
+	"github.com/lightningnetwork/lnd/chanbackup"
+	"github.com/lightningnetwork/lnd/keychain"
+	"golang.org/x/crypto/chacha20poly1305"
+)
+
+// backupTagSize is the size of the Poly1305 authentication tag at the end of
+// an encrypted channel backup.
+const backupTagSize = 16
+
+// localKeyFamilies are the key families lnd derives the keys of the local
+// channel config from.
+var localKeyFamilies = []struct {
+	name   string
+	family keychain.KeyFamily
+	desc   func(*chanbackup.Single) keychain.KeyDescriptor
+}{{
+	name:   "multisig key",
+	family: keychain.KeyFamilyMultiSig,
+	desc: func(s *chanbackup.Single) keychain.KeyDescriptor {
+		return s.LocalChanCfg.MultiSigKey
+	},
+}, {
+	name:   "revocation base point",
+	family: keychain.KeyFamilyRevocationBase,
+	desc: func(s *chanbackup.Single) keychain.KeyDescriptor {
+		return s.LocalChanCfg.RevocationBasePoint
+	},
+}, {
+	name:   "htlc base point",
+	family: keychain.KeyFamilyHtlcBase,
+	desc: func(s *chanbackup.Single) keychain.KeyDescriptor {
+		return s.LocalChanCfg.HtlcBasePoint
+	},
+}, {
+	name:   "payment base point",
+	family: keychain.KeyFamilyPaymentBase,
+	desc: func(s *chanbackup.Single) keychain.KeyDescriptor {
+		return s.LocalChanCfg.PaymentBasePoint
+	},
+}, {
+	name:   "delay base point",
+	family: keychain.KeyFamilyDelayBase,
+	desc: func(s *chanbackup.Single) keychain.KeyDescriptor {
+		return s.LocalChanCfg.DelayBasePoint
+	},
+}}
+
+type verifyBackupCommand struct {
+	RootKey    string `long:"rootkey" description:"BIP32 HD root key of the wallet that was used to create the backup. Leave empty to prompt for lnd 24 word aezeed."`
+	MultiFile  string `long:"multi_file" description:"The lnd channel.backup file to verify."`
+	NodePubKey string `long:"nodepubkey" description:"The identity public key of the node that created the backup (hex). If the root key is set as well, it is checked to belong to this node. Without the root key only the encryption envelope can be verified as the channels can't be decrypted."`
+}
+
+func (c *verifyBackupCommand) Execute(_ []string) error {
+	setupChainParams(cfg)
+
+	// Check that we have a backup file.
+	if c.MultiFile == "" {
+		return fmt.Errorf("backup file is required")
+	}
+	packed, err := ioutil.ReadFile(c.MultiFile)
+	if err != nil {
+		return fmt.Errorf("error reading backup file: %v", err)
+	}
+
+	// The encryption envelope consists of the nonce followed by the
+	// ciphertext which at least contains the authentication tag.
+	minSize := chacha20poly1305.NonceSizeX + backupTagSize
+	if len(packed) < minSize {
+		return fmt.Errorf("invalid encryption envelope: file has %d "+
+			"bytes but must be at least %d bytes", len(packed),
+			minSize)
+	}
+	fmt.Printf("Encryption envelope: OK (%d bytes)\n", len(packed))
+
+	// Without the root key, there is nothing more we can check.
+	if c.RootKey == "" && c.NodePubKey != "" {
+		fmt.Println("Channels not verified, the root key is required " +
+			"to decrypt them.")
+		return nil
+	}
+
+	var extendedKey *hdkeychain.ExtendedKey
+
+	// Check that root key is valid or fall back to console input.
+	switch {
+	case c.RootKey != "":
+		extendedKey, err = hdkeychain.NewKeyFromString(c.RootKey)
+
+	default:
+		extendedKey, _, err = rootKeyFromConsole()
+	}
+	if err != nil {
+		return fmt.Errorf("error reading root key: %v", err)
+	}
+	keyRing := &lnd.HDKeyRing{
+		ExtendedKey: extendedKey,
+		ChainParams: chainParams,
+	}
+
+	// A backup that can't be decrypted with the key of another node is
+	// not corrupted, so we tell those two cases apart.
+	if c.NodePubKey != "" {
+		nodePubKey, err := pubKeyFromHex(c.NodePubKey)
+		if err != nil {
+			return fmt.Errorf("error parsing node pubkey: %v", err)
+		}
+		identity, err := keyRing.DeriveKey(keychain.KeyLocator{
+			Family: keychain.KeyFamilyNodeKey,
+		})
+		if err != nil {
+			return fmt.Errorf("error deriving node key: %v", err)
+		}
+		if !identity.PubKey.IsEqual(nodePubKey) {
+			return fmt.Errorf("root key belongs to node %x and "+
+				"not to %s",
+				identity.PubKey.SerializeCompressed(),
+				c.NodePubKey)
+		}
+	}
+
+	plaintext, err := decryptBackup(packed, keyRing)
+	if err != nil {
+		return err
+	}
+	numInvalid, numChannels, err := verifyChannelBackups(
+		plaintext, keyRing,
+	)
+	if err != nil {
+		return err
+	}
+	if numInvalid > 0 {
+		return fmt.Errorf("%d of %d channels are invalid", numInvalid,
+			numChannels)
+	}
+	fmt.Printf("All %d channels are valid.\n", numChannels)
+	return nil
+}
+
+// decryptBackup decrypts a packed channel backup the same way lnd does, with
+// the hash of the static backup public key as the key and the nonce as
+// associated data.
+func decryptBackup(packed []byte, ring keychain.KeyRing) ([]byte, error) {
+	baseKey, err := ring.DeriveKey(keychain.KeyLocator{
+		Family: keychain.KeyFamilyStaticBackup,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error deriving backup key: %v", err)
+	}
+	encryptionKey := sha256.Sum256(baseKey.PubKey.SerializeCompressed())
+	aead, err := chacha20poly1305.NewX(encryptionKey[:])
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := packed[:chacha20poly1305.NonceSizeX]
+	plaintext, err := aead.Open(
+		nil, nonce, packed[chacha20poly1305.NonceSizeX:], nonce,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("could not decrypt backup, either the "+
+			"root key is wrong or the file is corrupted: %v", err)
+	}
+	return plaintext, nil
+}
+
+// verifyChannelBackups parses the single channel backups of a decrypted multi
+// backup one by one and prints the result of each. Every single backup is
+// prefixed with its length, so a corrupted entry doesn't prevent the others
+// from being checked. An error is only returned if the structure of the multi
+// backup itself is broken.
+func verifyChannelBackups(plaintext []byte, ring *lnd.HDKeyRing) (int, int,
+	error) {
+
+	r := bytes.NewReader(plaintext)
+	var header struct {
+		Version    uint8
+		NumBackups uint32
+	}
+	if err := binary.Read(r, binary.BigEndian, &header); err != nil {
+		return 0, 0, fmt.Errorf("error reading multi backup header: "+
+			"%v", err)
+	}
+	if header.Version != uint8(chanbackup.DefaultMultiVersion) {
+		return 0, 0, fmt.Errorf("unknown multi backup version %d",
+			header.Version)
+	}
+
+	numInvalid := 0
+	for i := uint32(0); i < header.NumBackups; i++ {
+		start := len(plaintext) - r.Len()
+		var prefix struct {
+			Version uint8
+			Length  uint16
+		}
+		err := binary.Read(r, binary.BigEndian, &prefix)
+		if err == nil && int(prefix.Length) > r.Len() {
+			err = io.ErrUnexpectedEOF
+		}
+		if err != nil {
+			return 0, 0, fmt.Errorf("error reading channel %d: "+
+				"backup is truncated: %v", i, err)
+		}
+		end := len(plaintext) - r.Len() + int(prefix.Length)
+		_, _ = r.Seek(int64(end), io.SeekStart)
+
+		var single chanbackup.Single
+		err = single.Deserialize(bytes.NewReader(plaintext[start:end]))
+		if err == nil {
+			err = verifySingle(&single, ring)
+		}
+		if err != nil {
+			numInvalid++
+			fmt.Printf("Channel %d (%v): INVALID: %v\n", i,
+				single.FundingOutpoint, err)
+			continue
+		}
+		fmt.Printf("Channel %d (%v): OK\n", i, single.FundingOutpoint)
+	}
+	if r.Len() > 0 {
+		return 0, 0, fmt.Errorf("%d unexpected bytes after the last "+
+			"channel", r.Len())
+	}
+	return numInvalid, int(header.NumBackups), nil
+}
+
+// verifySingle checks that a single channel backup belongs to the network and
+// that all the local keys of the channel can be derived from the root key.
+// The remote keys are already checked to be valid points when parsing.
+func verifySingle(single *chanbackup.Single, ring *lnd.HDKeyRing) error {
+	if !single.ChainHash.IsEqual(chainParams.GenesisHash) {
+		return fmt.Errorf("chain hash %v doesn't belong to %s",
+			single.ChainHash, chainParams.Name)
+	}
+	if single.FundingOutpoint.Hash == (chainhash.Hash{}) {
+		return fmt.Errorf("funding txid is empty")
+	}
+
+	for _, local := range localKeyFamilies {
+		desc := local.desc(single)
+		if desc.Family != local.family {
+			return fmt.Errorf("local %s has key family %d instead "+
+				"of %d", local.name, desc.Family, local.family)
+		}
+		if _, err := ring.DeriveKey(desc.KeyLocator); err != nil {
+			return fmt.Errorf("error deriving local %s: %v",
+				local.name, err)
+		}
+	}
+
+	// lnd derives the shachain root from the descriptor when restoring the
+	// channel, so it needs to be derivable as well.
+	if single.ShaChainRootDesc.PubKey == nil {
+		return nil
+	}
+	err := ring.CheckDescriptor(single.ShaChainRootDesc)
+	switch err {
+	case nil:
+		return nil
+
+	case keychain.ErrCannotDerivePrivKey:
+		return fmt.Errorf("shachain root can't be derived, the " +
+			"backup might be affected by lnd issue #3881, see " +
+			"the fixoldbackup command")
+
+	default:
+		return fmt.Errorf("error checking shachain root: %v", err)
+	}
+}
@@ -0,0 +1,269 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"path"
+
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/btcsuite/btcutil"
+	"github.com/lightningnetwork/lnd/channeldb"
+	"github.com/lightningnetwork/lnd/input"
+)
+
+const (
+	defaultMaxCloseFeeRate = 100
+)
+
+type verifyClosingTxCommand struct {
+	ChannelDB  string `long:"channeldb" description:"The lnd channel.db file to look up the channel of the closing transaction in."`
+	Psbt       string `long:"psbt" description:"The closing transaction to verify as a base64 encoded PSBT."`
+	Tx         string `long:"tx" description:"The closing transaction to verify as a hex encoded raw transaction."`
+	LocalAddr  string `long:"localaddr" description:"The address the local balance is expected to be paid to. Leave empty to use the upfront shutdown address of the channel if there is one."`
+	MaxFeeRate uint32 `long:"maxfeerate" description:"The maximum fee rate in sat/vByte that is considered reasonable. (default 100)"`
+}
+
+func (c *verifyClosingTxCommand) Execute(_ []string) error {
+	setupChainParams(cfg)
+
+	// Set default values.
+	if c.MaxFeeRate == 0 {
+		c.MaxFeeRate = defaultMaxCloseFeeRate
+	}
+
+	packet, err := parseClosingTx(c.Psbt, c.Tx)
+	if err != nil {
+		return err
+	}
+	var localScript []byte
+	if c.LocalAddr != "" {
+		addr, err := btcutil.DecodeAddress(c.LocalAddr, chainParams)
+		if err != nil {
+			return fmt.Errorf("error parsing local address: %v",
+				err)
+		}
+		localScript, err = txscript.PayToAddrScript(addr)
+		if err != nil {
+			return fmt.Errorf("error creating local script: %v",
+				err)
+		}
+	}
+
+	// Check that we have a channel DB.
+	if c.ChannelDB == "" {
+		return fmt.Errorf("channel DB is required")
+	}
+	db, err := channeldb.Open(
+		path.Dir(c.ChannelDB), channeldb.OptionSetSyncFreelist(true),
+		channeldb.OptionReadOnly(true),
+	)
+	if err != nil {
+		return fmt.Errorf("error opening channel DB: %v", err)
+	}
+	defer db.Close()
+	channels, err := db.FetchAllChannels()
+	if err != nil {
+		return fmt.Errorf("error fetching channels: %v", err)
+	}
+
+	return printCheckResults(verifyClosingTx(
+		packet.UnsignedTx, channels, localScript, c.MaxFeeRate,
+	))
+}
+
+// verifyClosingTx checks that a cooperative close transaction spends the
+// funding output of one of the channels and pays out the balances the same way
+// lnd would.
+func verifyClosingTx(tx *wire.MsgTx, channels []*channeldb.OpenChannel,
+	localScript []byte, maxFeeRate uint32) []*checkResult {
+
+	inputResult := &checkResult{name: "Funding input"}
+	_, channel, err := findFundingInput(tx, channels)
+	switch {
+	case err != nil:
+		inputResult.status = statusFail
+		inputResult.message = err.Error()
+		inputResult.fix = "make sure the transaction belongs to a " +
+			"channel of this node"
+		return []*checkResult{inputResult}
+
+	case len(tx.TxIn) != 1:
+		inputResult.status = statusFail
+		inputResult.message = fmt.Sprintf("transaction has %d inputs, "+
+			"a cooperative close only spends the funding output",
+			len(tx.TxIn))
+
+	default:
+		inputResult.status = statusPass
+		inputResult.message = fmt.Sprintf("transaction spends the "+
+			"funding output of channel %s",
+			channel.FundingOutpoint)
+	}
+
+	// The fee of the closing transaction is paid by the initiator of the
+	// channel who gets back the fee of the commitment transaction instead.
+	var totalOut btcutil.Amount
+	for _, txOut := range tx.TxOut {
+		totalOut += btcutil.Amount(txOut.Value)
+	}
+	fee := channel.Capacity - totalOut
+	localCommit := channel.LocalCommitment
+	localBalance := localCommit.LocalBalance.ToSatoshis()
+	remoteBalance := localCommit.RemoteBalance.ToSatoshis()
+	if channel.IsInitiator {
+		localBalance = localBalance - fee + localCommit.CommitFee
+	} else {
+		remoteBalance = remoteBalance - fee + localCommit.CommitFee
+	}
+	if len(localScript) == 0 {
+		localScript = channel.LocalShutdownScript
+	}
+
+	// Find our output by its script or, if we don't know the script, by
+	// the amount we expect.
+	addrResult := &checkResult{name: "Local address"}
+	localIndex := -1
+	for idx, txOut := range tx.TxOut {
+		switch {
+		case len(localScript) > 0:
+			if bytes.Equal(txOut.PkScript, localScript) {
+				localIndex = idx
+			}
+
+		case btcutil.Amount(txOut.Value) == localBalance:
+			localIndex = idx
+		}
+	}
+	switch {
+	case len(localScript) == 0:
+		addrResult.status = statusWarn
+		addrResult.message = "no local address known, the local " +
+			"output was identified by its amount"
+		addrResult.fix = "specify the expected address with --localaddr"
+
+	case localIndex >= 0:
+		addrResult.status = statusPass
+		addrResult.message = fmt.Sprintf("output %d pays to the "+
+			"expected local address", localIndex)
+
+	case localBalance < channel.LocalChanCfg.DustLimit:
+		addrResult.status = statusPass
+		addrResult.message = "local balance is below the dust limit, " +
+			"no local output expected"
+
+	default:
+		addrResult.status = statusFail
+		addrResult.message = "no output pays to the expected local " +
+			"address"
+		addrResult.fix = "do not sign the transaction, ask the " +
+			"remote party to use the correct address"
+	}
+
+	localResult := checkOutputAmount(
+		"Local amount", tx, []int{localIndex}, localBalance,
+		channel.LocalChanCfg.DustLimit,
+	)
+	var remoteIndexes []int
+	for idx := range tx.TxOut {
+		if idx != localIndex {
+			remoteIndexes = append(remoteIndexes, idx)
+		}
+	}
+	remoteResult := checkOutputAmount(
+		"Remote amount", tx, remoteIndexes, remoteBalance,
+		channel.RemoteChanCfg.DustLimit,
+	)
+
+	return []*checkResult{
+		inputResult, addrResult, localResult, remoteResult,
+		checkCloseFee(tx, fee, maxFeeRate),
+	}
+}
+
+// checkOutputAmount checks that the balance of one party is paid out with a
+// single output or that there is no output if the balance is below the dust
+// limit. An index of -1 means the output wasn't found.
+func checkOutputAmount(name string, tx *wire.MsgTx, indexes []int,
+	expected, dustLimit btcutil.Amount) *checkResult {
+
+	result := &checkResult{name: name}
+	if len(indexes) == 1 && indexes[0] < 0 {
+		indexes = nil
+	}
+	switch {
+	case len(indexes) > 1:
+		result.status = statusFail
+		result.message = fmt.Sprintf("expected at most one output, "+
+			"found %d", len(indexes))
+		result.fix = "do not sign the transaction"
+
+	case len(indexes) == 0 && expected < dustLimit:
+		result.status = statusPass
+		result.message = fmt.Sprintf("balance of %d sats is below the "+
+			"dust limit, no output expected", expected)
+
+	case len(indexes) == 0:
+		result.status = statusFail
+		result.message = fmt.Sprintf("no output found, expected %d "+
+			"sats", expected)
+		result.fix = "do not sign the transaction"
+
+	case btcutil.Amount(tx.TxOut[indexes[0]].Value) != expected:
+		result.status = statusFail
+		result.message = fmt.Sprintf("output %d pays %d sats, "+
+			"expected %d sats", indexes[0],
+			tx.TxOut[indexes[0]].Value, expected)
+		result.fix = "do not sign the transaction, the balance of " +
+			"the channel is not paid out correctly"
+
+	default:
+		result.status = statusPass
+		result.message = fmt.Sprintf("output %d pays the expected %d "+
+			"sats", indexes[0], expected)
+	}
+	return result
+}
+
+// checkCloseFee checks that the fee rate of the closing transaction is within
+// a reasonable range.
+func checkCloseFee(tx *wire.MsgTx, fee btcutil.Amount,
+	maxFeeRate uint32) *checkResult {
+
+	// The transaction isn't signed yet, so we add the size of the 2-of-2
+	// multisig witness of the funding input.
+	weight := int64(tx.SerializeSizeStripped())*4 +
+		input.WitnessHeaderSize + input.WitnessSize
+	vSize := (weight + 3) / 4
+	feeRate := float64(fee) / float64(vSize)
+
+	result := &checkResult{name: "Fee"}
+	switch {
+	case fee < 0:
+		result.status = statusFail
+		result.message = fmt.Sprintf("outputs exceed the channel "+
+			"capacity by %d sats", -fee)
+		result.fix = "do not sign the transaction"
+
+	case feeRate > float64(maxFeeRate):
+		result.status = statusFail
+		result.message = fmt.Sprintf("fee of %d sats (%.1f sat/vByte) "+
+			"is above the maximum of %d sat/vByte", fee, feeRate,
+			maxFeeRate)
+		result.fix = "ask the remote party for a lower fee or raise " +
+			"--maxfeerate"
+
+	case feeRate < 1:
+		result.status = statusWarn
+		result.message = fmt.Sprintf("fee of %d sats (%.1f sat/vByte) "+
+			"is below the minimum relay fee", fee, feeRate)
+		result.fix = "the transaction might not be relayed, ask the " +
+			"remote party for a higher fee"
+
+	default:
+		result.status = statusPass
+		result.message = fmt.Sprintf("fee of %d sats (%.1f sat/vByte)",
+			fee, feeRate)
+	}
+	return result
+}
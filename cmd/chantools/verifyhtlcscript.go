@@ -0,0 +1,139 @@
+package main
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	"github.com/btcsuite/btcd/wire"
+	"github.com/lightningnetwork/lnd/input"
+)
+
+type verifyHtlcScriptCommand struct {
+	Tx               string `long:"tx" description:"The transaction that contains the HTLC output, hex encoded."`
+	OutputIndex      uint32 `long:"outputindex" description:"The index of the HTLC output in the transaction given with --tx."`
+	OutPoint         string `long:"outpoint" description:"The HTLC output (txid:index) to look up in bitcoind instead of specifying --tx. Needs the transaction index of bitcoind."`
+	LocalHtlcPubKey  string `long:"localhtlcpubkey" description:"The local HTLC public key of the commitment (hex)."`
+	RemoteHtlcPubKey string `long:"remotehtlcpubkey" description:"The remote HTLC public key of the commitment (hex)."`
+	RevocationPubKey string `long:"revocationpubkey" description:"The revocation public key of the commitment (hex)."`
+	PaymentHash      string `long:"paymenthash" description:"The payment hash of the HTLC (hex)."`
+	CLTVExpiry       uint32 `long:"cltvexpiry" description:"The CLTV expiry of the HTLC. Only needed to check received HTLCs, leave empty if the output is an offered HTLC."`
+}
+
+func (c *verifyHtlcScriptCommand) Execute(_ []string) error {
+	setupChainParams(cfg)
+
+	keys, err := parseHtlcKeys(
+		c.LocalHtlcPubKey, c.RemoteHtlcPubKey, c.RevocationPubKey,
+	)
+	if err != nil {
+		return err
+	}
+	paymentHash, err := parsePaymentHash(c.PaymentHash)
+	if err != nil {
+		return err
+	}
+	txOut, err := c.htlcOutput()
+	if err != nil {
+		return err
+	}
+
+	// Compute the scripts of all HTLC types we have enough information for.
+	type candidate struct {
+		name     string
+		script   []byte
+		pkScript []byte
+	}
+	offered, err := input.SenderHTLCScript(
+		keys.local, keys.remote, keys.revocation, paymentHash,
+	)
+	if err != nil {
+		return fmt.Errorf("error creating offered HTLC script: %v", err)
+	}
+	candidates := []*candidate{{name: "offered", script: offered}}
+	if c.CLTVExpiry != 0 {
+		received, err := input.ReceiverHTLCScript(
+			c.CLTVExpiry, keys.remote, keys.local, keys.revocation,
+			paymentHash,
+		)
+		if err != nil {
+			return fmt.Errorf("error creating received HTLC "+
+				"script: %v", err)
+		}
+		candidates = append(candidates, &candidate{
+			name:   "received",
+			script: received,
+		})
+	}
+
+	for _, cand := range candidates {
+		cand.pkScript, err = input.WitnessScriptHash(cand.script)
+		if err != nil {
+			return fmt.Errorf("error hashing %s HTLC script: %v",
+				cand.name, err)
+		}
+		if bytes.Equal(cand.pkScript, txOut.PkScript) {
+			fmt.Printf("MATCH: %s HTLC\n", cand.name)
+			fmt.Printf("Value: %d sats\n", txOut.Value)
+			fmt.Printf("Witness script: %x\n", cand.script)
+			return nil
+		}
+	}
+
+	fmt.Printf("NO MATCH\n")
+	fmt.Printf("%-18s %x\n", "Actual pk script:", txOut.PkScript)
+	for _, cand := range candidates {
+		fmt.Printf("%-18s %x\n", "Expected "+cand.name+":",
+			cand.pkScript)
+	}
+	if c.CLTVExpiry == 0 {
+		fmt.Printf("Specify --cltvexpiry to also check for a " +
+			"received HTLC.\n")
+	}
+	return fmt.Errorf("output doesn't match any HTLC script")
+}
+
+// htlcOutput returns the output to verify, either from the given transaction
+// or looked up in bitcoind.
+func (c *verifyHtlcScriptCommand) htlcOutput() (*wire.TxOut, error) {
+	rawTx := c.Tx
+	index := c.OutputIndex
+	switch {
+	case c.Tx != "" && c.OutPoint != "":
+		return nil, fmt.Errorf("only one of tx or outpoint can be " +
+			"specified")
+
+	case c.OutPoint != "":
+		outPoint, err := parseOutPoint(c.OutPoint)
+		if err != nil {
+			return nil, fmt.Errorf("error parsing outpoint: %v",
+				err)
+		}
+		bitcoind := newBitcoind(cfg)
+		tx, err := bitcoind.GetRawTransaction(
+			outPoint.Hash.String(), "",
+		)
+		if err != nil {
+			return nil, fmt.Errorf("error looking up tx: %v", err)
+		}
+		rawTx = tx.Hex
+		index = outPoint.Index
+
+	case c.Tx == "":
+		return nil, fmt.Errorf("tx or outpoint is required")
+	}
+
+	txBytes, err := hex.DecodeString(strings.TrimSpace(rawTx))
+	if err != nil {
+		return nil, fmt.Errorf("error decoding tx hex: %v", err)
+	}
+	tx := &wire.MsgTx{}
+	if err := tx.Deserialize(bytes.NewReader(txBytes)); err != nil {
+		return nil, fmt.Errorf("error parsing tx: %v", err)
+	}
+	if int(index) >= len(tx.TxOut) {
+		return nil, fmt.Errorf("tx has no output %d", index)
+	}
+	return tx.TxOut[index], nil
+}
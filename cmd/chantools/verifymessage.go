@@ -0,0 +1,95 @@
+package main
+
+import (
+	"encoding/base64"
+	"fmt"
+
+	"github.com/btcsuite/btcd/btcec"
+	"github.com/btcsuite/btcutil"
+)
+
+type verifyMessageCommand struct {
+	Address   string `long:"address" description:"The P2PKH, P2SH-P2WPKH or P2WPKH address the message was signed with."`
+	Message   string `long:"message" description:"The message that was signed."`
+	Signature string `long:"signature" description:"The signature of the message, base64 encoded."`
+}
+
+func (c *verifyMessageCommand) Execute(_ []string) error {
+	setupChainParams(cfg)
+
+	// Make sure all parameters are set.
+	if c.Address == "" || c.Message == "" || c.Signature == "" {
+		return fmt.Errorf("address, message and signature are required")
+	}
+	addr, err := btcutil.DecodeAddress(c.Address, chainParams)
+	if err != nil {
+		return fmt.Errorf("error parsing address: %v", err)
+	}
+	sig, err := base64.StdEncoding.DecodeString(c.Signature)
+	if err != nil {
+		return fmt.Errorf("error decoding signature: %v", err)
+	}
+	if len(sig) != 65 {
+		return fmt.Errorf("invalid signature length %d, must be 65 "+
+			"bytes", len(sig))
+	}
+
+	var addrType string
+	switch addr.(type) {
+	case *btcutil.AddressPubKeyHash:
+		addrType = addrTypeP2PKH
+
+	case *btcutil.AddressScriptHash:
+		addrType = addrTypeNP2WKH
+
+	case *btcutil.AddressWitnessPubKeyHash:
+		addrType = addrTypeP2WKH
+
+	default:
+		return fmt.Errorf("messages can't be verified for address %s",
+			c.Address)
+	}
+
+	// Not all wallets use the BIP137 header of the address type, so we
+	// accept any header and only check the recovered key against the
+	// address. RecoverCompact only knows the P2PKH headers.
+	header := sig[0]
+	switch {
+	case header >= sigHeaderP2WKH && header < sigHeaderP2WKH+4:
+		sig[0] = header - sigHeaderP2WKH + sigHeaderP2PKH
+
+	case header >= sigHeaderNP2WKH && header < sigHeaderNP2WKH+4:
+		sig[0] = header - sigHeaderNP2WKH + sigHeaderP2PKH
+
+	case header < sigHeaderUncompressed || header >= sigHeaderNP2WKH:
+		return fmt.Errorf("invalid signature header %d", header)
+	}
+	pubKey, compressed, err := btcec.RecoverCompact(
+		btcec.S256(), sig, bitcoinMessageHash(c.Message),
+	)
+	if err != nil {
+		return fmt.Errorf("error recovering public key: %v", err)
+	}
+
+	pubKeyBytes := pubKey.SerializeCompressed()
+	if !compressed {
+		if addrType != addrTypeP2PKH {
+			return fmt.Errorf("signature is invalid, " +
+				"uncompressed keys can only be used with " +
+				"P2PKH addresses")
+		}
+		pubKeyBytes = pubKey.SerializeUncompressed()
+	}
+	signerAddr, err := pubKeyAddress(pubKeyBytes, addrType, chainParams)
+	if err != nil {
+		return err
+	}
+	if signerAddr.EncodeAddress() != addr.EncodeAddress() {
+		return fmt.Errorf("signature is invalid, the message was "+
+			"signed by %s", signerAddr.EncodeAddress())
+	}
+
+	fmt.Printf("Signature is valid, the message was signed by %s.\n",
+		c.Address)
+	return nil
+}
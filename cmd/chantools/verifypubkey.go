@@ -0,0 +1,47 @@
+package main
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	"github.com/btcsuite/btcd/btcec"
+	"github.com/btcsuite/btcutil"
+)
+
+type verifyPubKeyCommand struct {
+	PubKey string `long:"pubkey" description:"The public key to verify, hex encoded in the compressed (33 bytes) or uncompressed (65 bytes) format."`
+}
+
+func (c *verifyPubKeyCommand) Execute(_ []string) error {
+	setupChainParams(cfg)
+
+	if c.PubKey == "" {
+		return fmt.Errorf("pubkey is required")
+	}
+	pubKeyBytes, err := hex.DecodeString(strings.TrimSpace(c.PubKey))
+	if err != nil {
+		return fmt.Errorf("error decoding pubkey hex: %v", err)
+	}
+
+	// ParsePubKey makes sure the point is on the curve for both formats.
+	pubKey, err := btcec.ParsePubKey(pubKeyBytes, btcec.S256())
+	if err != nil {
+		return fmt.Errorf("invalid public key: %v", err)
+	}
+	compressed := len(pubKeyBytes) == btcec.PubKeyBytesLenCompressed
+
+	fmt.Printf("On curve: true\n")
+	fmt.Printf("Compressed: %v\n", compressed)
+	fmt.Printf("X: %064x\n", pubKey.X)
+	fmt.Printf("Y: %064x\n", pubKey.Y)
+	fmt.Printf("Key hash (HASH160): %x\n", btcutil.Hash160(pubKeyBytes))
+
+	// Scripts almost always use the compressed key, so its hash is the one
+	// that is needed most of the time.
+	if !compressed {
+		fmt.Printf("Key hash of compressed key (HASH160): %x\n",
+			btcutil.Hash160(pubKey.SerializeCompressed()))
+	}
+	return nil
+}
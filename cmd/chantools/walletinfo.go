@@ -3,16 +3,20 @@ package main
 import (
 	"encoding/hex"
 	"fmt"
+	"io"
 	"os"
 	"os/user"
 	"path/filepath"
 	"strings"
 
 	"github.com/btcsuite/btcd/btcec"
+	"github.com/btcsuite/btcutil"
+	"github.com/btcsuite/btcutil/hdkeychain"
 	"github.com/btcsuite/btcwallet/snacl"
 	"github.com/btcsuite/btcwallet/waddrmgr"
 	"github.com/btcsuite/btcwallet/wallet"
 	"github.com/btcsuite/btcwallet/walletdb"
+	"github.com/guggero/chantools/lnd"
 	"github.com/lightningnetwork/lnd/keychain"
 	"github.com/lightningnetwork/lnd/lnwallet"
 
@@ -39,9 +43,18 @@ var (
 	}
 )
 
+// accountSlip132Prefixes are the SLIP-0132 prefixes of the account xpubs of
+// the BIP43 purposes on mainnet and the test networks. SLIP-0132 doesn't
+// register a version for Taproot accounts, their xpub is shown as it is.
+var accountSlip132Prefixes = map[uint32][2]string{
+	49: {"ypub", "upub"},
+	84: {"zpub", "vpub"},
+}
+
 type walletInfoCommand struct {
-	WalletDB    string `long:"walletdb" description:"The lnd wallet.db file to dump the contents from."`
-	WithRootKey bool   `long:"withrootkey" description:"Should the BIP32 HD root key of the wallet be printed to standard out?"`
+	WalletDB     string `long:"walletdb" description:"The lnd wallet.db file to dump the contents from."`
+	WithRootKey  bool   `long:"withrootkey" description:"Should the BIP32 HD root key of the wallet be printed to standard out?"`
+	ShowAccounts bool   `long:"showaccounts" description:"Print the extended public keys of the root key and the first BIP44, BIP49, BIP84 and BIP86 accounts, for example to set up a watch-only wallet. No private keys are printed."`
 }
 
 func (c *walletInfoCommand) Execute(_ []string) error {
@@ -92,12 +105,24 @@ func (c *walletInfoCommand) Execute(_ []string) error {
 	if err != nil {
 		return err
 	}
+	if !c.WithRootKey && !c.ShowAccounts {
+		return nil
+	}
+	masterHDPrivKey, err := decryptRootKey(db, privateWalletPw)
+	if err != nil {
+		return err
+	}
 	if c.WithRootKey {
-		masterHDPrivKey, err := decryptRootKey(db, privateWalletPw)
+		fmt.Printf("BIP32 HD extended root key: %s\n", masterHDPrivKey)
+	}
+	if c.ShowAccounts {
+		rootKey, err := hdkeychain.NewKeyFromString(
+			string(masterHDPrivKey),
+		)
 		if err != nil {
-			return err
+			return fmt.Errorf("error parsing root key: %v", err)
 		}
-		fmt.Printf("BIP32 HD extended root key: %s\n", masterHDPrivKey)
+		return printAccountXPubs(os.Stdout, rootKey, false)
 	}
 	return nil
 }
@@ -154,6 +179,86 @@ func printScopeInfo(name string, w *wallet.Wallet, scopes []waddrmgr.KeyScope) {
 	}
 }
 
+// printAccountXPubs prints the extended public key of the root key and of the
+// first account of each of the default BIP43 purposes, together with the
+// SLIP-0132 encoding of the account xpub if there is one for the purpose. With
+// withDescriptors set, the receive and change descriptors of each account are
+// printed as well.
+func printAccountXPubs(w io.Writer, rootKey *hdkeychain.ExtendedKey,
+	withDescriptors bool) error {
+
+	rootXPub, err := rootKey.Neuter()
+	if err != nil {
+		return fmt.Errorf("error neutering root key: %v", err)
+	}
+	rootPubKey, err := rootKey.ECPubKey()
+	if err != nil {
+		return fmt.Errorf("error deriving root pubkey: %v", err)
+	}
+	fingerprint := btcutil.Hash160(rootPubKey.SerializeCompressed())[:4]
+	_, _ = fmt.Fprintf(w, "BIP32 HD extended root public key: %s\n",
+		rootXPub)
+	_, _ = fmt.Fprintf(w, "Master key fingerprint: %x\n", fingerprint)
+
+	prefixIndex := 0
+	if chainParams.Name != "mainnet" {
+		prefixIndex = 1
+	}
+	for _, purpose := range defaultExportPurposes {
+		path := []uint32{
+			hdkeychain.HardenedKeyStart + purpose,
+			hdkeychain.HardenedKeyStart + chainParams.HDCoinType,
+			hdkeychain.HardenedKeyStart + 0,
+		}
+		accountKey, err := lnd.DeriveChildren(rootKey, path)
+		if err != nil {
+			return fmt.Errorf("error deriving account %s: %v",
+				lnd.FormatPath(path), err)
+		}
+		accountXPub, err := accountKey.Neuter()
+		if err != nil {
+			return fmt.Errorf("error neutering account key: %v",
+				err)
+		}
+		_, _ = fmt.Fprintf(w, "Account %s (BIP%d):\n",
+			lnd.FormatPath(path), purpose)
+		_, _ = fmt.Fprintf(w, "  %s\n", accountXPub)
+
+		if prefixes, ok := accountSlip132Prefixes[purpose]; ok {
+			encoded, err := slip132Encode(
+				accountXPub, prefixes[prefixIndex],
+			)
+			if err != nil {
+				return err
+			}
+			_, _ = fmt.Fprintf(w, "  %s\n", encoded)
+		}
+
+		if !withDescriptors {
+			continue
+		}
+		keyOrigin, err := descriptorKeyOrigin(
+			rootKey, lnd.FormatPath(path),
+		)
+		if err != nil {
+			return err
+		}
+		for branch, name := range []string{"receive", "change"} {
+			desc, err := accountDescriptor(
+				path, keyOrigin, accountXPub.String(),
+				uint32(branch),
+			)
+			if err != nil {
+				return fmt.Errorf("error creating descriptor: "+
+					"%v", err)
+			}
+			_, _ = fmt.Fprintf(w, "  %s descriptor: %s\n", name,
+				desc)
+		}
+	}
+	return nil
+}
+
 func decryptRootKey(db walletdb.DB, privPassphrase []byte) ([]byte, error) {
 	// Step 1: Load the encryption parameters and encrypted keys from the
 	// database.
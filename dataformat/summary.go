@@ -28,10 +28,20 @@ func (b *BasePoint) Desc() *keychain.KeyDescriptor {
 	}
 }
 
+const (
+	OutTypeToLocal      = "to_local"
+	OutTypeToRemote     = "to_remote"
+	OutTypeHtlcOffered  = "htlc_offered"
+	OutTypeHtlcReceived = "htlc_received"
+)
+
 type Out struct {
-	Script    string `json:"script"`
-	ScriptAsm string `json:"script_asm"`
-	Value     uint64 `json:"value"`
+	Script      string `json:"script"`
+	ScriptAsm   string `json:"script_asm"`
+	Value       uint64 `json:"value"`
+	Type        string `json:"type,omitempty"`
+	CLTVExpiry  uint32 `json:"cltv_expiry,omitempty"`
+	PaymentHash string `json:"payment_hash,omitempty"`
 }
 
 type ForceClose struct {
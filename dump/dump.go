@@ -73,6 +73,16 @@ type OpenChannel struct {
 	RemoteShutdownScript    lnwire.DeliveryAddress
 }
 
+// HTLC is the information we want to dump from an HTLC that is in flight in a
+// channel. See `channeldb.HTLC` for information about the fields.
+type HTLC struct {
+	PaymentHash       string              `json:"payment_hash"`
+	AmountMsat        lnwire.MilliSatoshi `json:"amount_msat"`
+	Direction         string              `json:"direction"`
+	CltvExpiry        uint32              `json:"cltv_expiry"`
+	BlocksUntilExpiry int64               `json:"blocks_until_expiry"`
+}
+
 // ChannelConfig is the information we want to dump from a channel
 // configuration. See `channeldb.ChannelConfig` for more information about the
 // fields.
@@ -154,6 +164,50 @@ func ChannelDump(channels []*channeldb.OpenChannel, params *chaincfg.Params) (
 	return dumpChannels, nil
 }
 
+// HtlcDump converts the HTLCs that are in flight in the given channel into a
+// dumpable format. HTLCs that are only on one of the two commitments are
+// included as well. The blocks until expiry are calculated from the given
+// block height and are negative for HTLCs that already expired.
+func HtlcDump(channel *channeldb.OpenChannel, height uint32) []HTLC {
+	type htlcKey struct {
+		incoming bool
+		index    uint64
+	}
+	var (
+		dumpHtlcs []HTLC
+		seen      = make(map[htlcKey]bool)
+	)
+	commitments := []channeldb.ChannelCommitment{
+		channel.LocalCommitment, channel.RemoteCommitment,
+	}
+	for _, commitment := range commitments {
+		for _, htlc := range commitment.Htlcs {
+			key := htlcKey{
+				incoming: htlc.Incoming,
+				index:    htlc.HtlcIndex,
+			}
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+
+			direction := "out"
+			if htlc.Incoming {
+				direction = "in"
+			}
+			dumpHtlcs = append(dumpHtlcs, HTLC{
+				PaymentHash: hex.EncodeToString(htlc.RHash[:]),
+				AmountMsat:  htlc.Amt,
+				Direction:   direction,
+				CltvExpiry:  htlc.RefundTimeout,
+				BlocksUntilExpiry: int64(htlc.RefundTimeout) -
+					int64(height),
+			})
+		}
+	}
+	return dumpHtlcs
+}
+
 // BackupDump converts the given multi backup into a dumpable format.
 func BackupDump(multi *chanbackup.Multi, params *chaincfg.Params) []BackupSingle {
 
@@ -2,11 +2,17 @@ package lnd
 
 import (
 	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
 	"fmt"
 
 	"github.com/lightningnetwork/lnd/chanbackup"
 	"github.com/lightningnetwork/lnd/channeldb"
 	"github.com/lightningnetwork/lnd/keychain"
+	"golang.org/x/crypto/chacha20poly1305"
 )
 
 // CreateChannelBackup creates a channel backup file from all channels found in
@@ -33,3 +39,196 @@ func CreateChannelBackup(db *channeldb.DB, multiFile *chanbackup.MultiFile,
 	}
 	return nil
 }
+
+const (
+	// backupTagSize is the size of the poly1305 authentication tag at the
+	// end of an encrypted channel backup.
+	backupTagSize = 16
+)
+
+// PackedBackup is the encryption layer of a packed multi or single channel
+// backup. Everything but the nonce is encrypted as a whole, so this is all we
+// can learn about a backup without knowing the encryption key.
+type PackedBackup struct {
+	Nonce      []byte
+	Ciphertext []byte
+	AuthTag    []byte
+}
+
+// BackupEntry is the framing of a single channel backup inside the plaintext
+// of a multi channel backup.
+type BackupEntry struct {
+	Offset  int
+	Version byte
+	Length  uint16
+	Payload []byte
+}
+
+// DecodePackedBackup splits a packed backup into its nonce, ciphertext and
+// authentication tag without decrypting it.
+func DecodePackedBackup(packed []byte) (*PackedBackup, error) {
+	minSize := chacha20poly1305.NonceSizeX + backupTagSize
+	if len(packed) < minSize {
+		return nil, fmt.Errorf("payload size too small, must be at "+
+			"least %d bytes", minSize)
+	}
+
+	tagStart := len(packed) - backupTagSize
+	return &PackedBackup{
+		Nonce:      packed[:chacha20poly1305.NonceSizeX],
+		Ciphertext: packed[chacha20poly1305.NonceSizeX:tagStart],
+		AuthTag:    packed[tagStart:],
+	}, nil
+}
+
+// DecryptPackedBackup decrypts a packed backup with the static channel backup
+// key derived from the given key ring.
+func DecryptPackedBackup(packed *PackedBackup, ring keychain.KeyRing) ([]byte,
+	error) {
+
+	baseKey, err := ring.DeriveKey(keychain.KeyLocator{
+		Family: keychain.KeyFamilyStaticBackup,
+		Index:  0,
+	})
+	if err != nil {
+		return nil, err
+	}
+	encryptionKey := sha256.Sum256(baseKey.PubKey.SerializeCompressed())
+
+	cipher, err := chacha20poly1305.NewX(encryptionKey[:])
+	if err != nil {
+		return nil, err
+	}
+	ciphertext := append(
+		append([]byte{}, packed.Ciphertext...), packed.AuthTag...,
+	)
+	return cipher.Open(nil, packed.Nonce, ciphertext, packed.Nonce)
+}
+
+// DecodeMultiPlaintext decodes the framing of the plaintext of a multi channel
+// backup: The version, the number of entries and the version and length of
+// each entry. The entries themselves are not deserialized, so this also works
+// for corrupt or unknown entries.
+func DecodeMultiPlaintext(plaintext []byte) (byte, uint32, []*BackupEntry,
+	error) {
+
+	if len(plaintext) < 5 {
+		return 0, 0, nil, fmt.Errorf("plaintext too short: %d bytes",
+			len(plaintext))
+	}
+	version := plaintext[0]
+	numEntries := binary.BigEndian.Uint32(plaintext[1:5])
+
+	var (
+		entries []*BackupEntry
+		offset  = 5
+	)
+	for offset < len(plaintext) {
+		if offset+3 > len(plaintext) {
+			return version, numEntries, entries, fmt.Errorf(
+				"truncated entry header at offset %d", offset,
+			)
+		}
+		entry := &BackupEntry{
+			Offset:  offset,
+			Version: plaintext[offset],
+			Length: binary.BigEndian.Uint16(
+				plaintext[offset+1 : offset+3],
+			),
+		}
+		end := offset + 3 + int(entry.Length)
+		if end > len(plaintext) {
+			return version, numEntries, entries, fmt.Errorf(
+				"entry at offset %d exceeds plaintext by %d "+
+					"bytes", offset, end-len(plaintext),
+			)
+		}
+		entry.Payload = plaintext[offset+3 : end]
+		entries = append(entries, entry)
+		offset = end
+	}
+	return version, numEntries, entries, nil
+}
+
+// lncliBackup is the JSON output of lncli exportchanbackup. With --all, lncli
+// prints the RPC response which uses the protobuf JSON field names. A single
+// channel is printed with the original field names instead.
+type lncliBackup struct {
+	MultiChanBackup     *lncliMultiBackup `json:"multiChanBackup"`
+	MultiChanBackupOrig *lncliMultiBackup `json:"multi_chan_backup"`
+	ChanPoint           string            `json:"chan_point"`
+	ChanBackup          string            `json:"chan_backup"`
+}
+
+type lncliMultiBackup struct {
+	MultiChanBackup     string `json:"multiChanBackup"`
+	MultiChanBackupOrig string `json:"multi_chan_backup"`
+}
+
+// ParseLncliBackup parses the JSON output of lncli exportchanbackup and
+// decrypts the contained backup with the static channel backup key derived
+// from the given key ring. The output of a single channel is returned as a
+// multi backup with one channel.
+func ParseLncliBackup(content []byte, ring keychain.KeyRing) (
+	*chanbackup.Multi, error) {
+
+	backup := &lncliBackup{}
+	if err := json.Unmarshal(content, backup); err != nil {
+		return nil, fmt.Errorf("error parsing JSON: %v", err)
+	}
+	if backup.MultiChanBackup == nil {
+		backup.MultiChanBackup = backup.MultiChanBackupOrig
+	}
+
+	switch {
+	case backup.MultiChanBackup != nil:
+		packed := backup.MultiChanBackup.MultiChanBackup
+		if packed == "" {
+			packed = backup.MultiChanBackup.MultiChanBackupOrig
+		}
+		packedBytes, err := decodeLncliBytes(packed)
+		if err != nil {
+			return nil, fmt.Errorf("error decoding multi channel "+
+				"backup: %v", err)
+		}
+		multi := &chanbackup.Multi{}
+		err = multi.UnpackFromReader(bytes.NewReader(packedBytes), ring)
+		if err != nil {
+			return nil, fmt.Errorf("error unpacking multi channel "+
+				"backup: %v", err)
+		}
+		return multi, nil
+
+	case backup.ChanBackup != "":
+		packedBytes, err := decodeLncliBytes(backup.ChanBackup)
+		if err != nil {
+			return nil, fmt.Errorf("error decoding channel backup "+
+				"of %s: %v", backup.ChanPoint, err)
+		}
+		single := chanbackup.Single{}
+		err = single.UnpackFromReader(
+			bytes.NewReader(packedBytes), ring,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("error unpacking channel "+
+				"backup of %s: %v", backup.ChanPoint, err)
+		}
+		return &chanbackup.Multi{
+			Version:       chanbackup.DefaultMultiVersion,
+			StaticBackups: []chanbackup.Single{single},
+		}, nil
+
+	default:
+		return nil, fmt.Errorf("no channel backup found in JSON")
+	}
+}
+
+// decodeLncliBytes decodes a byte field of the lncli output. Byte fields are
+// base64 encoded by lncli but the hex encoding is supported as well, in case
+// the output was converted.
+func decodeLncliBytes(encoded string) ([]byte, error) {
+	if decoded, err := hex.DecodeString(encoded); err == nil {
+		return decoded, nil
+	}
+	return base64.StdEncoding.DecodeString(encoded)
+}
@@ -32,33 +32,85 @@ func DeriveChildren(key *hdkeychain.ExtendedKey, path []uint32) (
 	return currentKey, nil
 }
 
+const (
+	// MaxPathDepth is the maximum number of levels of a derivation path,
+	// the depth of an extended key is encoded in a single byte.
+	MaxPathDepth = 255
+)
+
+// StrictPath can be set to only accept derivation paths that start with m/.
+// Otherwise the leading m/ is optional.
+var StrictPath = false
+
+// ParsePath parses a derivation path in the m/x'/y/z notation. Both ' and h are
+// accepted as the marker of a hardened index, so paths like m/84h/0h/0h of
+// other wallets can be used as is.
 func ParsePath(path string) ([]uint32, error) {
 	path = strings.TrimSpace(path)
 	if len(path) == 0 {
 		return nil, fmt.Errorf("path cannot be empty")
 	}
-	if !strings.HasPrefix(path, "m/") {
+	switch {
+	case strings.HasPrefix(path, "m/"):
+		path = strings.TrimPrefix(path, "m/")
+
+	case path == "m":
+		return nil, fmt.Errorf("path must have at least one level " +
+			"below m")
+
+	case StrictPath:
 		return nil, fmt.Errorf("path must start with m/")
 	}
+
 	parts := strings.Split(path, "/")
-	indices := make([]uint32, len(parts)-1)
-	for i := 1; i < len(parts); i++ {
+	if len(parts) > MaxPathDepth {
+		return nil, fmt.Errorf("path has %d levels, BIP32 allows at "+
+			"most %d", len(parts), MaxPathDepth)
+	}
+	indices := make([]uint32, len(parts))
+	for i, part := range parts {
 		index := uint32(0)
-		part := parts[i]
-		if strings.Contains(parts[i], "'") {
+		part = strings.TrimSpace(part)
+		hardened := strings.HasSuffix(part, "'") ||
+			strings.HasSuffix(part, "h")
+		if hardened {
 			index += HardenedKeyStart
-			part = strings.TrimRight(parts[i], "'")
+			part = part[:len(part)-1]
 		}
-		parsed, err := strconv.Atoi(part)
+		parsed, err := strconv.ParseUint(part, 10, 32)
 		if err != nil {
 			return nil, fmt.Errorf("could not parse part \"%s\": "+
 				"%v", part, err)
 		}
-		indices[i-1] = index + uint32(parsed)
+
+		// Indexes above the hardened key start would wrap around or
+		// silently turn into a hardened index.
+		if parsed >= uint64(HardenedKeyStart) {
+			return nil, fmt.Errorf("index %d of part \"%s\" is "+
+				"out of range, must be smaller than %d",
+				parsed, parts[i], HardenedKeyStart)
+		}
+		indices[i] = index + uint32(parsed)
 	}
 	return indices, nil
 }
 
+// FormatPath formats a derivation path in the m/x'/y/z notation that is
+// understood by ParsePath.
+func FormatPath(path []uint32) string {
+	parts := []string{"m"}
+	for _, index := range path {
+		if index >= HardenedKeyStart {
+			parts = append(parts, fmt.Sprintf(
+				"%d'", index-HardenedKeyStart,
+			))
+			continue
+		}
+		parts = append(parts, strconv.Itoa(int(index)))
+	}
+	return strings.Join(parts, "/")
+}
+
 // DeriveKey derives the public key and private key in the WIF format for a
 // given key path of the extended key.
 func DeriveKey(extendedKey *hdkeychain.ExtendedKey, path string,
@@ -0,0 +1,369 @@
+package lnd
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+
+	"github.com/btcsuite/btcd/txscript"
+)
+
+// ScriptType is the type of an output script or witness script.
+type ScriptType uint8
+
+const (
+	ScriptTypeUnknown ScriptType = iota
+	ScriptTypeP2PKH
+	ScriptTypeP2SH
+	ScriptTypeP2WKH
+	ScriptTypeP2WSH
+	ScriptTypeP2TR
+
+	// ScriptTypeHtlcOffered is the witness script of an HTLC output that
+	// was offered by the owner of the commitment transaction.
+	ScriptTypeHtlcOffered
+
+	// ScriptTypeHtlcReceived is the witness script of an HTLC output that
+	// was received by the owner of the commitment transaction.
+	ScriptTypeHtlcReceived
+
+	// ScriptTypeToLocalCSV is the witness script of the time locked
+	// to_local output of a commitment transaction.
+	ScriptTypeToLocalCSV
+
+	// ScriptTypeToRemoteStatic is the witness script of the to_remote
+	// output of an anchor commitment transaction. The to_remote output of
+	// a non-anchor commitment is a plain P2WKH output.
+	ScriptTypeToRemoteStatic
+
+	// ScriptTypeAnchor is the witness script of an anchor output.
+	ScriptTypeAnchor
+)
+
+// String returns a human readable name of the script type.
+func (t ScriptType) String() string {
+	switch t {
+	case ScriptTypeP2PKH:
+		return "p2pkh"
+	case ScriptTypeP2SH:
+		return "p2sh"
+	case ScriptTypeP2WKH:
+		return "p2wkh"
+	case ScriptTypeP2WSH:
+		return "p2wsh"
+	case ScriptTypeP2TR:
+		return "p2tr"
+	case ScriptTypeHtlcOffered:
+		return "htlc_offered"
+	case ScriptTypeHtlcReceived:
+		return "htlc_received"
+	case ScriptTypeToLocalCSV:
+		return "to_local_csv"
+	case ScriptTypeToRemoteStatic:
+		return "to_remote_static"
+	case ScriptTypeAnchor:
+		return "anchor"
+	default:
+		return "unknown"
+	}
+}
+
+// Placeholders for data pushes in script templates. Real opcodes are always
+// in the range of a byte.
+const (
+	tplPubKey = -(iota + 1)
+	tplHash20
+	tplHash32
+	tplNumber
+)
+
+var (
+	scriptTemplates = []struct {
+		scriptType ScriptType
+		template   []int
+	}{{
+		scriptType: ScriptTypeP2PKH,
+		template: []int{
+			txscript.OP_DUP, txscript.OP_HASH160, tplHash20,
+			txscript.OP_EQUALVERIFY, txscript.OP_CHECKSIG,
+		},
+	}, {
+		scriptType: ScriptTypeP2SH,
+		template: []int{
+			txscript.OP_HASH160, tplHash20, txscript.OP_EQUAL,
+		},
+	}, {
+		scriptType: ScriptTypeP2WKH,
+		template:   []int{txscript.OP_0, tplHash20},
+	}, {
+		scriptType: ScriptTypeP2WSH,
+		template:   []int{txscript.OP_0, tplHash32},
+	}, {
+		scriptType: ScriptTypeP2TR,
+		template:   []int{txscript.OP_1, tplHash32},
+	}, {
+		scriptType: ScriptTypeToLocalCSV,
+		template: []int{
+			txscript.OP_IF, tplPubKey, txscript.OP_ELSE, tplNumber,
+			txscript.OP_CHECKSEQUENCEVERIFY, txscript.OP_DROP,
+			tplPubKey, txscript.OP_ENDIF, txscript.OP_CHECKSIG,
+		},
+	}, {
+		scriptType: ScriptTypeToRemoteStatic,
+		template: []int{
+			tplPubKey, txscript.OP_CHECKSIGVERIFY, txscript.OP_1,
+			txscript.OP_CHECKSEQUENCEVERIFY,
+		},
+	}, {
+		scriptType: ScriptTypeAnchor,
+		template: []int{
+			tplPubKey, txscript.OP_CHECKSIG, txscript.OP_IFDUP,
+			txscript.OP_NOTIF, txscript.OP_16,
+			txscript.OP_CHECKSEQUENCEVERIFY, txscript.OP_ENDIF,
+		},
+	}, {
+		scriptType: ScriptTypeHtlcOffered,
+		template:   offeredHtlcTemplate(false),
+	}, {
+		scriptType: ScriptTypeHtlcOffered,
+		template:   offeredHtlcTemplate(true),
+	}, {
+		scriptType: ScriptTypeHtlcReceived,
+		template:   receivedHtlcTemplate(false),
+	}, {
+		scriptType: ScriptTypeHtlcReceived,
+		template:   receivedHtlcTemplate(true),
+	}}
+)
+
+// offeredHtlcTemplate returns the template of the witness script of an
+// offered HTLC as defined in BOLT #3.
+func offeredHtlcTemplate(anchors bool) []int {
+	template := []int{
+		txscript.OP_DUP, txscript.OP_HASH160, tplHash20,
+		txscript.OP_EQUAL, txscript.OP_IF, txscript.OP_CHECKSIG,
+		txscript.OP_ELSE, tplPubKey, txscript.OP_SWAP,
+		txscript.OP_SIZE, tplNumber, txscript.OP_EQUAL,
+		txscript.OP_NOTIF, txscript.OP_DROP, txscript.OP_2,
+		txscript.OP_SWAP, tplPubKey, txscript.OP_2,
+		txscript.OP_CHECKMULTISIG, txscript.OP_ELSE,
+		txscript.OP_HASH160, tplHash20, txscript.OP_EQUALVERIFY,
+		txscript.OP_CHECKSIG, txscript.OP_ENDIF,
+	}
+	return withHtlcEnd(template, anchors)
+}
+
+// receivedHtlcTemplate returns the template of the witness script of a
+// received HTLC as defined in BOLT #3.
+func receivedHtlcTemplate(anchors bool) []int {
+	template := []int{
+		txscript.OP_DUP, txscript.OP_HASH160, tplHash20,
+		txscript.OP_EQUAL, txscript.OP_IF, txscript.OP_CHECKSIG,
+		txscript.OP_ELSE, tplPubKey, txscript.OP_SWAP,
+		txscript.OP_SIZE, tplNumber, txscript.OP_EQUAL,
+		txscript.OP_IF, txscript.OP_HASH160, tplHash20,
+		txscript.OP_EQUALVERIFY, txscript.OP_2, txscript.OP_SWAP,
+		tplPubKey, txscript.OP_2, txscript.OP_CHECKMULTISIG,
+		txscript.OP_ELSE, txscript.OP_DROP, tplNumber,
+		txscript.OP_CHECKLOCKTIMEVERIFY, txscript.OP_DROP,
+		txscript.OP_CHECKSIG, txscript.OP_ENDIF,
+	}
+	return withHtlcEnd(template, anchors)
+}
+
+// withHtlcEnd adds the end of an HTLC script template. HTLC outputs of anchor
+// channels have an additional relative time lock of one block.
+func withHtlcEnd(template []int, anchors bool) []int {
+	if anchors {
+		template = append(
+			template, txscript.OP_1,
+			txscript.OP_CHECKSEQUENCEVERIFY, txscript.OP_DROP,
+		)
+	}
+	return append(template, txscript.OP_ENDIF)
+}
+
+// scriptToken is a single opcode of a script together with its pushed data.
+type scriptToken struct {
+	opcode byte
+	data   []byte
+}
+
+// ClassifyScript returns the type of the given output script or witness
+// script. Output scripts of P2WSH outputs don't reveal what script they commit
+// to, so the witness script needs to be classified to find out what kind of
+// channel output it is.
+func ClassifyScript(script []byte) (ScriptType, error) {
+	tokens, err := parseScript(script)
+	if err != nil {
+		return ScriptTypeUnknown, err
+	}
+	for _, tpl := range scriptTemplates {
+		if matchTemplate(tokens, tpl.template) {
+			return tpl.scriptType, nil
+		}
+	}
+	return ScriptTypeUnknown, fmt.Errorf("unknown script %x", script)
+}
+
+func matchTemplate(tokens []scriptToken, template []int) bool {
+	if len(tokens) != len(template) {
+		return false
+	}
+	for idx, element := range template {
+		token := tokens[idx]
+		switch element {
+		case tplPubKey:
+			if !isPush(token) || len(token.data) != 33 {
+				return false
+			}
+
+		case tplHash20:
+			if !isPush(token) || len(token.data) != 20 {
+				return false
+			}
+
+		case tplHash32:
+			if !isPush(token) || len(token.data) != 32 {
+				return false
+			}
+
+		case tplNumber:
+			isSmallInt := token.opcode == txscript.OP_0 ||
+				(token.opcode >= txscript.OP_1 &&
+					token.opcode <= txscript.OP_16)
+			isNumPush := isPush(token) && len(token.data) > 0 &&
+				len(token.data) <= 5
+			if !isSmallInt && !isNumPush {
+				return false
+			}
+
+		default:
+			if isPush(token) || int(token.opcode) != element {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func isPush(token scriptToken) bool {
+	return token.opcode > txscript.OP_0 &&
+		token.opcode <= txscript.OP_PUSHDATA4
+}
+
+// parseScript splits a script into its opcodes and data pushes.
+func parseScript(script []byte) ([]scriptToken, error) {
+	var tokens []scriptToken
+	for i := 0; i < len(script); {
+		opcode := script[i]
+		i++
+
+		var dataLen int
+		switch {
+		case opcode > txscript.OP_0 && opcode < txscript.OP_PUSHDATA1:
+			dataLen = int(opcode)
+
+		case opcode == txscript.OP_PUSHDATA1:
+			if i+1 > len(script) {
+				return nil, fmt.Errorf("truncated push")
+			}
+			dataLen = int(script[i])
+			i++
+
+		case opcode == txscript.OP_PUSHDATA2:
+			if i+2 > len(script) {
+				return nil, fmt.Errorf("truncated push")
+			}
+			dataLen = int(binary.LittleEndian.Uint16(script[i:]))
+			i += 2
+
+		case opcode == txscript.OP_PUSHDATA4:
+			if i+4 > len(script) {
+				return nil, fmt.Errorf("truncated push")
+			}
+			dataLen = int(binary.LittleEndian.Uint32(script[i:]))
+			i += 4
+		}
+		if dataLen < 0 || i+dataLen > len(script) {
+			return nil, fmt.Errorf("push of %d bytes exceeds "+
+				"script", dataLen)
+		}
+		tokens = append(tokens, scriptToken{
+			opcode: opcode,
+			data:   script[i : i+dataLen],
+		})
+		i += dataLen
+	}
+	return tokens, nil
+}
+
+// ScriptTimeLocks returns the relative and the absolute time lock of a script,
+// which are the numbers pushed right before its OP_CHECKSEQUENCEVERIFY and its
+// OP_CHECKLOCKTIMEVERIFY. Zero is returned for a time lock the script doesn't
+// have. If a script has multiple time locks of the same kind, the highest one
+// is returned.
+func ScriptTimeLocks(script []byte) (uint32, uint32, error) {
+	tokens, err := parseScript(script)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	var csv, cltv uint32
+	for idx, token := range tokens {
+		if token.opcode != txscript.OP_CHECKSEQUENCEVERIFY &&
+			token.opcode != txscript.OP_CHECKLOCKTIMEVERIFY {
+
+			continue
+		}
+		if idx == 0 {
+			return 0, 0, fmt.Errorf("time lock without a number")
+		}
+		lock, err := scriptNumber(tokens[idx-1])
+		if err != nil {
+			return 0, 0, fmt.Errorf("invalid time lock: %v", err)
+		}
+		switch {
+		case token.opcode == txscript.OP_CHECKSEQUENCEVERIFY &&
+			lock > csv:
+
+			csv = lock
+
+		case token.opcode == txscript.OP_CHECKLOCKTIMEVERIFY &&
+			lock > cltv:
+
+			cltv = lock
+		}
+	}
+	return csv, cltv, nil
+}
+
+// scriptNumber decodes a non-negative number of up to 5 bytes that is pushed
+// onto the stack by a token, as used for time locks.
+func scriptNumber(token scriptToken) (uint32, error) {
+	switch {
+	case token.opcode == txscript.OP_0:
+		return 0, nil
+
+	case token.opcode >= txscript.OP_1 && token.opcode <= txscript.OP_16:
+		return uint32(token.opcode-txscript.OP_1) + 1, nil
+
+	case !isPush(token) || len(token.data) == 0 || len(token.data) > 5:
+		return 0, fmt.Errorf("opcode %d is not a number", token.opcode)
+	}
+
+	// Numbers are encoded in little endian with the sign in the highest
+	// bit of the last byte.
+	data := token.data
+	if data[len(data)-1]&0x80 != 0 {
+		return 0, fmt.Errorf("number is negative")
+	}
+	var number uint64
+	for i := len(data) - 1; i >= 0; i-- {
+		number = number<<8 | uint64(data[i])
+	}
+	if number > math.MaxUint32 {
+		return 0, fmt.Errorf("number %d is too large", number)
+	}
+	return uint32(number), nil
+}
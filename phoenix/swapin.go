@@ -0,0 +1,124 @@
+package phoenix
+
+import (
+	"fmt"
+
+	"github.com/btcsuite/btcd/btcec"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/btcsuite/btcutil"
+	"github.com/btcsuite/btcutil/hdkeychain"
+	"github.com/guggero/chantools/lnd"
+	"github.com/lightningnetwork/lnd/input"
+)
+
+const (
+	// DefaultRefundDelay is the relative time lock in blocks after which
+	// the user can spend a swap-in output without the server's signature.
+	// It corresponds to roughly six months.
+	DefaultRefundDelay = 25920
+
+	// swapInPurpose is the BIP43 purpose Phoenix uses for the swap-in user
+	// keys.
+	swapInPurpose = 52
+
+	// TimeoutWitnessSize is the size of the witness that spends a swap-in
+	// output through the timeout path:
+	//	- number_of_witness_elements: 1 byte
+	//	- server_sig_length: 1 byte (empty)
+	//	- user_sig_length: 1 byte
+	//	- user_sig: 73 bytes
+	//	- witness_script_length: 1 byte
+	//	- witness_script: 77 bytes
+	TimeoutWitnessSize = 1 + 1 + 1 + 73 + 1 + 77
+
+	// CooperativeWitnessSize is the size of the witness that spends a
+	// swap-in output through the cooperative path. It contains the
+	// server's signature in addition to the user's signature.
+	CooperativeWitnessSize = TimeoutWitnessSize + 73
+)
+
+// UserKeyPath returns the derivation path of the swap-in user key with the
+// given index: m/52'/<coin_type>'/0'/0/<index>.
+func UserKeyPath(params *chaincfg.Params, index uint32) []uint32 {
+	return []uint32{
+		lnd.HardenedKeyStart + swapInPurpose,
+		lnd.HardenedKeyStart + params.HDCoinType,
+		lnd.HardenedKeyStart + 0,
+		0,
+		index,
+	}
+}
+
+// UserKey derives the swap-in user key with the given index from the BIP32 root
+// key of the Phoenix BIP39 seed.
+func UserKey(rootKey *hdkeychain.ExtendedKey, params *chaincfg.Params,
+	index uint32) (*btcec.PrivateKey, error) {
+
+	key, err := lnd.DeriveChildren(rootKey, UserKeyPath(params, index))
+	if err != nil {
+		return nil, err
+	}
+	return key.ECPrivKey()
+}
+
+// SwapInScript returns the witness script of a swap-in output. It was created
+// from the miniscript policy
+//
+//	and(pk(user),or(99@pk(server),older(refundDelay)))
+//
+// which compiles to:
+//
+//	<user_key> OP_CHECKSIGVERIFY <server_key> OP_CHECKSIG OP_IFDUP
+//	OP_NOTIF
+//		<refund_delay> OP_CHECKSEQUENCEVERIFY
+//	OP_ENDIF
+func SwapInScript(userKey, serverKey *btcec.PublicKey, refundDelay uint32) (
+	[]byte, error) {
+
+	builder := txscript.NewScriptBuilder()
+	builder.AddData(userKey.SerializeCompressed())
+	builder.AddOp(txscript.OP_CHECKSIGVERIFY)
+	builder.AddData(serverKey.SerializeCompressed())
+	builder.AddOp(txscript.OP_CHECKSIG)
+	builder.AddOp(txscript.OP_IFDUP)
+	builder.AddOp(txscript.OP_NOTIF)
+	builder.AddInt64(int64(refundDelay))
+	builder.AddOp(txscript.OP_CHECKSEQUENCEVERIFY)
+	builder.AddOp(txscript.OP_ENDIF)
+	return builder.Script()
+}
+
+// SwapInAddress returns the P2WSH address of the given swap-in witness script.
+func SwapInAddress(witnessScript []byte, params *chaincfg.Params) (
+	*btcutil.AddressWitnessScriptHash, error) {
+
+	scriptHash, err := input.WitnessScriptHash(witnessScript)
+	if err != nil {
+		return nil, err
+	}
+	addr, err := btcutil.NewAddressWitnessScriptHash(
+		scriptHash[2:], params,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("could not create address: %v", err)
+	}
+	return addr, nil
+}
+
+// TimeoutWitness creates the witness that spends a swap-in output through the
+// timeout path. The input must have its sequence set to the refund delay. The
+// signatures are expected to have the sighash flag appended.
+func TimeoutWitness(userSig, witnessScript []byte) wire.TxWitness {
+	return wire.TxWitness{nil, userSig, witnessScript}
+}
+
+// CooperativeWitness creates the witness that spends a swap-in output with the
+// signatures of both the user and the server. The signatures are expected to
+// have the sighash flag appended.
+func CooperativeWitness(userSig, serverSig,
+	witnessScript []byte) wire.TxWitness {
+
+	return wire.TxWitness{serverSig, userSig, witnessScript}
+}
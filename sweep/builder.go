@@ -0,0 +1,601 @@
+package sweep
+
+import (
+	"crypto/rand"
+	"fmt"
+	"math"
+
+	"github.com/btcsuite/btcd/btcec"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/btcsuite/btcutil"
+	"github.com/btcsuite/btcutil/psbt"
+	"github.com/guggero/chantools/btc"
+	"github.com/lightningnetwork/lnd/input"
+)
+
+const (
+	// DefaultFeeRate is the fee rate in satoshis per vbyte that is used if
+	// no fee rate is set.
+	DefaultFeeRate = 2
+
+	// dustLimit is the minimum value of the sweep output.
+	dustLimit = 546
+
+	// rbfSequence is the sequence number of inputs that don't need a
+	// specific sequence. It signals replaceability as defined in BIP125 so
+	// the fee of a stuck sweep can be bumped.
+	rbfSequence = wire.MaxTxInSequenceNum - 2
+
+	// taprootKeyPathWitnessSize is the size of the witness of a Taproot
+	// key path spend, which is a single Schnorr signature with the default
+	// sighash type.
+	taprootKeyPathWitnessSize = 1 + 1 + btc.SchnorrSigSize
+)
+
+var (
+	// PsbtKeyTypeInputSignatureTweakSingle is the proprietary PSBT key lnd
+	// uses for the single tweak that needs to be applied to the key of an
+	// input before signing it.
+	PsbtKeyTypeInputSignatureTweakSingle = []byte{0x51}
+)
+
+// ScriptType is the type of the output script of an input to sweep.
+type ScriptType uint8
+
+const (
+	// ScriptTypeP2PKH is a legacy pay to public key hash output.
+	ScriptTypeP2PKH ScriptType = iota
+
+	// ScriptTypeNP2WKH is a pay to witness key hash output nested in a
+	// pay to script hash output.
+	ScriptTypeNP2WKH
+
+	// ScriptTypeP2WKH is a native pay to witness key hash output.
+	ScriptTypeP2WKH
+
+	// ScriptTypeP2WSH is a pay to witness script hash output. The witness
+	// script must be set in the UTXO.
+	ScriptTypeP2WSH
+
+	// ScriptTypeP2TR is a Taproot output that is spent with the key path.
+	// The key of the input is the internal key, the output must not commit
+	// to a script tree as defined in BIP86.
+	ScriptTypeP2TR
+
+	// ScriptTypeP2TRScriptPath is a Taproot output that is spent with the
+	// script of a leaf. The leaf script and its control block must be set
+	// in the UTXO. The key of the input signs the leaf script.
+	ScriptTypeP2TRScriptPath
+)
+
+// UTXO is an unspent output that should be swept.
+type UTXO struct {
+	// OutPoint is the outpoint of the output.
+	OutPoint wire.OutPoint
+
+	// Value is the value of the output in satoshis.
+	Value int64
+
+	// Sequence is the sequence number of the input. Must be set for inputs
+	// with a relative time lock. If zero, the input signals RBF.
+	Sequence uint32
+
+	// WitnessScript is the witness script of a P2WSH output.
+	WitnessScript []byte
+
+	// WitnessSize is the estimated size of the witness of a P2WSH output.
+	WitnessSize int
+
+	// Witness creates the witness stack of a P2WSH output from the
+	// signature, including the sighash flag. If nil, the witness consists
+	// of the signature followed by the witness script.
+	Witness func(sig []byte) wire.TxWitness
+
+	// TapLeafScript is the leaf script of a Taproot script path spend. Its
+	// witness consists of the signature, the leaf script and the control
+	// block, so the script must only need a single signature.
+	TapLeafScript []byte
+
+	// ControlBlock is the control block of the leaf of a Taproot script
+	// path spend.
+	ControlBlock []byte
+
+	// Derivation is the BIP32 derivation of the key of the input. It is
+	// only used for PSBTs so an external signer can find the key.
+	Derivation *psbt.Bip32Derivation
+
+	// SingleTweak is the tweak that is added to the derived key to get the
+	// key of the input. It is only used for PSBTs.
+	SingleTweak []byte
+}
+
+type sweepInput struct {
+	utxo       UTXO
+	key        *btcec.PrivateKey
+	scriptType ScriptType
+}
+
+// Builder constructs and signs a transaction that sweeps a set of UTXOs into a
+// single output.
+type Builder struct {
+	params   *chaincfg.Params
+	inputs   []*sweepInput
+	feeRate  float64
+	output   btcutil.Address
+	lockTime uint32
+	fee      int64
+	vsize    int64
+}
+
+// NewSweepBuilder returns a new builder for sweep transactions on the network
+// with the given parameters.
+func NewSweepBuilder(params *chaincfg.Params) *Builder {
+	return &Builder{
+		params:  params,
+		feeRate: DefaultFeeRate,
+	}
+}
+
+// AddInput adds an output to sweep that can be signed with the given key.
+func (b *Builder) AddInput(utxo UTXO, key *btcec.PrivateKey,
+	scriptType ScriptType) {
+
+	b.inputs = append(b.inputs, &sweepInput{
+		utxo:       utxo,
+		key:        key,
+		scriptType: scriptType,
+	})
+}
+
+// SetFeeRate sets the fee rate of the sweep transaction in satoshis per vbyte.
+func (b *Builder) SetFeeRate(satPerVbyte float64) {
+	b.feeRate = satPerVbyte
+}
+
+// SetLockTime sets the absolute time lock of the sweep transaction. It must be
+// set for inputs with an absolute time lock.
+func (b *Builder) SetLockTime(lockTime uint32) {
+	b.lockTime = lockTime
+}
+
+// SetOutput sets the address the funds are swept to.
+func (b *Builder) SetOutput(addr btcutil.Address) {
+	b.output = addr
+}
+
+// Fee returns the fee of the transaction created by the last call to Build.
+func (b *Builder) Fee() int64 {
+	return b.fee
+}
+
+// VSize returns the estimated virtual size of the transaction created by the
+// last call to Build.
+func (b *Builder) VSize() int64 {
+	return b.vsize
+}
+
+// InputValues returns the values of the inputs in the order they are added to
+// the transaction.
+func (b *Builder) InputValues() []int64 {
+	values := make([]int64, len(b.inputs))
+	for idx, in := range b.inputs {
+		values[idx] = in.utxo.Value
+	}
+	return values
+}
+
+// OutPoints returns the outpoints of the inputs in the order they are added to
+// the transaction.
+func (b *Builder) OutPoints() []wire.OutPoint {
+	outPoints := make([]wire.OutPoint, len(b.inputs))
+	for idx, in := range b.inputs {
+		outPoints[idx] = in.utxo.OutPoint
+	}
+	return outPoints
+}
+
+// Build creates the sweep transaction and signs all its inputs.
+func (b *Builder) Build() (*wire.MsgTx, error) {
+	tx, prevOuts, err := b.unsignedTx()
+	if err != nil {
+		return nil, err
+	}
+
+	sigHashes := txscript.NewTxSigHashes(tx)
+	for idx, in := range b.inputs {
+		err := b.sign(tx, sigHashes, idx, in, prevOuts)
+		if err != nil {
+			return nil, fmt.Errorf("error signing input %d: %v",
+				idx, err)
+		}
+	}
+	return tx, nil
+}
+
+// BuildPsbt creates the sweep transaction without signing it and returns it as
+// a PSBT that contains everything an external signer needs to know about the
+// inputs.
+func (b *Builder) BuildPsbt() (*psbt.Psbt, error) {
+	tx, prevOuts, err := b.unsignedTx()
+	if err != nil {
+		return nil, err
+	}
+	packet, err := psbt.NewPsbtFromUnsignedTx(tx)
+	if err != nil {
+		return nil, fmt.Errorf("error creating PSBT: %v", err)
+	}
+	updater, err := psbt.NewUpdater(packet)
+	if err != nil {
+		return nil, fmt.Errorf("error creating PSBT updater: %v", err)
+	}
+
+	for idx, in := range b.inputs {
+		err := b.addPsbtInput(updater, idx, in, prevOuts[idx])
+		if err != nil {
+			return nil, fmt.Errorf("error adding input %d to "+
+				"PSBT: %v", idx, err)
+		}
+	}
+	return packet, nil
+}
+
+// addPsbtInput adds the previous output, the scripts and the key information
+// of an input to the PSBT.
+func (b *Builder) addPsbtInput(updater *psbt.Updater, idx int, in *sweepInput,
+	prevOut *wire.TxOut) error {
+
+	// The PSBT version we use doesn't know the Taproot fields yet, so an
+	// external signer wouldn't know how to sign the input.
+	switch in.scriptType {
+	case ScriptTypeP2TR, ScriptTypeP2TRScriptPath:
+		return fmt.Errorf("PSBTs of Taproot inputs are not supported")
+	}
+
+	if err := updater.AddInWitnessUtxo(prevOut, idx); err != nil {
+		return err
+	}
+	err := updater.AddInSighashType(txscript.SigHashAll, idx)
+	if err != nil {
+		return err
+	}
+
+	switch in.scriptType {
+	case ScriptTypeNP2WKH:
+		pubKeyHash := btcutil.Hash160(
+			in.key.PubKey().SerializeCompressed(),
+		)
+		witnessProgram, err := p2wkhScript(pubKeyHash, b.params)
+		if err != nil {
+			return err
+		}
+		err = updater.AddInRedeemScript(witnessProgram, idx)
+		if err != nil {
+			return err
+		}
+
+	case ScriptTypeP2WSH:
+		err := updater.AddInWitnessScript(in.utxo.WitnessScript, idx)
+		if err != nil {
+			return err
+		}
+	}
+
+	if d := in.utxo.Derivation; d != nil {
+		err := updater.AddInBip32Derivation(
+			d.MasterKeyFingerprint, d.Bip32Path, d.PubKey, idx,
+		)
+		if err != nil {
+			return err
+		}
+	}
+	if in.utxo.SingleTweak != nil {
+		pInput := &updater.Upsbt.Inputs[idx]
+		pInput.Unknowns = append(pInput.Unknowns, &psbt.Unknown{
+			Key:   PsbtKeyTypeInputSignatureTweakSingle,
+			Value: in.utxo.SingleTweak,
+		})
+	}
+	return nil
+}
+
+// unsignedTx creates the unsigned sweep transaction and returns it together
+// with the outputs spent by its inputs.
+func (b *Builder) unsignedTx() (*wire.MsgTx, []*wire.TxOut, error) {
+	if len(b.inputs) == 0 {
+		return nil, nil, fmt.Errorf("no inputs to sweep")
+	}
+	if b.output == nil {
+		return nil, nil, fmt.Errorf("no sweep output set")
+	}
+	if !b.output.IsForNet(b.params) {
+		return nil, nil, fmt.Errorf("sweep address %s is not valid "+
+			"for network %s", b.output.EncodeAddress(),
+			b.params.Name)
+	}
+	sweepScript, err := payToAddrScript(b.output)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error creating sweep script: %v",
+			err)
+	}
+
+	var (
+		tx        = wire.NewMsgTx(2)
+		estimator input.TxWeightEstimator
+		prevOuts  = make([]*wire.TxOut, len(b.inputs))
+		totalIn   = int64(0)
+	)
+	for idx, in := range b.inputs {
+		if in.key == nil {
+			return nil, nil, fmt.Errorf("no key for input %d", idx)
+		}
+		pkScript, err := b.pkScript(in)
+		if err != nil {
+			return nil, nil, fmt.Errorf("error creating pk script "+
+				"of input %d: %v", idx, err)
+		}
+		prevOuts[idx] = wire.NewTxOut(in.utxo.Value, pkScript)
+		totalIn += in.utxo.Value
+
+		sequence := in.utxo.Sequence
+		if sequence == 0 {
+			sequence = rbfSequence
+		}
+		tx.TxIn = append(tx.TxIn, &wire.TxIn{
+			PreviousOutPoint: in.utxo.OutPoint,
+			Sequence:         sequence,
+		})
+
+		switch in.scriptType {
+		case ScriptTypeP2PKH:
+			estimator.AddP2PKHInput()
+
+		case ScriptTypeNP2WKH:
+			estimator.AddNestedP2WKHInput()
+
+		case ScriptTypeP2WKH:
+			estimator.AddP2WKHInput()
+
+		case ScriptTypeP2WSH:
+			estimator.AddWitnessInput(in.utxo.WitnessSize)
+
+		case ScriptTypeP2TR:
+			estimator.AddWitnessInput(taprootKeyPathWitnessSize)
+
+		case ScriptTypeP2TRScriptPath:
+			estimator.AddWitnessInput(tapScriptWitnessSize(in.utxo))
+		}
+	}
+	tx.LockTime = b.lockTime
+
+	switch b.output.(type) {
+	case *btcutil.AddressPubKeyHash:
+		estimator.AddP2PKHOutput()
+
+	case *btcutil.AddressScriptHash:
+		estimator.AddP2SHOutput()
+
+	case *btcutil.AddressWitnessPubKeyHash:
+		estimator.AddP2WKHOutput()
+
+	case *btcutil.AddressWitnessScriptHash:
+		estimator.AddP2WSHOutput()
+
+	// The script of a P2TR output is a 32 byte push just like the one of a
+	// P2WSH output, so they have the same size.
+	case *btc.AddressTaproot:
+		estimator.AddP2WSHOutput()
+
+	default:
+		return nil, nil, fmt.Errorf("unsupported sweep address type %T",
+			b.output)
+	}
+
+	b.vsize = int64(estimator.VSize())
+	b.fee = int64(math.Ceil(float64(b.vsize) * b.feeRate))
+	if totalIn-b.fee < dustLimit {
+		return nil, nil, fmt.Errorf("total input amount %d is too "+
+			"small to pay fee of %d sats", totalIn, b.fee)
+	}
+	tx.TxOut = []*wire.TxOut{wire.NewTxOut(totalIn-b.fee, sweepScript)}
+
+	return tx, prevOuts, nil
+}
+
+// pkScript returns the script of the output that is spent by the input.
+func (b *Builder) pkScript(in *sweepInput) ([]byte, error) {
+	pubKeyHash := btcutil.Hash160(in.key.PubKey().SerializeCompressed())
+	switch in.scriptType {
+	case ScriptTypeP2PKH:
+		addr, err := btcutil.NewAddressPubKeyHash(pubKeyHash, b.params)
+		if err != nil {
+			return nil, err
+		}
+		return txscript.PayToAddrScript(addr)
+
+	case ScriptTypeNP2WKH:
+		witnessProgram, err := p2wkhScript(pubKeyHash, b.params)
+		if err != nil {
+			return nil, err
+		}
+		addr, err := btcutil.NewAddressScriptHash(
+			witnessProgram, b.params,
+		)
+		if err != nil {
+			return nil, err
+		}
+		return txscript.PayToAddrScript(addr)
+
+	case ScriptTypeP2WKH:
+		return p2wkhScript(pubKeyHash, b.params)
+
+	case ScriptTypeP2WSH:
+		if len(in.utxo.WitnessScript) == 0 {
+			return nil, fmt.Errorf("witness script missing")
+		}
+		return input.WitnessScriptHash(in.utxo.WitnessScript)
+
+	case ScriptTypeP2TR:
+		_, outputKey, err := btc.TaprootOutputKey(in.key.PubKey(), nil)
+		if err != nil {
+			return nil, err
+		}
+		return taprootScript(outputKey)
+
+	case ScriptTypeP2TRScriptPath:
+		if len(in.utxo.TapLeafScript) == 0 {
+			return nil, fmt.Errorf("leaf script missing")
+		}
+		outputKey, err := btc.ParseControlBlock(
+			in.utxo.ControlBlock, in.utxo.TapLeafScript,
+		)
+		if err != nil {
+			return nil, err
+		}
+		return taprootScript(outputKey)
+
+	default:
+		return nil, fmt.Errorf("unknown script type %d", in.scriptType)
+	}
+}
+
+// sign adds the signature script and witness to the input with the given
+// index. The outputs spent by all inputs are needed for Taproot signatures.
+func (b *Builder) sign(tx *wire.MsgTx, sigHashes *txscript.TxSigHashes,
+	idx int, in *sweepInput, prevOuts []*wire.TxOut) error {
+
+	txIn := tx.TxIn[idx]
+	prevOut := prevOuts[idx]
+	switch in.scriptType {
+	case ScriptTypeP2PKH:
+		sigScript, err := txscript.SignatureScript(
+			tx, idx, prevOut.PkScript, txscript.SigHashAll, in.key,
+			true,
+		)
+		if err != nil {
+			return err
+		}
+		txIn.SignatureScript = sigScript
+
+	case ScriptTypeNP2WKH, ScriptTypeP2WKH:
+		pubKeyHash := btcutil.Hash160(
+			in.key.PubKey().SerializeCompressed(),
+		)
+		witnessProgram, err := p2wkhScript(pubKeyHash, b.params)
+		if err != nil {
+			return err
+		}
+		txIn.Witness, err = txscript.WitnessSignature(
+			tx, sigHashes, idx, prevOut.Value, witnessProgram,
+			txscript.SigHashAll, in.key, true,
+		)
+		if err != nil {
+			return err
+		}
+		if in.scriptType == ScriptTypeNP2WKH {
+			builder := txscript.NewScriptBuilder()
+			builder.AddData(witnessProgram)
+			txIn.SignatureScript, err = builder.Script()
+			if err != nil {
+				return err
+			}
+		}
+
+	case ScriptTypeP2WSH:
+		sig, err := txscript.RawTxInWitnessSignature(
+			tx, sigHashes, idx, prevOut.Value,
+			in.utxo.WitnessScript, txscript.SigHashAll, in.key,
+		)
+		if err != nil {
+			return err
+		}
+		if in.utxo.Witness != nil {
+			txIn.Witness = in.utxo.Witness(sig)
+			return nil
+		}
+		txIn.Witness = wire.TxWitness{sig, in.utxo.WitnessScript}
+
+	case ScriptTypeP2TR:
+		key, err := btc.TweakTaprootPrivKey(in.key, nil)
+		if err != nil {
+			return err
+		}
+		sig, err := taprootSignature(tx, prevOuts, idx, nil, key)
+		if err != nil {
+			return err
+		}
+		txIn.Witness = wire.TxWitness{sig}
+
+	case ScriptTypeP2TRScriptPath:
+		leafHash := btc.TapLeafHash(
+			in.utxo.ControlBlock[0]&^1, in.utxo.TapLeafScript,
+		)
+		sig, err := taprootSignature(
+			tx, prevOuts, idx, leafHash, in.key,
+		)
+		if err != nil {
+			return err
+		}
+		txIn.Witness = wire.TxWitness{
+			sig, in.utxo.TapLeafScript, in.utxo.ControlBlock,
+		}
+	}
+	return nil
+}
+
+// taprootSignature creates the Schnorr signature of a Taproot input with the
+// default sighash type, which doesn't need a sighash flag.
+func taprootSignature(tx *wire.MsgTx, prevOuts []*wire.TxOut, idx int,
+	leafHash []byte, key *btcec.PrivateKey) ([]byte, error) {
+
+	sigHash, err := btc.TaprootSigHash(tx, prevOuts, idx, leafHash)
+	if err != nil {
+		return nil, err
+	}
+	aux := make([]byte, 32)
+	if _, err := rand.Read(aux); err != nil {
+		return nil, err
+	}
+	return btc.SchnorrSign(key, sigHash, aux)
+}
+
+// tapScriptWitnessSize returns the size of the witness of a Taproot script path
+// spend, which is the signature, the leaf script and the control block.
+func tapScriptWitnessSize(utxo UTXO) int {
+	scriptLen := len(utxo.TapLeafScript)
+	controlBlockLen := len(utxo.ControlBlock)
+	return 1 + 1 + btc.SchnorrSigSize +
+		wire.VarIntSerializeSize(uint64(scriptLen)) + scriptLen +
+		wire.VarIntSerializeSize(uint64(controlBlockLen)) +
+		controlBlockLen
+}
+
+// taprootScript returns the output script of a Taproot output with the given
+// output key.
+func taprootScript(outputKey *btcec.PublicKey) ([]byte, error) {
+	builder := txscript.NewScriptBuilder()
+	builder.AddOp(txscript.OP_1)
+	builder.AddData(outputKey.SerializeCompressed()[1:])
+	return builder.Script()
+}
+
+// payToAddrScript returns the output script of the sweep address. The script
+// of a Taproot address is created here because txscript doesn't know them.
+func payToAddrScript(addr btcutil.Address) ([]byte, error) {
+	if _, ok := addr.(*btc.AddressTaproot); ok {
+		builder := txscript.NewScriptBuilder()
+		builder.AddOp(txscript.OP_1)
+		builder.AddData(addr.ScriptAddress())
+		return builder.Script()
+	}
+	return txscript.PayToAddrScript(addr)
+}
+
+func p2wkhScript(pubKeyHash []byte, params *chaincfg.Params) ([]byte, error) {
+	addr, err := btcutil.NewAddressWitnessPubKeyHash(pubKeyHash, params)
+	if err != nil {
+		return nil, err
+	}
+	return txscript.PayToAddrScript(addr)
+}